@@ -0,0 +1,325 @@
+// Package api serves telemetry and recordings over a local HTTP API for
+// third-party tools such as stream overlays and home cockpit scripts
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/export"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// DefaultPort is used when only a host is configured
+const DefaultPort = 8321
+
+// shutdownTimeout bounds how long Stop waits for requests in progress
+const shutdownTimeout = 5 * time.Second
+
+// Options configure the server
+type Options struct {
+	// Addr is the listen address, see ParseAddr
+	Addr     string
+	Source   telemetry.Source
+	Recorder *engine.Engine
+	// SecurityKey signs IGC exports, see export.Options
+	SecurityKey string
+	Logger      *logadapter.LogzWailsAdapter
+}
+
+// Status is the response of GET /v1/status
+type Status struct {
+	Connected bool          `json:"connected"`
+	Paused    bool          `json:"paused"`
+	Recording engine.Status `json:"recording"`
+}
+
+// Server is the HTTP API. All routes are under /v1.
+type Server struct {
+	opts    Options
+	handler http.Handler
+
+	mu       sync.Mutex
+	srv      *http.Server
+	listener net.Listener
+	stopped  bool
+	streams  map[*wsConn]struct{} // hijacked, so Shutdown does not close them
+}
+
+// ParseAddr turns a configured address into a listen address. A bare port
+// listens on localhost only, e.g. "8321" becomes "127.0.0.1:8321"; a host
+// without a port gets DefaultPort. Other hosts, e.g. "0.0.0.0:8321", must
+// be given explicitly.
+func ParseAddr(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("no API address given")
+	}
+	if port, err := strconv.Atoi(s); err == nil {
+		if port < 0 || port > 65535 {
+			return "", fmt.Errorf("invalid API port %d", port)
+		}
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return net.JoinHostPort(s, strconv.Itoa(DefaultPort)), nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid API port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// New returns a server, call Start to listen
+func New(opts Options) *Server {
+	s := &Server{opts: opts}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/status", s.getStatus)
+	mux.HandleFunc("GET /v1/airplane", s.getAirplane)
+	mux.HandleFunc("GET /v1/environment", s.getEnvironment)
+	mux.HandleFunc("GET /v1/simulator", s.getSimulator)
+	mux.HandleFunc("GET /v1/recordings", s.getRecordings)
+	mux.HandleFunc("GET /v1/recordings/{id}/export", s.getExport)
+	mux.HandleFunc("GET /v1/stream", s.getStream)
+	mux.HandleFunc("POST /v1/recording/start", s.postStart)
+	mux.HandleFunc("POST /v1/recording/stop", s.postStop)
+	mux.HandleFunc("POST /v1/simulator/pause", s.postPause)
+	s.handler = guard(mux)
+	return s
+}
+
+// Handler returns the routes of the server, e.g. for httptest
+func (s *Server) Handler() http.Handler {
+	return s.handler
+}
+
+// Start listens on the configured address and serves in the background
+func (s *Server) Start() error {
+	addr, err := ParseAddr(s.opts.Addr)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.srv != nil {
+		return errors.New("API server already running")
+	}
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	srv := &http.Server{Handler: s.handler, ReadHeaderTimeout: 10 * time.Second}
+	s.srv, s.listener, s.stopped = srv, ln, false
+	go func() {
+		if err := srv.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logError("[API] Server stopped: ", err)
+		}
+	}()
+	s.logInfo("[API] Listening on http://", ln.Addr())
+	return nil
+}
+
+// Addr returns the address the server listens on, empty when stopped
+func (s *Server) Addr() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
+
+// Stop shuts the server down, waiting a few seconds for requests in progress.
+// Streams are closed.
+func (s *Server) Stop() error {
+	s.mu.Lock()
+	srv := s.srv
+	s.srv, s.listener, s.stopped = nil, nil, true
+	streams := s.streams
+	s.streams = nil
+	s.mu.Unlock()
+	for conn := range streams {
+		conn.Close(closeGoingAway, "server stopping")
+	}
+	if srv == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	return srv.Shutdown(ctx)
+}
+
+// guard rejects requests from web pages. Browsers send an Origin with
+// cross-site POSTs, so a page cannot start a recording. GET requests may be
+// read by any page, e.g. an overlay in a streaming app.
+func guard(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Header.Get("Origin") != "" {
+			writeError(w, http.StatusForbidden, errors.New("requests from web pages may only read"))
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getStatus(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.status(s.opts.Source.Status(), s.opts.Recorder.Status()))
+}
+
+func (s *Server) getAirplane(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Source.GetAirplaneState())
+}
+
+func (s *Server) getEnvironment(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Source.GetEnvironmentState())
+}
+
+func (s *Server) getSimulator(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.opts.Source.GetSimulatorState())
+}
+
+func (s *Server) getRecordings(w http.ResponseWriter, r *http.Request) {
+	summaries, err := s.opts.Recorder.List()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	if summaries == nil {
+		summaries = []engine.Summary{}
+	}
+	writeJSON(w, http.StatusOK, summaries)
+}
+
+// getExport streams a recording, e.g.
+// /v1/recordings/{id}/export?format=csv&units=metric&channels=airplane.altitude
+func (s *Server) getExport(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	q := r.URL.Query()
+	format := export.Format(q.Get("format"))
+	if format == "" {
+		format = export.FormatCSV
+	}
+	ext, err := export.Extension(format)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	opts := export.Options{Units: export.Units(q.Get("units")), Pilot: q.Get("pilot"), SecurityKey: s.opts.SecurityKey}
+	if channels := q.Get("channels"); channels != "" {
+		for _, c := range strings.Split(channels, ",") {
+			opts.Channels = append(opts.Channels, strings.TrimSpace(c))
+		}
+	}
+	contentType := mime.TypeByExtension(ext)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	out := &lazyWriter{w: w, header: func() {
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", id+ext))
+	}}
+	err = export.Export(s.opts.Recorder, id, format, opts, out)
+	switch {
+	case err == nil:
+		out.flush()
+	case out.written:
+		// Too late for an error status, the client sees a truncated body
+		s.logError("[API] Export of ", id, " failed: ", err)
+	case errors.Is(err, engine.ErrRecordingNotFound):
+		writeError(w, http.StatusNotFound, err)
+	default:
+		writeError(w, http.StatusBadRequest, err)
+	}
+}
+
+func (s *Server) postStart(w http.ResponseWriter, r *http.Request) {
+	status, err := s.opts.Recorder.Start()
+	if errors.Is(err, engine.ErrAlreadyRecording) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+func (s *Server) postStop(w http.ResponseWriter, r *http.Request) {
+	summary, err := s.opts.Recorder.Stop()
+	if errors.Is(err, engine.ErrNotRecording) {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+// postPause toggles the simulator pause. The new state arrives with the next
+// simulator update, so the response has no body.
+func (s *Server) postPause(w http.ResponseWriter, r *http.Request) {
+	if !s.opts.Source.Status() {
+		writeError(w, http.StatusServiceUnavailable, errors.New("simulator not connected"))
+		return
+	}
+	s.opts.Source.TogglePause()
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// lazyWriter sets the response headers on the first write, so an export
+// that fails before writing can still answer with an error status
+type lazyWriter struct {
+	w       http.ResponseWriter
+	header  func()
+	written bool
+}
+
+func (l *lazyWriter) Write(p []byte) (int, error) {
+	l.flush()
+	return l.w.Write(p)
+}
+
+func (l *lazyWriter) flush() {
+	if !l.written {
+		l.written = true
+		l.header()
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
+
+func (s *Server) logInfo(args ...interface{}) {
+	if s.opts.Logger != nil {
+		s.opts.Logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (s *Server) logError(args ...interface{}) {
+	if s.opts.Logger != nil {
+		s.opts.Logger.Error(fmt.Sprint(args...))
+	}
+}
@@ -0,0 +1,317 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// fakeSource is a simulator connection with fixed states
+type fakeSource struct {
+	bus *telemetry.Bus
+
+	mu        sync.Mutex
+	connected bool
+	snapshot  telemetry.Snapshot
+	toggles   int
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		bus: telemetry.NewBus(),
+		snapshot: telemetry.Snapshot{
+			Airplane:    telemetry.AirplaneState{Title: "C172", Latitude: 50.1, Longitude: 14.26, Altitude: 1200},
+			Environment: telemetry.EnvironmentState{AmbientTemperature: 15, SeaLevelPressure: 29.92},
+			Simulator:   telemetry.SimulatorState{Sim: 1, AircraftLoaded: "c172.air"},
+		},
+	}
+}
+
+func (f *fakeSource) Name() string                           { return "Fake" }
+func (f *fakeSource) Bus() *telemetry.Bus                    { return f.bus }
+func (f *fakeSource) SetLogger(*logadapter.LogzWailsAdapter) {}
+func (f *fakeSource) StartConnection()                       {}
+func (f *fakeSource) StopConnection()                        {}
+
+func (f *fakeSource) GetAirplaneState() telemetry.AirplaneState {
+	return f.Snapshot().Airplane
+}
+
+func (f *fakeSource) GetEnvironmentState() telemetry.EnvironmentState {
+	return f.Snapshot().Environment
+}
+
+func (f *fakeSource) GetSimulatorState() telemetry.SimulatorState {
+	return f.Snapshot().Simulator
+}
+
+func (f *fakeSource) Status() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+func (f *fakeSource) Snapshot() telemetry.Snapshot {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.snapshot
+}
+
+func (f *fakeSource) TogglePause() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.toggles++
+	f.snapshot.Simulator.Pause = 1 - f.snapshot.Simulator.Pause
+}
+
+func (f *fakeSource) setConnected(connected bool) {
+	f.mu.Lock()
+	f.connected = connected
+	f.mu.Unlock()
+	f.bus.Publish(telemetry.TopicConnection, telemetry.ConnectionStatus{Connected: connected})
+}
+
+func newTestServer(t *testing.T) (*Server, *fakeSource) {
+	t.Helper()
+	source := newFakeSource()
+	rec := engine.New(source, engine.Options{Dir: t.TempDir()})
+	return New(Options{Source: source, Recorder: rec, SecurityKey: "test"}), source
+}
+
+// serve sends a request to the handler of s
+func serve(s *Server, method, target string, header http.Header) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(method, target, nil)
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// decode checks the status of a response and decodes its JSON body into v
+func decode(t *testing.T, w *httptest.ResponseRecorder, status int, v any) {
+	t.Helper()
+	if w.Code != status {
+		t.Fatalf("status %d, want %d: %s", w.Code, status, w.Body)
+	}
+	if v == nil {
+		return
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("content type %q, want application/json", ct)
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+		t.Fatalf("invalid JSON %q: %v", w.Body, err)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	s, source := newTestServer(t)
+	var status Status
+	decode(t, serve(s, "GET", "/v1/status", nil), http.StatusOK, &status)
+	if status.Connected || status.Paused || status.Recording.Recording {
+		t.Errorf("status %+v, want disconnected and idle", status)
+	}
+
+	source.setConnected(true)
+	source.TogglePause()
+	decode(t, serve(s, "GET", "/v1/status", nil), http.StatusOK, &status)
+	if !status.Connected || !status.Paused {
+		t.Errorf("status %+v, want connected and paused", status)
+	}
+}
+
+func TestStates(t *testing.T) {
+	s, source := newTestServer(t)
+	want := source.Snapshot()
+
+	var airplane telemetry.AirplaneState
+	decode(t, serve(s, "GET", "/v1/airplane", nil), http.StatusOK, &airplane)
+	if airplane != want.Airplane {
+		t.Errorf("airplane %+v, want %+v", airplane, want.Airplane)
+	}
+	var environment telemetry.EnvironmentState
+	decode(t, serve(s, "GET", "/v1/environment", nil), http.StatusOK, &environment)
+	if environment != want.Environment {
+		t.Errorf("environment %+v, want %+v", environment, want.Environment)
+	}
+	var simulator telemetry.SimulatorState
+	decode(t, serve(s, "GET", "/v1/simulator", nil), http.StatusOK, &simulator)
+	if simulator != want.Simulator {
+		t.Errorf("simulator %+v, want %+v", simulator, want.Simulator)
+	}
+}
+
+func TestRecording(t *testing.T) {
+	s, _ := newTestServer(t)
+	var summaries []engine.Summary
+	w := serve(s, "GET", "/v1/recordings", nil)
+	decode(t, w, http.StatusOK, &summaries)
+	if strings.TrimSpace(w.Body.String()) != "[]" {
+		t.Errorf("recordings %s, want an empty list", w.Body)
+	}
+
+	var status engine.Status
+	decode(t, serve(s, "POST", "/v1/recording/start", nil), http.StatusOK, &status)
+	if !status.Recording || status.ID == "" {
+		t.Fatalf("status %+v, want recording", status)
+	}
+	decode(t, serve(s, "POST", "/v1/recording/start", nil), http.StatusConflict, &map[string]string{})
+
+	var summary engine.Summary
+	decode(t, serve(s, "POST", "/v1/recording/stop", nil), http.StatusOK, &summary)
+	if summary.ID != status.ID {
+		t.Errorf("stopped %s, want %s", summary.ID, status.ID)
+	}
+	decode(t, serve(s, "POST", "/v1/recording/stop", nil), http.StatusConflict, &map[string]string{})
+
+	decode(t, serve(s, "GET", "/v1/recordings", nil), http.StatusOK, &summaries)
+	if len(summaries) != 1 || summaries[0].ID != status.ID {
+		t.Errorf("recordings %+v, want %s", summaries, status.ID)
+	}
+}
+
+func TestExport(t *testing.T) {
+	s, _ := newTestServer(t)
+	var status engine.Status
+	decode(t, serve(s, "POST", "/v1/recording/start", nil), http.StatusOK, &status)
+	decode(t, serve(s, "POST", "/v1/recording/stop", nil), http.StatusOK, nil)
+
+	w := serve(s, "GET", "/v1/recordings/"+status.ID+"/export?format=csv&units=metric&channels=airplane.altitude", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if got, want := w.Header().Get("Content-Disposition"), `attachment; filename="`+status.ID+`.csv"`; got != want {
+		t.Errorf("content disposition %q, want %q", got, want)
+	}
+	if !strings.Contains(w.Body.String(), "altitude") {
+		t.Errorf("CSV without the altitude column:\n%s", w.Body)
+	}
+
+	for _, c := range []struct {
+		name, target string
+		status       int
+	}{
+		{"unknown recording", "/v1/recordings/20000101-000000/export", http.StatusNotFound},
+		{"unknown format", "/v1/recordings/" + status.ID + "/export?format=doc", http.StatusBadRequest},
+		{"unknown channel", "/v1/recordings/" + status.ID + "/export?channels=airplane.warp", http.StatusBadRequest},
+	} {
+		w := serve(s, "GET", c.target, nil)
+		var body map[string]string
+		if w.Code != c.status {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, c.status)
+			continue
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body["error"] == "" {
+			t.Errorf("%s: body %q, want a JSON error", c.name, w.Body)
+		}
+	}
+}
+
+func TestPause(t *testing.T) {
+	s, source := newTestServer(t)
+	decode(t, serve(s, "POST", "/v1/simulator/pause", nil), http.StatusServiceUnavailable, &map[string]string{})
+	if source.toggles != 0 {
+		t.Error("paused while disconnected")
+	}
+	source.setConnected(true)
+	decode(t, serve(s, "POST", "/v1/simulator/pause", nil), http.StatusAccepted, nil)
+	if source.toggles != 1 {
+		t.Errorf("%d toggles, want 1", source.toggles)
+	}
+}
+
+func TestOriginGuard(t *testing.T) {
+	s, source := newTestServer(t)
+	source.setConnected(true)
+	page := http.Header{"Origin": {"https://example.com"}}
+	for _, target := range []string{"/v1/recording/start", "/v1/recording/stop", "/v1/simulator/pause"} {
+		decode(t, serve(s, "POST", target, page), http.StatusForbidden, &map[string]string{})
+	}
+	if s.opts.Recorder.Status().Recording || source.toggles != 0 {
+		t.Error("a cross-site POST changed the recorder or the simulator")
+	}
+
+	// Pages may read, e.g. an overlay
+	w := serve(s, "GET", "/v1/status", page)
+	decode(t, w, http.StatusOK, &Status{})
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("GET without Access-Control-Allow-Origin")
+	}
+}
+
+func TestRouting(t *testing.T) {
+	s, _ := newTestServer(t)
+	for _, c := range []struct {
+		method, target string
+		status         int
+	}{
+		{"GET", "/v1/recording/start", http.StatusMethodNotAllowed},
+		{"POST", "/v1/status", http.StatusMethodNotAllowed},
+		{"GET", "/v1/unknown", http.StatusNotFound},
+		{"GET", "/status", http.StatusNotFound},
+		// The stream needs a WebSocket handshake and valid topics
+		{"GET", "/v1/stream", http.StatusBadRequest},
+		{"GET", "/v1/stream?topics=weather", http.StatusBadRequest},
+		{"GET", "/v1/stream?rate=fast", http.StatusBadRequest},
+		{"GET", "/v1/stream?rate=1000", http.StatusBadRequest},
+	} {
+		if w := serve(s, c.method, c.target, nil); w.Code != c.status {
+			t.Errorf("%s %s: status %d, want %d", c.method, c.target, w.Code, c.status)
+		}
+	}
+}
+
+func TestParseAddr(t *testing.T) {
+	for _, c := range []struct {
+		in, want string
+		err      bool
+	}{
+		{"8321", "127.0.0.1:8321", false},
+		{" 9000 ", "127.0.0.1:9000", false},
+		{"0.0.0.0:8321", "0.0.0.0:8321", false},
+		{"localhost", "localhost:8321", false},
+		{"[::1]:80", "[::1]:80", false},
+		{"", "", true},
+		{"70000", "", true},
+		{"host:port", "", true},
+	} {
+		got, err := ParseAddr(c.in)
+		if (err != nil) != c.err || got != c.want {
+			t.Errorf("ParseAddr(%q) = %q, %v, want %q", c.in, got, err, c.want)
+		}
+	}
+}
+
+func TestStartStop(t *testing.T) {
+	s, _ := newTestServer(t)
+	s.opts.Addr = "127.0.0.1:0"
+	if err := s.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Start(); err == nil {
+		t.Error("started twice")
+	}
+	resp, err := http.Get("http://" + s.Addr() + "/v1/status")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("status %d over TCP", resp.StatusCode)
+	}
+	if err := s.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	if s.Addr() != "" {
+		t.Error("address kept after Stop")
+	}
+}
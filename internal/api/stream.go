@@ -0,0 +1,356 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/approach"
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/exceedance"
+	"github.com/mycrew-online/flight-data-recorder/internal/phase"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	// streamBuffer is the number of bus messages queued per client. A
+	// client that falls behind loses the oldest messages.
+	streamBuffer = 64
+	// writeTimeout drops a client that does not take a message in time
+	writeTimeout = 5 * time.Second
+	// pingInterval keeps idle connections alive, a client that does not
+	// answer within readTimeout is dropped
+	pingInterval = 30 * time.Second
+	readTimeout  = 2 * pingInterval
+	// maxRate bounds the rate clients may ask for, in messages per second
+	maxRate = 100
+)
+
+// streamTopics maps the topics clients subscribe to onto bus topics
+var streamTopics = map[string][]telemetry.Topic{
+	"airplane":    {telemetry.TopicAirplane},
+	"environment": {telemetry.TopicEnvironment},
+	"simulator":   {telemetry.TopicSimulator},
+	"status":      {telemetry.TopicConnection, engine.TopicStatus},
+	"phase":       {phase.TopicPhase},
+	"alerts":      {exceedance.TopicExceedance, approach.TopicReport},
+}
+
+// stateTopics are downsampled to the rate of a client, other topics are
+// events and always delivered
+var stateTopics = []string{"airplane", "environment", "simulator"}
+
+// subscription is what a client receives. A zero rate sends every update.
+type subscription struct {
+	Topics []string `json:"topics"`
+	Rate   float64  `json:"rate"`
+}
+
+// frame is a message to a client. Kind tells alerts apart, it is
+// exceedance or approach.
+type frame struct {
+	Topic string    `json:"topic"`
+	Kind  string    `json:"kind,omitempty"`
+	Time  time.Time `json:"time"`
+	Data  any       `json:"data"`
+}
+
+// validate checks the topics and rate, no topics subscribe to all
+func (sub *subscription) validate() error {
+	if len(sub.Topics) == 0 {
+		for name := range streamTopics {
+			sub.Topics = append(sub.Topics, name)
+		}
+		sort.Strings(sub.Topics)
+	}
+	for _, name := range sub.Topics {
+		if _, ok := streamTopics[name]; !ok {
+			return fmt.Errorf("unknown topic %q, want one of %s", name, strings.Join(topicNames(), ", "))
+		}
+	}
+	if sub.Rate < 0 || sub.Rate > maxRate {
+		return fmt.Errorf("rate must be between 0 and %d", maxRate)
+	}
+	return nil
+}
+
+func (sub subscription) has(name string) bool {
+	for _, t := range sub.Topics {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+func topicNames() []string {
+	var names []string
+	for name := range streamTopics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// getStream upgrades to a WebSocket and streams the subscribed topics, e.g.
+// /v1/stream?topics=airplane,status&rate=5. Clients change the subscription
+// by sending {"topics": [...], "rate": 5}.
+func (s *Server) getStream(w http.ResponseWriter, r *http.Request) {
+	var sub subscription
+	q := r.URL.Query()
+	if topics := q.Get("topics"); topics != "" {
+		for _, t := range strings.Split(topics, ",") {
+			sub.Topics = append(sub.Topics, strings.TrimSpace(t))
+		}
+	}
+	if rate := q.Get("rate"); rate != "" {
+		v, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, fmt.Errorf("invalid rate %q", rate))
+			return
+		}
+		sub.Rate = v
+	}
+	if err := sub.validate(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	conn, err := upgrade(w, r)
+	if err != nil {
+		return
+	}
+	if !s.track(conn) {
+		conn.Close(closeGoingAway, "server stopping")
+		return
+	}
+	defer s.untrack(conn)
+	s.logInfo("[API] Stream client ", r.RemoteAddr, " connected")
+
+	updates := make(chan subscription, 1)
+	done := make(chan struct{})
+	go s.readSubscriptions(conn, updates, done)
+	err = s.stream(conn, sub, updates, done)
+	conn.Close(closeNormal, "")
+	if err != nil && !errors.Is(err, errClosed) {
+		s.logInfo("[API] Stream client ", r.RemoteAddr, " dropped: ", err)
+		return
+	}
+	s.logInfo("[API] Stream client ", r.RemoteAddr, " disconnected")
+}
+
+// readSubscriptions reads subscription changes until the client goes away.
+// Only the latest change is kept.
+func (s *Server) readSubscriptions(conn *wsConn, updates chan subscription, done chan struct{}) {
+	defer close(done)
+	for {
+		data, err := conn.ReadMessage(readTimeout)
+		if err != nil {
+			return
+		}
+		var sub subscription
+		if err := json.Unmarshal(data, &sub); err != nil {
+			err = fmt.Errorf("invalid subscription: %w", err)
+			s.sendError(conn, err)
+			continue
+		}
+		if err := sub.validate(); err != nil {
+			s.sendError(conn, err)
+			continue
+		}
+		select {
+		case <-updates:
+		default:
+		}
+		updates <- sub
+	}
+}
+
+// stream forwards bus messages to the client. It runs on its own
+// subscription, so a slow client loses messages instead of stalling the
+// manager, and is dropped when a write times out.
+func (s *Server) stream(conn *wsConn, sub subscription, updates chan subscription, done chan struct{}) error {
+	bus := s.opts.Source.Bus()
+	var (
+		busSub  *telemetry.Subscription
+		ticker  *time.Ticker
+		tick    <-chan time.Time
+		pending map[telemetry.Topic]telemetry.Message
+	)
+	apply := func(next subscription) error {
+		if busSub != nil {
+			busSub.Close()
+		}
+		if ticker != nil {
+			ticker.Stop()
+			ticker, tick = nil, nil
+		}
+		sub = next
+		var topics []telemetry.Topic
+		for _, name := range sub.Topics {
+			topics = append(topics, streamTopics[name]...)
+		}
+		busSub = bus.Subscribe(telemetry.SubscribeOptions{
+			Topics: topics,
+			Buffer: streamBuffer,
+			Policy: telemetry.DropOldest,
+		})
+		pending = map[telemetry.Topic]telemetry.Message{}
+		if sub.Rate > 0 {
+			ticker = time.NewTicker(time.Duration(float64(time.Second) / sub.Rate))
+			tick = ticker.C
+		}
+		return s.sendSnapshot(conn, sub)
+	}
+	defer func() {
+		busSub.Close()
+		if ticker != nil {
+			ticker.Stop()
+		}
+	}()
+	if err := apply(sub); err != nil {
+		return err
+	}
+	ping := time.NewTicker(pingInterval)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-done:
+			return errClosed
+		case next := <-updates:
+			if err := apply(next); err != nil {
+				return err
+			}
+		case msg, ok := <-busSub.C():
+			if !ok {
+				return errClosed
+			}
+			if tick != nil && isState(msg.Topic) {
+				pending[msg.Topic] = msg
+				continue
+			}
+			if err := s.send(conn, msg); err != nil {
+				return err
+			}
+		case <-tick:
+			for _, name := range stateTopics {
+				topic := telemetry.Topic(name)
+				if msg, ok := pending[topic]; ok {
+					delete(pending, topic)
+					if err := s.send(conn, msg); err != nil {
+						return err
+					}
+				}
+			}
+		case <-ping.C:
+			if err := conn.Ping(writeTimeout); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func isState(topic telemetry.Topic) bool {
+	switch topic {
+	case telemetry.TopicAirplane, telemetry.TopicEnvironment, telemetry.TopicSimulator:
+		return true
+	}
+	return false
+}
+
+// sendSnapshot sends the current state of the subscribed topics, so a new
+// client does not wait for the next change
+func (s *Server) sendSnapshot(conn *wsConn, sub subscription) error {
+	now := time.Now()
+	src := s.opts.Source
+	if sub.has("status") {
+		if err := s.write(conn, frame{Topic: "status", Time: now, Data: s.status(src.Status(), s.opts.Recorder.Status())}); err != nil {
+			return err
+		}
+	}
+	if !src.Status() {
+		return nil
+	}
+	snapshot := src.Snapshot()
+	for _, f := range []frame{
+		{Topic: "airplane", Time: now, Data: snapshot.Airplane},
+		{Topic: "environment", Time: now, Data: snapshot.Environment},
+		{Topic: "simulator", Time: now, Data: snapshot.Simulator},
+	} {
+		if !sub.has(f.Topic) {
+			continue
+		}
+		if err := s.write(conn, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// send converts a bus message to a frame. Approach reports are alerts when
+// the approach was not stable or ended in a go-around.
+func (s *Server) send(conn *wsConn, msg telemetry.Message) error {
+	f := frame{Time: msg.Time, Data: msg.Payload}
+	switch p := msg.Payload.(type) {
+	case telemetry.ConnectionStatus:
+		f.Topic, f.Data = "status", s.status(p.Connected, s.opts.Recorder.Status())
+	case engine.Status:
+		f.Topic, f.Data = "status", s.status(s.opts.Source.Status(), p)
+	case exceedance.Event:
+		f.Topic, f.Kind = "alerts", exceedance.ReportKind
+	case approach.Report:
+		if p.Stable && p.Outcome == approach.Landed {
+			return nil
+		}
+		f.Topic, f.Kind = "alerts", approach.ReportKind
+	default:
+		f.Topic = string(msg.Topic)
+	}
+	return s.write(conn, f)
+}
+
+func (s *Server) status(connected bool, recording engine.Status) Status {
+	return Status{
+		Connected: connected,
+		Paused:    connected && s.opts.Source.GetSimulatorState().Pause != 0,
+		Recording: recording,
+	}
+}
+
+func (s *Server) write(conn *wsConn, f frame) error {
+	data, err := json.Marshal(f)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data, writeTimeout)
+}
+
+func (s *Server) sendError(conn *wsConn, err error) {
+	data, _ := json.Marshal(map[string]string{"error": err.Error()})
+	conn.WriteText(data, writeTimeout)
+}
+
+// track registers a stream so Stop can close it, false once stopped
+func (s *Server) track(conn *wsConn) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return false
+	}
+	if s.streams == nil {
+		s.streams = map[*wsConn]struct{}{}
+	}
+	s.streams[conn] = struct{}{}
+	return true
+}
+
+func (s *Server) untrack(conn *wsConn) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.streams, conn)
+}
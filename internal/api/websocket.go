@@ -0,0 +1,259 @@
+package api
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// The server side of RFC 6455, limited to what the stream needs: text
+// messages in both directions, ping, pong and close. Extensions and
+// subprotocols are not negotiated.
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// Opcodes
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// Close codes
+const (
+	closeNormal      = 1000
+	closeGoingAway   = 1001
+	closeProtocol    = 1002
+	closeUnsupported = 1003
+	closeTooBig      = 1009
+)
+
+// maxMessageSize bounds messages from clients, which only send subscriptions
+const maxMessageSize = 4096
+
+var errClosed = errors.New("websocket closed")
+
+// wsConn is an upgraded connection. Reads must come from a single
+// goroutine, writes are safe for concurrent use.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+
+	writeMu sync.Mutex
+	closed  bool
+}
+
+// upgrade answers the opening handshake and takes over the connection. A
+// failed handshake is answered with an error.
+func upgrade(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		return nil, handshakeError(w, http.StatusBadRequest, "not a websocket handshake")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		return nil, handshakeError(w, http.StatusBadRequest, "unsupported websocket version")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		return nil, handshakeError(w, http.StatusBadRequest, "missing Sec-WebSocket-Key")
+	}
+	hj, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, handshakeError(w, http.StatusInternalServerError, "connection cannot be upgraded")
+	}
+	conn, rw, err := hj.Hijack()
+	if err != nil {
+		return nil, handshakeError(w, http.StatusInternalServerError, err.Error())
+	}
+	sum := sha1.Sum([]byte(key + websocketGUID))
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n"
+	conn.SetDeadline(time.Time{})
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, br: rw.Reader}, nil
+}
+
+func handshakeError(w http.ResponseWriter, status int, message string) error {
+	err := errors.New(message)
+	writeError(w, status, err)
+	return err
+}
+
+func headerContains(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage returns the next text message. Pings are answered while
+// reading. It returns errClosed when the client closes the connection.
+func (c *wsConn) ReadMessage(timeout time.Duration) ([]byte, error) {
+	var message []byte
+	started := false
+	for {
+		c.conn.SetReadDeadline(time.Now().Add(timeout))
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload, timeout); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		case opClose:
+			code := closeNormal
+			if len(payload) >= 2 {
+				code = int(binary.BigEndian.Uint16(payload))
+			}
+			c.Close(code, "")
+			return nil, errClosed
+		case opText:
+			if started {
+				return nil, c.fail(closeProtocol, "new message before the last one ended")
+			}
+			started, message = true, payload
+		case opContinuation:
+			if !started {
+				return nil, c.fail(closeProtocol, "continuation without a message")
+			}
+			if len(message)+len(payload) > maxMessageSize {
+				return nil, c.fail(closeTooBig, "message too big")
+			}
+			message = append(message, payload...)
+		case opBinary:
+			return nil, c.fail(closeUnsupported, "binary messages are not supported")
+		default:
+			return nil, c.fail(closeProtocol, "unknown opcode")
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// readFrame reads a single masked client frame
+func (c *wsConn) readFrame() (fin bool, op byte, payload []byte, err error) {
+	var head [2]byte
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+	fin, op = head[0]&0x80 != 0, head[0]&0x0f
+	if head[0]&0x70 != 0 {
+		return fin, op, nil, c.fail(closeProtocol, "reserved bits set")
+	}
+	if head[1]&0x80 == 0 {
+		return fin, op, nil, c.fail(closeProtocol, "client frames must be masked")
+	}
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err = io.ReadFull(c.br, ext[:]); err != nil {
+			return
+		}
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	if op >= opClose && (size > 125 || !fin) {
+		return fin, op, nil, c.fail(closeProtocol, "invalid control frame")
+	}
+	if size > maxMessageSize {
+		return fin, op, nil, c.fail(closeTooBig, "message too big")
+	}
+	var mask [4]byte
+	if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, op, payload, nil
+}
+
+// WriteText sends a text message. A client that does not take it within
+// timeout is too slow and the write fails.
+func (c *wsConn) WriteText(data []byte, timeout time.Duration) error {
+	return c.writeFrame(opText, data, timeout)
+}
+
+// Ping asks the client for a pong, which resets the read timeout
+func (c *wsConn) Ping(timeout time.Duration) error {
+	return c.writeFrame(opPing, nil, timeout)
+}
+
+func (c *wsConn) writeFrame(op byte, payload []byte, timeout time.Duration) error {
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return errClosed
+	}
+	head := make([]byte, 2, 10+len(payload))
+	head[0] = 0x80 | op
+	switch n := len(payload); {
+	case n < 126:
+		head[1] = byte(n)
+	case n <= 0xffff:
+		head[1] = 126
+		head = binary.BigEndian.AppendUint16(head, uint16(n))
+	default:
+		head[1] = 127
+		head = binary.BigEndian.AppendUint64(head, uint64(n))
+	}
+	c.conn.SetWriteDeadline(time.Now().Add(timeout))
+	_, err := c.conn.Write(append(head, payload...))
+	return err
+}
+
+// Close sends a close frame and closes the connection
+func (c *wsConn) Close(code int, reason string) error {
+	payload := binary.BigEndian.AppendUint16(nil, uint16(code))
+	c.writeFrame(opClose, append(payload, reason...), time.Second)
+	c.writeMu.Lock()
+	defer c.writeMu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	return c.conn.Close()
+}
+
+// fail closes the connection after a protocol error
+func (c *wsConn) fail(code int, reason string) error {
+	c.Close(code, reason)
+	return fmt.Errorf("websocket: %s", reason)
+}
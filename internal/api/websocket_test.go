@@ -0,0 +1,455 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const testTimeout = 5 * time.Second
+
+// The handshake example of RFC 6455
+const (
+	testKey    = "dGhlIHNhbXBsZSBub25jZQ=="
+	testAccept = "s3pPLMBiTxaQ9kYGzzhZRbK+xOo="
+)
+
+var testMask = [4]byte{0x37, 0xfa, 0x21, 0x3d}
+
+// tcpPair returns both ends of a loopback TCP connection. Unlike net.Pipe
+// it buffers, so a side may write without the other reading.
+func tcpPair(t *testing.T) (client, server net.Conn) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := ln.Accept()
+		accepted <- conn
+	}()
+	client, err = net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	server = <-accepted
+	if server == nil {
+		t.Fatal("no connection accepted")
+	}
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+// newTestConn returns a client connection and the server side as wsConn
+func newTestConn(t *testing.T) (net.Conn, *wsConn) {
+	t.Helper()
+	client, server := tcpPair(t)
+	return client, &wsConn{conn: server, br: bufio.NewReader(server)}
+}
+
+// clientFrame encodes a frame as a client sends it, masked unless unmasked
+func clientFrame(fin bool, op byte, payload []byte, unmasked bool) []byte {
+	b := []byte{op, 0}
+	if fin {
+		b[0] |= 0x80
+	}
+	if !unmasked {
+		b[1] = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		b[1] |= byte(n)
+	case n <= 0xffff:
+		b[1] |= 126
+		b = binary.BigEndian.AppendUint16(b, uint16(n))
+	default:
+		b[1] |= 127
+		b = binary.BigEndian.AppendUint64(b, uint64(n))
+	}
+	if unmasked {
+		return append(b, payload...)
+	}
+	b = append(b, testMask[:]...)
+	for i, c := range payload {
+		b = append(b, c^testMask[i%4])
+	}
+	return b
+}
+
+type serverFrame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+// readServerFrame reads a frame sent by the server, which must not be masked
+func readServerFrame(t *testing.T, conn net.Conn, br *bufio.Reader) serverFrame {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	var head [2]byte
+	if _, err := io.ReadFull(br, head[:]); err != nil {
+		t.Fatalf("reading frame: %v", err)
+	}
+	if head[1]&0x80 != 0 {
+		t.Fatal("server frame is masked")
+	}
+	size := uint64(head[1] & 0x7f)
+	switch size {
+	case 126:
+		var ext [2]byte
+		io.ReadFull(br, ext[:])
+		size = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		io.ReadFull(br, ext[:])
+		size = binary.BigEndian.Uint64(ext[:])
+	}
+	payload := make([]byte, size)
+	if _, err := io.ReadFull(br, payload); err != nil {
+		t.Fatalf("reading payload: %v", err)
+	}
+	return serverFrame{fin: head[0]&0x80 != 0, op: head[0] & 0x0f, payload: payload}
+}
+
+// closeCode returns the status code of a close frame
+func closeCode(t *testing.T, f serverFrame) int {
+	t.Helper()
+	if f.op != opClose {
+		t.Fatalf("opcode %#x, want close", f.op)
+	}
+	if len(f.payload) < 2 {
+		t.Fatal("close frame without a code")
+	}
+	return int(binary.BigEndian.Uint16(f.payload))
+}
+
+func TestHandshake(t *testing.T) {
+	s, _ := newTestServer(t)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+
+	valid := http.Header{
+		"Connection":            {"keep-alive, Upgrade"},
+		"Upgrade":               {"websocket"},
+		"Sec-Websocket-Version": {"13"},
+		"Sec-Websocket-Key":     {testKey},
+	}
+	for _, c := range []struct {
+		name   string
+		change func(h http.Header)
+	}{
+		{"no upgrade", func(h http.Header) { h.Del("Upgrade") }},
+		{"no connection upgrade", func(h http.Header) { h.Set("Connection", "keep-alive") }},
+		{"old version", func(h http.Header) { h.Set("Sec-Websocket-Version", "8") }},
+		{"no key", func(h http.Header) { h.Del("Sec-Websocket-Key") }},
+	} {
+		h := valid.Clone()
+		c.change(h)
+		w := serve(s, "GET", "/v1/stream", h)
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d, want %d", c.name, w.Code, http.StatusBadRequest)
+		}
+		if c.name == "old version" && w.Header().Get("Sec-WebSocket-Version") != "13" {
+			t.Errorf("%s: supported version not announced", c.name)
+		}
+	}
+
+	conn, br := dialStream(t, srv, "topics=status")
+	defer conn.Close()
+	if topic, _ := readStreamFrame(t, conn, br); topic != "status" {
+		t.Errorf("first message on %s, want status", topic)
+	}
+}
+
+// dialStream opens /v1/stream with query on srv and checks the handshake
+func dialStream(t *testing.T, srv *httptest.Server, query string) (net.Conn, *bufio.Reader) {
+	t.Helper()
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	request := "GET /v1/stream?" + query + " HTTP/1.1\r\n" +
+		"Host: " + srv.Listener.Addr().String() + "\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Sec-WebSocket-Version: 13\r\n" +
+		"Sec-WebSocket-Key: " + testKey + "\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	conn.SetReadDeadline(time.Now().Add(testTimeout))
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("handshake status %d, want %d", resp.StatusCode, http.StatusSwitchingProtocols)
+	}
+	if got := resp.Header.Get("Sec-WebSocket-Accept"); got != testAccept {
+		t.Fatalf("Sec-WebSocket-Accept %q, want %q", got, testAccept)
+	}
+	return conn, br
+}
+
+// readStreamFrame reads the next text message of the stream as a frame
+func readStreamFrame(t *testing.T, conn net.Conn, br *bufio.Reader) (string, json.RawMessage) {
+	t.Helper()
+	for {
+		f := readServerFrame(t, conn, br)
+		if f.op == opPing {
+			continue
+		}
+		if f.op != opText || !f.fin {
+			t.Fatalf("frame with opcode %#x, fin %v, want a text message", f.op, f.fin)
+		}
+		var msg struct {
+			Topic string          `json:"topic"`
+			Data  json.RawMessage `json:"data"`
+		}
+		if err := json.Unmarshal(f.payload, &msg); err != nil {
+			t.Fatalf("invalid message %q: %v", f.payload, err)
+		}
+		return msg.Topic, msg.Data
+	}
+}
+
+func TestStream(t *testing.T) {
+	s, source := newTestServer(t)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	conn, br := dialStream(t, srv, "topics=status")
+	defer conn.Close()
+
+	// The current status first, then changes
+	topic, data := readStreamFrame(t, conn, br)
+	var status Status
+	json.Unmarshal(data, &status)
+	if topic != "status" || status.Connected {
+		t.Fatalf("first message %s %s, want the disconnected status", topic, data)
+	}
+	source.setConnected(true)
+	topic, data = readStreamFrame(t, conn, br)
+	json.Unmarshal(data, &status)
+	if topic != "status" || !status.Connected {
+		t.Fatalf("message %s %s, want the connected status", topic, data)
+	}
+
+	// Change the subscription with a fragmented message and a ping between
+	// the fragments, which must be answered at once
+	sub := []byte(`{"topics": ["airplane"]}`)
+	conn.Write(clientFrame(false, opText, sub[:10], false))
+	conn.Write(clientFrame(true, opPing, []byte("are you there"), false))
+	if f := readServerFrame(t, conn, br); f.op != opPong || string(f.payload) != "are you there" {
+		t.Fatalf("answer to ping: opcode %#x %q, want pong with the ping payload", f.op, f.payload)
+	}
+	conn.Write(clientFrame(true, opContinuation, sub[10:], false))
+
+	// The snapshot of the new topic, then updates
+	topic, data = readStreamFrame(t, conn, br)
+	var airplane telemetry.AirplaneState
+	json.Unmarshal(data, &airplane)
+	if topic != "airplane" || airplane.Title != "C172" {
+		t.Fatalf("message %s %s, want the airplane snapshot", topic, data)
+	}
+	source.bus.Publish(telemetry.TopicAirplane, telemetry.AirplaneState{Title: "A320"})
+	topic, data = readStreamFrame(t, conn, br)
+	json.Unmarshal(data, &airplane)
+	if topic != "airplane" || airplane.Title != "A320" {
+		t.Fatalf("message %s %s, want the airplane update", topic, data)
+	}
+
+	// An invalid subscription is answered with an error and keeps the stream
+	conn.Write(clientFrame(true, opText, []byte(`{"topics": ["weather"]}`), false))
+	f := readServerFrame(t, conn, br)
+	if f.op != opText || !strings.Contains(string(f.payload), "unknown topic") {
+		t.Fatalf("answer to an invalid subscription %q, want an error", f.payload)
+	}
+
+	// Closing is answered with a close frame
+	conn.Write(clientFrame(true, opClose, binary.BigEndian.AppendUint16(nil, closeNormal), false))
+	for {
+		f := readServerFrame(t, conn, br)
+		if f.op == opText {
+			continue // an update sent before the close
+		}
+		if code := closeCode(t, f); code != closeNormal {
+			t.Errorf("close code %d, want %d", code, closeNormal)
+		}
+		break
+	}
+}
+
+func TestStreamStop(t *testing.T) {
+	s, _ := newTestServer(t)
+	srv := httptest.NewServer(s.Handler())
+	defer srv.Close()
+	conn, br := dialStream(t, srv, "topics=status")
+	defer conn.Close()
+	readStreamFrame(t, conn, br)
+
+	// Wait for the stream to be tracked before stopping
+	deadline := time.Now().Add(testTimeout)
+	for {
+		s.mu.Lock()
+		n := len(s.streams)
+		s.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("stream not tracked")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	s.Stop()
+	if code := closeCode(t, readServerFrame(t, conn, br)); code != closeGoingAway {
+		t.Errorf("close code %d, want %d", code, closeGoingAway)
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	long := bytes.Repeat([]byte("x"), 300) // needs the 16 bit length
+	for _, c := range []struct {
+		name   string
+		frames [][]byte
+		want   string
+	}{
+		{"single frame", [][]byte{clientFrame(true, opText, []byte("hello"), false)}, "hello"},
+		{"empty", [][]byte{clientFrame(true, opText, nil, false)}, ""},
+		{"16 bit length", [][]byte{clientFrame(true, opText, long, false)}, string(long)},
+		{"fragments", [][]byte{
+			clientFrame(false, opText, []byte("he"), false),
+			clientFrame(false, opContinuation, []byte("ll"), false),
+			clientFrame(true, opContinuation, []byte("o"), false),
+		}, "hello"},
+		{"pong between messages", [][]byte{
+			clientFrame(true, opPong, nil, false),
+			clientFrame(true, opText, []byte("hello"), false),
+		}, "hello"},
+	} {
+		client, conn := newTestConn(t)
+		for _, f := range c.frames {
+			client.Write(f)
+		}
+		got, err := conn.ReadMessage(testTimeout)
+		if err != nil || string(got) != c.want {
+			t.Errorf("%s: %q, %v, want %q", c.name, got, err, c.want)
+		}
+	}
+}
+
+func TestReadMessageErrors(t *testing.T) {
+	half := bytes.Repeat([]byte("x"), maxMessageSize/2+1)
+	for _, c := range []struct {
+		name   string
+		frames [][]byte
+		code   int
+	}{
+		{"not masked", [][]byte{clientFrame(true, opText, []byte("hello"), true)}, closeProtocol},
+		{"reserved bits", [][]byte{append([]byte{0xc1}, clientFrame(true, opText, nil, false)[1:]...)}, closeProtocol},
+		{"binary", [][]byte{clientFrame(true, opBinary, []byte{1}, false)}, closeUnsupported},
+		{"unknown opcode", [][]byte{clientFrame(true, 0x3, nil, false)}, closeProtocol},
+		{"fragmented ping", [][]byte{clientFrame(false, opPing, nil, false)}, closeProtocol},
+		{"long ping", [][]byte{clientFrame(true, opPing, make([]byte, 126), false)}, closeProtocol},
+		{"frame too big", [][]byte{clientFrame(true, opText, make([]byte, maxMessageSize+1), false)}, closeTooBig},
+		{"message too big", [][]byte{
+			clientFrame(false, opText, half, false),
+			clientFrame(true, opContinuation, half, false),
+		}, closeTooBig},
+		{"continuation first", [][]byte{clientFrame(true, opContinuation, []byte("x"), false)}, closeProtocol},
+		{"message in a message", [][]byte{
+			clientFrame(false, opText, []byte("a"), false),
+			clientFrame(true, opText, []byte("b"), false),
+		}, closeProtocol},
+	} {
+		client, conn := newTestConn(t)
+		for _, f := range c.frames {
+			client.Write(f)
+		}
+		if _, err := conn.ReadMessage(testTimeout); err == nil || errors.Is(err, errClosed) {
+			t.Errorf("%s: error %v, want a protocol error", c.name, err)
+			continue
+		}
+		if code := closeCode(t, readServerFrame(t, client, bufio.NewReader(client))); code != c.code {
+			t.Errorf("%s: close code %d, want %d", c.name, code, c.code)
+		}
+	}
+}
+
+func TestClientClose(t *testing.T) {
+	client, conn := newTestConn(t)
+	client.Write(clientFrame(true, opClose, binary.BigEndian.AppendUint16(nil, closeGoingAway), false))
+	if _, err := conn.ReadMessage(testTimeout); !errors.Is(err, errClosed) {
+		t.Fatalf("error %v, want %v", err, errClosed)
+	}
+	// The close code is echoed
+	if code := closeCode(t, readServerFrame(t, client, bufio.NewReader(client))); code != closeGoingAway {
+		t.Errorf("close code %d, want %d", code, closeGoingAway)
+	}
+	if err := conn.WriteText([]byte("late"), time.Second); !errors.Is(err, errClosed) {
+		t.Errorf("write after close: %v, want %v", err, errClosed)
+	}
+}
+
+func TestWriteFrames(t *testing.T) {
+	client, conn := newTestConn(t)
+	br := bufio.NewReader(client)
+	for _, size := range []int{0, 125, 126, 0xffff, 0x10000} {
+		payload := bytes.Repeat([]byte("y"), size)
+		go conn.WriteText(payload, testTimeout)
+		f := readServerFrame(t, client, br)
+		if !f.fin || f.op != opText || !bytes.Equal(f.payload, payload) {
+			t.Errorf("%d bytes: fin %v, opcode %#x, %d bytes", size, f.fin, f.op, len(f.payload))
+		}
+	}
+	if err := conn.Ping(testTimeout); err != nil {
+		t.Fatal(err)
+	}
+	if f := readServerFrame(t, client, br); f.op != opPing || len(f.payload) != 0 {
+		t.Errorf("ping: opcode %#x with %d bytes", f.op, len(f.payload))
+	}
+	conn.Close(closeGoingAway, "bye")
+	f := readServerFrame(t, client, br)
+	if code := closeCode(t, f); code != closeGoingAway || string(f.payload[2:]) != "bye" {
+		t.Errorf("close %d %q, want %d \"bye\"", code, f.payload[2:], closeGoingAway)
+	}
+}
+
+// TestSlowClient checks that a client that stops reading is dropped when a
+// write does not complete within the write timeout, as the stream does
+// after writeTimeout
+func TestSlowClient(t *testing.T) {
+	_, conn := newTestConn(t)
+	message := bytes.Repeat([]byte("z"), 64<<10)
+	deadline := time.Now().Add(testTimeout)
+	for {
+		err := conn.WriteText(message, 50*time.Millisecond)
+		if err != nil {
+			if !errors.Is(err, os.ErrDeadlineExceeded) {
+				t.Errorf("error %v, want a timeout", err)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("writes to a client that does not read never timed out")
+		}
+	}
+}
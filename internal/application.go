@@ -2,24 +2,67 @@ package internal
 
 import (
 	"context"
+	"fmt"
+	"os"
+	"time"
 
+	"github.com/mycrew-online/flight-data-recorder/internal/approach"
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/exceedance"
+	"github.com/mycrew-online/flight-data-recorder/internal/export"
+	"github.com/mycrew-online/flight-data-recorder/internal/landing"
+	"github.com/mycrew-online/flight-data-recorder/internal/logbook"
 	"github.com/mycrew-online/flight-data-recorder/internal/logger"
-	simconnectmanager "github.com/mycrew-online/flight-data-recorder/pkg/simconnect-manager"
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	"github.com/mycrew-online/flight-data-recorder/internal/phase"
+	"github.com/mycrew-online/flight-data-recorder/internal/playback"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
 	"github.com/wailsapp/wails/v2/pkg/runtime"
 )
 
+// AppVersion is written to recordings, keep in sync with wails.json
+const AppVersion = "0.1.0"
+
 // App struct
 type App struct {
 	ctx        context.Context
-	simconnect *simconnectmanager.SimConnectManager
+	core       *Core
+	simconnect telemetry.Source
+	recorder   *engine.Engine
+	recovered  []engine.Summary
+	stateSub   *telemetry.Subscription
+	eventsSub  *telemetry.Subscription
+}
+
+// frontendEvents maps bus topics to the Wails events they are emitted as
+var frontendEvents = map[telemetry.Topic]string{
+	engine.TopicStatus:         "recording::status",
+	phase.TopicPhase:           "flight::phase",
+	landing.TopicReport:        "landing::report",
+	oooi.TopicBlockTimes:       "flight::block-times",
+	exceedance.TopicExceedance: "flight::exceedance",
+	approach.TopicReport:       "approach::report",
 }
 
 // NewApp creates a new App application struct
 func NewApp() *App {
-	mgr := simconnectmanager.NewSimConnectManager()
-	mgr.SetLogger(logger.AppLogger)
+	// The local HTTP API, GDL 90 and NMEA outputs are off unless their
+	// environment variables set an address, X-Plane or FlightGear replace
+	// SimConnect when their address is set
+	core := NewCore(CoreOptions{
+		API:        os.Getenv("MCRWFDR_API"),
+		GDL90:      os.Getenv("MCRWFDR_GDL90"),
+		NMEATCP:    os.Getenv("MCRWFDR_NMEA_TCP"),
+		NMEAUDP:    os.Getenv("MCRWFDR_NMEA_UDP"),
+		XPlane:     os.Getenv("MCRWFDR_XPLANE"),
+		FlightGear: os.Getenv("MCRWFDR_FLIGHTGEAR"),
+		Logger:     logger.AppLogger,
+	})
 	return &App{
-		simconnect: mgr,
+		core:       core,
+		simconnect: core.Source,
+		recorder:   core.Recorder,
 	}
 }
 
@@ -27,37 +70,43 @@ func NewApp() *App {
 // so we can call the runtime methods
 func (a *App) Startup(ctx context.Context) {
 	a.ctx = ctx
-	a.simconnect.SetWailsContext(ctx)
-	logger.AppLogger.Info("App has started")
-
-	// Start SimConnect connection monitoring
-	a.simconnect.StartConnection()
-
-	// Listen for connection status changes
-	go func() {
-		for status := range a.simconnect.StatusChan() {
-			if status {
-				logger.AppLogger.Info("SimConnect connection established!")
-			} else {
-				logger.AppLogger.Warning("SimConnect disconnected.")
-			}
+	a.stateSub = telemetry.SubscribeWails(ctx, a.simconnect.Bus())
+	a.core.Player.OnStatus(func(status playback.Status) {
+		runtime.EventsEmit(a.ctx, "playback::status", status)
+	})
+	topics := make([]telemetry.Topic, 0, len(frontendEvents))
+	for topic := range frontendEvents {
+		topics = append(topics, topic)
+	}
+	a.eventsSub = a.simconnect.Bus().Subscribe(telemetry.SubscribeOptions{
+		Topics: topics,
+		Buffer: 16,
+		Policy: telemetry.DropOldest,
+	})
+	go func(sub *telemetry.Subscription) {
+		for msg := range sub.C() {
+			runtime.EventsEmit(a.ctx, frontendEvents[msg.Topic], msg.Payload)
 		}
-	}()
+	}(a.eventsSub)
+	logger.AppLogger.Info("App has started")
+	a.recovered = a.core.Start()
 }
 
 func (a *App) Shutdown(ctx context.Context) {
 	logger.AppLogger.Info("App is shutting down")
-	a.simconnect.StopConnection()
+	a.core.Stop()
+	a.eventsSub.Close()
+	a.stateSub.Close()
 }
 
-// GetSimStatus returns the current SimConnect connection status
+// GetSimStatus returns the current simulator connection status
 func (a *App) GetSimStatus() bool {
 	return a.simconnect.Status()
 }
 
-// GetAirplaneState returns the current airplane state from the SimConnect manager
+// GetAirplaneState returns the current airplane state from the simulator source
 
-// GetEnvironmentState returns the current environment state from the SimConnect manager
+// GetEnvironmentState returns the current environment state from the simulator source
 func (a *App) GetEnvironmentState() interface{} {
 	// Return as interface{} for Wails binding (or use EnvironmentState if Wails supports it directly)
 	return a.simconnect.GetEnvironmentState()
@@ -68,16 +117,238 @@ func (a *App) GetAirplaneState() interface{} {
 	return a.simconnect.GetAirplaneState()
 }
 
-// GetSimulatorState returns the current simulator state from the SimConnect manager
+// GetSimulatorState returns the current simulator state from the simulator source
 func (a *App) GetSimulatorState() interface{} {
 	return a.simconnect.GetSimulatorState()
 }
 
+// GetFlightPhase returns the current flight phase and when it was entered
+func (a *App) GetFlightPhase() phase.Status {
+	return a.core.Phases.Status()
+}
+
+// GetLastLandingReport returns the report of the most recent landing, or
+// nil before the first landing
+func (a *App) GetLastLandingReport() *landing.Report {
+	return a.core.Landings.Last()
+}
+
+// GetLastApproachReport returns the stabilized approach report of the most
+// recent approach, or nil before the first approach
+func (a *App) GetLastApproachReport() *approach.Report {
+	return a.core.Approaches.Last()
+}
+
+// ReloadApproachCriteria reads the approach criteria file again after it was
+// edited
+func (a *App) ReloadApproachCriteria() error {
+	return a.core.ReloadCriteria()
+}
+
+// GetCurrentBlockTimes returns the Out, Off, On and In times of the current
+// leg
+func (a *App) GetCurrentBlockTimes() oooi.BlockTimes {
+	return a.core.Blocks.Current()
+}
+
+// GetActiveExceedances returns the exceedances in progress
+func (a *App) GetActiveExceedances() []exceedance.Event {
+	return a.core.Monitor.Active()
+}
+
+// GetExceedanceRules returns the exceedance rules being evaluated
+func (a *App) GetExceedanceRules() []exceedance.Rule {
+	return a.core.Monitor.Rules()
+}
+
+// ReloadExceedanceRules reads the rules file again after it was edited
+func (a *App) ReloadExceedanceRules() error {
+	return a.core.ReloadRules()
+}
+
+// AnalyzeRecording evaluates a stored recording against the exceedance rules
+func (a *App) AnalyzeRecording(id string) ([]exceedance.Event, error) {
+	return a.core.AnalyzeRecording(id)
+}
+
 // Toggle Pause
 func (a *App) TogglePause() {
 	a.simconnect.TogglePause()
 }
 
+// StartRecording starts a new flight recording
+func (a *App) StartRecording() (engine.Status, error) {
+	return a.recorder.Start()
+}
+
+// StopRecording stops the current flight recording and returns its summary
+func (a *App) StopRecording() (engine.Summary, error) {
+	return a.recorder.Stop()
+}
+
+// GetRecordingStatus returns the current recorder status
+func (a *App) GetRecordingStatus() engine.Status {
+	return a.recorder.Status()
+}
+
+// GetRecoveredRecordings returns recordings recovered from unfinished journals on startup
+func (a *App) GetRecoveredRecordings() []engine.Summary {
+	return a.recovered
+}
+
+// ListRecordings returns the summaries of all stored recordings, newest first
+func (a *App) ListRecordings() ([]engine.Summary, error) {
+	return a.recorder.List()
+}
+
+// GetChannels returns the recorded channels with their units, e.g. to pick
+// the channels of an export
+func (a *App) GetChannels() []flightrecording.Channel {
+	return engine.Channels
+}
+
+// ExportFlight exports a stored recording to a file. Without options.Path a
+// save dialog asks for the destination. It returns the written path, or an
+// empty string when the dialog was cancelled.
+func (a *App) ExportFlight(id string, format export.Format, options export.Options) (string, error) {
+	ext, err := export.Extension(format)
+	if err != nil {
+		return "", err
+	}
+	if options.Path == "" {
+		options.Path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export flight",
+			DefaultFilename: id + ext,
+			Filters: []runtime.FileFilter{{
+				DisplayName: fmt.Sprintf("%s (*%s)", format, ext),
+				Pattern:     "*" + ext,
+			}},
+		})
+		if err != nil || options.Path == "" {
+			return "", err
+		}
+	}
+	if err := export.ExportFile(a.recorder, id, format, options); err != nil {
+		logger.AppLogger.Error("Failed to export flight " + id + ": " + err.Error())
+		return "", err
+	}
+	logger.AppLogger.Info("Exported flight " + id + " to " + options.Path)
+	return options.Path, nil
+}
+
+// GetLogbook returns the logbook entries in chronological order
+func (a *App) GetLogbook() []logbook.Entry {
+	return a.core.Logbook.Entries()
+}
+
+// GetLogbookTotals returns the lifetime, per aircraft and per month totals
+func (a *App) GetLogbookTotals() logbook.Totals {
+	return a.core.Logbook.Totals()
+}
+
+// AddRecordingToLogbook adds the flight of a stored recording, e.g. one made
+// before the logbook existed
+func (a *App) AddRecordingToLogbook(id string) (logbook.Entry, error) {
+	return a.core.AddToLogbook(id)
+}
+
+// RemoveLogbookEntry deletes a logbook entry
+func (a *App) RemoveLogbookEntry(id string) error {
+	return a.core.Logbook.Remove(id)
+}
+
+// ExportLogbook writes the logbook as CSV. Without a path a save dialog asks
+// for the destination. It returns the written path, or an empty string when
+// the dialog was cancelled.
+func (a *App) ExportLogbook(path string) (string, error) {
+	if path == "" {
+		var err error
+		path, err = runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+			Title:           "Export logbook",
+			DefaultFilename: "logbook.csv",
+			Filters:         []runtime.FileFilter{{DisplayName: "CSV (*.csv)", Pattern: "*.csv"}},
+		})
+		if err != nil || path == "" {
+			return "", err
+		}
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	if err := a.core.Logbook.ExportCSV(f); err != nil {
+		f.Close()
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	logger.AppLogger.Info("Exported logbook to " + path)
+	return path, nil
+}
+
+// ImportLogbook adds the entries of a CSV logbook. Without a path an open
+// dialog asks for the file. It returns the number of imported entries.
+func (a *App) ImportLogbook(path string) (int, error) {
+	if path == "" {
+		var err error
+		path, err = runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+			Title:   "Import logbook",
+			Filters: []runtime.FileFilter{{DisplayName: "CSV (*.csv)", Pattern: "*.csv"}},
+		})
+		if err != nil || path == "" {
+			return 0, err
+		}
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	n, err := a.core.Logbook.ImportCSV(f)
+	if err != nil {
+		logger.AppLogger.Error("Failed to import logbook " + path + ": " + err.Error())
+		return 0, err
+	}
+	logger.AppLogger.Info(fmt.Sprintf("Imported %d logbook entries from %s", n, path))
+	return n, nil
+}
+
+// LoadPlayback loads a stored recording for playback into the simulator
+func (a *App) LoadPlayback(id string) (playback.Status, error) {
+	return a.core.Player.Load(a.recorder, id)
+}
+
+// PlaybackPlay starts or resumes playback and freezes the aircraft physics
+func (a *App) PlaybackPlay() (playback.Status, error) {
+	return a.core.Player.Play()
+}
+
+// PlaybackPause pauses playback, the aircraft stays frozen
+func (a *App) PlaybackPause() playback.Status {
+	return a.core.Player.Pause()
+}
+
+// PlaybackSeek moves playback to the given number of seconds into the recording
+func (a *App) PlaybackSeek(seconds float64) (playback.Status, error) {
+	return a.core.Player.Seek(time.Duration(seconds * float64(time.Second)))
+}
+
+// PlaybackSetSpeed sets the playback speed, 1 is real time
+func (a *App) PlaybackSetSpeed(speed float64) (playback.Status, error) {
+	return a.core.Player.SetSpeed(speed)
+}
+
+// PlaybackStop ends playback and releases the aircraft
+func (a *App) PlaybackStop() playback.Status {
+	return a.core.Player.Stop()
+}
+
+// GetPlaybackStatus returns the current playback status
+func (a *App) GetPlaybackStatus() playback.Status {
+	return a.core.Player.Status()
+}
+
 func (a *App) RunSimulator() {
 	runtime.BrowserOpenURL(a.ctx, "steam://rungameid/2537590")
 }
@@ -0,0 +1,127 @@
+package approach
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Conditions select the applicable gate
+const (
+	ConditionsAuto = "auto"
+	ConditionsIMC  = "imc"
+	ConditionsVMC  = "vmc"
+)
+
+// Criteria are the stabilization criteria and gates. Speeds are in knots,
+// heights in feet above ground, angles in degrees.
+type Criteria struct {
+	// Conditions is auto, imc or vmc. Auto selects IMC when the visibility
+	// is below IMCVisibility meters.
+	Conditions    string  `json:"conditions"`
+	IMCVisibility float64 `json:"imc_visibility"`
+	IMCGate       float64 `json:"imc_gate"`
+	VMCGate       float64 `json:"vmc_gate"`
+	// TargetSpeeds are approach speeds keyed by a part of the aircraft
+	// title. Without a match the speed crossing 50 ft is the target.
+	TargetSpeeds map[string]float64 `json:"target_speeds"`
+	SpeedBelow   float64            `json:"speed_below"`
+	SpeedAbove   float64            `json:"speed_above"`
+	MaxSinkRate  float64            `json:"max_sink_rate"` // feet per minute
+	MaxBank      float64            `json:"max_bank"`
+	// Glide slope, localizer and runway heading are checked when NAV1 is
+	// tuned to an ILS. The defaults are about one dot.
+	MaxGlideSlopeError float64 `json:"max_glide_slope_error"`
+	MaxLocalizerError  float64 `json:"max_localizer_error"`
+	MaxHeadingError    float64 `json:"max_heading_error"` // from the localizer course
+}
+
+// DefaultCriteria returns common airline stabilization criteria
+func DefaultCriteria() Criteria {
+	return Criteria{
+		Conditions:         ConditionsAuto,
+		IMCVisibility:      5000,
+		IMCGate:            1000,
+		VMCGate:            500,
+		TargetSpeeds:       map[string]float64{},
+		SpeedBelow:         5,
+		SpeedAbove:         10,
+		MaxSinkRate:        1000,
+		MaxBank:            15,
+		MaxGlideSlopeError: 0.35,
+		MaxLocalizerError:  1.25,
+		MaxHeadingError:    10,
+	}
+}
+
+// DefaultPath returns the default criteria file in the user config dir
+func DefaultPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "mcrwfdr", "approach.json")
+}
+
+// LoadCriteria reads a criteria file. Missing settings keep their default.
+// A missing file is created with the defaults, so they can be edited.
+func LoadCriteria(path string) (Criteria, error) {
+	c := DefaultCriteria()
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		data, err := json.MarshalIndent(c, "", "  ")
+		if err != nil {
+			return c, err
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return c, fmt.Errorf("failed to create criteria directory: %w", err)
+		}
+		if err := os.WriteFile(path, data, 0o644); err != nil {
+			return c, fmt.Errorf("failed to write default criteria: %w", err)
+		}
+		return c, nil
+	}
+	if err != nil {
+		return c, fmt.Errorf("failed to read criteria: %w", err)
+	}
+	if err := json.Unmarshal(data, &c); err != nil {
+		return DefaultCriteria(), fmt.Errorf("failed to decode %s: %w", filepath.Base(path), err)
+	}
+	if err := c.validate(); err != nil {
+		return DefaultCriteria(), fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return c, nil
+}
+
+func (c Criteria) validate() error {
+	switch c.Conditions {
+	case ConditionsAuto, ConditionsIMC, ConditionsVMC:
+	default:
+		return fmt.Errorf("unknown conditions %q, want auto, imc or vmc", c.Conditions)
+	}
+	if c.IMCGate <= 0 || c.VMCGate <= 0 {
+		return errors.New("gates must be above ground")
+	}
+	for _, v := range []float64{c.SpeedBelow, c.SpeedAbove, c.MaxSinkRate, c.MaxBank, c.MaxGlideSlopeError, c.MaxLocalizerError, c.MaxHeadingError} {
+		if v < 0 {
+			return errors.New("limits must not be negative")
+		}
+	}
+	return nil
+}
+
+// targetSpeed returns the configured approach speed of an aircraft. The
+// longest matching key wins, so "A320neo" can override "A320".
+func (c Criteria) targetSpeed(title string) (float64, bool) {
+	title = strings.ToLower(title)
+	best, speed := -1, 0.0
+	for key, v := range c.TargetSpeeds {
+		if len(key) > best && strings.Contains(title, strings.ToLower(key)) {
+			best, speed = len(key), v
+		}
+	}
+	return speed, best >= 0
+}
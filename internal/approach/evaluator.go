@@ -0,0 +1,369 @@
+// Package approach evaluates stabilized approach criteria at the 1000 ft and
+// 500 ft gates and detects go-arounds
+package approach
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// TopicReport carries a Report on the telemetry bus
+const TopicReport telemetry.Topic = "approach"
+
+// ReportKind is the kind of approach reports attached to a recording
+const ReportKind = "approach"
+
+// Outcomes of an approach
+const (
+	Landed   = "landed"
+	GoAround = "go-around"
+)
+
+// Target speed sources
+const (
+	TargetConfigured = "configured"
+	TargetThreshold  = "threshold"
+)
+
+const (
+	// armMargin above the highest gate arms the evaluator on the way down
+	armMargin = 500.0
+	// armVS is the vertical speed that arms the evaluator, feet per minute
+	armVS = -200.0
+	// A go-around is a climb of goAroundClimb above the lowest height with
+	// at least goAroundVS for goAroundHold
+	goAroundClimb = 150.0
+	goAroundVS    = 300.0
+	goAroundHold  = 3 * time.Second
+	// thresholdAGL is the height the threshold speed is taken at
+	thresholdAGL = 50.0
+)
+
+// Check is a single criterion at a gate. Min and Max are the limits of
+// Value, nil when unbounded.
+type Check struct {
+	Name  string   `json:"name"`
+	Value float64  `json:"value"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+	Pass  bool     `json:"pass"`
+}
+
+// Gate is the evaluation at a gate height. Checks without data, e.g. the
+// glide slope on a visual approach, are left out.
+type Gate struct {
+	Height     float64   `json:"height"`
+	Applicable bool      `json:"applicable"` // the gate of the conditions
+	Reached    bool      `json:"reached"`    // descended through the gate
+	Time       time.Time `json:"time"`
+	Stable     bool      `json:"stable"`
+	Checks     []Check   `json:"checks"`
+}
+
+// Report is the evaluation of an approach. The bank check is positive right
+// wing down.
+type Report struct {
+	Start        time.Time `json:"start"`
+	End          time.Time `json:"end"`
+	Outcome      string    `json:"outcome"`
+	Conditions   string    `json:"conditions"` // imc or vmc
+	TargetSpeed  float64   `json:"target_speed"`
+	TargetSource string    `json:"target_source"` // configured, threshold or empty
+	Gates        []Gate    `json:"gates"`
+	Stable       bool      `json:"stable"` // at the applicable gate
+	MinimumAGL   float64   `json:"minimum_agl"`
+	Latitude     float64   `json:"latitude"` // position at the end
+	Longitude    float64   `json:"longitude"`
+}
+
+// crossing is the state when descending through a gate
+type crossing struct {
+	time time.Time
+	air  telemetry.AirplaneState
+}
+
+// Evaluator follows approaches from the regular airplane updates. An
+// approach arms below the highest gate plus armMargin while descending and
+// ends with a landing or a go-around. Approaches that level off above the
+// gates are dropped without a report.
+type Evaluator struct {
+	logger *logadapter.LogzWailsAdapter
+
+	mu        sync.Mutex
+	criteria  Criteria
+	armed     bool
+	start     time.Time
+	onGround  bool
+	haveSim   bool
+	env       telemetry.EnvironmentState
+	minAGL    float64
+	crossings map[float64]crossing
+	threshold float64 // IAS at thresholdAGL, 0 until crossed
+	climbing  time.Time
+	last      *Report
+}
+
+// NewEvaluator returns an evaluator of the default criteria
+func NewEvaluator() *Evaluator {
+	return &Evaluator{criteria: DefaultCriteria()}
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (v *Evaluator) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	v.logger = logger
+}
+
+// Criteria returns the criteria in use
+func (v *Evaluator) Criteria() Criteria {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.criteria
+}
+
+// SetCriteria replaces the criteria, effective from the next approach
+func (v *Evaluator) SetCriteria(c Criteria) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.criteria = c
+	return nil
+}
+
+// Last returns the most recent report, or nil before the first approach
+func (v *Evaluator) Last() *Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.last == nil {
+		return nil
+	}
+	r := *v.last
+	return &r
+}
+
+// UpdateSimulator feeds the on ground state
+func (v *Evaluator) UpdateSimulator(s telemetry.SimulatorState) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.onGround, v.haveSim = s.OnGround, true
+}
+
+// UpdateEnvironment feeds the visibility
+func (v *Evaluator) UpdateEnvironment(e telemetry.EnvironmentState) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.env = e
+}
+
+// UpdateAirplane feeds an airplane state and returns the report of an
+// approach it ended
+func (v *Evaluator) UpdateAirplane(t time.Time, a telemetry.AirplaneState) *Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if !v.haveSim {
+		return nil
+	}
+	agl := a.AltAboveGround
+	top := math.Max(v.criteria.IMCGate, v.criteria.VMCGate)
+
+	if !v.armed {
+		if !v.onGround && agl < top+armMargin && a.VerticalSpeed < armVS {
+			v.armed, v.start, v.minAGL = true, t, agl
+			v.crossings = map[float64]crossing{}
+			v.threshold, v.climbing = 0, time.Time{}
+		}
+		return nil
+	}
+
+	if v.onGround {
+		return v.finish(t, a, Landed)
+	}
+	for _, gate := range []float64{v.criteria.IMCGate, v.criteria.VMCGate} {
+		if _, ok := v.crossings[gate]; !ok && v.minAGL >= gate && agl < gate {
+			v.crossings[gate] = crossing{time: t, air: a}
+		}
+	}
+	if v.threshold == 0 && v.minAGL >= thresholdAGL && agl < thresholdAGL {
+		v.threshold = a.Airspeed
+	}
+	v.minAGL = math.Min(v.minAGL, agl)
+	if v.minAGL >= top && agl > top+2*armMargin {
+		// Climbed away before reaching the gates
+		v.armed = false
+		return nil
+	}
+
+	if agl > v.minAGL+goAroundClimb && a.VerticalSpeed > goAroundVS {
+		if v.climbing.IsZero() {
+			v.climbing = t
+		}
+		if t.Sub(v.climbing) >= goAroundHold {
+			if v.minAGL >= top {
+				// Levelled off or climbed before reaching the gates
+				v.armed = false
+				return nil
+			}
+			return v.finish(t, a, GoAround)
+		}
+	} else {
+		v.climbing = time.Time{}
+	}
+	return nil
+}
+
+// Reset drops an approach in progress, e.g. when the connection is lost
+func (v *Evaluator) Reset() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.armed, v.haveSim = false, false
+}
+
+// finish evaluates the gates of the approach that ended at t
+func (v *Evaluator) finish(t time.Time, a telemetry.AirplaneState, outcome string) *Report {
+	v.armed = false
+	c := v.criteria
+	r := Report{
+		Start:      v.start,
+		End:        t,
+		Outcome:    outcome,
+		Conditions: v.conditions(),
+		MinimumAGL: math.Max(v.minAGL, 0),
+		Latitude:   a.Latitude,
+		Longitude:  a.Longitude,
+	}
+	if speed, ok := c.targetSpeed(a.Title); ok {
+		r.TargetSpeed, r.TargetSource = speed, TargetConfigured
+	} else if v.threshold > 0 {
+		r.TargetSpeed, r.TargetSource = v.threshold, TargetThreshold
+	}
+	applicable := c.VMCGate
+	if r.Conditions == ConditionsIMC {
+		applicable = c.IMCGate
+	}
+	for _, height := range []float64{c.IMCGate, c.VMCGate} {
+		if len(r.Gates) > 0 && r.Gates[0].Height == height {
+			continue
+		}
+		g := Gate{Height: height, Applicable: height == applicable}
+		if x, ok := v.crossings[height]; ok {
+			g.Reached, g.Time = true, x.time
+			g.Checks = c.checks(x.air, r.TargetSpeed)
+			g.Stable = true
+			for _, check := range g.Checks {
+				g.Stable = g.Stable && check.Pass
+			}
+		}
+		if g.Applicable {
+			r.Stable = g.Stable
+		}
+		r.Gates = append(r.Gates, g)
+	}
+	v.last = &r
+	return &r
+}
+
+// conditions returns the configured conditions, or IMC or VMC from the
+// visibility
+func (v *Evaluator) conditions() string {
+	switch v.criteria.Conditions {
+	case ConditionsIMC, ConditionsVMC:
+		return v.criteria.Conditions
+	}
+	if v.env.AmbientVisibility > 0 && v.env.AmbientVisibility < v.criteria.IMCVisibility {
+		return ConditionsIMC
+	}
+	return ConditionsVMC
+}
+
+// checks evaluates the criteria for the state at a gate
+func (c Criteria) checks(a telemetry.AirplaneState, target float64) []Check {
+	var checks []Check
+	if target > 0 {
+		checks = append(checks, check("speed", a.Airspeed-target, -c.SpeedBelow, c.SpeedAbove))
+	}
+	sink := -c.MaxSinkRate
+	checks = append(checks, Check{Name: "sink_rate", Value: a.VerticalSpeed, Min: &sink, Pass: a.VerticalSpeed >= sink})
+	checks = append(checks, check("bank", -a.Bank, -c.MaxBank, c.MaxBank))
+	if a.NavHasGlideSlope {
+		checks = append(checks, check("glide_slope", a.NavGlideSlopeError, -c.MaxGlideSlopeError, c.MaxGlideSlopeError))
+	}
+	if a.NavHasLocalizer {
+		checks = append(checks, check("localizer", a.NavRadialError, -c.MaxLocalizerError, c.MaxLocalizerError))
+		heading := math.Mod(a.HeadingMagnetic-a.NavLocalizerCourse+540, 360) - 180
+		checks = append(checks, check("runway_heading", heading, -c.MaxHeadingError, c.MaxHeadingError))
+	}
+	return checks
+}
+
+func check(name string, value, min, max float64) Check {
+	return Check{Name: name, Value: value, Min: &min, Max: &max, Pass: value >= min && value <= max}
+}
+
+// Attach feeds the evaluator from bus and publishes reports as TopicReport.
+// Close the returned subscription to detach.
+func (v *Evaluator) Attach(bus *telemetry.Bus) *telemetry.Subscription {
+	// The gates are checked with the sample that crosses them, a dropped
+	// sample grades the approach further down
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicEnvironment,
+			telemetry.TopicConnection,
+		},
+		Buffer: 256,
+		Policy: telemetry.Block,
+	})
+	relay := telemetry.NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range sub.C() {
+			var report *Report
+			switch p := msg.Payload.(type) {
+			case telemetry.AirplaneState:
+				report = v.UpdateAirplane(msg.Time, p)
+			case telemetry.SimulatorState:
+				v.UpdateSimulator(p)
+			case telemetry.EnvironmentState:
+				v.UpdateEnvironment(p)
+			case telemetry.ConnectionStatus:
+				if !p.Connected {
+					v.Reset()
+				}
+			}
+			if report != nil {
+				v.logInfo("[Approach] ", report.Outcome, ", ", stability(report.Stable), " at ", report.applicableGate(), " ft (", report.Conditions, ")")
+				relay.Publish(TopicReport, *report)
+			}
+		}
+	}()
+	return sub
+}
+
+func (r *Report) applicableGate() float64 {
+	for _, g := range r.Gates {
+		if g.Applicable {
+			return g.Height
+		}
+	}
+	return 0
+}
+
+func stability(stable bool) string {
+	if stable {
+		return "stable"
+	}
+	return "not stable"
+}
+
+func (v *Evaluator) logInfo(args ...interface{}) {
+	if v.logger != nil {
+		v.logger.Info(fmt.Sprint(args...))
+	}
+}
@@ -0,0 +1,219 @@
+package approach
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+// point is the state at a second of the approach, bank is negative right
+// wing down as reported by the simulator
+type point struct {
+	agl, vs, ias, bank float64
+	ground             bool
+}
+
+// descend returns points a second apart down from agl from to to in steps of
+// 100 ft at 140 kt
+func descend(from, to float64) []point {
+	var points []point
+	for agl := from; agl >= to; agl -= 100 {
+		points = append(points, point{agl: agl, vs: -700, ias: 140})
+	}
+	return points
+}
+
+// climb returns points a second apart up from agl from to to in steps of
+// 100 ft
+func climb(from, to float64) []point {
+	var points []point
+	for agl := from; agl <= to; agl += 100 {
+		points = append(points, point{agl: agl, vs: 1200, ias: 150})
+	}
+	return points
+}
+
+// landing is a descent through both gates to 40 ft and the touchdown
+func landing() []point {
+	return append(descend(1600, 100), point{agl: 40, vs: -500, ias: 135}, point{ground: true, ias: 130})
+}
+
+func join(parts ...[]point) []point {
+	var points []point
+	for _, p := range parts {
+		points = append(points, p...)
+	}
+	return points
+}
+
+// below changes the points under agl with change
+func below(points []point, agl float64, change func(*point)) []point {
+	for i := range points {
+		if points[i].agl < agl {
+			change(&points[i])
+		}
+	}
+	return points
+}
+
+// fly feeds the points to v, one a second, and returns the reports
+func fly(v *Evaluator, title string, points []point) []*Report {
+	var reports []*Report
+	for i, p := range points {
+		t := testStart.Add(time.Duration(i) * time.Second)
+		v.UpdateSimulator(telemetry.SimulatorState{OnGround: p.ground})
+		a := telemetry.AirplaneState{Title: title, Latitude: float64(i), AltAboveGround: p.agl, VerticalSpeed: p.vs, Airspeed: p.ias, Bank: p.bank}
+		if r := v.UpdateAirplane(t, a); r != nil {
+			reports = append(reports, r)
+		}
+	}
+	return reports
+}
+
+// describe summarizes a report with times in seconds of the approach. The
+// applicable gate is starred, failed checks follow unstable gates.
+func describe(r *Report) string {
+	second := func(t time.Time) float64 { return t.Sub(testStart).Seconds() }
+	var gates []string
+	for _, g := range r.Gates {
+		s := fmt.Sprint(g.Height)
+		if g.Applicable {
+			s += "*"
+		}
+		switch {
+		case !g.Reached:
+			s += " not reached"
+		case g.Stable:
+			s += fmt.Sprintf(" at %v stable", second(g.Time))
+		default:
+			var failed []string
+			for _, c := range g.Checks {
+				if !c.Pass {
+					failed = append(failed, fmt.Sprintf("%s %v", c.Name, c.Value))
+				}
+			}
+			s += fmt.Sprintf(" at %v unstable on %s", second(g.Time), strings.Join(failed, " and "))
+		}
+		gates = append(gates, s)
+	}
+	return fmt.Sprintf("%s in %s from %v to %v at %v, min %v ft, target %v %s, gates %s, stable %v",
+		r.Outcome, r.Conditions, second(r.Start), second(r.End), r.Latitude, r.MinimumAGL, r.TargetSpeed, r.TargetSource,
+		strings.Join(gates, ", "), r.Stable)
+}
+
+func TestEvaluator(t *testing.T) {
+	for _, c := range []struct {
+		name       string
+		criteria   func(*Criteria)
+		visibility float64
+		title      string
+		points     []point
+		want       string // the report, empty when none
+	}{
+		// Armed below 1500 ft, the gates are checked when descending
+		// through them and the target is the speed at 50 ft
+		{"stable landing", nil, 0, "", landing(),
+			"landed in vmc from 2 to 17 at 17, min 40 ft, target 135 threshold, gates 1000 at 7 stable, 500* at 12 stable, stable true"},
+		{"low visibility", nil, 3000, "", landing(),
+			"landed in imc from 2 to 17 at 17, min 40 ft, target 135 threshold, gates 1000* at 7 stable, 500 at 12 stable, stable true"},
+		{"visual approach in low visibility", func(c *Criteria) { c.Conditions = ConditionsVMC }, 3000, "", landing(),
+			"landed in vmc from 2 to 17 at 17, min 40 ft, target 135 threshold, gates 1000 at 7 stable, 500* at 12 stable, stable true"},
+		{"instrument approach in good visibility", func(c *Criteria) { c.Conditions = ConditionsIMC }, 20000, "", landing(),
+			"landed in imc from 2 to 17 at 17, min 40 ft, target 135 threshold, gates 1000* at 7 stable, 500 at 12 stable, stable true"},
+		{"right bank at 500 ft", nil, 0, "", below(landing(), 600, func(p *point) { p.bank = -20 }),
+			"landed in vmc from 2 to 17 at 17, min 40 ft, target 135 threshold, gates 1000 at 7 stable, 500* at 12 unstable on bank 20, stable false"},
+		{"configured target speed", func(c *Criteria) { c.TargetSpeeds = map[string]float64{"A320": 125, "A320neo": 150} }, 0, "Airbus A320neo", landing(),
+			"landed in vmc from 2 to 17 at 17, min 40 ft, target 150 configured, gates 1000 at 7 unstable on speed -10, 500* at 12 unstable on speed -10, stable false"},
+		{"one gate", func(c *Criteria) { c.IMCGate, c.VMCGate = 800, 800 }, 3000, "", landing(),
+			"landed in imc from 4 to 17 at 17, min 40 ft, target 135 threshold, gates 800* at 9 stable, stable true"},
+		// A climb of 150 ft from the lowest height held for 3 s
+		{"go-around", nil, 0, "", join(descend(1600, 300), climb(400, 1000)),
+			"go-around in vmc from 2 to 18 at 18, min 300 ft, target 0 , gates 1000 at 7 stable, 500* at 12 stable, stable true"},
+		{"go-around above the lower gate", nil, 0, "", join(descend(1600, 700), climb(800, 1500)),
+			"go-around in vmc from 2 to 14 at 14, min 700 ft, target 0 , gates 1000 at 7 stable, 500* not reached, stable false"},
+		{"short climb", nil, 0, "", join(descend(1600, 300), climb(400, 600), landing()[11:]),
+			"landed in vmc from 2 to 23 at 23, min 40 ft, target 135 threshold, gates 1000 at 7 stable, 500* at 12 stable, stable true"},
+		// Approaches leaving before the gates are dropped
+		{"climb away", nil, 0, "", join(descend(1600, 1100), []point{{agl: 2100, vs: 3000}, {ground: true}}), ""},
+		{"level off", nil, 0, "", join(descend(1600, 1100), climb(1200, 1700), []point{{ground: true}}), ""},
+		{"no descent", nil, 0, "", []point{{agl: 1200, ias: 100}, {agl: 900, ias: 100}, {ground: true}}, ""},
+	} {
+		v := NewEvaluator()
+		if c.criteria != nil {
+			criteria := DefaultCriteria()
+			c.criteria(&criteria)
+			if err := v.SetCriteria(criteria); err != nil {
+				t.Fatal(err)
+			}
+		}
+		v.UpdateEnvironment(telemetry.EnvironmentState{AmbientVisibility: c.visibility})
+		reports := fly(v, c.title, c.points)
+		var got []string
+		for _, r := range reports {
+			got = append(got, describe(r))
+		}
+		if strings.Join(got, "; ") != c.want {
+			t.Errorf("%s:\n%s\nwant\n%s", c.name, strings.Join(got, "; "), c.want)
+		}
+		if len(reports) > 0 && describe(v.Last()) != describe(reports[len(reports)-1]) {
+			t.Errorf("%s: last report %s", c.name, describe(v.Last()))
+		}
+	}
+}
+
+func TestReset(t *testing.T) {
+	v := NewEvaluator()
+	points := landing()
+	fly(v, "", points[:8])
+	v.Reset()
+	// The simulator state is needed again after a reconnect
+	if r := v.UpdateAirplane(testStart, telemetry.AirplaneState{AltAboveGround: 800, VerticalSpeed: -700}); r != nil {
+		t.Errorf("report before a simulator state: %s", describe(r))
+	}
+	// A new approach from 800 ft, without the crossing of 1000 ft
+	reports := fly(v, "", points[8:])
+	want := "landed in vmc from 0 to 9 at 9, min 40 ft, target 135 threshold, gates 1000 not reached, 500* at 4 stable, stable true"
+	if len(reports) != 1 {
+		t.Fatalf("%d reports, want 1", len(reports))
+	}
+	if got := describe(reports[0]); got != want {
+		t.Errorf("%s, want %s", got, want)
+	}
+}
+
+func TestChecks(t *testing.T) {
+	c := DefaultCriteria()
+	for _, a := range []struct {
+		name  string
+		state telemetry.AirplaneState
+		want  string
+	}{
+		{"visual", telemetry.AirplaneState{Airspeed: 138, VerticalSpeed: -800, Bank: 10},
+			"speed -2 pass, sink_rate -800 pass, bank -10 pass"},
+		{"fast and sinking", telemetry.AirplaneState{Airspeed: 151, VerticalSpeed: -1100, Bank: -16},
+			"speed 11 fail, sink_rate -1100 fail, bank 16 fail"},
+		{"ILS", telemetry.AirplaneState{Airspeed: 140, VerticalSpeed: -700, Bank: 2, NavHasGlideSlope: true, NavGlideSlopeError: 0.5,
+			NavHasLocalizer: true, NavRadialError: -1, HeadingMagnetic: 355, NavLocalizerCourse: 5},
+			"speed 0 pass, sink_rate -700 pass, bank -2 pass, glide_slope 0.5 fail, localizer -1 pass, runway_heading -10 pass"},
+		{"heading across north", telemetry.AirplaneState{Airspeed: 140, VerticalSpeed: -700, Bank: -5,
+			NavHasLocalizer: true, HeadingMagnetic: 15, NavLocalizerCourse: 359},
+			"speed 0 pass, sink_rate -700 pass, bank 5 pass, localizer 0 pass, runway_heading 16 fail"},
+	} {
+		var got []string
+		for _, check := range c.checks(a.state, 140) {
+			result := "fail"
+			if check.Pass {
+				result = "pass"
+			}
+			got = append(got, fmt.Sprintf("%s %v %s", check.Name, check.Value, result))
+		}
+		if strings.Join(got, ", ") != a.want {
+			t.Errorf("%s: %s, want %s", a.name, strings.Join(got, ", "), a.want)
+		}
+	}
+}
@@ -0,0 +1,528 @@
+// Package cli implements the headless command line mode, e.g. for recording
+// on a simulator PC as a scheduled task without opening a window.
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	logz "github.com/mrlm-net/go-logz/pkg/logger"
+	"github.com/mycrew-online/flight-data-recorder/internal"
+	"github.com/mycrew-online/flight-data-recorder/internal/approach"
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/exceedance"
+	"github.com/mycrew-online/flight-data-recorder/internal/export"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/internal/logbook"
+	flightgearsource "github.com/mycrew-online/flight-data-recorder/pkg/flightgear-source"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// maxRate is the highest sample rate accepted by --rate
+const maxRate = 60
+
+// progressInterval is how often the record command logs its progress
+const progressInterval = time.Minute
+
+const (
+	xplaneUsage     = "record from X-Plane instead of SimConnect, e.g. 49000 or 192.168.1.20 (default: $MCRWFDR_XPLANE, SimConnect when empty)"
+	flightgearUsage = "record from FlightGear instead of SimConnect, receiving its generic protocol on e.g. 5500 (default: $MCRWFDR_FLIGHTGEAR, SimConnect when empty)"
+	protocolUsage   = "FlightGear generic protocol file, created with the shipped protocol when missing"
+)
+
+// command is a CLI subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	commands = []command{
+		{"record", "Record flights whenever the simulator is connected", runRecord},
+		{"status", "Show simulator connection and stored recordings", runStatus},
+		{"list", "List stored recordings", runList},
+		{"export", "Export a recording to CSV, GPX, KML, Tacview ACMI or IGC", runExport},
+		{"logbook", "Show logbook totals, import or export the logbook as CSV", runLogbook},
+		{"exceedances", "List the exceedances of a recording", runExceedances},
+		{"help", "Show this help", runHelp},
+	}
+}
+
+// IsCommand reports whether arg selects the command line mode
+func IsCommand(arg string) bool {
+	if arg == "-h" || arg == "--help" {
+		return true
+	}
+	for _, c := range commands {
+		if c.name == arg {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes the command line and returns the process exit code
+func Run(args []string) int {
+	attachConsole()
+	if len(args) == 0 {
+		usage(os.Stderr)
+		return 2
+	}
+	name := args[0]
+	if name == "-h" || name == "--help" {
+		name = "help"
+	}
+	for _, c := range commands {
+		if c.name != name {
+			continue
+		}
+		err := c.run(args[1:])
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "Error:", err)
+			return 1
+		}
+		return 0
+	}
+	fmt.Fprintf(os.Stderr, "Unknown command %q\n\n", name)
+	usage(os.Stderr)
+	return 2
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: mcrwfdr <command> [flags]")
+	fmt.Fprintln(w, "\nRun without a command to start the desktop app.")
+	fmt.Fprintln(w, "\nCommands:")
+	for _, c := range commands {
+		fmt.Fprintf(w, "  %-12s %s\n", c.name, c.summary)
+	}
+	fmt.Fprintln(w, "\nRun 'mcrwfdr <command> -h' for the flags of a command.")
+}
+
+func runHelp(args []string) error {
+	usage(os.Stdout)
+	return nil
+}
+
+func runRecord(args []string) error {
+	fs := flag.NewFlagSet("record", flag.ContinueOnError)
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	rate := fs.String("rate", "", "sample rate, e.g. 5hz or 200ms (default: on every state update)")
+	book := fs.String("logbook", logbook.DefaultPath(), "logbook file completed flights are added to")
+	rules := fs.String("rules", exceedance.DefaultPath(), "exceedance rules file")
+	criteria := fs.String("approach", approach.DefaultPath(), "stabilized approach criteria file")
+	apiAddr := fs.String("api", os.Getenv("MCRWFDR_API"), "serve the local HTTP API, e.g. 8321 or 0.0.0.0:8321 (default: $MCRWFDR_API, disabled when empty)")
+	gdl := fs.String("gdl90", os.Getenv("MCRWFDR_GDL90"), "send GDL 90 to an EFB app, e.g. 4000 to broadcast or 192.168.1.20:4000 (default: $MCRWFDR_GDL90, disabled when empty)")
+	nmeaTCP := fs.String("nmea-tcp", os.Getenv("MCRWFDR_NMEA_TCP"), "serve NMEA 0183 to TCP clients, e.g. 10110 (default: $MCRWFDR_NMEA_TCP, disabled when empty)")
+	nmeaUDP := fs.String("nmea-udp", os.Getenv("MCRWFDR_NMEA_UDP"), "send NMEA 0183 over UDP, e.g. 10110 to broadcast (default: $MCRWFDR_NMEA_UDP, disabled when empty)")
+	xplane := fs.String("xplane", os.Getenv("MCRWFDR_XPLANE"), xplaneUsage)
+	flightgear := fs.String("flightgear", os.Getenv("MCRWFDR_FLIGHTGEAR"), flightgearUsage)
+	fgProtocol := fs.String("flightgear-protocol", flightgearsource.DefaultPath(), protocolUsage)
+	logFile := fs.String("log", "", "also append log messages to this file")
+	verbose := fs.Bool("verbose", false, "log every state update")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	interval, err := parseRate(*rate)
+	if err != nil {
+		return err
+	}
+	log, err := newLogger(*logFile, *verbose)
+	if err != nil {
+		return err
+	}
+
+	core := internal.NewCore(internal.CoreOptions{
+		Dir:                *out,
+		Logbook:            *book,
+		Rules:              *rules,
+		Approach:           *criteria,
+		API:                *apiAddr,
+		GDL90:              *gdl,
+		NMEATCP:            *nmeaTCP,
+		NMEAUDP:            *nmeaUDP,
+		XPlane:             *xplane,
+		FlightGear:         *flightgear,
+		FlightGearProtocol: *fgProtocol,
+		Logger:             log,
+		SampleInterval:     interval,
+		AutoRecord:         true,
+	})
+	for _, s := range core.Start() {
+		log.Info(fmt.Sprintf("Recovered recording %s (%d samples)", s.ID, s.SampleCount))
+	}
+	log.Info("Recording to " + core.Recorder.Dir() + ", waiting for the simulator. Press Ctrl+C to stop.")
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(stop)
+	ticker := time.NewTicker(progressInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			log.Info("Stopping...")
+			core.Stop()
+			return nil
+		case <-ticker.C:
+			if st := core.Recorder.Status(); st.Recording {
+				log.Info(fmt.Sprintf("Recording %s: %d samples", st.ID, st.SampleCount))
+			}
+		}
+	}
+}
+
+func runStatus(args []string) error {
+	fs := flag.NewFlagSet("status", flag.ContinueOnError)
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	timeout := fs.Duration("timeout", 10*time.Second, "how long to wait for the simulator")
+	xplane := fs.String("xplane", os.Getenv("MCRWFDR_XPLANE"), xplaneUsage)
+	flightgear := fs.String("flightgear", os.Getenv("MCRWFDR_FLIGHTGEAR"), flightgearUsage)
+	fgProtocol := fs.String("flightgear-protocol", flightgearsource.DefaultPath(), protocolUsage)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	log, err := newLogger("", false)
+	if err != nil {
+		return err
+	}
+	// Only connect; recovering journals here could take over the journal
+	// of a recorder running in another process
+	core := internal.NewCore(internal.CoreOptions{
+		Dir:                *out,
+		XPlane:             *xplane,
+		FlightGear:         *flightgear,
+		FlightGearProtocol: *fgProtocol,
+		Logger:             log,
+	})
+	sub := core.Source.Bus().Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{telemetry.TopicConnection, telemetry.TopicAirplane},
+	})
+	defer sub.Close()
+	core.Source.StartConnection()
+	defer core.Source.StopConnection()
+
+	connected, haveAirplane := false, false
+	deadline := time.After(*timeout)
+wait:
+	for !haveAirplane {
+		select {
+		case msg := <-sub.C():
+			switch p := msg.Payload.(type) {
+			case telemetry.ConnectionStatus:
+				connected = p.Connected
+			case telemetry.AirplaneState:
+				haveAirplane = true
+			}
+		case <-deadline:
+			break wait
+		}
+	}
+
+	if connected {
+		snap := core.Source.Snapshot()
+		fmt.Println("Simulator:  connected")
+		fmt.Printf("Aircraft:   %s\n", orDash(snap.Airplane.Title))
+		fmt.Printf("Flight:     %s\n", orDash(snap.Simulator.FlightLoaded))
+		if haveAirplane {
+			fmt.Printf("Position:   %.5f %.5f, %.0f ft, %.0f kt\n",
+				snap.Airplane.Latitude, snap.Airplane.Longitude, snap.Airplane.Altitude, snap.Airplane.Airspeed)
+		}
+		fmt.Printf("Paused:     %t\n", snap.Simulator.Pause != 0)
+	} else {
+		fmt.Println("Simulator:  not connected")
+	}
+
+	summaries, err := core.Recorder.List()
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recordings: %d in %s\n", len(summaries), core.Recorder.Dir())
+	if len(summaries) > 0 {
+		s := summaries[0]
+		fmt.Printf("Latest:     %s %s, %s\n", s.ID, orDash(s.AircraftTitle),
+			(time.Duration(s.DurationSeconds) * time.Second).String())
+	}
+	return nil
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	summaries, err := engine.New(nil, engine.Options{Dir: *out}).List()
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tSTARTED (UTC)\tDURATION\tSAMPLES\tAIRCRAFT")
+	for _, s := range summaries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", s.ID, s.StartedAt.UTC().Format("2006-01-02 15:04:05"),
+			(time.Duration(s.DurationSeconds) * time.Second).String(), s.SampleCount, orDash(s.AircraftTitle))
+	}
+	return w.Flush()
+}
+
+func runExport(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcrwfdr export <id> [flags]")
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	format := fs.String("format", string(export.FormatCSV), fmt.Sprintf("export format %v", export.Formats()))
+	file := fs.String("file", "", "destination file, - for stdout (default: <id> with the format extension)")
+	channels := fs.String("channels", "", "comma separated channels to export (default: all)")
+	units := fs.String("units", string(export.UnitsAviation), "unit system: aviation, metric or imperial")
+	pilot := fs.String("pilot", "", "pilot name written to IGC files")
+	igcKey := fs.String("igc-key", os.Getenv("MCRWFDR_IGC_KEY"), "key signing IGC files (default: $MCRWFDR_IGC_KEY or a generated local key)")
+	listChannels := fs.Bool("list-channels", false, "list the available channels and exit")
+	ids, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if *listChannels {
+		for _, c := range engine.Channels {
+			fmt.Printf("%-40s %s\n", c.Name, c.Unit)
+		}
+		return nil
+	}
+	if len(ids) != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one recording id")
+	}
+	id := ids[0]
+	opts := export.Options{Units: export.Units(*units), Pilot: *pilot, SecurityKey: *igcKey}
+	if *channels != "" {
+		for _, c := range strings.Split(*channels, ",") {
+			opts.Channels = append(opts.Channels, strings.TrimSpace(c))
+		}
+	}
+	e := engine.New(nil, engine.Options{Dir: *out})
+	if *file == "-" {
+		return export.Export(e, id, export.Format(*format), opts, os.Stdout)
+	}
+	opts.Path = *file
+	if opts.Path == "" {
+		ext, err := export.Extension(export.Format(*format))
+		if err != nil {
+			return err
+		}
+		opts.Path = id + ext
+	}
+	if err := export.ExportFile(e, id, export.Format(*format), opts); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Exported", id, "to", opts.Path)
+	return nil
+}
+
+func runLogbook(args []string) error {
+	fs := flag.NewFlagSet("logbook", flag.ContinueOnError)
+	path := fs.String("logbook", logbook.DefaultPath(), "logbook file")
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	add := fs.String("add", "", "add the flight of a stored recording")
+	importFile := fs.String("import", "", "import entries from a CSV file")
+	exportFile := fs.String("export", "", "export the logbook to a CSV file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	book := logbook.New(*path)
+	if err := book.Load(); err != nil {
+		return err
+	}
+
+	switch {
+	case *add != "":
+		e := engine.New(nil, engine.Options{Dir: *out})
+		summary, err := e.Summary(*add)
+		if err != nil {
+			return err
+		}
+		entry, err := logbook.FromRecording(e, summary)
+		if err != nil {
+			return err
+		}
+		if err := book.Add(entry); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Added %s %s-%s to the logbook\n", entry.Date, orDash(entry.Departure), orDash(entry.Arrival))
+		return nil
+	case *importFile != "":
+		f, err := os.Open(*importFile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		n, err := book.ImportCSV(f)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Imported %d entries from %s\n", n, *importFile)
+		return nil
+	case *exportFile == "-":
+		return book.ExportCSV(os.Stdout)
+	case *exportFile != "":
+		f, err := os.Create(*exportFile)
+		if err != nil {
+			return err
+		}
+		if err := book.ExportCSV(f); err != nil {
+			f.Close()
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Exported the logbook to", *exportFile)
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "DATE\tFROM\tTO\tAIRCRAFT\tBLOCK\tAIR\tNIGHT\tLDG D/N")
+	for _, e := range book.Entries() {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%d/%d\n", e.Date, orDash(e.Departure), orDash(e.Arrival),
+			orDash(e.Aircraft), hoursMinutes(e.BlockMinutes), hoursMinutes(e.AirMinutes), hoursMinutes(e.NightMinutes),
+			e.DayLandings, e.NightLandings)
+	}
+	totals := book.Totals()
+	fmt.Fprintln(w)
+	printTotal(w, "Lifetime", totals.Lifetime)
+	for _, g := range totals.PerAircraft {
+		printTotal(w, orDash(g.Key), g.Total)
+	}
+	for _, g := range totals.PerMonth {
+		printTotal(w, g.Key, g.Total)
+	}
+	return w.Flush()
+}
+
+func runExceedances(args []string) error {
+	fs := flag.NewFlagSet("exceedances", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: mcrwfdr exceedances <id> [flags]")
+		fs.PrintDefaults()
+	}
+	out := fs.String("out", engine.DefaultDir(), "directory where recordings are stored")
+	rulesFile := fs.String("rules", exceedance.DefaultPath(), "exceedance rules file")
+	ids, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(ids) != 1 {
+		fs.Usage()
+		return errors.New("expected exactly one recording id")
+	}
+	rules, err := exceedance.LoadRules(*rulesFile)
+	if err != nil {
+		return err
+	}
+	events, err := exceedance.Analyze(engine.New(nil, engine.Options{Dir: *out}), ids[0], rules)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "START (UTC)\tDURATION\tSEVERITY\tPEAK\tTHRESHOLD\tRULE")
+	for _, ev := range events {
+		fmt.Fprintf(w, "%s\t%.0fs\t%s\t%.1f\t%.1f\t%s\n", ev.Start.UTC().Format("15:04:05"), ev.Seconds,
+			ev.Severity, ev.Peak, ev.Threshold, ev.Name)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%d exceedances\n", len(events))
+	return nil
+}
+
+func printTotal(w io.Writer, name string, t logbook.Total) {
+	fmt.Fprintf(w, "%s\t%d flights\t\t\t%s\t%s\t%s\t%d/%d\n", name, t.Flights,
+		hoursMinutes(t.BlockMinutes), hoursMinutes(t.AirMinutes), hoursMinutes(t.NightMinutes),
+		t.DayLandings, t.NightLandings)
+}
+
+// hoursMinutes formats minutes as H:MM
+func hoursMinutes(minutes int) string {
+	return fmt.Sprintf("%d:%02d", minutes/60, minutes%60)
+}
+
+// parseInterspersed parses flags that may follow positional arguments, e.g.
+// "export <id> --format csv", and returns the positional arguments
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// parseRate parses a sample rate given in Hz ("5hz") or as an interval
+// ("200ms"). An empty rate returns zero, i.e. a sample per state update.
+func parseRate(s string) (time.Duration, error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return 0, nil
+	}
+	if hz, ok := strings.CutSuffix(s, "hz"); ok {
+		rate, err := strconv.ParseFloat(strings.TrimSpace(hz), 64)
+		if err != nil || rate <= 0 || rate > maxRate {
+			return 0, fmt.Errorf("invalid rate %q: want a frequency up to %dhz", s, maxRate)
+		}
+		return time.Duration(float64(time.Second) / rate), nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < time.Second/maxRate {
+		return 0, fmt.Errorf("invalid rate %q: want e.g. 5hz or 200ms", s)
+	}
+	return d, nil
+}
+
+// newLogger logs to the console and optionally appends to a file
+func newLogger(logFile string, verbose bool) (*logadapter.LogzWailsAdapter, error) {
+	level := logz.Info
+	if verbose {
+		level = logz.Debug
+	}
+	outputs := []logz.OutputFunc{logz.ConsoleOutput()}
+	if logFile != "" {
+		file, err := logz.FileOutput(logFile)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, file)
+	}
+	return logadapter.New(logz.NewLogger(logz.LogOptions{
+		Level:   level,
+		Format:  logz.StringOutput,
+		Prefix:  "FDR",
+		Outputs: []logz.OutputFunc{logz.MultiOutput(outputs...)},
+	})), nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package cli
+
+// attachConsole is only needed for Windows GUI executables
+func attachConsole() {}
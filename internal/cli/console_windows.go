@@ -0,0 +1,27 @@
+package cli
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachParentProcess is ATTACH_PARENT_PROCESS, i.e. (DWORD)-1
+const attachParentProcess = ^uintptr(0)
+
+// attachConsole connects stdout and stderr to the console of the parent
+// process. The app is built as a GUI executable, which gets no console of
+// its own when started from a terminal.
+func attachConsole() {
+	if _, err := os.Stdout.Stat(); err == nil {
+		// Already attached or redirected, e.g. to a file
+		return
+	}
+	attach := syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole")
+	if r, _, _ := attach.Call(attachParentProcess); r == 0 {
+		return
+	}
+	if out, err := os.OpenFile("CONOUT$", os.O_RDWR, 0); err == nil {
+		os.Stdout = out
+		os.Stderr = out
+	}
+}
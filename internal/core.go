@@ -0,0 +1,423 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/api"
+	"github.com/mycrew-online/flight-data-recorder/internal/approach"
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/exceedance"
+	"github.com/mycrew-online/flight-data-recorder/internal/gdl90"
+	"github.com/mycrew-online/flight-data-recorder/internal/landing"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/internal/logbook"
+	"github.com/mycrew-online/flight-data-recorder/internal/nmea"
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	"github.com/mycrew-online/flight-data-recorder/internal/phase"
+	"github.com/mycrew-online/flight-data-recorder/internal/playback"
+	flightgearsource "github.com/mycrew-online/flight-data-recorder/pkg/flightgear-source"
+	simconnectmanager "github.com/mycrew-online/flight-data-recorder/pkg/simconnect-manager"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+	xplanesource "github.com/mycrew-online/flight-data-recorder/pkg/xplane-source"
+)
+
+// CoreOptions configures the connection and recording services
+type CoreOptions struct {
+	Dir      string // Recordings directory, engine.DefaultDir() when empty
+	Logbook  string // Logbook file, logbook.DefaultPath() when empty
+	Rules    string // Exceedance rules file, exceedance.DefaultPath() when empty
+	Approach string // Approach criteria file, approach.DefaultPath() when empty
+	API      string // Local HTTP API address, see api.ParseAddr; disabled when empty
+	GDL90    string // GDL 90 destination, see gdl90.ParseAddr; disabled when empty
+	NMEATCP  string // NMEA listen address, see nmea.NewOutput; disabled when empty
+	NMEAUDP  string // NMEA destination, see nmea.NewOutput; disabled when empty
+	XPlane   string // X-Plane address, see xplanesource.ParseAddr; records from X-Plane instead of SimConnect when set
+	// FlightGear is the address receiving FlightGear's generic protocol, see
+	// flightgearsource.ParseAddr; records from FlightGear instead of
+	// SimConnect when set
+	FlightGear string
+	// FlightGearProtocol is the protocol file, flightgearsource.DefaultPath()
+	// when empty
+	FlightGearProtocol string
+	Logger             *logadapter.LogzWailsAdapter
+	// SampleInterval writes recording samples at a fixed rate instead of on
+	// every state update. Intervals below a second request airplane data
+	// every simulation frame.
+	SampleInterval time.Duration
+	// AutoRecord starts a recording when the simulator connects and stops
+	// it when the connection is lost
+	AutoRecord bool
+}
+
+// Core wires the simulator source to the recording engine. It is shared by
+// the Wails application and the headless command line.
+type Core struct {
+	Source       telemetry.Source
+	Recorder     *engine.Engine
+	Player       *playback.Player
+	Phases       *phase.Detector
+	Landings     *landing.Analyzer
+	Blocks       *oooi.Tracker
+	Logbook      *logbook.Logbook
+	Monitor      *exceedance.Monitor
+	Approaches   *approach.Evaluator
+	API          *api.Server        // nil when disabled
+	GDL90        *gdl90.Broadcaster // nil when disabled
+	NMEA         *nmea.Output       // nil when disabled
+	logger       *logadapter.LogzWailsAdapter
+	autoRecord   bool
+	rulesPath    string
+	criteriaPath string
+	statusSub    *telemetry.Subscription
+	phaseSub     *telemetry.Subscription
+	landingSub   *telemetry.Subscription
+	blocksSub    *telemetry.Subscription
+	monitorSub   *telemetry.Subscription
+	approachSub  *telemetry.Subscription
+	gdl90Sub     *telemetry.Subscription
+	nmeaSub      *telemetry.Subscription
+	eventSub     *telemetry.Subscription
+}
+
+// NewCore creates the simulator source and the recording engine
+func NewCore(opts CoreOptions) *Core {
+	if opts.Dir == "" {
+		opts.Dir = engine.DefaultDir()
+	}
+	if opts.Logbook == "" {
+		opts.Logbook = logbook.DefaultPath()
+	}
+	if opts.Rules == "" {
+		opts.Rules = exceedance.DefaultPath()
+	}
+	if opts.Approach == "" {
+		opts.Approach = approach.DefaultPath()
+	}
+	source := newSource(opts)
+	if opts.Logger != nil {
+		source.SetLogger(opts.Logger)
+	}
+	rec := engine.New(source, engine.Options{
+		Dir:            opts.Dir,
+		AppVersion:     AppVersion,
+		DataSource:     source.Name(),
+		SampleInterval: opts.SampleInterval,
+	})
+	if opts.Logger != nil {
+		rec.SetLogger(opts.Logger)
+	}
+	source.Bus().AddListener(rec)
+	player := playback.New(source)
+	landings := landing.NewAnalyzer(source)
+	monitor := exceedance.NewMonitor()
+	approaches := approach.NewEvaluator()
+	if opts.Logger != nil {
+		player.SetLogger(opts.Logger)
+		landings.SetLogger(opts.Logger)
+		monitor.SetLogger(opts.Logger)
+		approaches.SetLogger(opts.Logger)
+	}
+	c := &Core{
+		Source:       source,
+		Recorder:     rec,
+		Player:       player,
+		Phases:       phase.NewDetector(),
+		Landings:     landings,
+		Blocks:       oooi.NewTracker(),
+		Logbook:      logbook.New(opts.Logbook),
+		Monitor:      monitor,
+		Approaches:   approaches,
+		logger:       opts.Logger,
+		autoRecord:   opts.AutoRecord,
+		rulesPath:    opts.Rules,
+		criteriaPath: opts.Approach,
+	}
+	if opts.API != "" {
+		c.API = api.New(api.Options{
+			Addr:        opts.API,
+			Source:      source,
+			Recorder:    rec,
+			SecurityKey: os.Getenv("MCRWFDR_IGC_KEY"),
+			Logger:      opts.Logger,
+		})
+	}
+	if opts.GDL90 != "" {
+		broadcaster, err := gdl90.NewBroadcaster(opts.GDL90)
+		if err != nil {
+			c.logError("GDL 90 output disabled: " + err.Error())
+		} else {
+			broadcaster.SetLogger(opts.Logger)
+			c.GDL90 = broadcaster
+		}
+	}
+	if opts.NMEATCP != "" || opts.NMEAUDP != "" {
+		output, err := nmea.NewOutput(opts.NMEATCP, opts.NMEAUDP)
+		if err != nil {
+			c.logError("NMEA output disabled: " + err.Error())
+		} else {
+			output.SetLogger(opts.Logger)
+			c.NMEA = output
+		}
+	}
+	rec.OnStatus(func(status engine.Status) {
+		source.Bus().Publish(engine.TopicStatus, status)
+	})
+	// Completed flights go to the logbook
+	rec.OnStopped(func(summary engine.Summary) {
+		c.addToLogbook(summary)
+	})
+	return c
+}
+
+// newSource returns the X-Plane or FlightGear adapter when its address is
+// set and the SimConnect manager otherwise. Sample intervals below a second
+// publish airplane data at the full rate of the simulator.
+func newSource(opts CoreOptions) telemetry.Source {
+	highRate := opts.SampleInterval > 0 && opts.SampleInterval < time.Second
+	if opts.XPlane != "" {
+		adapter, err := xplanesource.New(opts.XPlane)
+		if err == nil {
+			if highRate {
+				adapter.SetAirplaneInterval(0)
+			}
+			return adapter
+		}
+		if opts.Logger != nil {
+			opts.Logger.Error("X-Plane disabled, using SimConnect: " + err.Error())
+		}
+	} else if opts.FlightGear != "" {
+		adapter, err := newFlightGear(opts)
+		if err == nil {
+			if highRate {
+				adapter.SetAirplaneInterval(0)
+			}
+			return adapter
+		}
+		if opts.Logger != nil {
+			opts.Logger.Error("FlightGear disabled, using SimConnect: " + err.Error())
+		}
+	}
+	mgr := simconnectmanager.NewSimConnectManager()
+	if highRate {
+		mgr.SetAirplanePeriod(simconnectmanager.PeriodSimFrame)
+	}
+	return mgr
+}
+
+func newFlightGear(opts CoreOptions) (*flightgearsource.Adapter, error) {
+	if opts.FlightGearProtocol == "" {
+		opts.FlightGearProtocol = flightgearsource.DefaultPath()
+	}
+	protocol, err := flightgearsource.LoadProtocol(opts.FlightGearProtocol)
+	if err != nil {
+		return nil, err
+	}
+	return flightgearsource.New(opts.FlightGear, protocol)
+}
+
+// Start recovers unfinished recordings and starts connecting to the
+// simulator. It returns the recovered recordings.
+func (c *Core) Start() []engine.Summary {
+	// Recover recordings left unfinished by a crash or forced shutdown
+	recovered, err := c.Recorder.Recover()
+	if err != nil {
+		c.logError("Failed to recover recordings: " + err.Error())
+	}
+	if err := c.Logbook.Load(); err != nil {
+		c.logError("Failed to load logbook: " + err.Error())
+	}
+	for _, summary := range recovered {
+		c.addToLogbook(summary)
+	}
+	c.loadRules()
+	c.loadCriteria()
+
+	// Listen for connection status changes
+	c.statusSub = c.Source.Bus().Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{telemetry.TopicConnection},
+	})
+	go c.watchConnection(c.statusSub)
+
+	// Detect flight phases, grade landings and approaches, track block times
+	// and monitor exceedances, store the results with system events in the
+	// recording
+	c.phaseSub = c.Phases.Attach(c.Source.Bus())
+	c.landingSub = c.Landings.Attach(c.Source.Bus())
+	c.blocksSub = c.Blocks.Attach(c.Source.Bus())
+	c.monitorSub = c.Monitor.Attach(c.Source.Bus())
+	c.approachSub = c.Approaches.Attach(c.Source.Bus())
+	c.eventSub = c.Source.Bus().Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			phase.TopicPhase, telemetry.TopicSystemEvent, landing.TopicReport,
+			oooi.TopicBlockTimes, exceedance.TopicExceedance, approach.TopicReport,
+		},
+		Policy: telemetry.Block,
+	})
+	go c.recordEvents(c.eventSub)
+
+	if c.GDL90 != nil {
+		if c.gdl90Sub, err = c.GDL90.Attach(c.Source.Bus()); err != nil {
+			c.logError("Failed to start GDL 90 output: " + err.Error())
+		}
+	}
+	if c.NMEA != nil {
+		if c.nmeaSub, err = c.NMEA.Attach(c.Source.Bus()); err != nil {
+			c.logError("Failed to start NMEA output: " + err.Error())
+		}
+	}
+	if c.API != nil {
+		if err := c.API.Start(); err != nil {
+			c.logError("Failed to start the API: " + err.Error())
+		}
+	}
+
+	// Start connecting to the simulator
+	c.Source.StartConnection()
+	return recovered
+}
+
+// Stop finishes playback and an active recording and disconnects from the
+// simulator
+func (c *Core) Stop() {
+	for _, sub := range []*telemetry.Subscription{c.statusSub, c.phaseSub, c.landingSub, c.blocksSub, c.monitorSub, c.approachSub, c.gdl90Sub, c.nmeaSub, c.eventSub} {
+		if sub != nil {
+			sub.Close()
+		}
+	}
+	if c.API != nil {
+		if err := c.API.Stop(); err != nil {
+			c.logError("Failed to stop the API: " + err.Error())
+		}
+	}
+	c.Player.Stop()
+	if c.Recorder.Status().Recording {
+		if _, err := c.Recorder.Stop(); err != nil {
+			c.logError("Failed to stop recording: " + err.Error())
+		}
+	}
+	c.Source.StopConnection()
+}
+
+func (c *Core) watchConnection(sub *telemetry.Subscription) {
+	for msg := range sub.C() {
+		connected := msg.Payload.(telemetry.ConnectionStatus).Connected
+		if connected {
+			c.logInfo("Simulator connection established!")
+		} else if c.logger != nil {
+			c.logger.Warning("Simulator disconnected.")
+		}
+		if !c.autoRecord {
+			continue
+		}
+		if connected {
+			if _, err := c.Recorder.Start(); err != nil && !errors.Is(err, engine.ErrAlreadyRecording) {
+				c.logError("Failed to start recording: " + err.Error())
+			}
+		} else if c.Recorder.Status().Recording {
+			if _, err := c.Recorder.Stop(); err != nil {
+				c.logError("Failed to stop recording: " + err.Error())
+			}
+		}
+	}
+}
+
+// AddToLogbook adds the flight of a stored recording to the logbook
+func (c *Core) AddToLogbook(id string) (logbook.Entry, error) {
+	summary, err := c.Recorder.Summary(id)
+	if err != nil {
+		return logbook.Entry{}, err
+	}
+	entry, err := logbook.FromRecording(c.Recorder, summary)
+	if err != nil {
+		return entry, err
+	}
+	return entry, c.Logbook.Add(entry)
+}
+
+func (c *Core) addToLogbook(summary engine.Summary) {
+	entry, err := logbook.FromRecording(c.Recorder, summary)
+	if errors.Is(err, logbook.ErrNoFlight) {
+		return
+	}
+	if err == nil {
+		err = c.Logbook.Add(entry)
+	}
+	if err != nil {
+		c.logError("Failed to add recording " + summary.ID + " to the logbook: " + err.Error())
+		return
+	}
+	c.logInfo("Added flight " + summary.ID + " to the logbook")
+}
+
+// ReloadRules reads the exceedance rules file again
+func (c *Core) ReloadRules() error {
+	rules, err := exceedance.LoadRules(c.rulesPath)
+	if err != nil {
+		return err
+	}
+	return c.Monitor.SetRules(rules)
+}
+
+// AnalyzeRecording evaluates a stored recording against the exceedance rules
+func (c *Core) AnalyzeRecording(id string) ([]exceedance.Event, error) {
+	return exceedance.Analyze(c.Recorder, id, c.Monitor.Rules())
+}
+
+// ReloadCriteria reads the approach criteria file again
+func (c *Core) ReloadCriteria() error {
+	criteria, err := approach.LoadCriteria(c.criteriaPath)
+	if err != nil {
+		return err
+	}
+	return c.Approaches.SetCriteria(criteria)
+}
+
+func (c *Core) loadCriteria() {
+	if err := c.ReloadCriteria(); err != nil {
+		c.logError("Failed to load approach criteria, using the defaults: " + err.Error())
+	}
+}
+
+func (c *Core) loadRules() {
+	if err := c.ReloadRules(); err != nil {
+		c.logError("Failed to load exceedance rules, using the built-in rules: " + err.Error())
+	}
+}
+
+// recordEvents stores bus messages as events of the active recording.
+// Landing and approach reports, exceedances and block times are also
+// attached to the summary of the flight.
+func (c *Core) recordEvents(sub *telemetry.Subscription) {
+	for msg := range sub.C() {
+		var err error
+		switch msg.Topic {
+		case landing.TopicReport:
+			err = c.Recorder.AddReport(msg.Time, landing.ReportKind, msg.Payload)
+		case approach.TopicReport:
+			err = c.Recorder.AddReport(msg.Time, approach.ReportKind, msg.Payload)
+		case exceedance.TopicExceedance:
+			err = c.Recorder.AddReport(msg.Time, exceedance.ReportKind, msg.Payload)
+		case oooi.TopicBlockTimes:
+			err = c.Recorder.SetBlockTimes(msg.Time, msg.Payload.(oooi.BlockTimes))
+		default:
+			err = c.Recorder.RecordEvent(msg.Time, string(msg.Topic), msg.Payload)
+		}
+		if err != nil && !errors.Is(err, engine.ErrNotRecording) {
+			c.logError("Failed to record event: " + err.Error())
+		}
+	}
+}
+
+func (c *Core) logInfo(message string) {
+	if c.logger != nil {
+		c.logger.Info(message)
+	}
+}
+
+func (c *Core) logError(message string) {
+	if c.logger != nil {
+		c.logger.Error(message)
+	}
+}
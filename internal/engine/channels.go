@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// channelUnits lists the unit of every recorded channel, keyed by channel name
+var channelUnits = map[string]string{
+	"airplane.latitude":                  "degrees",
+	"airplane.longitude":                 "degrees",
+	"airplane.altitude":                  "feet",
+	"airplane.heading":                   "degrees",
+	"airplane.heading_magnetic":          "degrees",
+	"airplane.airspeed":                  "knots",
+	"airplane.bank":                      "degrees",
+	"airplane.alt_above_ground":          "feet",
+	"airplane.pitch":                     "degrees",
+	"airplane.vertical_speed":            "feet per minute",
+	"airplane.ground_velocity":           "knots",
+	"airplane.ground_track":              "degrees",
+	"airplane.airspeed_true":             "knots",
+	"airplane.angle_of_attack":           "degrees",
+	"airplane.nav_localizer_course":      "degrees",
+	"airplane.nav_radial_error":          "degrees",
+	"airplane.nav_glide_slope_error":     "degrees",
+	"environment.zulu_time":              "seconds",
+	"environment.local_time":             "seconds",
+	"environment.sim_time":               "seconds",
+	"environment.sea_level_pressure":     "inHg",
+	"environment.ambient_temperature":    "celsius",
+	"environment.ambient_wind_direction": "degrees",
+	"environment.ambient_wind_velocity":  "knots",
+	"environment.ambient_visibility":     "meters",
+	"environment.time_zone_offset":       "seconds",
+	"environment.zulu_sunrise_time":      "seconds",
+	"environment.zulu_sunset_time":       "seconds",
+	"simulator.simulation_rate":          "ratio",
+}
+
+// channelField maps a recording channel to a field of one of the state structs
+type channelField struct {
+	group int // index into the Sample states, see sampleStates
+	field int
+}
+
+var (
+	stateTypes = []reflect.Type{
+		reflect.TypeOf(telemetry.AirplaneState{}),
+		reflect.TypeOf(telemetry.EnvironmentState{}),
+		reflect.TypeOf(telemetry.SimulatorState{}),
+	}
+	statePrefixes = []string{"airplane.", "environment.", "simulator."}
+
+	// Channels is the channel table of recordings written by this version
+	Channels, channelFields = buildChannels()
+)
+
+// buildChannels derives the channel table from the json tags of the state structs
+func buildChannels() ([]flightrecording.Channel, []channelField) {
+	var channels []flightrecording.Channel
+	var fields []channelField
+	for g, t := range stateTypes {
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "" || name == "-" {
+				continue
+			}
+			var typ flightrecording.ChannelType
+			switch f.Type.Kind() {
+			case reflect.Float64:
+				typ = flightrecording.TypeFloat64
+			case reflect.Int, reflect.Int32:
+				typ = flightrecording.TypeInt32
+			case reflect.Bool:
+				typ = flightrecording.TypeBool
+			case reflect.String:
+				typ = flightrecording.TypeString
+			default:
+				continue
+			}
+			name = statePrefixes[g] + name
+			channels = append(channels, flightrecording.Channel{Name: name, Unit: channelUnits[name], Type: typ})
+			fields = append(fields, channelField{group: g, field: i})
+		}
+	}
+	return channels, fields
+}
+
+func sampleStates(s *Sample) []reflect.Value {
+	return []reflect.Value{
+		reflect.ValueOf(&s.Airplane).Elem(),
+		reflect.ValueOf(&s.Environment).Elem(),
+		reflect.ValueOf(&s.Simulator).Elem(),
+	}
+}
+
+// flatten converts a sample to frame values in channel table order
+func flatten(s Sample) []any {
+	states := sampleStates(&s)
+	values := make([]any, len(channelFields))
+	for i, cf := range channelFields {
+		v := states[cf.group].Field(cf.field)
+		switch v.Kind() {
+		case reflect.Int, reflect.Int32:
+			values[i] = int32(v.Int())
+		default:
+			values[i] = v.Interface()
+		}
+	}
+	return values
+}
+
+// Values returns the values of the sample in Channels order
+func (s Sample) Values() []any {
+	return flatten(s)
+}
+
+// unflatten converts frame values back to a sample. Channels are matched by
+// name, so recordings with a different channel table can still be read.
+func unflatten(channels []flightrecording.Channel, f flightrecording.Frame) Sample {
+	s := Sample{Time: f.Time}
+	states := sampleStates(&s)
+	for i, c := range channels {
+		if i >= len(f.Values) {
+			break
+		}
+		idx := ChannelIndex(c.Name)
+		if idx < 0 {
+			continue
+		}
+		cf := channelFields[idx]
+		v := states[cf.group].Field(cf.field)
+		switch val := f.Values[i].(type) {
+		case float64:
+			if v.Kind() == reflect.Float64 {
+				v.SetFloat(val)
+			}
+		case int32:
+			if v.Kind() == reflect.Int || v.Kind() == reflect.Int32 {
+				v.SetInt(int64(val))
+			}
+		case bool:
+			if v.Kind() == reflect.Bool {
+				v.SetBool(val)
+			}
+		case string:
+			if v.Kind() == reflect.String {
+				v.SetString(val)
+			}
+		}
+	}
+	return s
+}
+
+var channelIndexes = func() map[string]int {
+	m := make(map[string]int, len(Channels))
+	for i, c := range Channels {
+		m[c.Name] = i
+	}
+	return m
+}()
+
+// ChannelIndex returns the position of a channel in Channels, or -1
+func ChannelIndex(name string) int {
+	if i, ok := channelIndexes[name]; ok {
+		return i
+	}
+	return -1
+}
@@ -0,0 +1,31 @@
+package engine
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+var procLockFileEx = syscall.NewLazyDLL("kernel32.dll").NewProc("LockFileEx")
+
+// lockFile takes an exclusive lock on f, released when f is closed. It
+// returns errLocked when another open file holds the lock. Windows locks
+// are mandatory, so the last byte of the largest possible file is locked to
+// leave the data readable.
+func lockFile(f *os.File) error {
+	ol := syscall.Overlapped{Offset: ^uint32(0), OffsetHigh: ^uint32(0)}
+	r, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 1, 0, uintptr(unsafe.Pointer(&ol)))
+	if r != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return errLocked
+	}
+	return err
+}
@@ -1,7 +1,557 @@
 package engine
 
-func New() *Engine {
-	return &Engine{}
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	recordingExt = ".fdr"
+	summaryExt   = ".json"
+	journalExt   = ".fdr.partial" // In-progress recording, renamed on Stop
+	idFormat     = "20060102-150405"
+)
+
+// TopicStatus carries the Status on the telemetry bus when a recording
+// starts or stops, see OnStatus
+const TopicStatus telemetry.Topic = "recording"
+
+// DefaultSyncInterval is how often an in-progress recording is fsynced
+const DefaultSyncInterval = 5 * time.Second
+
+var (
+	ErrAlreadyRecording = errors.New("recording already in progress")
+	ErrNotRecording     = errors.New("no recording in progress")
+)
+
+// Source provides the latest known simulator states, used to seed a new recording
+type Source interface {
+	GetAirplaneState() telemetry.AirplaneState
+	GetEnvironmentState() telemetry.EnvironmentState
+	GetSimulatorState() telemetry.SimulatorState
 }
 
-type Engine struct{}
+// Options configures the recording engine
+type Options struct {
+	Dir        string // Directory where recordings are stored
+	AppVersion string // Application version written to the recording header
+	DataSource string // Simulator name written to the recording header
+	// SyncInterval is how often the journal is flushed and fsynced,
+	// DefaultSyncInterval when zero
+	SyncInterval time.Duration
+	// SampleInterval writes a sample at a fixed rate from the latest states.
+	// When zero a sample is written on every state update.
+	SampleInterval time.Duration
+}
+
+// Sample holds all simulator states at a point in time; every state update
+// produces one Sample in the recording
+type Sample struct {
+	Time        time.Time                  `json:"time"`
+	Airplane    telemetry.AirplaneState    `json:"airplane"`
+	Environment telemetry.EnvironmentState `json:"environment"`
+	Simulator   telemetry.SimulatorState   `json:"simulator"`
+}
+
+// Status describes the current state of the recorder
+type Status struct {
+	Recording     bool      `json:"recording"`
+	ID            string    `json:"id,omitempty"`
+	StartedAt     time.Time `json:"started_at"`
+	SampleCount   int       `json:"sample_count"`
+	AircraftTitle string    `json:"aircraft_title,omitempty"`
+}
+
+// Summary describes a finished recording
+type Summary struct {
+	ID              string    `json:"id"`
+	StartedAt       time.Time `json:"started_at"`
+	StoppedAt       time.Time `json:"stopped_at"`
+	DurationSeconds float64   `json:"duration_seconds"`
+	SampleCount     int       `json:"sample_count"`
+	AircraftTitle   string    `json:"aircraft_title"`
+	Recovered       bool      `json:"recovered"` // Restored from an unfinished journal
+	Reports         []Report  `json:"reports,omitempty"`
+	// BlockTimes of the leg flown during the recording
+	BlockTimes *oooi.BlockTimes `json:"block_times,omitempty"`
+}
+
+// blockTimesEvent names the events storing the latest block times
+const blockTimesEvent = "block-times"
+
+// reportSuffix is appended to the kind of a report to name its event
+const reportSuffix = "::report"
+
+// Report is an analysis result attached to a flight, e.g. a landing report.
+// Reports are stored as events of the recording and in its summary.
+type Report struct {
+	Kind string          `json:"kind"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// recording holds the open file of an in-progress recording
+type recording struct {
+	id        string
+	startedAt time.Time
+	file      *os.File
+	writer    *flightrecording.Writer
+	latest    Sample
+	samples   int
+	title     string
+	reports   []Report
+	blocks    *oooi.BlockTimes
+	done      chan struct{}
+}
+
+// Engine records simulator state updates to disk
+type Engine struct {
+	source         Source
+	dir            string
+	appVersion     string
+	dataSource     string
+	syncInterval   time.Duration
+	sampleInterval time.Duration
+	logger         *logadapter.LogzWailsAdapter
+	mu             sync.Mutex
+	current        *recording
+	onStatus       func(Status)
+	onStopped      func(Summary)
+}
+
+func New(source Source, opts Options) *Engine {
+	if opts.SyncInterval <= 0 {
+		opts.SyncInterval = DefaultSyncInterval
+	}
+	return &Engine{
+		source:         source,
+		dir:            opts.Dir,
+		appVersion:     opts.AppVersion,
+		dataSource:     opts.DataSource,
+		syncInterval:   opts.SyncInterval,
+		sampleInterval: opts.SampleInterval,
+	}
+}
+
+// DefaultDir returns the default directory for recordings in the user config dir
+func DefaultDir() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "mcrwfdr", "recordings")
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (e *Engine) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	e.logger = logger
+}
+
+// Dir returns the directory where recordings are stored
+func (e *Engine) Dir() string {
+	return e.dir
+}
+
+// Start opens a new recording and seeds it with the current simulator states
+func (e *Engine) Start() (Status, error) {
+	status, err := e.start()
+	if err != nil {
+		return status, err
+	}
+	e.mu.Lock()
+	fn := e.onStatus
+	e.mu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+	return status, nil
+}
+
+func (e *Engine) start() (Status, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current != nil {
+		return e.statusLocked(), ErrAlreadyRecording
+	}
+	if err := os.MkdirAll(e.dir, 0o755); err != nil {
+		return Status{}, fmt.Errorf("failed to create recordings directory: %w", err)
+	}
+	now := time.Now().UTC()
+	id, f, err := e.createJournal(now)
+	if err != nil {
+		return Status{}, fmt.Errorf("failed to create recording: %w", err)
+	}
+	var latest Sample
+	if e.source != nil {
+		latest.Airplane = e.source.GetAirplaneState()
+		latest.Environment = e.source.GetEnvironmentState()
+		latest.Simulator = e.source.GetSimulatorState()
+	}
+	w, err := flightrecording.NewWriter(f, flightrecording.Header{
+		AppVersion:    e.appVersion,
+		CreatedAt:     now,
+		AircraftTitle: latest.Airplane.Title,
+		FlightLoaded:  latest.Simulator.FlightLoaded,
+		FlightPlan:    latest.Simulator.FlightPlan,
+		Channels:      Channels,
+		DataSource:    e.dataSource,
+	})
+	if err != nil {
+		f.Close()
+		return Status{}, fmt.Errorf("failed to write recording header: %w", err)
+	}
+	e.current = &recording{
+		id:        id,
+		startedAt: now,
+		file:      f,
+		writer:    w,
+		latest:    latest,
+		title:     latest.Airplane.Title,
+		done:      make(chan struct{}),
+	}
+	e.writeLocked(now)
+	go e.syncLoop(e.current)
+	if e.sampleInterval > 0 {
+		go e.sampleLoop(e.current)
+	}
+	e.logInfo("[Engine] Recording started: ", id)
+	return e.statusLocked(), nil
+}
+
+// OnStatus registers a callback invoked with the new status whenever a
+// recording starts or stops. It is called without holding the engine lock.
+func (e *Engine) OnStatus(fn func(Status)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStatus = fn
+}
+
+// OnStopped registers a callback invoked with the summary of every
+// recording stopped successfully. It is called without holding the engine
+// lock.
+func (e *Engine) OnStopped(fn func(Summary)) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.onStopped = fn
+}
+
+// Stop flushes and closes the current recording and returns its summary
+func (e *Engine) Stop() (Summary, error) {
+	summary, err := e.stop()
+	if err != nil {
+		return summary, err
+	}
+	e.mu.Lock()
+	onStatus, onStopped := e.onStatus, e.onStopped
+	e.mu.Unlock()
+	if onStatus != nil {
+		onStatus(Status{})
+	}
+	if onStopped != nil {
+		onStopped(summary)
+	}
+	return summary, nil
+}
+
+func (e *Engine) stop() (Summary, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	rec := e.current
+	if rec == nil {
+		return Summary{}, ErrNotRecording
+	}
+	e.current = nil
+	close(rec.done)
+	stoppedAt := time.Now().UTC()
+	summary := Summary{
+		ID:              rec.id,
+		StartedAt:       rec.startedAt,
+		StoppedAt:       stoppedAt,
+		DurationSeconds: stoppedAt.Sub(rec.startedAt).Seconds(),
+		SampleCount:     rec.samples,
+		AircraftTitle:   rec.title,
+		Reports:         rec.reports,
+		BlockTimes:      rec.blocks,
+	}
+	if err := rec.writer.Close(); err != nil {
+		rec.file.Close()
+		return summary, fmt.Errorf("failed to finish recording: %w", err)
+	}
+	if err := rec.file.Sync(); err != nil {
+		rec.file.Close()
+		return summary, fmt.Errorf("failed to sync recording: %w", err)
+	}
+	if err := rec.file.Close(); err != nil {
+		return summary, fmt.Errorf("failed to close recording: %w", err)
+	}
+	if err := os.Rename(e.journalPath(rec.id), e.recordingPath(rec.id)); err != nil {
+		return summary, fmt.Errorf("failed to finalize recording: %w", err)
+	}
+	if err := writeSummary(e.summaryPath(rec.id), summary); err != nil {
+		return summary, err
+	}
+	e.logInfo("[Engine] Recording stopped: ", rec.id, " (", rec.samples, " samples)")
+	return summary, nil
+}
+
+// Status returns the current recorder status
+func (e *Engine) Status() Status {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.statusLocked()
+}
+
+// OnAirplaneState implements telemetry.StateListener
+func (e *Engine) OnAirplaneState(state telemetry.AirplaneState) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return
+	}
+	e.current.latest.Airplane = state
+	if state.Title != "" {
+		e.current.title = state.Title
+	}
+	e.updatedLocked()
+}
+
+// OnEnvironmentState implements telemetry.StateListener
+func (e *Engine) OnEnvironmentState(state telemetry.EnvironmentState) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return
+	}
+	e.current.latest.Environment = state
+	e.updatedLocked()
+}
+
+// OnSimulatorState implements telemetry.StateListener
+func (e *Engine) OnSimulatorState(state telemetry.SimulatorState) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return
+	}
+	e.current.latest.Simulator = state
+	e.updatedLocked()
+}
+
+// RecordEvent stores a named event with a JSON encoded payload in the
+// active recording
+func (e *Engine) RecordEvent(t time.Time, name string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode event %s: %w", name, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return ErrNotRecording
+	}
+	if err := e.current.writer.WriteEvent(t.UTC(), name, data); err != nil {
+		return fmt.Errorf("failed to write event %s: %w", name, err)
+	}
+	return nil
+}
+
+// AddReport attaches a JSON encoded report of the given kind to the active
+// recording
+func (e *Engine) AddReport(t time.Time, kind string, payload any) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s report: %w", kind, err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return ErrNotRecording
+	}
+	t = t.UTC()
+	if err := e.current.writer.WriteEvent(t, kind+reportSuffix, data); err != nil {
+		return fmt.Errorf("failed to write %s report: %w", kind, err)
+	}
+	e.current.reports = append(e.current.reports, Report{Kind: kind, Time: t, Data: data})
+	return nil
+}
+
+// SetBlockTimes stores the latest block times of the leg flown during the
+// active recording
+func (e *Engine) SetBlockTimes(t time.Time, times oooi.BlockTimes) error {
+	data, err := json.Marshal(times)
+	if err != nil {
+		return fmt.Errorf("failed to encode block times: %w", err)
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.current == nil {
+		return ErrNotRecording
+	}
+	if err := e.current.writer.WriteEvent(t.UTC(), blockTimesEvent, data); err != nil {
+		return fmt.Errorf("failed to write block times: %w", err)
+	}
+	e.current.blocks = &times
+	return nil
+}
+
+// updatedLocked writes a sample for a state update unless samples are
+// written at a fixed rate by sampleLoop
+func (e *Engine) updatedLocked() {
+	if e.sampleInterval <= 0 {
+		e.writeLocked(time.Now().UTC())
+	}
+}
+
+func (e *Engine) writeLocked(t time.Time) {
+	rec := e.current
+	rec.latest.Time = t
+	if err := rec.writer.WriteFrame(t, flatten(rec.latest)); err != nil {
+		e.logError("[Engine] Failed to write sample: ", err)
+		return
+	}
+	rec.samples++
+}
+
+// syncLoop periodically flushes and fsyncs the journal of rec so a crash
+// loses at most SyncInterval worth of samples
+func (e *Engine) syncLoop(rec *recording) {
+	ticker := time.NewTicker(e.syncInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rec.done:
+			return
+		case <-ticker.C:
+			e.mu.Lock()
+			if e.current == rec {
+				if err := rec.writer.Flush(); err != nil {
+					e.logError("[Engine] Failed to flush journal: ", err)
+				} else if err := rec.file.Sync(); err != nil {
+					e.logError("[Engine] Failed to sync journal: ", err)
+				}
+			}
+			e.mu.Unlock()
+		}
+	}
+}
+
+// sampleLoop writes the latest states of rec every SampleInterval
+func (e *Engine) sampleLoop(rec *recording) {
+	ticker := time.NewTicker(e.sampleInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-rec.done:
+			return
+		case t := <-ticker.C:
+			e.mu.Lock()
+			if e.current == rec {
+				e.writeLocked(t.UTC())
+			}
+			e.mu.Unlock()
+		}
+	}
+}
+
+func (e *Engine) statusLocked() Status {
+	if e.current == nil {
+		return Status{}
+	}
+	return Status{
+		Recording:     true,
+		ID:            e.current.id,
+		StartedAt:     e.current.startedAt,
+		SampleCount:   e.current.samples,
+		AircraftTitle: e.current.title,
+	}
+}
+
+// createJournal creates and locks the journal of a recording started at now.
+// The ID is the start time to the second, with a counter appended when a
+// recording of the same second exists.
+func (e *Engine) createJournal(now time.Time) (string, *os.File, error) {
+	base := now.Format(idFormat)
+	for n := 1; ; n++ {
+		id := base
+		if n > 1 {
+			id = fmt.Sprintf("%s-%d", base, n)
+		}
+		if e.exists(id) {
+			continue
+		}
+		f, err := os.OpenFile(e.journalPath(id), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if errors.Is(err, os.ErrExist) {
+			continue
+		}
+		if err != nil {
+			return "", nil, err
+		}
+		// Keeps Recover of other processes off the journal while recording
+		err = lockFile(f)
+		if errors.Is(err, errLocked) {
+			// Another process is recovering the empty journal, it will set
+			// it aside as corrupt
+			f.Close()
+			continue
+		}
+		if err != nil {
+			e.logError("[Engine] Failed to lock journal ", id, ": ", err)
+		}
+		return id, f, nil
+	}
+}
+
+// exists reports whether a finished recording or its summary uses id
+func (e *Engine) exists(id string) bool {
+	for _, path := range []string{e.recordingPath(id), e.summaryPath(id)} {
+		if _, err := os.Lstat(path); err == nil || !errors.Is(err, os.ErrNotExist) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) recordingPath(id string) string {
+	return filepath.Join(e.dir, id+recordingExt)
+}
+
+func (e *Engine) journalPath(id string) string {
+	return filepath.Join(e.dir, id+journalExt)
+}
+
+func (e *Engine) summaryPath(id string) string {
+	return filepath.Join(e.dir, id+summaryExt)
+}
+
+func writeSummary(path string, summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode summary: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write summary: %w", err)
+	}
+	return nil
+}
+
+func (e *Engine) logInfo(args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (e *Engine) logError(args ...interface{}) {
+	if e.logger != nil {
+		e.logger.Error(fmt.Sprint(args...))
+	}
+}
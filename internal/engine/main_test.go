@@ -0,0 +1,57 @@
+package engine
+
+import (
+	"os"
+	"testing"
+)
+
+func TestIDsWithinOneSecond(t *testing.T) {
+	e := New(nil, Options{Dir: t.TempDir()})
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		if _, err := e.Start(); err != nil {
+			t.Fatal(err)
+		}
+		summary, err := e.Stop()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[summary.ID] {
+			t.Fatalf("recording %d reused the ID %s", i, summary.ID)
+		}
+		seen[summary.ID] = true
+		if _, err := os.Stat(e.recordingPath(summary.ID)); err != nil {
+			t.Errorf("recording %s: %v", summary.ID, err)
+		}
+	}
+	summaries, err := e.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summaries) != 3 {
+		t.Errorf("listed %d recordings, want 3", len(summaries))
+	}
+}
+
+func TestIDSkipsExistingFiles(t *testing.T) {
+	e := New(nil, Options{Dir: t.TempDir()})
+	status, err := e.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.Stop(); err != nil {
+		t.Fatal(err)
+	}
+	// Only the summary is left, e.g. after the recording was deleted by hand
+	if err := os.Remove(e.recordingPath(status.ID)); err != nil {
+		t.Fatal(err)
+	}
+	id, f, err := e.createJournal(status.StartedAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if id != status.ID+"-2" {
+		t.Errorf("ID %s, want %s-2", id, status.ID)
+	}
+}
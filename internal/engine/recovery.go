@@ -0,0 +1,156 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+)
+
+// corruptExt marks journals that could not be recovered
+const corruptExt = ".corrupt"
+
+// errLocked is returned when a journal is locked by a recording in progress
+var errLocked = errors.New("journal is locked")
+
+// Recover finds journals of recordings that were never stopped, e.g. after a
+// crash of the app or the simulator, repairs them and stores them as regular
+// recordings. Journals still locked by a recording in progress, in this or
+// another process, are skipped. It returns the summaries of the recovered
+// recordings.
+func (e *Engine) Recover() ([]Summary, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	entries, err := os.ReadDir(e.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	var recovered []Summary
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, journalExt) {
+			continue
+		}
+		id := strings.TrimSuffix(name, journalExt)
+		if e.current != nil && e.current.id == id {
+			continue
+		}
+		summary, err := e.recoverJournal(id)
+		if errors.Is(err, errLocked) {
+			e.logInfo("[Engine] Skipping recording ", id, " in progress")
+			continue
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// Recovered by another process meanwhile
+			continue
+		}
+		if err != nil {
+			e.logError("[Engine] Failed to recover recording ", id, ": ", err)
+			if err := os.Rename(e.journalPath(id), e.journalPath(id)+corruptExt); err != nil {
+				e.logError("[Engine] Failed to set aside journal ", id, ": ", err)
+			}
+			continue
+		}
+		e.logInfo("[Engine] Recovered recording ", id, " (", summary.SampleCount, " samples)")
+		recovered = append(recovered, summary)
+	}
+	return recovered, nil
+}
+
+func (e *Engine) recoverJournal(id string) (Summary, error) {
+	path := e.journalPath(id)
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return Summary{}, err
+	}
+	if err := lockFile(f); errors.Is(err, errLocked) {
+		f.Close()
+		return Summary{}, err
+	} else if err != nil {
+		e.logError("[Engine] Failed to lock journal ", id, ": ", err)
+	}
+	result, err := flightrecording.Repair(f)
+	if err != nil {
+		f.Close()
+		return Summary{}, err
+	}
+	if result.TruncatedBytes > 0 {
+		e.logInfo("[Engine] Truncated ", result.TruncatedBytes, " bytes of partial data from ", filepath.Base(path))
+	}
+	summary, err := summarize(id, f)
+	if err != nil {
+		f.Close()
+		return Summary{}, err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return Summary{}, err
+	}
+	if err := f.Close(); err != nil {
+		return Summary{}, err
+	}
+	if err := os.Rename(path, e.recordingPath(id)); err != nil {
+		return Summary{}, err
+	}
+	if err := writeSummary(e.summaryPath(id), summary); err != nil {
+		return Summary{}, err
+	}
+	return summary, nil
+}
+
+// summarize rebuilds the summary of a recording by reading all its samples
+func summarize(id string, f io.ReadSeeker) (Summary, error) {
+	r, err := flightrecording.NewReader(f)
+	if err != nil {
+		return Summary{}, err
+	}
+	h := r.Header()
+	summary := Summary{
+		ID:            id,
+		StartedAt:     h.CreatedAt,
+		StoppedAt:     h.CreatedAt,
+		AircraftTitle: h.AircraftTitle,
+		Recovered:     true,
+	}
+	for {
+		frame, err := r.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return summary, err
+		}
+		s := unflatten(h.Channels, frame)
+		summary.SampleCount++
+		summary.StoppedAt = s.Time
+		if s.Airplane.Title != "" {
+			summary.AircraftTitle = s.Airplane.Title
+		}
+	}
+	summary.DurationSeconds = summary.StoppedAt.Sub(summary.StartedAt).Seconds()
+	events, err := r.Events()
+	if err != nil {
+		return summary, err
+	}
+	for _, ev := range events {
+		if kind, ok := strings.CutSuffix(ev.Name, reportSuffix); ok {
+			summary.Reports = append(summary.Reports, Report{Kind: kind, Time: ev.Time, Data: ev.Data})
+		}
+		if ev.Name == blockTimesEvent {
+			var times oooi.BlockTimes
+			if err := json.Unmarshal(ev.Data, &times); err == nil {
+				summary.BlockTimes = &times
+			}
+		}
+	}
+	return summary, nil
+}
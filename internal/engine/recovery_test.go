@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// writeJournal writes the journal of a recording that was never stopped
+func writeJournal(t *testing.T, e *Engine, id string, samples int) {
+	t.Helper()
+	f, err := os.Create(e.journalPath(id))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	start := time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+	w, err := flightrecording.NewWriter(f, flightrecording.Header{CreatedAt: start, Channels: Channels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < samples; i++ {
+		s := Sample{Time: start.Add(time.Duration(i) * time.Second), Airplane: telemetry.AirplaneState{Title: "C172"}}
+		if err := w.WriteFrame(s.Time, s.Values()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRecover(t *testing.T) {
+	e := New(nil, Options{Dir: t.TempDir()})
+	writeJournal(t, e, "crashed", 3)
+	recovered, err := e.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != "crashed" || recovered[0].SampleCount != 3 || !recovered[0].Recovered {
+		t.Fatalf("recovered %+v, want crashed with 3 samples", recovered)
+	}
+	if recovered[0].DurationSeconds != 2 || recovered[0].AircraftTitle != "C172" {
+		t.Errorf("summary %+v, want 2 s of the C172", recovered[0])
+	}
+	if _, err := os.Stat(e.journalPath("crashed")); !os.IsNotExist(err) {
+		t.Error("journal left after recovery")
+	}
+	if _, err := e.Summary("crashed"); err != nil {
+		t.Errorf("no stored summary: %v", err)
+	}
+}
+
+// TestRecoverSkipsLockedJournal recovers with a second engine on the same
+// directory, as a second instance of the app or the CLI does
+func TestRecoverSkipsLockedJournal(t *testing.T) {
+	dir := t.TempDir()
+	recorder := New(nil, Options{Dir: dir})
+	status, err := recorder.Start()
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := New(nil, Options{Dir: dir})
+	writeJournal(t, other, "crashed", 1)
+
+	recovered, err := other.Recover()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recovered) != 1 || recovered[0].ID != "crashed" {
+		t.Errorf("recovered %+v, want only the crashed journal", recovered)
+	}
+	if _, err := os.Stat(recorder.journalPath(status.ID)); err != nil {
+		t.Fatalf("journal of the recording in progress: %v", err)
+	}
+	if _, err := os.Stat(recorder.journalPath(status.ID) + corruptExt); !os.IsNotExist(err) {
+		t.Error("recording in progress set aside as corrupt")
+	}
+
+	summary, err := recorder.Stop()
+	if err != nil {
+		t.Fatalf("Stop after Recover of another engine: %v", err)
+	}
+	if summary.Recovered {
+		t.Error("stopped recording marked as recovered")
+	}
+}
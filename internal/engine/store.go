@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+)
+
+var ErrRecordingNotFound = errors.New("recording not found")
+
+// RecordingReader reads the samples of a stored recording
+type RecordingReader struct {
+	file   *os.File
+	reader *flightrecording.Reader
+}
+
+// List returns the summaries of all finished recordings, newest first
+func (e *Engine) List() ([]Summary, error) {
+	entries, err := os.ReadDir(e.dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to list recordings: %w", err)
+	}
+	var summaries []Summary
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, summaryExt) {
+			continue
+		}
+		summary, err := e.Summary(strings.TrimSuffix(name, summaryExt))
+		if err != nil {
+			e.logError("[Engine] Skipping unreadable summary ", name, ": ", err)
+			continue
+		}
+		summaries = append(summaries, summary)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].StartedAt.After(summaries[j].StartedAt)
+	})
+	return summaries, nil
+}
+
+// Summary returns the summary of a finished recording
+func (e *Engine) Summary(id string) (Summary, error) {
+	var summary Summary
+	if !validID(id) {
+		return summary, ErrRecordingNotFound
+	}
+	data, err := os.ReadFile(e.summaryPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return summary, ErrRecordingNotFound
+	}
+	if err != nil {
+		return summary, err
+	}
+	if err := json.Unmarshal(data, &summary); err != nil {
+		return summary, fmt.Errorf("failed to decode summary: %w", err)
+	}
+	return summary, nil
+}
+
+// Open opens a stored recording for reading
+func (e *Engine) Open(id string) (*RecordingReader, error) {
+	if !validID(id) {
+		return nil, ErrRecordingNotFound
+	}
+	f, err := os.Open(e.recordingPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrRecordingNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	r, err := flightrecording.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("failed to open recording %s: %w", filepath.Base(f.Name()), err)
+	}
+	return &RecordingReader{file: f, reader: r}, nil
+}
+
+// validID reports whether id can name a recording. IDs come from the API
+// and the command line and must not reach outside the recordings directory.
+func validID(id string) bool {
+	return id != "" && !strings.ContainsAny(id, `/\`)
+}
+
+// Header returns the recording header
+func (r *RecordingReader) Header() flightrecording.Header {
+	return r.reader.Header()
+}
+
+// Next returns the next sample, or io.EOF after the last one
+func (r *RecordingReader) Next() (Sample, error) {
+	f, err := r.reader.Next()
+	if err != nil {
+		return Sample{}, err
+	}
+	return unflatten(r.reader.Header().Channels, f), nil
+}
+
+// Seek positions the reader at the first sample at or after t
+func (r *RecordingReader) Seek(t time.Time) error {
+	return r.reader.Seek(t)
+}
+
+// Rewind positions the reader at the first sample
+func (r *RecordingReader) Rewind() error {
+	return r.reader.Rewind()
+}
+
+// Events returns the events stored in the recording
+func (r *RecordingReader) Events() ([]flightrecording.Event, error) {
+	return r.reader.Events()
+}
+
+// Close closes the underlying file
+func (r *RecordingReader) Close() error {
+	return r.file.Close()
+}
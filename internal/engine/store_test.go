@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestInvalidIDs(t *testing.T) {
+	// A recording next to the recordings directory, which IDs from the API
+	// or the command line must not reach
+	parent := t.TempDir()
+	outside := New(nil, Options{Dir: parent})
+	if _, err := outside.Start(); err != nil {
+		t.Fatal(err)
+	}
+	summary, err := outside.Stop()
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := New(nil, Options{Dir: filepath.Join(parent, "recordings")})
+
+	for _, id := range []string{"", "../" + summary.ID, `..\` + summary.ID, filepath.Join(parent, summary.ID)} {
+		if _, err := e.Summary(id); !errors.Is(err, ErrRecordingNotFound) {
+			t.Errorf("Summary(%q): error %v, want %v", id, err, ErrRecordingNotFound)
+		}
+		if r, err := e.Open(id); !errors.Is(err, ErrRecordingNotFound) {
+			if r != nil {
+				r.Close()
+			}
+			t.Errorf("Open(%q): error %v, want %v", id, err, ErrRecordingNotFound)
+		}
+	}
+
+	if got, err := outside.Summary(summary.ID); err != nil || got.ID != summary.ID {
+		t.Errorf("Summary(%q) = %+v, %v, want the recording", summary.ID, got, err)
+	}
+	r, err := outside.Open(summary.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+}
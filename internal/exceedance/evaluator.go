@@ -0,0 +1,215 @@
+package exceedance
+
+import (
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+// maxGap between samples ends active exceedances, e.g. after a reconnect
+const maxGap = 10 * time.Second
+
+// Event is an exceedance of a rule. Peak is the most extreme value, after
+// Abs, and Threshold the level of the severity it reached.
+type Event struct {
+	RuleID    string    `json:"rule_id"`
+	Name      string    `json:"name"`
+	Severity  Severity  `json:"severity"`
+	Channel   string    `json:"channel"`
+	Threshold float64   `json:"threshold"`
+	Peak      float64   `json:"peak"`
+	PeakTime  time.Time `json:"peak_time"`
+	Start     time.Time `json:"start"`
+	End       time.Time `json:"end"` // zero while the exceedance lasts
+	Seconds   float64   `json:"seconds"`
+	Latitude  float64   `json:"latitude"` // position at the peak
+	Longitude float64   `json:"longitude"`
+	Altitude  float64   `json:"altitude"`
+}
+
+// accessor reads a channel from the values of a sample
+type accessor func(values []any) float64
+
+type condition struct {
+	value               accessor
+	above, below, equal *float64
+}
+
+type compiled struct {
+	rule  Rule
+	value accessor
+	when  []condition
+	above bool
+	level []Level // from the lowest severity
+}
+
+// Evaluator checks samples against rules. It is not safe for concurrent use.
+type Evaluator struct {
+	rules  []compiled
+	active []*Event // per rule, nil when not exceeded
+	last   time.Time
+}
+
+// NewEvaluator validates the rules and returns an evaluator for them
+func NewEvaluator(rules []Rule) (*Evaluator, error) {
+	v := &Evaluator{}
+	for _, r := range rules {
+		// validate sorts the levels, keep the caller's slices untouched
+		r.Above = append([]Level(nil), r.Above...)
+		r.Below = append([]Level(nil), r.Below...)
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+		c := compiled{rule: r, value: channel(r.Channel), above: len(r.Above) > 0, level: r.Above}
+		if !c.above {
+			c.level = r.Below
+		}
+		if r.Abs {
+			value := c.value
+			c.value = func(values []any) float64 {
+				x := value(values)
+				if x < 0 {
+					return -x
+				}
+				return x
+			}
+		}
+		for _, w := range r.When {
+			c.when = append(c.when, condition{value: channel(w.Channel), above: w.Above, below: w.Below, equal: w.Equals})
+		}
+		v.rules = append(v.rules, c)
+	}
+	v.active = make([]*Event, len(v.rules))
+	return v, nil
+}
+
+// Update evaluates a sample and returns the exceedances it ended. Samples
+// taken while paused are skipped.
+func (v *Evaluator) Update(s engine.Sample) []Event {
+	if s.Simulator.Pause != 0 {
+		return nil
+	}
+	var done []Event
+	if !v.last.IsZero() && s.Time.Sub(v.last) > maxGap {
+		done = v.Flush()
+	}
+	v.last = s.Time
+	values := s.Values()
+	for i, c := range v.rules {
+		ev := v.active[i]
+		level := -1
+		if c.applies(values) {
+			level = c.levelOf(c.value(values))
+		}
+		if level < 0 {
+			if ev != nil {
+				v.active[i] = nil
+				ev.End = s.Time
+				ev.Seconds = ev.End.Sub(ev.Start).Seconds()
+				if ev.Seconds >= c.rule.MinSeconds {
+					done = append(done, *ev)
+				}
+			}
+			continue
+		}
+		x := c.value(values)
+		if ev == nil {
+			ev = &Event{RuleID: c.rule.ID, Name: c.rule.Name, Channel: c.rule.Channel, Start: s.Time, Peak: x}
+			v.active[i] = ev
+		}
+		if ev.PeakTime.IsZero() || c.above && x > ev.Peak || !c.above && x < ev.Peak {
+			ev.Peak, ev.PeakTime = x, s.Time
+			ev.Latitude, ev.Longitude, ev.Altitude = s.Airplane.Latitude, s.Airplane.Longitude, s.Airplane.Altitude
+		}
+		if severityRank[c.level[level].Severity] > severityRank[ev.Severity] {
+			ev.Severity, ev.Threshold = c.level[level].Severity, c.level[level].Value
+		}
+		ev.Seconds = s.Time.Sub(ev.Start).Seconds()
+	}
+	return done
+}
+
+// Active returns the exceedances that lasted their minimum time and still
+// last
+func (v *Evaluator) Active() []Event {
+	var list []Event
+	for i, ev := range v.active {
+		if ev != nil && ev.Seconds >= v.rules[i].rule.MinSeconds {
+			list = append(list, *ev)
+		}
+	}
+	return list
+}
+
+// Flush ends all exceedances at the last sample, e.g. at the end of a
+// recording
+func (v *Evaluator) Flush() []Event {
+	var done []Event
+	for i, ev := range v.active {
+		if ev == nil {
+			continue
+		}
+		v.active[i] = nil
+		ev.End = v.last
+		ev.Seconds = ev.End.Sub(ev.Start).Seconds()
+		if ev.Seconds >= v.rules[i].rule.MinSeconds {
+			done = append(done, *ev)
+		}
+	}
+	return done
+}
+
+func (c compiled) applies(values []any) bool {
+	for _, w := range c.when {
+		x := w.value(values)
+		if w.above != nil && x <= *w.above || w.below != nil && x >= *w.below || w.equal != nil && x != *w.equal {
+			return false
+		}
+	}
+	return true
+}
+
+// levelOf returns the index of the highest level x exceeds, or -1
+func (c compiled) levelOf(x float64) int {
+	level := -1
+	for i, l := range c.level {
+		if c.above && x > l.Value || !c.above && x < l.Value {
+			level = i
+		}
+	}
+	return level
+}
+
+// channel returns the accessor of a validated channel
+func channel(name string) accessor {
+	switch name {
+	case ChannelPitch:
+		return negated(engine.ChannelIndex("airplane.pitch"))
+	case ChannelBank:
+		return negated(engine.ChannelIndex("airplane.bank"))
+	}
+	i := engine.ChannelIndex(name)
+	return func(values []any) float64 {
+		return number(values[i])
+	}
+}
+
+func negated(i int) accessor {
+	return func(values []any) float64 {
+		return -number(values[i])
+	}
+}
+
+func number(v any) float64 {
+	switch x := v.(type) {
+	case float64:
+		return x
+	case int32:
+		return float64(x)
+	case bool:
+		if x {
+			return 1
+		}
+	}
+	return 0
+}
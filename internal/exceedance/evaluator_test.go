@@ -0,0 +1,184 @@
+package exceedance
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+func value(x float64) *float64 { return &x }
+
+// testRules are a bank rule below 1000 ft and a nose up pitch rule
+func testRules() []Rule {
+	return []Rule{
+		{
+			ID: "bank", Name: "Bank", Channel: ChannelBank, Abs: true,
+			Above:      []Level{{High, 45}, {Low, 30}, {Medium, 35}},
+			When:       []Condition{{Channel: "airplane.alt_above_ground", Below: value(1000)}},
+			MinSeconds: 2,
+		},
+		{ID: "pitch", Name: "Pitch", Channel: ChannelPitch, Above: []Level{{Low, 15}, {High, 20}}},
+	}
+}
+
+// point is a sample at a second of the flight, with the simulator signs:
+// bank is negative right wing down and pitch negative nose up
+type point struct {
+	at          int
+	bank, pitch float64
+	agl         float64
+	paused      bool
+}
+
+func (p point) sample() engine.Sample {
+	s := engine.Sample{
+		Time:     testStart.Add(time.Duration(p.at) * time.Second),
+		Airplane: telemetry.AirplaneState{Latitude: float64(p.at), Bank: p.bank, Pitch: p.pitch, AltAboveGround: p.agl},
+	}
+	if p.paused {
+		s.Simulator.Pause = 1
+	}
+	return s
+}
+
+// level returns points at 500 ft for the seconds from to to
+func level(from, to int, bank, pitch float64) []point {
+	var points []point
+	for at := from; at <= to; at++ {
+		points = append(points, point{at: at, bank: bank, pitch: pitch, agl: 500})
+	}
+	return points
+}
+
+func join(parts ...[]point) []point {
+	var points []point
+	for _, p := range parts {
+		points = append(points, p...)
+	}
+	return points
+}
+
+// describe lists events as rule, severity, threshold, peak, peak second and
+// the seconds they lasted from and to, open ended while they last
+func describe(events []Event) string {
+	second := func(t time.Time) string {
+		if t.IsZero() {
+			return ""
+		}
+		return fmt.Sprint(t.Sub(testStart).Seconds())
+	}
+	var list []string
+	for _, ev := range events {
+		list = append(list, fmt.Sprintf("%s %s %v peak %v at %s %s-%s", ev.RuleID, ev.Severity, ev.Threshold, ev.Peak,
+			second(ev.PeakTime), second(ev.Start), second(ev.End)))
+	}
+	return strings.Join(list, ", ")
+}
+
+func TestLevelOf(t *testing.T) {
+	v, err := NewEvaluator([]Rule{
+		testRules()[0],
+		{ID: "sink", Channel: "airplane.vertical_speed", Below: []Level{{High, -2000}, {Low, -1000}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bank, sink := v.rules[0], v.rules[1]
+	for _, c := range []struct {
+		rule  compiled
+		x     float64
+		level int
+	}{
+		{bank, 29, -1},
+		{bank, 30, -1}, // levels must be exceeded
+		{bank, 31, 0},
+		{bank, 40, 1},
+		{bank, 60, 2},
+		{sink, -900, -1},
+		{sink, -1500, 0},
+		{sink, -2500, 1},
+	} {
+		if level := c.rule.levelOf(c.x); level != c.level {
+			t.Errorf("%s at %v: level %d, want %d", c.rule.rule.ID, c.x, level, c.level)
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	for _, c := range []struct {
+		name   string
+		points []point
+		want   string
+	}{
+		{"escalation", join(level(0, 0, -32, 0), level(1, 1, -40, 0), level(2, 2, -50, 0), level(3, 3, -40, 0), level(4, 4, -10, 0)),
+			"bank high 45 peak 50 at 2 0-4"},
+		{"left bank", join(level(0, 2, 40, 0), level(3, 3, 0, 0)), "bank medium 35 peak 40 at 0 0-3"},
+		{"shorter than the minimum time", join(level(0, 0, 40, 0), level(1, 1, 0, 0)), ""},
+		{"outside the condition", []point{{at: 0, bank: 50, agl: 1500}, {at: 1, bank: 50, agl: 1500}, {at: 2, bank: 50, agl: 1500}}, ""},
+		{"nose up", join(level(0, 1, 0, -25), level(2, 2, 0, 0)), "pitch high 20 peak 25 at 0 0-2"},
+		{"nose down", join(level(0, 1, 0, 25), level(2, 2, 0, 0)), ""},
+		{"paused", join(level(0, 1, 40, 0), []point{{at: 2, agl: 500, paused: true}, {at: 5, agl: 500, paused: true}}, level(6, 6, 40, 0), level(7, 7, 0, 0)),
+			"bank medium 35 peak 40 at 0 0-7"},
+		{"gap", join(level(0, 2, 40, 0), level(13, 15, 50, 0), level(16, 16, 0, 0)),
+			"bank medium 35 peak 40 at 0 0-2, bank high 45 peak 50 at 13 13-16"},
+		{"end of the recording", level(0, 3, 40, 0), "bank medium 35 peak 40 at 0 0-3"},
+	} {
+		v, err := NewEvaluator(testRules())
+		if err != nil {
+			t.Fatal(err)
+		}
+		var events []Event
+		for _, p := range c.points {
+			events = append(events, v.Update(p.sample())...)
+		}
+		events = append(events, v.Flush()...)
+		if got := describe(events); got != c.want {
+			t.Errorf("%s: events %q, want %q", c.name, got, c.want)
+		}
+		for _, ev := range events {
+			if ev.Seconds != ev.End.Sub(ev.Start).Seconds() || ev.Latitude != ev.PeakTime.Sub(testStart).Seconds() {
+				t.Errorf("%s: %+v, want the duration and the position at the peak", c.name, ev)
+			}
+		}
+	}
+}
+
+func TestActive(t *testing.T) {
+	v, err := NewEvaluator(testRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range []struct {
+		point point
+		want  string
+	}{
+		{point{at: 0, bank: -40, agl: 500}, ""},
+		{point{at: 1, bank: -40, agl: 500}, ""}, // shorter than the minimum time
+		{point{at: 2, bank: -50, agl: 500}, "bank high 45 peak 50 at 2 0-"},
+		{point{at: 3, agl: 500}, ""},
+	} {
+		v.Update(c.point.sample())
+		if got := describe(v.Active()); got != c.want {
+			t.Errorf("second %d: active %q, want %q", c.point.at, got, c.want)
+		}
+	}
+}
+
+func TestNewEvaluatorKeepsRules(t *testing.T) {
+	rules := testRules()
+	if _, err := NewEvaluator(rules); err != nil {
+		t.Fatal(err)
+	}
+	if rules[0].Above[0].Severity != High {
+		t.Errorf("levels %v sorted in place", rules[0].Above)
+	}
+	if _, err := NewEvaluator(append(rules, Rule{ID: "x", Channel: "airplane.warp", Above: []Level{{Low, 1}}})); err == nil {
+		t.Error("invalid rule accepted")
+	}
+}
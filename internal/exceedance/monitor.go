@@ -0,0 +1,149 @@
+package exceedance
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// TopicExceedance carries a finished Event on the telemetry bus
+const TopicExceedance telemetry.Topic = "exceedance"
+
+// ReportKind is the kind of exceedance reports attached to a recording
+const ReportKind = "exceedance"
+
+// Monitor evaluates the live telemetry against the rules
+type Monitor struct {
+	logger *logadapter.LogzWailsAdapter
+
+	mu        sync.Mutex
+	rules     []Rule
+	eval      *Evaluator
+	env       telemetry.EnvironmentState
+	simulator telemetry.SimulatorState
+	haveSim   bool
+}
+
+// NewMonitor returns a monitor evaluating the built-in rules, see SetRules
+func NewMonitor() *Monitor {
+	rules := DefaultRules()
+	eval, err := NewEvaluator(rules)
+	if err != nil {
+		panic("invalid built-in exceedance rules: " + err.Error())
+	}
+	return &Monitor{rules: rules, eval: eval}
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (m *Monitor) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	m.logger = logger
+}
+
+// Rules returns the rules being evaluated
+func (m *Monitor) Rules() []Rule {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]Rule(nil), m.rules...)
+}
+
+// SetRules replaces the rules. Exceedances in progress are discarded.
+func (m *Monitor) SetRules(rules []Rule) error {
+	eval, err := NewEvaluator(rules)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.rules, m.eval = append([]Rule(nil), rules...), eval
+	return nil
+}
+
+// Active returns the exceedances in progress
+func (m *Monitor) Active() []Event {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.eval.Active()
+}
+
+// Attach feeds the monitor from bus and publishes finished exceedances as
+// TopicExceedance. Close the returned subscription to detach.
+func (m *Monitor) Attach(bus *telemetry.Bus) *telemetry.Subscription {
+	// Rules that must hold for a while see a dropped sample as a gap, which
+	// can shorten an exceedance or miss its peak
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicEnvironment,
+			telemetry.TopicConnection,
+		},
+		Buffer: 256,
+		Policy: telemetry.Block,
+	})
+	relay := telemetry.NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range sub.C() {
+			var done []Event
+			m.mu.Lock()
+			switch p := msg.Payload.(type) {
+			case telemetry.AirplaneState:
+				if m.haveSim {
+					done = m.eval.Update(engine.Sample{Time: msg.Time, Airplane: p, Environment: m.env, Simulator: m.simulator})
+				}
+			case telemetry.SimulatorState:
+				m.simulator, m.haveSim = p, true
+			case telemetry.EnvironmentState:
+				m.env = p
+			case telemetry.ConnectionStatus:
+				if !p.Connected {
+					done = m.eval.Flush()
+					m.haveSim = false
+				}
+			}
+			m.mu.Unlock()
+			for _, ev := range done {
+				m.logInfo("[Exceedance] ", ev.Severity, " ", ev.Name, ": peak ", fmt.Sprintf("%.1f", ev.Peak), " for ", fmt.Sprintf("%.0f", ev.Seconds), " s")
+				relay.Publish(TopicExceedance, ev)
+			}
+		}
+	}()
+	return sub
+}
+
+// Analyze evaluates a stored recording against rules and returns its
+// exceedances in order of their end
+func Analyze(e *engine.Engine, id string, rules []Rule) ([]Event, error) {
+	eval, err := NewEvaluator(rules)
+	if err != nil {
+		return nil, err
+	}
+	r, err := e.Open(id)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	var events []Event
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read recording: %w", err)
+		}
+		events = append(events, eval.Update(s)...)
+	}
+	return append(events, eval.Flush()...), nil
+}
+
+func (m *Monitor) logInfo(args ...interface{}) {
+	if m.logger != nil {
+		m.logger.Info(fmt.Sprint(args...))
+	}
+}
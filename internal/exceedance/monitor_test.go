@@ -0,0 +1,64 @@
+package exceedance
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+)
+
+const testID = "20260411-100000"
+
+// storeRecording writes points as the stored recording testID and returns an
+// engine reading it
+func storeRecording(t *testing.T, points []point) *engine.Engine {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, testID+".fdr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := flightrecording.NewWriter(f, flightrecording.Header{CreatedAt: testStart, Channels: engine.Channels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range points {
+		s := p.sample()
+		if err := w.WriteFrame(s.Time, s.Values()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return engine.New(nil, engine.Options{Dir: dir})
+}
+
+func TestAnalyze(t *testing.T) {
+	e := storeRecording(t, join(
+		level(0, 3, -40, -25),
+		[]point{{at: 4, agl: 500, paused: true}},
+		level(5, 6, -50, 0),
+		level(7, 8, 0, 0),
+		level(20, 22, 40, 0),
+	))
+	events, err := Analyze(e, testID, testRules())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// In order of their end, the last one at the end of the recording
+	want := "pitch high 20 peak 25 at 0 0-5, bank high 45 peak 50 at 5 0-7, bank medium 35 peak 40 at 20 20-22"
+	if got := describe(events); got != want {
+		t.Errorf("events %q, want %q", got, want)
+	}
+
+	if _, err := Analyze(e, "20260411-110000", testRules()); err == nil {
+		t.Error("missing recording analyzed")
+	}
+	if _, err := Analyze(e, testID, []Rule{{ID: "x", Channel: ChannelBank}}); err == nil {
+		t.Error("invalid rules accepted")
+	}
+}
@@ -0,0 +1,189 @@
+// Package exceedance detects FOQA style exceedances, e.g. a steep bank close
+// to the ground, by evaluating samples against configurable rules
+package exceedance
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+)
+
+// Severity of an exceedance, ordered low < medium < high
+type Severity string
+
+const (
+	Low    Severity = "low"
+	Medium Severity = "medium"
+	High   Severity = "high"
+)
+
+var severityRank = map[Severity]int{Low: 1, Medium: 2, High: 3}
+
+// Virtual channels with the attitude in the usual sign convention. The
+// recorded airplane.pitch is negative nose up and airplane.bank is negative
+// right wing down.
+const (
+	ChannelPitch = "attitude.pitch" // degrees, positive nose up
+	ChannelBank  = "attitude.bank"  // degrees, positive right wing down
+)
+
+// Level is the threshold of a severity
+type Level struct {
+	Severity Severity `json:"severity"`
+	Value    float64  `json:"value"`
+}
+
+// Condition restricts when a rule applies, e.g. only below 1000 ft AGL.
+// Booleans like simulator.on_ground are 1 or 0.
+type Condition struct {
+	Channel string   `json:"channel"`
+	Above   *float64 `json:"above,omitempty"`
+	Below   *float64 `json:"below,omitempty"`
+	Equals  *float64 `json:"equals,omitempty"`
+}
+
+// Rule is an exceedance of a channel above or below its levels. Exactly one
+// of Above and Below is set. The value must exceed the lowest level for
+// MinSeconds before it counts, the highest level reached sets the severity.
+type Rule struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	Channel    string      `json:"channel"` // recording channel or virtual attitude channel
+	Abs        bool        `json:"abs,omitempty"`
+	Above      []Level     `json:"above,omitempty"`
+	Below      []Level     `json:"below,omitempty"`
+	When       []Condition `json:"when,omitempty"`
+	MinSeconds float64     `json:"min_seconds,omitempty"`
+}
+
+// file is the JSON document of a rules file
+type file struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+//go:embed default_rules.json
+var defaultRules []byte
+
+// DefaultPath returns the default rules file in the user config dir
+func DefaultPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "mcrwfdr", "exceedances.json")
+}
+
+// DefaultRules returns the built-in rules
+func DefaultRules() []Rule {
+	rules, err := parseRules(defaultRules)
+	if err != nil {
+		panic("invalid built-in exceedance rules: " + err.Error())
+	}
+	return rules
+}
+
+// LoadRules reads and validates a rules file. A missing file is created with
+// the built-in rules, so they can be edited.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create rules directory: %w", err)
+		}
+		if err := os.WriteFile(path, defaultRules, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write default rules: %w", err)
+		}
+		return DefaultRules(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read rules: %w", err)
+	}
+	rules, err := parseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+	}
+	return rules, nil
+}
+
+func parseRules(data []byte) ([]Rule, error) {
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode rules: %w", err)
+	}
+	seen := map[string]bool{}
+	for i := range f.Rules {
+		r := &f.Rules[i]
+		if err := r.validate(); err != nil {
+			return nil, fmt.Errorf("rule %d %q: %w", i+1, r.ID, err)
+		}
+		if seen[r.ID] {
+			return nil, fmt.Errorf("rule %d: duplicate id %q", i+1, r.ID)
+		}
+		seen[r.ID] = true
+	}
+	return f.Rules, nil
+}
+
+// validate checks the rule and sorts its levels from the lowest severity
+func (r *Rule) validate() error {
+	if r.ID == "" {
+		return errors.New("missing id")
+	}
+	if r.Name == "" {
+		r.Name = r.ID
+	}
+	if !validChannel(r.Channel) {
+		return fmt.Errorf("unknown channel %q", r.Channel)
+	}
+	if (len(r.Above) == 0) == (len(r.Below) == 0) {
+		return errors.New("exactly one of above and below must have levels")
+	}
+	levels, above := r.Above, true
+	if len(r.Below) > 0 {
+		levels, above = r.Below, false
+	}
+	for _, l := range levels {
+		if severityRank[l.Severity] == 0 {
+			return fmt.Errorf("unknown severity %q", l.Severity)
+		}
+	}
+	sort.Slice(levels, func(i, j int) bool {
+		return severityRank[levels[i].Severity] < severityRank[levels[j].Severity]
+	})
+	for i := 1; i < len(levels); i++ {
+		if levels[i].Severity == levels[i-1].Severity {
+			return fmt.Errorf("severity %q set twice", levels[i].Severity)
+		}
+		if above && levels[i].Value < levels[i-1].Value || !above && levels[i].Value > levels[i-1].Value {
+			return errors.New("higher severities must have stricter values")
+		}
+	}
+	for _, c := range r.When {
+		if !validChannel(c.Channel) {
+			return fmt.Errorf("unknown condition channel %q", c.Channel)
+		}
+		if c.Above == nil && c.Below == nil && c.Equals == nil {
+			return fmt.Errorf("condition on %q has no above, below or equals", c.Channel)
+		}
+	}
+	if r.MinSeconds < 0 {
+		return errors.New("negative min_seconds")
+	}
+	return nil
+}
+
+func validChannel(name string) bool {
+	if name == ChannelPitch || name == ChannelBank {
+		return true
+	}
+	i := engine.ChannelIndex(name)
+	return i >= 0 && engine.Channels[i].Type != flightrecording.TypeString
+}
@@ -0,0 +1,70 @@
+package exceedance
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRules(t *testing.T) {
+	for _, c := range []struct {
+		name  string
+		rules string
+		err   string
+	}{
+		{"valid", `{"id": "bank", "channel": "attitude.bank", "above": [{"severity": "high", "value": 45}, {"severity": "low", "value": 30}]}`, ""},
+		{"missing id", `{"channel": "attitude.bank", "above": [{"severity": "low", "value": 30}]}`, "missing id"},
+		{"duplicate id", `{"id": "bank", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}]},
+			{"id": "bank", "channel": "airplane.bank", "below": [{"severity": "low", "value": -30}]}`, `duplicate id "bank"`},
+		{"unknown channel", `{"id": "x", "channel": "airplane.warp", "above": [{"severity": "low", "value": 1}]}`, `unknown channel "airplane.warp"`},
+		{"text channel", `{"id": "x", "channel": "airplane.title", "above": [{"severity": "low", "value": 1}]}`, `unknown channel "airplane.title"`},
+		{"unknown condition channel", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}],
+			"when": [{"channel": "airplane.warp", "below": 1}]}`, `unknown condition channel "airplane.warp"`},
+		{"empty condition", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}],
+			"when": [{"channel": "simulator.on_ground"}]}`, "has no above, below or equals"},
+		{"no levels", `{"id": "x", "channel": "attitude.bank"}`, "exactly one of above and below"},
+		{"above and below", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}],
+			"below": [{"severity": "low", "value": -30}]}`, "exactly one of above and below"},
+		{"unknown severity", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "severe", "value": 30}]}`, `unknown severity "severe"`},
+		{"severity twice", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}, {"severity": "low", "value": 40}]}`, `severity "low" set twice`},
+		{"higher severity above less strict", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}, {"severity": "high", "value": 25}]}`, "stricter values"},
+		{"higher severity below less strict", `{"id": "x", "channel": "airplane.vertical_speed", "below": [{"severity": "low", "value": -1000}, {"severity": "medium", "value": -500}]}`, "stricter values"},
+		{"negative minimum time", `{"id": "x", "channel": "attitude.bank", "above": [{"severity": "low", "value": 30}], "min_seconds": -1}`, "negative min_seconds"},
+	} {
+		rules, err := parseRules([]byte(`{"version": 1, "rules": [` + c.rules + `]}`))
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			} else if levels := rules[0].Above; levels[0].Severity != Low || levels[1].Severity != High || rules[0].Name != "bank" {
+				t.Errorf("%s: %+v, want the levels from low to high and the id as name", c.name, rules[0])
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.err)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config", "exceedances.json")
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != len(DefaultRules()) {
+		t.Errorf("%d rules, want the %d built-in rules", len(rules), len(DefaultRules()))
+	}
+	// The built-in rules are written for editing
+	if data, err := os.ReadFile(path); err != nil || string(data) != string(defaultRules) {
+		t.Errorf("rules file not created: %v", err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"version": 1, "rules": [{"id": "x"}]}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(path); err == nil || !strings.Contains(err.Error(), "exceedances.json") {
+		t.Errorf("error %v, want the invalid rule in exceedances.json", err)
+	}
+}
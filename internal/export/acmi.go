@@ -0,0 +1,132 @@
+package export
+
+import (
+	"archive/zip"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	// acmiObjectID is the Tacview object ID of the user aircraft
+	acmiObjectID = "1"
+	mpsPerFPM    = 0.00508
+	// acmiDefaultSource is the simulator of recordings without a data
+	// source, which were all recorded over SimConnect
+	acmiDefaultSource = "Microsoft Flight Simulator"
+)
+
+// writeACMI writes the recording as a Tacview ACMI 2.2 text file with the
+// user aircraft as the only object. Pause, crash and view changes become
+// ACMI events.
+func writeACMI(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	bw := bufio.NewWriter(w)
+	h := r.Header()
+	var (
+		start    time.Time
+		last     telemetry.SimulatorState
+		lastTime = -1.0
+		started  bool
+	)
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+		a := s.Airplane
+		if a.Latitude == 0 && a.Longitude == 0 {
+			continue
+		}
+		if !started {
+			started = true
+			start = s.Time
+			ref, ok := s.Environment.ZuluDateTime()
+			if !ok {
+				ref = h.CreatedAt
+			}
+			title := a.Title
+			if title == "" {
+				title = h.AircraftTitle
+			}
+			fmt.Fprintln(bw, "FileType=text/acmi/tacview")
+			fmt.Fprintln(bw, "FileVersion=2.2")
+			fmt.Fprintf(bw, "0,ReferenceTime=%s\n", ref.UTC().Format(time.RFC3339))
+			fmt.Fprintf(bw, "0,RecordingTime=%s\n", h.CreatedAt.UTC().Format(time.RFC3339))
+			source := h.DataSource
+			if source == "" {
+				source = acmiDefaultSource
+			}
+			fmt.Fprintf(bw, "0,DataSource=%s\n", acmiEscape(source))
+			fmt.Fprintf(bw, "0,DataRecorder=MyCrew.online FDR %s\n", acmiEscape(h.AppVersion))
+			if h.FlightLoaded != "" {
+				fmt.Fprintf(bw, "0,Title=%s\n", acmiEscape(h.FlightLoaded))
+			}
+			fmt.Fprintf(bw, "#0\n%s,Type=Air+FixedWing,Name=%s,Color=Blue\n", acmiObjectID, acmiEscape(title))
+			last = s.Simulator
+		}
+		offset := s.Time.Sub(start).Seconds()
+		if offset != lastTime {
+			fmt.Fprintf(bw, "#%.2f\n", offset)
+			lastTime = offset
+		}
+		fmt.Fprintf(bw, "%s,T=%.7f|%.7f|%.2f|%.1f|%.1f|%.1f,IAS=%.2f,TAS=%.2f,AOA=%.1f,VerticalSpeed=%.2f\n",
+			acmiObjectID,
+			a.Longitude, a.Latitude, a.Altitude*metersPerFoot,
+			simBank(a.Bank), simPitch(a.Pitch), normalizeHeading(a.Heading),
+			a.Airspeed*mpsPerKnot, a.AirspeedTrue*mpsPerKnot, a.AngleOfAttack, a.VerticalSpeed*mpsPerFPM)
+		writeACMIEvents(bw, last, s.Simulator)
+		last = s.Simulator
+	}
+	if !started {
+		return errors.New("recording has no position data")
+	}
+	return bw.Flush()
+}
+
+// writeACMIEvents writes an event for every simulator state change between
+// prev and cur
+func writeACMIEvents(w io.Writer, prev, cur telemetry.SimulatorState) {
+	if prev.Pause != cur.Pause {
+		if cur.Pause != 0 {
+			fmt.Fprintf(w, "0,Event=Bookmark|%s|Paused\n", acmiObjectID)
+		} else {
+			fmt.Fprintf(w, "0,Event=Bookmark|%s|Resumed\n", acmiObjectID)
+		}
+	}
+	if prev.Crashed == 0 && cur.Crashed != 0 {
+		fmt.Fprintf(w, "0,Event=Destroyed|%s|\n", acmiObjectID)
+	}
+	if prev.View != cur.View {
+		fmt.Fprintf(w, "0,Event=Message|%s|View changed to %d\n", acmiObjectID, cur.View)
+	}
+}
+
+// writeACMIZip writes the ACMI text file compressed in a zip archive, as
+// read by Tacview from .zip.acmi files
+func writeACMIZip(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	zw := zip.NewWriter(w)
+	f, err := zw.CreateHeader(&zip.FileHeader{
+		Name:     "flight.txt.acmi",
+		Method:   zip.Deflate,
+		Modified: r.Header().CreatedAt,
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeACMI(f, r, opts); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// acmiEscape escapes the characters with a meaning in ACMI property values
+var acmiEscape = strings.NewReplacer(`\`, `\\`, ",", `\,`, "\n", `\`+"\n").Replace
@@ -0,0 +1,32 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+func TestACMIDataSource(t *testing.T) {
+	samples := []engine.Sample{{
+		Time:     testStart,
+		Airplane: telemetry.AirplaneState{Title: "C172", Latitude: 50.1, Longitude: 14.26, Altitude: 1200},
+	}}
+	for _, c := range []struct {
+		source string
+		want   string
+	}{
+		{"X-Plane", "0,DataSource=X-Plane\n"},
+		{"FlightGear", "0,DataSource=FlightGear\n"},
+		// Recordings made before the data source was stored
+		{"", "0,DataSource=Microsoft Flight Simulator\n"},
+	} {
+		e := storeRecording(t, flightrecording.Header{CreatedAt: testStart, AppVersion: "1.0.0", DataSource: c.source}, samples)
+		acmi := export(t, e, FormatACMI, Options{})
+		if !strings.Contains(acmi, c.want) {
+			t.Errorf("source %q: ACMI does not contain %q:\n%s", c.source, c.want, acmi)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package export
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+const (
+	csvTimeFormat    = "2006-01-02T15:04:05.000Z07:00"
+	csvSimZuluFormat = "2006-01-02T15:04:05Z07:00"
+)
+
+// writeCSV writes one row per sample: the UTC time of the sample, the
+// simulator Zulu time and the selected channels. Column headers carry the
+// unit, e.g. "airplane.altitude [meters]".
+func writeCSV(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	indexes, err := selectChannels(opts.Channels)
+	if err != nil {
+		return err
+	}
+	convert := make([]func(float64) float64, len(indexes))
+	header := []string{"time_utc", "sim_zulu"}
+	for i, idx := range indexes {
+		c := engine.Channels[idx]
+		unit, fn := convertUnit(opts.Units, c.Unit)
+		convert[i] = fn
+		if unit != "" {
+			header = append(header, fmt.Sprintf("%s [%s]", c.Name, unit))
+		} else {
+			header = append(header, c.Name)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	row := make([]string, len(header))
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+		row[0] = s.Time.UTC().Format(csvTimeFormat)
+		row[1] = ""
+		if zulu, ok := s.Environment.ZuluDateTime(); ok {
+			row[1] = zulu.Format(csvSimZuluFormat)
+		}
+		values := s.Values()
+		for i, idx := range indexes {
+			row[i+2] = formatValue(values[idx], convert[i])
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatValue(v any, convert func(float64) float64) string {
+	switch v := v.(type) {
+	case float64:
+		if convert != nil {
+			v = convert(v)
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,97 @@
+package export
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// csvSamples are a sample with the simulator date and one before the
+// simulator reported it
+func csvSamples() []engine.Sample {
+	return []engine.Sample{
+		{
+			Time: testStart.Add(250 * time.Millisecond),
+			Airplane: telemetry.AirplaneState{
+				Title:    "Cessna 172, G1000",
+				Altitude: 1000,
+				Airspeed: 125,
+			},
+			Environment: telemetry.EnvironmentState{
+				ZuluYear: 2026, ZuluMonth: 4, ZuluDay: 11, ZuluTime: 12*3600 + 30*60 + 15,
+				AmbientTemperature: 15,
+				AmbientVisibility:  1609.344,
+			},
+			Simulator: telemetry.SimulatorState{OnGround: true},
+		},
+		{
+			Time:     testStart.Add(time.Second),
+			Airplane: telemetry.AirplaneState{Title: "Cessna 172, G1000", Altitude: -10, Airspeed: 62.5},
+		},
+	}
+}
+
+func TestCSVHeader(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart}, csvSamples())
+	header := strings.Split(strings.SplitN(export(t, e, FormatCSV, Options{}), "\n", 2)[0], ",")
+	// All channels in the order of the channel table, after the times
+	want := []string{"time_utc", "sim_zulu"}
+	for _, c := range engine.Channels {
+		if c.Unit != "" {
+			want = append(want, fmt.Sprintf("%s [%s]", c.Name, c.Unit))
+		} else {
+			want = append(want, c.Name)
+		}
+	}
+	if strings.Join(header, ",") != strings.Join(want, ",") {
+		t.Errorf("header\n%q\nwant\n%q", header, want)
+	}
+}
+
+func TestCSV(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart}, csvSamples())
+	channels := []string{
+		"airplane.altitude",
+		"airplane.title",
+		"simulator.on_ground",
+		"airplane.airspeed",
+		"environment.ambient_temperature",
+		"environment.ambient_visibility",
+	}
+	for _, c := range []struct {
+		units Units
+		want  string
+	}{
+		{"", "" +
+			"time_utc,sim_zulu,airplane.altitude [feet],airplane.title,simulator.on_ground,airplane.airspeed [knots],environment.ambient_temperature [celsius],environment.ambient_visibility [meters]\n" +
+			"2026-04-11T10:00:00.250Z,2026-04-11T12:30:15Z,1000,\"Cessna 172, G1000\",true,125,15,1609.344\n" +
+			"2026-04-11T10:00:01.000Z,,-10,\"Cessna 172, G1000\",false,62.5,0,0\n"},
+		{UnitsMetric, "" +
+			"time_utc,sim_zulu,airplane.altitude [meters],airplane.title,simulator.on_ground,airplane.airspeed [km/h],environment.ambient_temperature [celsius],environment.ambient_visibility [meters]\n" +
+			"2026-04-11T10:00:00.250Z,2026-04-11T12:30:15Z,304.8,\"Cessna 172, G1000\",true,231.5,15,1609.344\n" +
+			"2026-04-11T10:00:01.000Z,,-3.048,\"Cessna 172, G1000\",false,115.75,0,0\n"},
+		{UnitsImperial, "" +
+			"time_utc,sim_zulu,airplane.altitude [feet],airplane.title,simulator.on_ground,airplane.airspeed [mph],environment.ambient_temperature [fahrenheit],environment.ambient_visibility [statute miles]\n" +
+			"2026-04-11T10:00:00.250Z,2026-04-11T12:30:15Z,1000,\"Cessna 172, G1000\",true,143.847375,59,1\n" +
+			"2026-04-11T10:00:01.000Z,,-10,\"Cessna 172, G1000\",false,71.9236875,32,0\n"},
+	} {
+		got := export(t, e, FormatCSV, Options{Channels: channels, Units: c.units})
+		if got != c.want {
+			t.Errorf("units %q:\n%s\nwant\n%s", c.units, got, c.want)
+		}
+	}
+}
+
+func TestCSVUnknownChannel(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart}, csvSamples())
+	var b strings.Builder
+	err := Export(e, testID, FormatCSV, Options{Channels: []string{"airplane.altitude", "airplane.warp"}}, &b)
+	if err == nil || !strings.Contains(err.Error(), `unknown channel "airplane.warp"`) {
+		t.Errorf("error %v, want the unknown channel", err)
+	}
+}
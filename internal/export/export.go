@@ -0,0 +1,135 @@
+// Package export converts stored recordings to formats used by other tools
+package export
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+// Format identifies an export file format
+type Format string
+
+const (
+	FormatCSV Format = "csv"
+	FormatGPX Format = "gpx"
+	FormatKML Format = "kml"
+	// FormatACMI is a Tacview text file, FormatACMIZip the same compressed
+	FormatACMI    Format = "acmi"
+	FormatACMIZip Format = "acmi-zip"
+	FormatIGC     Format = "igc"
+)
+
+var ErrUnknownFormat = errors.New("unknown export format")
+
+// Options configures an export. Not every format uses every option.
+type Options struct {
+	// Channels to export, e.g. "airplane.altitude"; all channels when empty
+	Channels []string `json:"channels"`
+	// Units converts values to a unit system, UnitsAviation when empty
+	Units Units `json:"units"`
+	// Path of the exported file, chosen by the caller when empty
+	Path string `json:"path"`
+	// Pilot is written to the IGC header
+	Pilot string `json:"pilot"`
+	// SecurityKey signs IGC files, a key generated per installation when empty
+	SecurityKey string `json:"security_key"`
+}
+
+// exporter writes a recording in one format
+type exporter struct {
+	ext   string
+	write func(w io.Writer, r *engine.RecordingReader, opts Options) error
+}
+
+var exporters = map[Format]exporter{
+	FormatCSV: {ext: ".csv", write: writeCSV},
+	FormatGPX: {ext: ".gpx", write: writeGPX},
+	FormatKML: {ext: ".kml", write: writeKML},
+
+	FormatACMI:    {ext: ".txt.acmi", write: writeACMI},
+	FormatACMIZip: {ext: ".zip.acmi", write: writeACMIZip},
+	FormatIGC:     {ext: ".igc", write: writeIGC},
+}
+
+// Formats returns the supported formats
+func Formats() []Format {
+	formats := make([]Format, 0, len(exporters))
+	for f := range exporters {
+		formats = append(formats, f)
+	}
+	sort.Slice(formats, func(i, j int) bool { return formats[i] < formats[j] })
+	return formats
+}
+
+// Extension returns the file extension of format including the dot
+func Extension(format Format) (string, error) {
+	x, ok := exporters[format]
+	if !ok {
+		return "", fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	return x.ext, nil
+}
+
+// Export writes the recording id in the given format to w
+func Export(e *engine.Engine, id string, format Format, opts Options, w io.Writer) error {
+	x, ok := exporters[format]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+	}
+	if _, ok := unitSystems[opts.Units]; !ok && opts.Units != "" {
+		return fmt.Errorf("unknown unit system %q", opts.Units)
+	}
+	r, err := e.Open(id)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return x.write(w, r, opts)
+}
+
+// ExportFile exports the recording id to opts.Path. A partially written
+// file is removed when the export fails.
+func ExportFile(e *engine.Engine, id string, format Format, opts Options) error {
+	if opts.Path == "" {
+		return errors.New("no export path given")
+	}
+	f, err := os.Create(opts.Path)
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	if err := Export(e, id, format, opts, f); err != nil {
+		f.Close()
+		os.Remove(opts.Path)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(opts.Path)
+		return fmt.Errorf("failed to write export file: %w", err)
+	}
+	return nil
+}
+
+// selectChannels resolves channel names to indexes into engine.Channels
+func selectChannels(names []string) ([]int, error) {
+	if len(names) == 0 {
+		all := make([]int, len(engine.Channels))
+		for i := range all {
+			all[i] = i
+		}
+		return all, nil
+	}
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		i := engine.ChannelIndex(name)
+		if i < 0 {
+			return nil, fmt.Errorf("unknown channel %q", name)
+		}
+		indexes = append(indexes, i)
+	}
+	return indexes, nil
+}
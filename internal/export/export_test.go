@@ -0,0 +1,53 @@
+package export
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+)
+
+const testID = "20260411-100000"
+
+// storeRecording writes samples as the stored recording testID and returns
+// an engine reading it
+func storeRecording(t *testing.T, h flightrecording.Header, samples []engine.Sample) *engine.Engine {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, testID+".fdr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	h.Channels = engine.Channels
+	w, err := flightrecording.NewWriter(f, h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range samples {
+		if err := w.WriteFrame(s.Time, s.Values()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return engine.New(nil, engine.Options{Dir: dir})
+}
+
+// export returns the recording of e exported in format
+func export(t *testing.T, e *engine.Engine, format Format, opts Options) string {
+	t.Helper()
+	var b bytes.Buffer
+	if err := Export(e, testID, format, opts, &b); err != nil {
+		t.Fatal(err)
+	}
+	return b.String()
+}
+
+// testStart is the time of the first sample of test recordings
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
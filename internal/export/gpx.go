@@ -0,0 +1,57 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+// writeGPX writes the flight path as a GPX 1.1 track. Heading and ground
+// speed are stored in the Garmin TrackPointExtension as course and speed.
+func writeGPX(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	t, err := readTrack(r)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<gpx version="1.1" creator="MyCrew.online FDR"`)
+	fmt.Fprintln(bw, `  xmlns="http://www.topografix.com/GPX/1/1"`)
+	fmt.Fprintln(bw, `  xmlns:gpxtpx="http://www.garmin.com/xmlschemas/TrackPointExtension/v2"`)
+	fmt.Fprintln(bw, `  xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`)
+	fmt.Fprintln(bw, `  xsi:schemaLocation="http://www.topografix.com/GPX/1/1 http://www.topografix.com/GPX/1/1/gpx.xsd http://www.garmin.com/xmlschemas/TrackPointExtension/v2 http://www8.garmin.com/xmlschemas/TrackPointExtensionv2.xsd">`)
+	fmt.Fprintf(bw, "  <metadata>\n    <name>%s</name>\n", escape(t.Name))
+	if len(t.Points) > 0 {
+		fmt.Fprintf(bw, "    <time>%s</time>\n", t.Points[0].Time.Format(time.RFC3339))
+	}
+	fmt.Fprintln(bw, "  </metadata>")
+	fmt.Fprintf(bw, "  <trk>\n    <name>%s</name>\n    <trkseg>\n", escape(t.Name))
+	for _, p := range t.Points {
+		fmt.Fprintf(bw, "      <trkpt lat=\"%.7f\" lon=\"%.7f\">\n", p.Latitude, p.Longitude)
+		fmt.Fprintf(bw, "        <ele>%.2f</ele>\n", p.Altitude)
+		fmt.Fprintf(bw, "        <time>%s</time>\n", p.Time.Format(time.RFC3339Nano))
+		fmt.Fprintln(bw, "        <extensions>")
+		fmt.Fprintln(bw, "          <gpxtpx:TrackPointExtension>")
+		fmt.Fprintf(bw, "            <gpxtpx:speed>%.2f</gpxtpx:speed>\n", p.GroundSpeed)
+		fmt.Fprintf(bw, "            <gpxtpx:course>%.1f</gpxtpx:course>\n", normalizeHeading(p.Heading))
+		fmt.Fprintln(bw, "          </gpxtpx:TrackPointExtension>")
+		fmt.Fprintln(bw, "        </extensions>")
+		fmt.Fprintln(bw, "      </trkpt>")
+	}
+	fmt.Fprintln(bw, "    </trkseg>\n  </trk>\n</gpx>")
+	return bw.Flush()
+}
+
+// normalizeHeading maps a heading to [0, 360)
+func normalizeHeading(h float64) float64 {
+	for h < 0 {
+		h += 360
+	}
+	for h >= 360 {
+		h -= 360
+	}
+	return h
+}
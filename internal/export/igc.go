@@ -0,0 +1,192 @@
+package export
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+const (
+	// igcManufacturer identifies the recorder in the A record. Recorders
+	// not approved by the IGC use a three character code starting with X.
+	igcManufacturer = "XMC"
+	igcSerial       = "FDR"
+	// igcKeyFile stores the generated security key in the config directory
+	igcKeyFile = "igc.key"
+)
+
+// writeIGC writes the recording as an IGC flight log: A and H records
+// describing recorder, pilot and glider, one B record per second and a G
+// record holding an HMAC-SHA256 of all other records.
+func writeIGC(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	key, err := igcKey(opts.SecurityKey)
+	if err != nil {
+		return err
+	}
+	h := r.Header()
+	iw := &igcWriter{w: bufio.NewWriter(w), mac: hmac.New(sha256.New, key)}
+	var lastFix time.Time
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read recording: %w", err)
+		}
+		a := s.Airplane
+		if a.Latitude == 0 && a.Longitude == 0 {
+			continue
+		}
+		t, ok := s.Environment.ZuluDateTime()
+		if !ok {
+			t = s.Time.UTC()
+		}
+		if lastFix.IsZero() {
+			title := a.Title
+			if title == "" {
+				title = h.AircraftTitle
+			}
+			iw.header(t, opts.Pilot, title, h.AppVersion)
+		} else if t.Truncate(time.Second).Equal(lastFix) {
+			// B records have a resolution of one second
+			continue
+		}
+		lastFix = t.Truncate(time.Second)
+		iw.fix(t, a.Latitude, a.Longitude, s.Environment.PressureAltitude(a.Altitude)*metersPerFoot, a.Altitude*metersPerFoot)
+	}
+	if lastFix.IsZero() {
+		return errors.New("recording has no position data")
+	}
+	return iw.close()
+}
+
+// igcWriter writes CRLF terminated IGC records and hashes them for the G record
+type igcWriter struct {
+	w   *bufio.Writer
+	mac hash.Hash
+	err error
+}
+
+func (iw *igcWriter) record(format string, args ...any) {
+	line := fmt.Sprintf(format, args...)
+	iw.mac.Write([]byte(line))
+	if _, err := iw.w.WriteString(line + "\r\n"); err != nil && iw.err == nil {
+		iw.err = err
+	}
+}
+
+func (iw *igcWriter) header(date time.Time, pilot, glider, version string) {
+	iw.record("A%s%s", igcManufacturer, igcSerial)
+	iw.record("HFDTEDATE:%s,01", date.Format("020106"))
+	iw.record("HFFXA035")
+	iw.record("HFPLTPILOTINCHARGE:%s", igcText(pilot))
+	iw.record("HFCM2CREW2:NIL")
+	iw.record("HFGTYGLIDERTYPE:%s", igcText(glider))
+	iw.record("HFGIDGLIDERID:")
+	iw.record("HFDTMGPSDATUM:WGS84")
+	iw.record("HFRFWFIRMWAREVERSION:%s", igcText(version))
+	iw.record("HFRHWHARDWAREVERSION:Flight Simulator")
+	iw.record("HFFTYFRTYPE:MyCrew.online,FDR")
+	iw.record("HFGPSRECEIVER:Simulator")
+	iw.record("HFPRSPRESSALTSENSOR:Simulator")
+	iw.record("HFALGALTGPS:GEO")
+	iw.record("HFALPALTPRESSURE:ISA")
+}
+
+// fix writes a B record; altitudes are in meters
+func (iw *igcWriter) fix(t time.Time, lat, lon, pressureAlt, gnssAlt float64) {
+	iw.record("B%s%s%sA%s%s",
+		t.Format("150405"),
+		igcCoordinate(lat, 2, "N", "S"),
+		igcCoordinate(lon, 3, "E", "W"),
+		igcAltitude(pressureAlt),
+		igcAltitude(gnssAlt))
+}
+
+// close appends the G record and flushes the output
+func (iw *igcWriter) close() error {
+	sum := strings.ToUpper(hex.EncodeToString(iw.mac.Sum(nil)))
+	for len(sum) > 0 {
+		n := min(len(sum), 32)
+		if _, err := iw.w.WriteString("G" + sum[:n] + "\r\n"); err != nil && iw.err == nil {
+			iw.err = err
+		}
+		sum = sum[n:]
+	}
+	if iw.err != nil {
+		return iw.err
+	}
+	return iw.w.Flush()
+}
+
+// igcCoordinate formats degrees as DDMMmmm or DDDMMmmm followed by the hemisphere
+func igcCoordinate(deg float64, width int, pos, neg string) string {
+	hemi := pos
+	if deg < 0 {
+		hemi = neg
+		deg = -deg
+	}
+	thousandths := int(math.Round(deg * 60000)) // minutes * 1000
+	return fmt.Sprintf("%0*d%05d%s", width, thousandths/60000, thousandths%60000, hemi)
+}
+
+// igcAltitude formats meters as five characters, negative values as -NNNN
+func igcAltitude(m float64) string {
+	v := int(math.Round(m))
+	if v < 0 {
+		return fmt.Sprintf("-%04d", min(-v, 9999))
+	}
+	return fmt.Sprintf("%05d", min(v, 99999))
+}
+
+// igcText restricts header values to the printable ASCII allowed in IGC files
+func igcText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e {
+			return -1
+		}
+		return r
+	}, s)
+}
+
+// igcKey returns the security key of the G record: the configured key, or
+// a random key generated on first use and stored in the config directory
+func igcKey(configured string) ([]byte, error) {
+	if configured != "" {
+		return []byte(configured), nil
+	}
+	base, err := os.UserConfigDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate IGC key: %w", err)
+	}
+	path := filepath.Join(base, "mcrwfdr", igcKeyFile)
+	if key, err := os.ReadFile(path); err == nil && len(key) > 0 {
+		return key, nil
+	}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, err
+	}
+	key := []byte(hex.EncodeToString(raw))
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to store IGC key: %w", err)
+	}
+	if err := os.WriteFile(path, key, 0o600); err != nil {
+		return nil, fmt.Errorf("failed to store IGC key: %w", err)
+	}
+	return key, nil
+}
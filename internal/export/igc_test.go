@@ -0,0 +1,196 @@
+package export
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const testKey = "0123456789abcdef"
+
+// igcLines splits an IGC file into records, checking the CRLF line ends
+func igcLines(t *testing.T, igc string) []string {
+	t.Helper()
+	if !strings.HasSuffix(igc, "\r\n") {
+		t.Fatal("IGC file does not end with CRLF")
+	}
+	lines := strings.Split(strings.TrimSuffix(igc, "\r\n"), "\r\n")
+	for _, line := range lines {
+		if strings.ContainsAny(line, "\r\n") {
+			t.Fatalf("record %q is not CRLF terminated", line)
+		}
+	}
+	return lines
+}
+
+// verifyIGC checks the G record of an IGC file against key
+func verifyIGC(lines []string, key string) bool {
+	mac := hmac.New(sha256.New, []byte(key))
+	var g strings.Builder
+	for _, line := range lines {
+		if strings.HasPrefix(line, "G") {
+			g.WriteString(line[1:])
+			continue
+		}
+		mac.Write([]byte(line))
+	}
+	return g.String() == strings.ToUpper(hex.EncodeToString(mac.Sum(nil)))
+}
+
+func records(lines []string, kind string) []string {
+	var out []string
+	for _, line := range lines {
+		if strings.HasPrefix(line, kind) {
+			out = append(out, line)
+		}
+	}
+	return out
+}
+
+func TestIGC(t *testing.T) {
+	// Four samples a second for three seconds, after a sample without a
+	// position that is skipped
+	samples := []engine.Sample{{Time: testStart.Add(-time.Second)}}
+	for i := 0; i < 12; i++ {
+		samples = append(samples, engine.Sample{
+			Time: testStart.Add(time.Duration(i) * 250 * time.Millisecond),
+			Airplane: telemetry.AirplaneState{
+				Title:     "C172",
+				Latitude:  50.1,
+				Longitude: 14.26,
+				Altitude:  1200 + float64(i),
+			},
+			Environment: telemetry.EnvironmentState{SeaLevelPressure: telemetry.StandardPressure},
+		})
+	}
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart, AppVersion: "1.0.0"}, samples)
+	lines := igcLines(t, export(t, e, FormatIGC, Options{Pilot: "Jan Novák", SecurityKey: testKey}))
+
+	if lines[0] != "AXMCFDR" {
+		t.Errorf("A record %q, want AXMCFDR", lines[0])
+	}
+	headers := records(lines, "H")
+	for _, want := range []string{
+		"HFDTEDATE:110426,01",
+		"HFPLTPILOTINCHARGE:Jan Novk", // IGC files are printable ASCII
+		"HFGTYGLIDERTYPE:C172",
+		"HFRFWFIRMWAREVERSION:1.0.0",
+		"HFDTMGPSDATUM:WGS84",
+	} {
+		if !contains(headers, want) {
+			t.Errorf("no header %q in %q", want, headers)
+		}
+	}
+
+	// One B record a second with the altitude of the first sample of the second
+	want := []string{
+		"B1000005006000N01415600EA0036600366",
+		"B1000015006000N01415600EA0036700367",
+		"B1000025006000N01415600EA0036800368",
+	}
+	fixes := records(lines, "B")
+	if strings.Join(fixes, "\n") != strings.Join(want, "\n") {
+		t.Errorf("B records\n%s\nwant\n%s", strings.Join(fixes, "\n"), strings.Join(want, "\n"))
+	}
+
+	g := records(lines, "G")
+	if len(g) != 2 || len(g[0]) != 33 || len(g[1]) != 33 {
+		t.Errorf("G records %q, want the 64 hex digits of the HMAC in two records", g)
+	}
+	if lines[len(lines)-1] != g[len(g)-1] {
+		t.Error("G record is not the last record")
+	}
+	if !verifyIGC(lines, testKey) {
+		t.Error("G record does not verify with the key")
+	}
+	if verifyIGC(lines, "another key") {
+		t.Error("G record verifies with another key")
+	}
+	tampered := append([]string(nil), lines...)
+	for i, line := range tampered {
+		if strings.HasPrefix(line, "B") {
+			tampered[i] = strings.Replace(line, "N", "S", 1)
+			break
+		}
+	}
+	if verifyIGC(tampered, testKey) {
+		t.Error("G record verifies after a B record was changed")
+	}
+}
+
+func TestIGCFixFormat(t *testing.T) {
+	zulu := time.Date(2026, 4, 12, 8, 30, 15, 0, time.UTC)
+	samples := []engine.Sample{{
+		Time: testStart,
+		Airplane: telemetry.AirplaneState{
+			Latitude:  -33.9461,
+			Longitude: -151.1772,
+			Altitude:  -100,
+		},
+		// The simulator time is used when known
+		Environment: telemetry.EnvironmentState{
+			ZuluYear:         2026,
+			ZuluMonth:        4,
+			ZuluDay:          12,
+			ZuluTime:         8*3600 + 30*60 + 15,
+			SeaLevelPressure: 30.92,
+		},
+	}}
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart, AircraftTitle: "Header title"}, samples)
+	lines := igcLines(t, export(t, e, FormatIGC, Options{SecurityKey: testKey}))
+
+	if !contains(lines, "HFDTEDATE:"+zulu.Format("020106")+",01") {
+		t.Errorf("no date header for %v", zulu)
+	}
+	// Without an aircraft title in the samples the header title is used
+	if !contains(lines, "HFGTYGLIDERTYPE:Header title") {
+		t.Error("no glider type from the recording header")
+	}
+	// 33°56.766'S 151°10.632'W, pressure altitude about 1000 ft below the
+	// true altitude at a sea level pressure of 30.92 inHg
+	if fixes := records(lines, "B"); len(fixes) != 1 || fixes[0] != "B0830153356766S15110632WA-0335-0030" {
+		t.Errorf("B records %q, want [B0830153356766S15110632WA-0335-0030]", fixes)
+	}
+}
+
+func TestIGCWithoutPosition(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart}, []engine.Sample{{Time: testStart}})
+	var b bytes.Buffer
+	if err := Export(e, testID, FormatIGC, Options{SecurityKey: testKey}, &b); err == nil {
+		t.Error("exported a recording without position data")
+	}
+}
+
+func TestIGCAltitude(t *testing.T) {
+	for _, c := range []struct {
+		meters float64
+		want   string
+	}{
+		{0, "00000"},
+		{365.76, "00366"},
+		{99999.6, "99999"},
+		{-30.48, "-0030"},
+		{-12000, "-9999"},
+	} {
+		if got := igcAltitude(c.meters); got != c.want {
+			t.Errorf("igcAltitude(%v) = %q, want %q", c.meters, got, c.want)
+		}
+	}
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
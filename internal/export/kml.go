@@ -0,0 +1,82 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+// writeKML writes the flight path as KML: an extruded path at absolute
+// altitude, placemarks for takeoff and landing and a time animated gx:Track
+// carrying heading, pitch and bank as gx:angles and ground speed as
+// extended data.
+func writeKML(w io.Writer, r *engine.RecordingReader, opts Options) error {
+	t, err := readTrack(r)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, `<?xml version="1.0" encoding="UTF-8"?>`)
+	fmt.Fprintln(bw, `<kml xmlns="http://www.opengis.net/kml/2.2" xmlns:gx="http://www.google.com/kml/ext/2.2">`)
+	fmt.Fprintf(bw, "<Document>\n  <name>%s</name>\n", escape(t.Name))
+	fmt.Fprint(bw, `  <Style id="path">
+    <LineStyle><color>ff00a5ff</color><width>3</width></LineStyle>
+    <PolyStyle><color>4000a5ff</color></PolyStyle>
+  </Style>
+  <Style id="aircraft">
+    <IconStyle><Icon><href>http://maps.google.com/mapfiles/kml/shapes/airports.png</href></Icon></IconStyle>
+    <LineStyle><color>ff0000ff</color><width>2</width></LineStyle>
+  </Style>
+  <Schema id="fdr">
+    <gx:SimpleArrayField name="speed" type="float"><displayName>Ground speed (m/s)</displayName></gx:SimpleArrayField>
+  </Schema>
+`)
+
+	// Extruded path
+	fmt.Fprintln(bw, "  <Placemark>\n    <name>Flight path</name>\n    <styleUrl>#path</styleUrl>")
+	fmt.Fprintln(bw, "    <LineString>\n      <extrude>1</extrude>\n      <tessellate>1</tessellate>\n      <altitudeMode>absolute</altitudeMode>\n      <coordinates>")
+	for _, p := range t.Points {
+		fmt.Fprintf(bw, "        %.7f,%.7f,%.2f\n", p.Longitude, p.Latitude, p.Altitude)
+	}
+	fmt.Fprintln(bw, "      </coordinates>\n    </LineString>\n  </Placemark>")
+
+	// Takeoff and landing
+	takeoff, landing := t.takeoffLanding()
+	writeKMLPoint(bw, "Takeoff", t.Points, takeoff)
+	writeKMLPoint(bw, "Landing", t.Points, landing)
+
+	// Time animated track
+	fmt.Fprintf(bw, "  <Placemark>\n    <name>%s</name>\n    <styleUrl>#aircraft</styleUrl>\n", escape(t.Name))
+	fmt.Fprintln(bw, "    <gx:Track>\n      <altitudeMode>absolute</altitudeMode>")
+	for _, p := range t.Points {
+		fmt.Fprintf(bw, "      <when>%s</when>\n", p.Time.Format(time.RFC3339Nano))
+	}
+	for _, p := range t.Points {
+		fmt.Fprintf(bw, "      <gx:coord>%.7f %.7f %.2f</gx:coord>\n", p.Longitude, p.Latitude, p.Altitude)
+	}
+	for _, p := range t.Points {
+		// KML tilt is positive nose up, roll positive right wing down
+		fmt.Fprintf(bw, "      <gx:angles>%.1f %.1f %.1f</gx:angles>\n", normalizeHeading(p.Heading), p.Pitch, p.Bank)
+	}
+	fmt.Fprintln(bw, "      <ExtendedData>\n        <SchemaData schemaUrl=\"#fdr\">\n          <gx:SimpleArrayData name=\"speed\">")
+	for _, p := range t.Points {
+		fmt.Fprintf(bw, "            <gx:value>%.2f</gx:value>\n", p.GroundSpeed)
+	}
+	fmt.Fprintln(bw, "          </gx:SimpleArrayData>\n        </SchemaData>\n      </ExtendedData>")
+	fmt.Fprintln(bw, "    </gx:Track>\n  </Placemark>\n</Document>\n</kml>")
+	return bw.Flush()
+}
+
+func writeKMLPoint(w io.Writer, name string, points []trackPoint, i int) {
+	if i < 0 {
+		return
+	}
+	p := points[i]
+	fmt.Fprintf(w, "  <Placemark>\n    <name>%s</name>\n", name)
+	fmt.Fprintf(w, "    <TimeStamp><when>%s</when></TimeStamp>\n", p.Time.Format(time.RFC3339))
+	fmt.Fprintf(w, "    <Point>\n      <altitudeMode>absolute</altitudeMode>\n      <coordinates>%.7f,%.7f,%.2f</coordinates>\n    </Point>\n", p.Longitude, p.Latitude, p.Altitude)
+	fmt.Fprintln(w, "  </Placemark>")
+}
@@ -0,0 +1,114 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+)
+
+const (
+	metersPerFoot = 0.3048
+	mpsPerKnot    = 0.514444
+)
+
+// trackPoint is a recorded position of the aircraft
+type trackPoint struct {
+	Time        time.Time
+	Latitude    float64 // degrees
+	Longitude   float64 // degrees
+	Altitude    float64 // meters MSL
+	Heading     float64 // degrees true
+	Pitch       float64 // degrees, positive nose up
+	Bank        float64 // degrees, positive right wing down
+	GroundSpeed float64 // meters per second
+	OnGround    bool
+}
+
+// track is the flight path of a recording
+type track struct {
+	Name   string
+	Points []trackPoint
+}
+
+// readTrack reads the positions of a recording. Samples without a position
+// and samples that did not move the aircraft are skipped.
+func readTrack(r *engine.RecordingReader) (track, error) {
+	h := r.Header()
+	t := track{Name: h.AircraftTitle}
+	if t.Name == "" {
+		t.Name = "Flight"
+	}
+	t.Name += " " + h.CreatedAt.UTC().Format("2006-01-02 15:04Z")
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			return t, nil
+		}
+		if err != nil {
+			return t, fmt.Errorf("failed to read recording: %w", err)
+		}
+		a := s.Airplane
+		if a.Latitude == 0 && a.Longitude == 0 {
+			continue
+		}
+		p := trackPoint{
+			Time:        s.Time.UTC(),
+			Latitude:    a.Latitude,
+			Longitude:   a.Longitude,
+			Altitude:    a.Altitude * metersPerFoot,
+			Heading:     a.Heading,
+			Pitch:       simPitch(a.Pitch),
+			Bank:        simBank(a.Bank),
+			GroundSpeed: a.GroundVelocity * mpsPerKnot,
+			OnGround:    s.Simulator.OnGround,
+		}
+		if n := len(t.Points); n > 0 {
+			last := t.Points[n-1]
+			if last.Latitude == p.Latitude && last.Longitude == p.Longitude && last.Altitude == p.Altitude && last.OnGround == p.OnGround {
+				continue
+			}
+		}
+		t.Points = append(t.Points, p)
+	}
+}
+
+// simPitch converts a SimConnect pitch, which is negative nose up, to the
+// usual aviation convention
+func simPitch(pitch float64) float64 {
+	return -pitch
+}
+
+// simBank converts a SimConnect bank, which is negative for a right bank, to
+// the usual aviation convention
+func simBank(bank float64) float64 {
+	return -bank
+}
+
+// takeoffLanding returns the index of the first point after the aircraft
+// left the ground and of the first point after the last touchdown, -1 when
+// the recording has none
+func (t track) takeoffLanding() (takeoff, landing int) {
+	takeoff, landing = -1, -1
+	for i := 1; i < len(t.Points); i++ {
+		prev, cur := t.Points[i-1].OnGround, t.Points[i].OnGround
+		if prev && !cur && takeoff < 0 {
+			takeoff = i
+		}
+		if !prev && cur && takeoff >= 0 {
+			landing = i
+		}
+	}
+	return takeoff, landing
+}
+
+// escape returns s escaped for XML text and attributes
+func escape(s string) string {
+	var b bytes.Buffer
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
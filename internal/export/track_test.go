@@ -0,0 +1,211 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const tpxNamespace = "http://www.garmin.com/xmlschemas/TrackPointExtension/v2"
+
+// trackSamples are a taxi, a takeoff, a touch and go and a landing, half a
+// second apart, after a sample without a position and with a sample that
+// did not move
+func trackSamples() []engine.Sample {
+	type state struct {
+		lat, alt, heading, pitch, bank, speed float64
+		onGround                              bool
+	}
+	var samples []engine.Sample
+	for i, s := range []state{
+		{},
+		{50.000, 1000, -10, -1, 0.5, 10, true},
+		{50.000, 1000, -10, -1, 0.5, 10, true},
+		{50.001, 1000, 370, 0, 0, 60, true},
+		{50.002, 1100, 10, -5, 10, 70, false},
+		{50.003, 1000, 10, -2, 0, 65, true},
+		{50.004, 1100, 10, -5, -10, 70, false},
+		{50.005, 1000, 10, -2, 0, 60, true},
+		{50.006, 1000, 10, 0, 0, 20, true},
+	} {
+		sample := engine.Sample{
+			Time:      testStart.Add(time.Duration(i) * 500 * time.Millisecond),
+			Simulator: telemetry.SimulatorState{OnGround: s.onGround},
+		}
+		if s.lat != 0 {
+			sample.Airplane = telemetry.AirplaneState{
+				Latitude:       s.lat,
+				Longitude:      14,
+				Altitude:       s.alt,
+				Heading:        s.heading,
+				Pitch:          s.pitch,
+				Bank:           s.bank,
+				GroundVelocity: s.speed,
+			}
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
+func TestGPX(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart, AircraftTitle: "Cessna 172 & G1000"}, trackSamples())
+	var gpx struct {
+		XMLName  xml.Name `xml:"http://www.topografix.com/GPX/1/1 gpx"`
+		Metadata struct {
+			Name string `xml:"name"`
+			Time string `xml:"time"`
+		} `xml:"metadata"`
+		Track struct {
+			Name   string `xml:"name"`
+			Points []struct {
+				Lat       float64 `xml:"lat,attr"`
+				Lon       float64 `xml:"lon,attr"`
+				Elevation float64 `xml:"ele"`
+				Time      string  `xml:"time"`
+				TPX       struct {
+					XMLName xml.Name
+					Speed   float64 `xml:"speed"`
+					Course  float64 `xml:"course"`
+				} `xml:"extensions>TrackPointExtension"`
+			} `xml:"trkseg>trkpt"`
+		} `xml:"trk"`
+	}
+	if err := xml.Unmarshal([]byte(export(t, e, FormatGPX, Options{})), &gpx); err != nil {
+		t.Fatal(err)
+	}
+	name := "Cessna 172 & G1000 2026-04-11 10:00Z"
+	if gpx.Metadata.Name != name || gpx.Track.Name != name || gpx.Metadata.Time != "2026-04-11T10:00:00Z" {
+		t.Errorf("metadata %+v, track %q, want %q from the first point", gpx.Metadata, gpx.Track.Name, name)
+	}
+
+	// Elevation in meters, speed in meters per second and the heading as
+	// course from 0 to 360
+	var points []string
+	for _, p := range gpx.Track.Points {
+		if p.TPX.XMLName.Space != tpxNamespace {
+			t.Errorf("extension in namespace %q, want %q", p.TPX.XMLName.Space, tpxNamespace)
+		}
+		points = append(points, fmt.Sprintf("%v %v %v %s %v %v", p.Lat, p.Lon, p.Elevation, p.Time, p.TPX.Speed, p.TPX.Course))
+	}
+	want := []string{
+		"50 14 304.8 2026-04-11T10:00:00.5Z 5.14 350",
+		"50.001 14 304.8 2026-04-11T10:00:01.5Z 30.87 10",
+		"50.002 14 335.28 2026-04-11T10:00:02Z 36.01 10",
+		"50.003 14 304.8 2026-04-11T10:00:02.5Z 33.44 10",
+		"50.004 14 335.28 2026-04-11T10:00:03Z 36.01 10",
+		"50.005 14 304.8 2026-04-11T10:00:03.5Z 30.87 10",
+		"50.006 14 304.8 2026-04-11T10:00:04Z 10.29 10",
+	}
+	if strings.Join(points, "\n") != strings.Join(want, "\n") {
+		t.Errorf("track points\n%s\nwant\n%s", strings.Join(points, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+// kmlDocument is the part of a KML export the tests check
+type kmlDocument struct {
+	Document struct {
+		Name       string `xml:"name"`
+		Placemarks []struct {
+			Name  string `xml:"name"`
+			When  string `xml:"TimeStamp>when"`
+			Point *struct {
+				AltitudeMode string `xml:"altitudeMode"`
+				Coordinates  string `xml:"coordinates"`
+			} `xml:"Point"`
+			Path *struct {
+				AltitudeMode string `xml:"altitudeMode"`
+				Coordinates  string `xml:"coordinates"`
+			} `xml:"LineString"`
+			Track *struct {
+				AltitudeMode string   `xml:"altitudeMode"`
+				When         []string `xml:"when"`
+				Coord        []string `xml:"coord"`
+				Angles       []string `xml:"angles"`
+				Speed        []string `xml:"ExtendedData>SchemaData>SimpleArrayData>value"`
+			} `xml:"Track"`
+		} `xml:"Placemark"`
+	} `xml:"Document"`
+}
+
+func TestKML(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart, AircraftTitle: "Cessna 172 & G1000"}, trackSamples())
+	var kml kmlDocument
+	if err := xml.Unmarshal([]byte(export(t, e, FormatKML, Options{})), &kml); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range kml.Document.Placemarks {
+		names = append(names, p.Name)
+	}
+	if want := "Flight path, Takeoff, Landing, Cessna 172 & G1000 2026-04-11 10:00Z"; strings.Join(names, ", ") != want {
+		t.Fatalf("placemarks %q, want %s", names, want)
+	}
+	path, takeoff, landing, track := kml.Document.Placemarks[0].Path, kml.Document.Placemarks[1], kml.Document.Placemarks[2], kml.Document.Placemarks[3].Track
+
+	if path == nil || path.AltitudeMode != "absolute" || len(strings.Fields(path.Coordinates)) != 7 {
+		t.Errorf("path %+v, want 7 coordinates at absolute altitude", path)
+	}
+	// The first point in the air and the first on the ground after the
+	// touch and go
+	for _, c := range []struct {
+		name        string
+		when, coord string
+	}{
+		{"takeoff", "2026-04-11T10:00:02Z", "14.0000000,50.0020000,335.28"},
+		{"landing", "2026-04-11T10:00:03Z", "14.0000000,50.0050000,304.80"},
+	} {
+		p := takeoff
+		if c.name == "landing" {
+			p = landing
+		}
+		if p.When != c.when || p.Point == nil || p.Point.AltitudeMode != "absolute" || p.Point.Coordinates != c.coord {
+			t.Errorf("%s at %s %+v, want %s at absolute %s", c.name, p.When, p.Point, c.when, c.coord)
+		}
+	}
+
+	if track == nil {
+		t.Fatal("no gx:Track")
+	}
+	if track.AltitudeMode != "absolute" {
+		t.Errorf("track altitude mode %q, want absolute", track.AltitudeMode)
+	}
+	if len(track.When) != 7 || len(track.Coord) != 7 || len(track.Angles) != 7 || len(track.Speed) != 7 {
+		t.Fatalf("%d when, %d coord, %d angles and %d speeds, want 7 of each", len(track.When), len(track.Coord), len(track.Angles), len(track.Speed))
+	}
+	// Heading, tilt positive nose up and roll positive right wing down
+	for _, c := range []struct {
+		i                          int
+		when, coord, angles, speed string
+	}{
+		{0, "2026-04-11T10:00:00.5Z", "14.0000000 50.0000000 304.80", "350.0 1.0 -0.5", "5.14"},
+		{2, "2026-04-11T10:00:02Z", "14.0000000 50.0020000 335.28", "10.0 5.0 -10.0", "36.01"},
+		{4, "2026-04-11T10:00:03Z", "14.0000000 50.0040000 335.28", "10.0 5.0 10.0", "36.01"},
+	} {
+		if track.When[c.i] != c.when || track.Coord[c.i] != c.coord || track.Angles[c.i] != c.angles || track.Speed[c.i] != c.speed {
+			t.Errorf("track point %d: %s, %s, %s, %s, want %s, %s, %s, %s", c.i,
+				track.When[c.i], track.Coord[c.i], track.Angles[c.i], track.Speed[c.i], c.when, c.coord, c.angles, c.speed)
+		}
+	}
+}
+
+func TestKMLWithoutFlight(t *testing.T) {
+	e := storeRecording(t, flightrecording.Header{CreatedAt: testStart}, trackSamples()[:4])
+	var kml kmlDocument
+	if err := xml.Unmarshal([]byte(export(t, e, FormatKML, Options{})), &kml); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, p := range kml.Document.Placemarks {
+		names = append(names, p.Name)
+	}
+	if want := "Flight path, Flight 2026-04-11 10:00Z"; strings.Join(names, ", ") != want {
+		t.Errorf("placemarks %q, want %s", names, want)
+	}
+}
@@ -0,0 +1,48 @@
+package export
+
+// Units selects the unit system of exported values
+type Units string
+
+const (
+	// UnitsAviation keeps values as recorded: feet, knots, feet per minute, inHg
+	UnitsAviation Units = "aviation"
+	// UnitsMetric uses meters, km/h, meters per second and hPa
+	UnitsMetric Units = "metric"
+	// UnitsImperial uses feet, mph, feet per minute, inHg, fahrenheit and statute miles
+	UnitsImperial Units = "imperial"
+)
+
+// conversion converts a value from a recorded unit to Unit
+type conversion struct {
+	Unit    string
+	Convert func(float64) float64
+}
+
+func scale(f float64) func(float64) float64 {
+	return func(v float64) float64 { return v * f }
+}
+
+// unitSystems maps recorded units to their conversion per unit system.
+// Units without an entry are exported as recorded.
+var unitSystems = map[Units]map[string]conversion{
+	UnitsAviation: {},
+	UnitsMetric: {
+		"feet":            {"meters", scale(0.3048)},
+		"knots":           {"km/h", scale(1.852)},
+		"feet per minute": {"meters per second", scale(0.00508)},
+		"inHg":            {"hPa", scale(33.8639)},
+	},
+	UnitsImperial: {
+		"knots":   {"mph", scale(1.150779)},
+		"celsius": {"fahrenheit", func(v float64) float64 { return v*9/5 + 32 }},
+		"meters":  {"statute miles", scale(1 / 1609.344)},
+	},
+}
+
+// convertUnit returns the unit and conversion of a channel recorded in unit
+func convertUnit(units Units, unit string) (string, func(float64) float64) {
+	if c, ok := unitSystems[units][unit]; ok {
+		return c.Unit, c.Convert
+	}
+	return unit, nil
+}
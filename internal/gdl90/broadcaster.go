@@ -0,0 +1,216 @@
+package gdl90
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// DefaultPort is the port EFB apps listen on for GDL 90
+const DefaultPort = 4000
+
+const (
+	// ahrsInterval is the AHRS rate, the other messages are sent every
+	// ahrsPerSecond ticks
+	ahrsInterval  = 200 * time.Millisecond
+	ahrsPerSecond = 5
+	// deviceName and deviceLongName identify the recorder in ForeFlight
+	deviceName     = "MCRWFDR"
+	deviceLongName = "Flight Recorder"
+	// ownshipAddress is a placeholder, the simulator has no ICAO address
+	ownshipAddress = 0xf00000
+)
+
+// ParseAddr turns a configured destination into a UDP address. A bare port
+// broadcasts on the local network, e.g. "4000" becomes
+// "255.255.255.255:4000"; a host without a port gets DefaultPort.
+func ParseAddr(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("no GDL 90 destination given")
+	}
+	if port, err := strconv.Atoi(s); err == nil {
+		if port <= 0 || port > 65535 {
+			return "", fmt.Errorf("invalid GDL 90 port %d", port)
+		}
+		return net.JoinHostPort("255.255.255.255", strconv.Itoa(port)), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return net.JoinHostPort(s, strconv.Itoa(DefaultPort)), nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid GDL 90 port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Broadcaster sends the aircraft to an EFB app: heartbeat, device ID,
+// ownship report and geometric altitude once a second and AHRS five times a
+// second. Only the heartbeat is sent while the simulator is disconnected.
+type Broadcaster struct {
+	addr   string
+	logger *logadapter.LogzWailsAdapter
+
+	mu        sync.Mutex
+	air       telemetry.AirplaneState
+	env       telemetry.EnvironmentState
+	onGround  bool
+	connected bool
+	haveAir   bool
+}
+
+// NewBroadcaster returns a broadcaster sending to addr, see ParseAddr
+func NewBroadcaster(addr string) (*Broadcaster, error) {
+	addr, err := ParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Broadcaster{addr: addr}, nil
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (b *Broadcaster) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	b.logger = logger
+}
+
+// Addr returns the destination address
+func (b *Broadcaster) Addr() string {
+	return b.addr
+}
+
+// Attach feeds the broadcaster from bus and starts sending. Close the
+// returned subscription to stop.
+func (b *Broadcaster) Attach(bus *telemetry.Bus) (*telemetry.Subscription, error) {
+	raddr, err := net.ResolveUDPAddr("udp", b.addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid GDL 90 destination: %w", err)
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open GDL 90 socket: %w", err)
+	}
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicEnvironment,
+			telemetry.TopicConnection,
+		},
+		Policy: telemetry.DropOldest,
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range sub.C() {
+			b.update(msg)
+		}
+	}()
+	go b.send(conn, done)
+	b.logInfo("[GDL90] Sending to ", b.addr)
+	return sub, nil
+}
+
+func (b *Broadcaster) update(msg telemetry.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch p := msg.Payload.(type) {
+	case telemetry.AirplaneState:
+		b.air, b.haveAir = p, true
+	case telemetry.SimulatorState:
+		b.onGround = p.OnGround
+	case telemetry.EnvironmentState:
+		b.env = p
+	case telemetry.ConnectionStatus:
+		b.connected = p.Connected
+		if !p.Connected {
+			b.haveAir = false
+		}
+	}
+}
+
+// send writes the messages at their rates until done is closed
+func (b *Broadcaster) send(conn *net.UDPConn, done chan struct{}) {
+	defer conn.Close()
+	ticker := time.NewTicker(ahrsInterval)
+	defer ticker.Stop()
+	failing := false
+	for tick := 0; ; tick++ {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			var packet []byte
+			for _, msg := range b.messages(now, tick%ahrsPerSecond == 0) {
+				packet = append(packet, Frame(msg)...)
+			}
+			if len(packet) == 0 {
+				continue
+			}
+			// Log the first failure only, e.g. while the network is down
+			_, err := conn.Write(packet)
+			if err != nil && !failing {
+				b.logError("[GDL90] Failed to send: ", err)
+			}
+			failing = err != nil
+		}
+	}
+}
+
+// messages returns the messages due at now, the once a second messages when
+// second is set
+func (b *Broadcaster) messages(now time.Time, second bool) [][]byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	valid := b.connected && b.haveAir
+	var msgs [][]byte
+	if second {
+		msgs = append(msgs, Heartbeat(now, valid), DeviceID(deviceName, deviceLongName))
+	}
+	if !valid {
+		return msgs
+	}
+	a := b.air
+	if second {
+		msgs = append(msgs, OwnshipReport(Ownship{
+			Latitude:         a.Latitude,
+			Longitude:        a.Longitude,
+			PressureAltitude: b.env.PressureAltitude(a.Altitude),
+			Airborne:         !b.onGround,
+			GroundSpeed:      a.GroundVelocity,
+			VerticalSpeed:    a.VerticalSpeed,
+			Track:            a.GroundTrack,
+			Address:          ownshipAddress,
+			Callsign:         deviceName,
+			Valid:            true,
+		}), GeoAltitude(a.Altitude))
+	}
+	// The simulator reports pitch and bank negative nose up and right wing
+	// down
+	return append(msgs, AHRSReport(AHRS{
+		Roll:              -a.Bank,
+		Pitch:             -a.Pitch,
+		Heading:           a.Heading,
+		IndicatedAirspeed: a.Airspeed,
+		TrueAirspeed:      a.AirspeedTrue,
+	}))
+}
+
+func (b *Broadcaster) logInfo(args ...interface{}) {
+	if b.logger != nil {
+		b.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (b *Broadcaster) logError(args ...interface{}) {
+	if b.logger != nil {
+		b.logger.Error(fmt.Sprint(args...))
+	}
+}
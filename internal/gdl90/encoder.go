@@ -0,0 +1,226 @@
+// Package gdl90 broadcasts the simulated aircraft as a GDL 90 device, so
+// EFB apps such as ForeFlight and Garmin Pilot use it as GPS and AHRS.
+// Messages follow the GDL 90 Data Interface Specification (560-1058-00 Rev
+// A) and the ForeFlight extensions for the device ID and AHRS.
+package gdl90
+
+import (
+	"encoding/binary"
+	"math"
+	"time"
+)
+
+// Message IDs
+const (
+	idHeartbeat   = 0x00
+	idOwnship     = 0x0a
+	idGeoAltitude = 0x0b
+	idForeFlight  = 0x65
+	// ForeFlight sub IDs
+	subID   = 0x00
+	subAHRS = 0x01
+)
+
+const (
+	flagByte    = 0x7e
+	controlByte = 0x7d
+	// latLonResolution is the weight of the least significant bit of
+	// latitudes and longitudes, in degrees
+	latLonResolution = 180.0 / (1 << 23)
+)
+
+var crcTable = func() [256]uint16 {
+	var table [256]uint16
+	for i := range table {
+		crc := uint16(i) << 8
+		for bit := 0; bit < 8; bit++ {
+			if crc&0x8000 != 0 {
+				crc = crc<<1 ^ 0x1021
+			} else {
+				crc <<= 1
+			}
+		}
+		table[i] = crc
+	}
+	return table
+}()
+
+// CRC returns the CRC-CCITT of a message as defined by the specification
+func CRC(msg []byte) uint16 {
+	var crc uint16
+	for _, b := range msg {
+		crc = crcTable[crc>>8] ^ crc<<8 ^ uint16(b)
+	}
+	return crc
+}
+
+// Frame appends the CRC, escapes flag and control bytes and adds the flags
+// around a message
+func Frame(msg []byte) []byte {
+	crc := CRC(msg)
+	out := make([]byte, 0, len(msg)+6)
+	out = append(out, flagByte)
+	body := append(append([]byte(nil), msg...), byte(crc), byte(crc>>8))
+	for _, b := range body {
+		if b == flagByte || b == controlByte {
+			out = append(out, controlByte, b^0x20)
+			continue
+		}
+		out = append(out, b)
+	}
+	return append(out, flagByte)
+}
+
+// Heartbeat returns the heartbeat message for t, sent every second
+func Heartbeat(t time.Time, gpsValid bool) []byte {
+	t = t.UTC()
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	seconds := uint32(t.Sub(midnight) / time.Second)
+	msg := make([]byte, 7)
+	msg[0] = idHeartbeat
+	msg[1] = 0x01 // UAT initialized
+	if gpsValid {
+		msg[1] |= 0x80
+	}
+	msg[2] = byte(seconds>>16)<<7 | 0x01 // time stamp bit 16, UTC OK
+	binary.LittleEndian.PutUint16(msg[3:], uint16(seconds))
+	return msg
+}
+
+// Ownship is the position report of the aircraft. Altitudes are in feet,
+// speeds in knots and the vertical speed in feet per minute.
+type Ownship struct {
+	Latitude         float64
+	Longitude        float64
+	PressureAltitude float64
+	Airborne         bool
+	GroundSpeed      float64
+	VerticalSpeed    float64
+	Track            float64 // true, degrees
+	Address          uint32  // ICAO address, 24 bits
+	Callsign         string
+	Valid            bool // position and velocity valid
+}
+
+// OwnshipReport returns the ownship report message
+func OwnshipReport(o Ownship) []byte {
+	msg := make([]byte, 28)
+	msg[0] = idOwnship
+	msg[1] = 0x00 // no alert, ADS-B with ICAO address
+	putUint24(msg[2:], o.Address)
+	if o.Valid {
+		// Truncated like the example in the specification
+		putUint24(msg[5:], uint32(int32(o.Latitude/latLonResolution)))
+		putUint24(msg[8:], uint32(int32(wrapLongitude(o.Longitude)/latLonResolution)))
+	}
+
+	altitude := uint16(0xfff) // invalid
+	if o.Valid {
+		altitude = uint16(clamp(math.Round((o.PressureAltitude+1000)/25), 0, 0xffe))
+	}
+	misc := byte(0x01) // updated report, true track
+	if o.Airborne {
+		misc |= 0x08
+	}
+	msg[11] = byte(altitude >> 4)
+	msg[12] = byte(altitude&0x0f)<<4 | misc
+	if o.Valid {
+		msg[13] = 0xbb // NIC < 7.5 m, NACp < 3 m: the simulator knows
+	}
+
+	// Without a position the velocity is not known either
+	horizontal, vertical := uint16(0xfff), uint16(0x800)
+	if o.Valid {
+		horizontal = uint16(clamp(math.Round(o.GroundSpeed), 0, 0xffe))
+		vertical = uint16(int16(clamp(math.Round(o.VerticalSpeed/64), -510, 510))) & 0xfff
+	}
+	msg[14] = byte(horizontal >> 4)
+	msg[15] = byte(horizontal&0x0f)<<4 | byte(vertical>>8)
+	msg[16] = byte(vertical)
+	msg[17] = byte(int(math.Round(normalizeDegrees(o.Track)/360*256)) % 256)
+	msg[18] = 0x01 // emitter category: light aircraft
+
+	callsign := []byte(o.Callsign)
+	for i := 0; i < 8; i++ {
+		msg[19+i] = ' '
+		if i < len(callsign) && callsign[i] >= 0x20 && callsign[i] < 0x7f {
+			msg[19+i] = callsign[i]
+		}
+	}
+	return msg
+}
+
+// GeoAltitude returns the ownship geometric altitude message. The device ID
+// declares the altitude as MSL, in feet.
+func GeoAltitude(altitude float64) []byte {
+	msg := make([]byte, 5)
+	msg[0] = idGeoAltitude
+	binary.BigEndian.PutUint16(msg[1:], uint16(int16(clamp(math.Round(altitude/5), math.MinInt16, math.MaxInt16))))
+	binary.BigEndian.PutUint16(msg[3:], 0x000a) // no warning, VFOM 10 m
+	return msg
+}
+
+// AHRS is the attitude of the aircraft, positive nose up and right wing
+// down, in degrees and knots
+type AHRS struct {
+	Roll              float64
+	Pitch             float64
+	Heading           float64 // true
+	IndicatedAirspeed float64
+	TrueAirspeed      float64
+}
+
+// AHRSReport returns the ForeFlight AHRS message, sent five times a second
+func AHRSReport(a AHRS) []byte {
+	msg := make([]byte, 12)
+	msg[0], msg[1] = idForeFlight, subAHRS
+	binary.BigEndian.PutUint16(msg[2:], uint16(int16(clamp(math.Round(a.Roll*10), -1800, 1800))))
+	binary.BigEndian.PutUint16(msg[4:], uint16(int16(clamp(math.Round(a.Pitch*10), -1800, 1800))))
+	heading := math.Round(normalizeDegrees(a.Heading) * 10)
+	if heading >= 3600 {
+		heading = 0
+	}
+	binary.BigEndian.PutUint16(msg[6:], uint16(heading)) // bit 15 clear: true heading
+	binary.BigEndian.PutUint16(msg[8:], uint16(clamp(math.Round(a.IndicatedAirspeed), 0, 0xfffe)))
+	binary.BigEndian.PutUint16(msg[10:], uint16(clamp(math.Round(a.TrueAirspeed), 0, 0xfffe)))
+	return msg
+}
+
+// DeviceID returns the ForeFlight ID message naming the device. name is cut
+// to 8 bytes and longName to 16.
+func DeviceID(name, longName string) []byte {
+	msg := make([]byte, 39)
+	msg[0], msg[1] = idForeFlight, subID
+	msg[2] = 1 // version
+	for i := 3; i < 11; i++ {
+		msg[i] = 0xff // no serial number
+	}
+	copy(msg[11:19], name)
+	copy(msg[19:35], longName)
+	binary.BigEndian.PutUint32(msg[35:], 0x01) // geometric altitude is MSL
+	return msg
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v>>16), byte(v>>8), byte(v)
+}
+
+func clamp(v, lo, hi float64) float64 {
+	return math.Max(lo, math.Min(hi, v))
+}
+
+func normalizeDegrees(d float64) float64 {
+	d = math.Mod(d, 360)
+	if d < 0 {
+		d += 360
+	}
+	return d
+}
+
+// wrapLongitude keeps 180 east representable in 24 bits
+func wrapLongitude(lon float64) float64 {
+	if lon >= 180 {
+		return lon - 360
+	}
+	return lon
+}
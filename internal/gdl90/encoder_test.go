@@ -0,0 +1,186 @@
+package gdl90
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// specHeartbeat is the heartbeat of the message example in the specification
+var specHeartbeat = []byte{0x00, 0x81, 0x41, 0xdb, 0xd0, 0x08, 0x02}
+
+func TestCRC(t *testing.T) {
+	for _, c := range []struct {
+		msg  []byte
+		want uint16
+	}{
+		{nil, 0},
+		{specHeartbeat, 0x8bb3},
+		{[]byte{0x7e, 0x7d, 0x01}, 0xe258},
+	} {
+		if got := CRC(c.msg); got != c.want {
+			t.Errorf("CRC(% x) = %#04x, want %#04x", c.msg, got, c.want)
+		}
+	}
+}
+
+func TestFrame(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		msg  []byte
+		want []byte
+	}{
+		{"specification example", specHeartbeat, []byte{0x7e, 0x00, 0x81, 0x41, 0xdb, 0xd0, 0x08, 0x02, 0xb3, 0x8b, 0x7e}},
+		{"flag and control bytes", []byte{0x7e, 0x7d, 0x01}, []byte{0x7e, 0x7d, 0x5e, 0x7d, 0x5d, 0x01, 0x58, 0xe2, 0x7e}},
+		// The CRC 0x0b7d is escaped too
+		{"control byte in the CRC", []byte{0x0b, 0x7d}, []byte{0x7e, 0x0b, 0x7d, 0x5d, 0x7d, 0x5d, 0x0b, 0x7e}},
+	} {
+		if got := Frame(c.msg); !bytes.Equal(got, c.want) {
+			t.Errorf("%s: % x, want % x", c.name, got, c.want)
+		}
+	}
+}
+
+func TestHeartbeat(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		at       time.Time
+		gpsValid bool
+		want     []byte
+	}{
+		// 36000 s since midnight
+		{"morning", time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC), true, []byte{0x00, 0x81, 0x01, 0xa0, 0x8c, 0x00, 0x00}},
+		// 65580 s, bit 16 goes to the second status byte
+		{"evening", time.Date(2026, 4, 11, 18, 13, 0, 0, time.UTC), false, []byte{0x00, 0x01, 0x81, 0x2c, 0x00, 0x00, 0x00}},
+		{"other time zone", time.Date(2026, 4, 11, 12, 0, 0, 0, time.FixedZone("CEST", 2*3600)), true, []byte{0x00, 0x81, 0x01, 0xa0, 0x8c, 0x00, 0x00}},
+	} {
+		if got := Heartbeat(c.at, c.gpsValid); !bytes.Equal(got, c.want) {
+			t.Errorf("%s: % x, want % x", c.name, got, c.want)
+		}
+	}
+}
+
+func TestOwnshipReport(t *testing.T) {
+	callsign := func(s string) []byte { return append([]byte(s), 0x00) }
+	for _, c := range []struct {
+		name string
+		o    Ownship
+		want []byte
+	}{
+		// The traffic report example of the specification as ownship
+		{"specification example", Ownship{
+			Latitude:         44.90708,
+			Longitude:        -122.99488,
+			PressureAltitude: 5000,
+			Airborne:         true,
+			GroundSpeed:      123,
+			VerticalSpeed:    64,
+			Track:            45,
+			Address:          0xab4549,
+			Callsign:         "N825V",
+			Valid:            true,
+		}, append([]byte{
+			0x0a, 0x00, 0xab, 0x45, 0x49,
+			0x1f, 0xef, 0x15, 0xa8, 0x89, 0x78,
+			0x0f, 0x09, 0xbb,
+			0x07, 0xb0, 0x01,
+			0x20, 0x01,
+		}, callsign("N825V   ")...)},
+		{"south, on the ground, descending", Ownship{
+			Latitude:         -33.9461,
+			Longitude:        151.1772,
+			PressureAltitude: -1200, // below the lowest altitude
+			VerticalSpeed:    -500,
+			Track:            270,
+			Address:          ownshipAddress,
+			Callsign:         "MCRWFDR",
+			Valid:            true,
+		}, append([]byte{
+			0x0a, 0x00, 0xf0, 0x00, 0x00,
+			0xe7, 0xdc, 0x4e, 0x6b, 0x80, 0xf8,
+			0x00, 0x01, 0xbb,
+			0x00, 0x0f, 0xf8,
+			0xc0, 0x01,
+		}, callsign("MCRWFDR ")...)},
+		{"limits", Ownship{
+			Longitude:        180, // same as -180
+			PressureAltitude: 200000,
+			Airborne:         true,
+			GroundSpeed:      5000,
+			VerticalSpeed:    40000,
+			Track:            359.9,
+			Callsign:         "TOOLONGCALL\x01",
+			Valid:            true,
+		}, append([]byte{
+			0x0a, 0x00, 0x00, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x80, 0x00, 0x00,
+			0xff, 0xe9, 0xbb,
+			0xff, 0xe1, 0xfe,
+			0x00, 0x01,
+		}, callsign("TOOLONGC")...)},
+		// No position, altitude or velocity: 0xfff for the altitude and
+		// the horizontal velocity, 0x800 for the vertical velocity
+		{"invalid", Ownship{
+			Latitude:         50.1,
+			Longitude:        14.26,
+			PressureAltitude: 1200,
+			GroundSpeed:      100,
+			VerticalSpeed:    500,
+			Address:          ownshipAddress,
+		}, append([]byte{
+			0x0a, 0x00, 0xf0, 0x00, 0x00,
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+			0xff, 0xf1, 0x00,
+			0xff, 0xf8, 0x00,
+			0x00, 0x01,
+		}, callsign("        ")...)},
+	} {
+		if got := OwnshipReport(c.o); !bytes.Equal(got, c.want) {
+			t.Errorf("%s:\n% x\nwant\n% x", c.name, got, c.want)
+		}
+	}
+}
+
+func TestGeoAltitude(t *testing.T) {
+	for _, c := range []struct {
+		altitude float64
+		want     []byte
+	}{
+		{1200, []byte{0x0b, 0x00, 0xf0, 0x00, 0x0a}},
+		{-100, []byte{0x0b, 0xff, 0xec, 0x00, 0x0a}},
+		{1e9, []byte{0x0b, 0x7f, 0xff, 0x00, 0x0a}},
+	} {
+		if got := GeoAltitude(c.altitude); !bytes.Equal(got, c.want) {
+			t.Errorf("GeoAltitude(%v) = % x, want % x", c.altitude, got, c.want)
+		}
+	}
+}
+
+func TestAHRSReport(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		a    AHRS
+		want []byte
+	}{
+		{"left turn", AHRS{Roll: -10.5, Pitch: 5.2, Heading: 90, IndicatedAirspeed: 110, TrueAirspeed: 115.4},
+			[]byte{0x65, 0x01, 0xff, 0x97, 0x00, 0x34, 0x03, 0x84, 0x00, 0x6e, 0x00, 0x73}},
+		// 359.96 rounds to 360.0, which is north
+		{"north", AHRS{Heading: 359.96}, []byte{0x65, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}},
+		{"limits", AHRS{Roll: 200, Pitch: -200, Heading: -90, IndicatedAirspeed: -5, TrueAirspeed: 1e6},
+			[]byte{0x65, 0x01, 0x07, 0x08, 0xf8, 0xf8, 0x0a, 0x8c, 0x00, 0x00, 0xff, 0xfe}},
+	} {
+		if got := AHRSReport(c.a); !bytes.Equal(got, c.want) {
+			t.Errorf("%s: % x, want % x", c.name, got, c.want)
+		}
+	}
+}
+
+func TestDeviceID(t *testing.T) {
+	want := []byte{0x65, 0x00, 0x01, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}
+	want = append(want, "MCRWFDR\x00"...)
+	want = append(want, "Flight Data Reco"...) // cut to 16 bytes
+	want = append(want, 0x00, 0x00, 0x00, 0x01)
+	if got := DeviceID("MCRWFDR", "Flight Data Recorder"); !bytes.Equal(got, want) {
+		t.Errorf("% x, want % x", got, want)
+	}
+}
@@ -0,0 +1,306 @@
+// Package landing grades touchdowns from per-frame simulator data
+package landing
+
+import (
+	"fmt"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// TopicReport carries a Report on the telemetry bus
+const TopicReport telemetry.Topic = "landing"
+
+// ReportKind is the kind of landing reports attached to a recording
+const ReportKind = "landing"
+
+const (
+	// armAGL arms the analyzer on the way down; per-frame data is requested
+	// from here until the landing is graded
+	armAGL = 1000.0
+	// armVS held for armHold below armAGL arms the analyzer, so a sink
+	// after takeoff or in turbulence does not, feet per minute
+	armVS   = -200.0
+	armHold = 5 * time.Second
+	// disarmAGL cancels an armed analyzer that climbed away without landing
+	disarmAGL = 1500.0
+	// armTimeout cancels an armed analyzer that never touched down
+	armTimeout = 15 * time.Minute
+	// floatAGL is the height the float distance is measured from
+	floatAGL = 50.0
+	// settleTime without an air/ground transition completes the touchdown
+	// window, bounces are counted within it
+	settleTime = 5 * time.Second
+	// maxFrames caps the frames buffered before touchdown
+	maxFrames     = 30000
+	feetPerMeter  = 3.28084
+	earthRadiusFt = 6371008.8 * feetPerMeter
+)
+
+// Report grades a single touchdown. Pitch is positive nose up and bank is
+// positive right wing down.
+type Report struct {
+	Time          time.Time `json:"time"`
+	Latitude      float64   `json:"latitude"`
+	Longitude     float64   `json:"longitude"`
+	Heading       float64   `json:"heading"`
+	VerticalSpeed float64   `json:"vertical_speed"` // feet per minute, last airborne frame
+	PeakG         float64   `json:"peak_g"`
+	Pitch         float64   `json:"pitch"`          // degrees at contact
+	Bank          float64   `json:"bank"`           // degrees at contact
+	GroundSpeed   float64   `json:"ground_speed"`   // knots at contact
+	Airspeed      float64   `json:"airspeed"`       // knots indicated at contact
+	FloatDistance float64   `json:"float_distance"` // feet from 50 ft AGL to contact
+	FloatSeconds  float64   `json:"float_seconds"`
+	Bounces       int       `json:"bounces"`
+	TouchAndGo    bool      `json:"touch_and_go"` // airborne again when the window closed
+	Frames        int       `json:"frames"`       // per-frame samples analysed
+}
+
+type frame struct {
+	time  time.Time
+	state telemetry.TouchdownState
+}
+
+// Analyzer watches the descent at the regular rate and requests per-frame
+// data below armAGL. When the aircraft touches down it keeps sampling until
+// it settled on the ground or flew away, then publishes a Report.
+type Analyzer struct {
+	source telemetry.TouchdownSource // nil when per-frame data is unavailable
+	logger *logadapter.LogzWailsAdapter
+
+	mu       sync.Mutex
+	armed    bool
+	armedAt  time.Time
+	descent  time.Time // start of the descent below armAGL, zero if none
+	onGround bool
+	frames   []frame
+	contact  int // index of the first ground frame, -1 before touchdown
+	lastTurn time.Time
+	last     *Report
+}
+
+// NewAnalyzer returns an analyzer requesting per-frame data from source.
+// Sources that are no TouchdownSource never arm the analyzer.
+func NewAnalyzer(source telemetry.Source) *Analyzer {
+	touchdown, _ := source.(telemetry.TouchdownSource)
+	return &Analyzer{source: touchdown, contact: -1}
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (a *Analyzer) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	a.logger = logger
+}
+
+// Last returns the most recent report, or nil before the first landing
+func (a *Analyzer) Last() *Report {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.last == nil {
+		return nil
+	}
+	r := *a.last
+	return &r
+}
+
+// Attach feeds the analyzer from bus and publishes reports as TopicReport.
+// Close the returned subscription to detach, which also ends a per-frame
+// data request in progress.
+func (a *Analyzer) Attach(bus *telemetry.Bus) *telemetry.Subscription {
+	// Per-frame data arrives at up to 60 Hz and the touchdown rate comes
+	// from the frames around the contact, so none may be dropped. The
+	// buffer absorbs bursts before the source has to wait.
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicTouchdown,
+			telemetry.TopicConnection,
+		},
+		Buffer: 1024,
+		Policy: telemetry.Block,
+	})
+	relay := telemetry.NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range sub.C() {
+			var report *Report
+			switch p := msg.Payload.(type) {
+			case telemetry.AirplaneState:
+				a.updateAirplane(msg.Time, p)
+			case telemetry.SimulatorState:
+				a.mu.Lock()
+				a.onGround = p.OnGround
+				a.mu.Unlock()
+			case telemetry.TouchdownState:
+				report = a.updateFrame(msg.Time, p)
+			case telemetry.ConnectionStatus:
+				if !p.Connected {
+					a.disarm("connection lost")
+				}
+			}
+			if report != nil {
+				relay.Publish(TopicReport, *report)
+			}
+		}
+		a.disarm("")
+	}()
+	return sub
+}
+
+// updateAirplane arms and disarms the analyzer from the regular updates
+func (a *Analyzer) updateAirplane(t time.Time, s telemetry.AirplaneState) {
+	a.mu.Lock()
+	armed, onGround, armedAt, touched := a.armed, a.onGround, a.armedAt, a.contact >= 0
+	if onGround || s.AltAboveGround >= armAGL || s.VerticalSpeed >= armVS {
+		a.descent = time.Time{}
+	} else if a.descent.IsZero() {
+		a.descent = t
+	}
+	descending := !a.descent.IsZero() && t.Sub(a.descent) >= armHold
+	a.mu.Unlock()
+	switch {
+	case !armed && descending:
+		a.arm(t)
+	case armed && !touched && (s.AltAboveGround > disarmAGL || t.Sub(armedAt) > armTimeout):
+		a.disarm("no touchdown")
+	case armed && !touched && onGround:
+		// Armed too late, the touchdown was missed
+		a.disarm("on ground")
+	}
+}
+
+func (a *Analyzer) arm(t time.Time) {
+	if a.source == nil {
+		return
+	}
+	if err := a.source.RequestTouchdownData(true); err != nil {
+		a.logError("[Landing] Failed to request touchdown data: ", err)
+		return
+	}
+	a.mu.Lock()
+	a.armed, a.armedAt = true, t
+	a.frames, a.contact = a.frames[:0], -1
+	a.mu.Unlock()
+	a.logDebug("[Landing] Armed, requesting per-frame data")
+}
+
+func (a *Analyzer) disarm(reason string) {
+	a.mu.Lock()
+	if !a.armed {
+		a.mu.Unlock()
+		return
+	}
+	a.armed = false
+	a.frames, a.contact = nil, -1
+	a.mu.Unlock()
+	if err := a.source.RequestTouchdownData(false); err != nil {
+		a.logError("[Landing] Failed to stop touchdown data: ", err)
+	}
+	if reason != "" {
+		a.logDebug("[Landing] Disarmed: ", reason)
+	}
+}
+
+// updateFrame adds a per-frame sample and returns the report once the
+// touchdown window is complete
+func (a *Analyzer) updateFrame(t time.Time, s telemetry.TouchdownState) *Report {
+	a.mu.Lock()
+	if !a.armed {
+		a.mu.Unlock()
+		return nil
+	}
+	prevGround := len(a.frames) > 0 && a.frames[len(a.frames)-1].state.OnGround
+	if a.contact < 0 && len(a.frames) >= maxFrames {
+		// Keep the most recent half, the float starts close to the ground
+		a.frames = append(a.frames[:0], a.frames[maxFrames/2:]...)
+	}
+	a.frames = append(a.frames, frame{time: t, state: s})
+	switch {
+	case a.contact < 0 && s.OnGround && len(a.frames) > 1 && !prevGround:
+		a.contact = len(a.frames) - 1
+		a.lastTurn = t
+	case a.contact >= 0 && s.OnGround != prevGround:
+		a.lastTurn = t
+	}
+	if a.contact < 0 || t.Sub(a.lastTurn) < settleTime {
+		a.mu.Unlock()
+		return nil
+	}
+	r := analyze(a.frames, a.contact)
+	a.last = &r
+	a.mu.Unlock()
+	a.disarm("")
+	a.logInfo("[Landing] Touchdown at ", math.Round(r.VerticalSpeed), " fpm, ", math.Round(r.PeakG*100)/100, " G, ", r.Bounces, " bounces")
+	return &r
+}
+
+// analyze grades the touchdown at frames[contact]
+func analyze(frames []frame, contact int) Report {
+	c := frames[contact]
+	r := Report{
+		Time:          c.time,
+		Latitude:      c.state.Latitude,
+		Longitude:     c.state.Longitude,
+		Heading:       c.state.Heading,
+		VerticalSpeed: c.state.VerticalSpeed,
+		Pitch:         -c.state.Pitch,
+		Bank:          -c.state.Bank,
+		GroundSpeed:   c.state.GroundVelocity,
+		Airspeed:      c.state.Airspeed,
+		Frames:        len(frames),
+	}
+	if contact > 0 {
+		r.VerticalSpeed = frames[contact-1].state.VerticalSpeed
+	}
+	// Float from the last frame at or above 50 ft
+	for i := contact - 1; i >= 0; i-- {
+		f := frames[i]
+		if f.state.AltAboveGround >= floatAGL {
+			r.FloatDistance = distanceFeet(f.state.Latitude, f.state.Longitude, c.state.Latitude, c.state.Longitude)
+			r.FloatSeconds = c.time.Sub(f.time).Seconds()
+			break
+		}
+	}
+	// Peak G and bounces from contact to the end of the window
+	onGround := true
+	for _, f := range frames[contact:] {
+		r.PeakG = max(r.PeakG, f.state.GForce)
+		if f.state.OnGround && !onGround {
+			r.Bounces++
+		}
+		onGround = f.state.OnGround
+	}
+	r.TouchAndGo = !onGround
+	return r
+}
+
+// distanceFeet returns the great circle distance between two positions
+func distanceFeet(lat1, lon1, lat2, lon2 float64) float64 {
+	rad := math.Pi / 180
+	dLat := (lat2 - lat1) * rad
+	dLon := (lon2 - lon1) * rad
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1*rad)*math.Cos(lat2*rad)*math.Sin(dLon/2)*math.Sin(dLon/2)
+	return 2 * earthRadiusFt * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+func (a *Analyzer) logInfo(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (a *Analyzer) logDebug(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Debug(fmt.Sprint(args...))
+	}
+}
+
+func (a *Analyzer) logError(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Error(fmt.Sprint(args...))
+	}
+}
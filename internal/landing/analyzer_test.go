@@ -0,0 +1,179 @@
+package landing
+
+import (
+	"fmt"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+// fakeSource records the per-frame data requests
+type fakeSource struct {
+	requests []bool
+}
+
+func (f *fakeSource) RequestTouchdownData(enabled bool) error {
+	f.requests = append(f.requests, enabled)
+	return nil
+}
+
+func newTestAnalyzer() (*Analyzer, *fakeSource) {
+	source := &fakeSource{}
+	return &Analyzer{source: source, contact: -1}, source
+}
+
+func TestArming(t *testing.T) {
+	type update struct {
+		seconds int
+		agl, vs float64
+	}
+	for _, c := range []struct {
+		name     string
+		onGround bool
+		updates  []update
+		want     []bool
+	}{
+		{"sustained descent", false, []update{{10, 900, -500}}, []bool{true}},
+		{"sink after takeoff", false, []update{{3, 400, -400}, {10, 600, 800}}, nil},
+		{"turbulence", false, []update{{3, 800, -300}, {1, 800, 100}, {3, 800, -300}, {1, 800, 0}}, nil},
+		{"slow descent", false, []update{{30, 900, -150}}, nil},
+		{"above the arming height", false, []update{{30, 1200, -700}}, nil},
+		{"on the ground", true, []update{{10, 0, -500}}, nil},
+		{"climb away after arming", false, []update{{10, 900, -500}, {1, 1600, 1000}}, []bool{true, false}},
+	} {
+		a, source := newTestAnalyzer()
+		a.onGround = c.onGround
+		at := testStart
+		for _, u := range c.updates {
+			for i := 0; i < u.seconds; i++ {
+				a.updateAirplane(at, telemetry.AirplaneState{AltAboveGround: u.agl, VerticalSpeed: u.vs})
+				at = at.Add(time.Second)
+			}
+		}
+		if fmt.Sprint(source.requests) != fmt.Sprint(c.want) {
+			t.Errorf("%s: requests %v, want %v", c.name, source.requests, c.want)
+		}
+	}
+}
+
+// approachFrames returns frames 100 ms apart flying north from 50°N, one
+// thousandth of a degree per frame, at the given heights above ground
+func approachFrames(heights ...float64) []frame {
+	var frames []frame
+	for i, agl := range heights {
+		frames = append(frames, frame{
+			time: testStart.Add(time.Duration(i) * 100 * time.Millisecond),
+			state: telemetry.TouchdownState{
+				Latitude:       50 + float64(i)/1000,
+				Longitude:      14,
+				AltAboveGround: agl,
+				VerticalSpeed:  -600 + 100*float64(i),
+				GForce:         1,
+				OnGround:       agl == 0,
+			},
+		})
+	}
+	return frames
+}
+
+func TestAnalyze(t *testing.T) {
+	// The float from 50 ft: the frames at 50 ft and at contact are three
+	// thousandths of a degree of latitude apart
+	floatFeet := 0.003 * math.Pi / 180 * earthRadiusFt
+
+	landing := approachFrames(80, 50, 20, 2, 0, 1, 0, 0)
+	landing[4].state.GForce = 1.6
+	landing[4].state.Pitch, landing[4].state.Bank = -3, 1
+	landing[4].state.GroundVelocity, landing[4].state.Airspeed = 55, 60
+	landing[6].state.GForce = 1.9
+
+	touchAndGo := approachFrames(60, 30, 0, 0, 10)
+	firstFrame := approachFrames(0, 0)
+
+	for _, c := range []struct {
+		name    string
+		frames  []frame
+		contact int
+		want    Report
+	}{
+		{"bounce", landing, 4, Report{
+			VerticalSpeed: -300, // the last airborne frame
+			PeakG:         1.9,  // after the bounce
+			Pitch:         3,
+			Bank:          -1,
+			GroundSpeed:   55,
+			Airspeed:      60,
+			FloatDistance: floatFeet,
+			FloatSeconds:  0.3,
+			Bounces:       1,
+			Frames:        8,
+		}},
+		{"touch and go", touchAndGo, 2, Report{
+			VerticalSpeed: -500,
+			PeakG:         1,
+			FloatDistance: 2 * floatFeet / 3,
+			FloatSeconds:  0.2,
+			TouchAndGo:    true,
+			Frames:        5,
+		}},
+		// Per-frame data started on the ground: the contact frame's own
+		// vertical speed and no float
+		{"first frame on the ground", firstFrame, 0, Report{VerticalSpeed: -600, PeakG: 1, Frames: 2}},
+	} {
+		r := analyze(c.frames, c.contact)
+		contact := c.frames[c.contact]
+		c.want.Time, c.want.Latitude, c.want.Longitude = contact.time, contact.state.Latitude, contact.state.Longitude
+		if math.Abs(r.FloatDistance-c.want.FloatDistance) > 0.01 || math.Abs(r.FloatSeconds-c.want.FloatSeconds) > 1e-9 {
+			t.Errorf("%s: float %.2f ft in %v s, want %.2f ft in %v s", c.name, r.FloatDistance, r.FloatSeconds, c.want.FloatDistance, c.want.FloatSeconds)
+		}
+		r.FloatDistance, r.FloatSeconds = c.want.FloatDistance, c.want.FloatSeconds
+		if r != c.want {
+			t.Errorf("%s:\n%+v\nwant\n%+v", c.name, r, c.want)
+		}
+	}
+}
+
+func TestMaxFrames(t *testing.T) {
+	a, source := newTestAnalyzer()
+	a.arm(testStart)
+	at := testStart
+	feed := func(agl float64) *Report {
+		r := a.updateFrame(at, telemetry.TouchdownState{AltAboveGround: agl, VerticalSpeed: -100, OnGround: agl == 0})
+		at = at.Add(10 * time.Millisecond)
+		return r
+	}
+	// A long descent: the oldest half is dropped once maxFrames are buffered
+	for i := 0; i < maxFrames+10; i++ {
+		feed(120 - float64(i)/float64(maxFrames)*100)
+	}
+	if len(a.frames) != maxFrames/2+10 {
+		t.Fatalf("%d frames buffered, want %d", len(a.frames), maxFrames/2+10)
+	}
+	if want := testStart.Add(maxFrames / 2 * 10 * time.Millisecond); !a.frames[0].time.Equal(want) {
+		t.Errorf("first buffered frame at %v, want %v", a.frames[0].time, want)
+	}
+
+	// The report comes once the aircraft settled on the ground
+	var r *Report
+	ground := 0
+	for r == nil {
+		r = feed(0)
+		ground++
+		if ground > 1000 {
+			t.Fatal("no report after settling")
+		}
+	}
+	if r.Frames != maxFrames/2+10+ground {
+		t.Errorf("report from %d frames, want %d", r.Frames, maxFrames/2+10+ground)
+	}
+	if r.FloatSeconds == 0 {
+		t.Error("no float, the frames above 50 ft were dropped")
+	}
+	if fmt.Sprint(source.requests) != "[true false]" {
+		t.Errorf("requests %v, want per-frame data until the report", source.requests)
+	}
+}
@@ -0,0 +1,241 @@
+package logbook
+
+import (
+	"crypto/sha1"
+	"encoding/csv"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvHeader are the columns written by ExportCSV. Times are HH:MM Zulu on
+// the entry date, durations are minutes.
+var csvHeader = []string{
+	"id", "date", "departure", "arrival", "aircraft",
+	"out", "off", "on", "in",
+	"block_minutes", "air_minutes", "night_minutes",
+	"day_landings", "night_landings", "remarks",
+}
+
+// csvAliases maps column names of common spreadsheet logbooks to csvHeader
+var csvAliases = map[string]string{
+	"from":           "departure",
+	"dep":            "departure",
+	"to":             "arrival",
+	"arr":            "arrival",
+	"type":           "aircraft",
+	"aircraft type":  "aircraft",
+	"block":          "block_minutes",
+	"block time":     "block_minutes",
+	"total":          "block_minutes",
+	"total time":     "block_minutes",
+	"air":            "air_minutes",
+	"air time":       "air_minutes",
+	"night":          "night_minutes",
+	"night time":     "night_minutes",
+	"landings day":   "day_landings",
+	"landings night": "night_landings",
+	"landings":       "day_landings",
+	"remark":         "remarks",
+}
+
+// ExportCSV writes all entries as CSV
+func (l *Logbook) ExportCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range l.Entries() {
+		row := []string{
+			e.ID, e.Date, e.Departure, e.Arrival, e.Aircraft,
+			clock(e.Out), clock(e.Off), clock(e.On), clock(e.In),
+			strconv.Itoa(e.BlockMinutes), strconv.Itoa(e.AirMinutes), strconv.Itoa(e.NightMinutes),
+			strconv.Itoa(e.DayLandings), strconv.Itoa(e.NightLandings), e.Remarks,
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportCSV adds the rows of a CSV logbook and returns the number of
+// entries imported. Columns are matched by name, see csvHeader and
+// csvAliases; a date column is required. Durations are minutes or H:MM.
+// Rows keep the ID of an exported logbook, other rows get an ID derived
+// from their content, so importing the same row twice replaces the earlier
+// entry.
+func (l *Logbook) ImportCSV(r io.Reader) (int, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if alias, ok := csvAliases[name]; ok {
+			name = alias
+		}
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	if _, ok := columns["date"]; !ok {
+		return 0, errors.New("CSV logbook has no date column")
+	}
+
+	var entries []Entry
+	for line := 2; ; line++ {
+		row, err := cr.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("failed to read CSV line %d: %w", line, err)
+		}
+		field := func(name string) string {
+			if i, ok := columns[name]; ok && i < len(row) {
+				return strings.TrimSpace(row[i])
+			}
+			return ""
+		}
+		if strings.Join(row, "") == "" {
+			continue
+		}
+		entry, err := parseRow(field)
+		if err != nil {
+			return 0, fmt.Errorf("CSV line %d: %w", line, err)
+		}
+		entry.ID = field("id")
+		if entry.ID == "" {
+			sum := sha1.Sum([]byte(strings.Join(row, "\x1f")))
+			entry.ID = "csv-" + hex.EncodeToString(sum[:6])
+		}
+		entry.Imported = true
+		entries = append(entries, entry)
+	}
+	if len(entries) == 0 {
+		return 0, nil
+	}
+	if err := l.Add(entries...); err != nil {
+		return 0, err
+	}
+	return len(entries), nil
+}
+
+func parseRow(field func(string) string) (Entry, error) {
+	date, err := parseDate(field("date"))
+	if err != nil {
+		return Entry{}, err
+	}
+	e := Entry{
+		Date:      date.Format("2006-01-02"),
+		Departure: field("departure"),
+		Arrival:   field("arrival"),
+		Aircraft:  field("aircraft"),
+		Remarks:   field("remarks"),
+	}
+	// Times after midnight continue on the next day
+	var last *time.Time
+	for _, t := range []struct {
+		name string
+		dst  **time.Time
+	}{{"out", &e.Out}, {"off", &e.Off}, {"on", &e.On}, {"in", &e.In}} {
+		v := field(t.name)
+		if v == "" {
+			continue
+		}
+		ts, err := time.Parse("15:04", v)
+		if err != nil {
+			return Entry{}, fmt.Errorf("invalid %s time %q", t.name, v)
+		}
+		at := date.Add(time.Duration(ts.Hour())*time.Hour + time.Duration(ts.Minute())*time.Minute)
+		if last != nil && at.Before(*last) {
+			at = at.Add(24 * time.Hour)
+		}
+		*t.dst, last = &at, &at
+	}
+	for _, d := range []struct {
+		name string
+		dst  *int
+	}{
+		{"block_minutes", &e.BlockMinutes}, {"air_minutes", &e.AirMinutes}, {"night_minutes", &e.NightMinutes},
+	} {
+		if *d.dst, err = parseMinutes(field(d.name)); err != nil {
+			return Entry{}, fmt.Errorf("invalid %s %q", d.name, field(d.name))
+		}
+	}
+	if e.BlockMinutes == 0 && e.Out != nil && e.In != nil {
+		e.BlockMinutes = int(e.In.Sub(*e.Out).Minutes())
+	}
+	if e.AirMinutes == 0 && e.Off != nil && e.On != nil {
+		e.AirMinutes = int(e.On.Sub(*e.Off).Minutes())
+	}
+	for _, c := range []struct {
+		name string
+		dst  *int
+	}{{"day_landings", &e.DayLandings}, {"night_landings", &e.NightLandings}} {
+		v := field(c.name)
+		if v == "" {
+			continue
+		}
+		if *c.dst, err = strconv.Atoi(v); err != nil || *c.dst < 0 {
+			return Entry{}, fmt.Errorf("invalid %s %q", c.name, v)
+		}
+	}
+	return e, nil
+}
+
+// csvDateFormats are the date formats accepted on import
+var csvDateFormats = []string{"2006-01-02", "02.01.2006", "2006/01/02", "01/02/2006"}
+
+func parseDate(s string) (time.Time, error) {
+	for _, layout := range csvDateFormats {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date %q", s)
+}
+
+// parseMinutes parses a duration in minutes ("95") or hours and minutes
+// ("1:35"). An empty duration is zero.
+func parseMinutes(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	if h, m, ok := strings.Cut(s, ":"); ok {
+		hours, err := strconv.Atoi(h)
+		if err != nil || hours < 0 {
+			return 0, errors.New("invalid hours")
+		}
+		mins, err := strconv.Atoi(m)
+		if err != nil || mins < 0 || mins > 59 {
+			return 0, errors.New("invalid minutes")
+		}
+		return hours*60 + mins, nil
+	}
+	mins, err := strconv.Atoi(s)
+	if err != nil || mins < 0 {
+		return 0, errors.New("invalid minutes")
+	}
+	return mins, nil
+}
+
+func clock(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format("15:04")
+}
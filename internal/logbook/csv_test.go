@@ -0,0 +1,92 @@
+package logbook
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCSVRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	l := New(filepath.Join(dir, "logbook.json"))
+	if err := l.Add(testEntries()...); err != nil {
+		t.Fatal(err)
+	}
+	var b bytes.Buffer
+	if err := l.ExportCSV(&b); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(b.String(), "\n")
+	for i, want := range []string{
+		"id,date,departure,arrival,aircraft,out,off,on,in,block_minutes,air_minutes,night_minutes,day_landings,night_landings,remarks",
+		"20260411-100000,2026-04-11,LKPR,EDDM,C172,10:00,10:10,11:40,11:50,110,90,50,0,1,",
+		`20260412-080000,2026-04-12,EDDM,LKPR,A320,08:00,08:15,09:05,09:15,75,50,0,1,0,"Gate B12, ""quoted"""`,
+		"20260530-230000,2026-05-30,LKPR,EGLL,C172,23:00,23:10,00:50,01:00,120,100,120,1,2,",
+	} {
+		if lines[i] != want {
+			t.Errorf("line %d %q, want %q", i+1, lines[i], want)
+		}
+	}
+
+	imported := New(filepath.Join(dir, "imported.json"))
+	n, err := imported.ImportCSV(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Errorf("%d entries imported, want 3", n)
+	}
+	// Times after midnight are on the next day
+	for i, e := range imported.Entries() {
+		if !e.Imported {
+			t.Errorf("entry %s not marked imported", e.ID)
+		}
+		e.Imported = false
+		got, _ := json.Marshal(e)
+		want, _ := json.Marshal(testEntries()[i])
+		if string(got) != string(want) {
+			t.Errorf("imported\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestImportCSV(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "logbook.json"))
+	// A spreadsheet logbook with aliased columns, dotted dates and H:MM
+	data := "\ufeffDate,From,To,Type,Off,On,Total time,Landings\n" +
+		"11.04.2026,LKPR,EDDM,C172,23:30,00:45,1:35,2\n" +
+		",,,,,,,\n"
+	for i := 0; i < 2; i++ {
+		// Rows without an ID replace themselves when imported again
+		n, err := l.ImportCSV(strings.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 1 || len(l.Entries()) != 1 {
+			t.Fatalf("import %d: %d imported, %d entries, want 1", i+1, n, len(l.Entries()))
+		}
+	}
+	e := l.Entries()[0]
+	if !strings.HasPrefix(e.ID, "csv-") || e.Date != "2026-04-11" || e.Departure != "LKPR" || e.Arrival != "EDDM" || e.Aircraft != "C172" {
+		t.Errorf("%+v, want the row", e)
+	}
+	if e.BlockMinutes != 95 || e.AirMinutes != 75 || e.DayLandings != 2 {
+		t.Errorf("block %d, air %d min, %d landings, want 95, 75 and 2", e.BlockMinutes, e.AirMinutes, e.DayLandings)
+	}
+
+	for _, c := range []struct {
+		name, data, err string
+	}{
+		{"no date column", "from,to\nLKPR,EDDM\n", "no date column"},
+		{"invalid date", "date\n2026-13-01\n", `line 2: invalid date "2026-13-01"`},
+		{"invalid time", "date,out\n2026-04-11,25:00\n", `invalid out time "25:00"`},
+		{"invalid minutes", "date,block\n2026-04-11,1:75\n", `invalid block_minutes "1:75"`},
+		{"negative landings", "date,landings\n2026-04-11,-1\n", `invalid day_landings "-1"`},
+	} {
+		if _, err := l.ImportCSV(strings.NewReader(c.data)); err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: error %v, want %q", c.name, err, c.err)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+package logbook
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/landing"
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// ErrNoFlight is returned for recordings without a takeoff and a landing
+var ErrNoFlight = errors.New("recording contains no complete flight")
+
+const (
+	// civilTwilight extends day before sunrise and after sunset
+	civilTwilight = 30 * 60
+	secondsPerDay = 24 * 60 * 60
+	// TimeOfDay values of EnvironmentState
+	timeOfDayDay   = 1
+	timeOfDayNight = 3
+)
+
+// FromRecording builds the entry of a finished recording from its summary
+// and samples. The block and air times come from the OOOI times of the
+// summary, night time and the day/night split of landings from the
+// environment of the samples.
+func FromRecording(e *engine.Engine, summary engine.Summary) (Entry, error) {
+	bt := summary.BlockTimes
+	if bt == nil || bt.Off == nil || bt.On == nil {
+		return Entry{}, ErrNoFlight
+	}
+	r, err := e.Open(summary.ID)
+	if err != nil {
+		return Entry{}, err
+	}
+	defer r.Close()
+	h := r.Header()
+
+	entry := Entry{
+		ID:       summary.ID,
+		Aircraft: summary.AircraftTitle,
+		Out:      zulu(bt.Out),
+		Off:      zulu(bt.Off),
+		On:       zulu(bt.On),
+		In:       zulu(bt.In),
+	}
+	entry.Date = firstTime(entry).Format("2006-01-02")
+	entry.Departure, entry.Arrival = planAirports(h.FlightPlan)
+	entry.BlockMinutes = minutes(bt.SimBlockSeconds, bt.BlockSeconds)
+	entry.AirMinutes = minutes(bt.SimAirSeconds, bt.AirSeconds)
+
+	// Night time is counted over the block time, or the air time when the
+	// aircraft never reached a stand
+	from, to := bt.Off.UTC, bt.On.UTC
+	total := entry.AirMinutes
+	if bt.Out != nil && bt.In != nil {
+		from, to = bt.Out.UTC, bt.In.UTC
+		total = entry.BlockMinutes
+	}
+	landings := landingTimes(summary, bt)
+	landingNight := make([]bool, len(landings))
+
+	var (
+		prev                *engine.Sample
+		windowSec, nightSec float64
+	)
+	for {
+		s, err := r.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return Entry{}, fmt.Errorf("failed to read recording: %w", err)
+		}
+		for i, t := range landings {
+			if !s.Time.After(t) {
+				landingNight[i] = isNight(s.Environment)
+			}
+		}
+		if prev != nil {
+			start, end := maxTime(prev.Time, from), minTime(s.Time, to)
+			if d := end.Sub(start).Seconds(); d > 0 {
+				windowSec += d
+				if isNight(prev.Environment) {
+					nightSec += d
+				}
+			}
+		}
+		prev = &s
+	}
+	if windowSec > 0 {
+		entry.NightMinutes = int(math.Round(float64(total) * nightSec / windowSec))
+	}
+	for _, night := range landingNight {
+		if night {
+			entry.NightLandings++
+		} else {
+			entry.DayLandings++
+		}
+	}
+	return entry, nil
+}
+
+// landingTimes returns the times of the graded landings of the flight, or
+// the On time when none were graded
+func landingTimes(summary engine.Summary, bt *oooi.BlockTimes) []time.Time {
+	var times []time.Time
+	for _, report := range summary.Reports {
+		if report.Kind != landing.ReportKind {
+			continue
+		}
+		var r landing.Report
+		if err := json.Unmarshal(report.Data, &r); err == nil {
+			times = append(times, r.Time)
+		}
+	}
+	if len(times) == 0 {
+		times = append(times, bt.On.UTC)
+	}
+	return times
+}
+
+// isNight reports whether the simulator environment is between the end of
+// evening and the beginning of morning civil twilight. The simulator's
+// TimeOfDay decides outright for day and night, sunrise and sunset decide
+// during dawn and dusk.
+func isNight(env telemetry.EnvironmentState) bool {
+	switch env.TimeOfDay {
+	case timeOfDayDay:
+		return false
+	case timeOfDayNight:
+		return true
+	}
+	if env.ZuluSunriseTime == env.ZuluSunsetTime {
+		return false
+	}
+	now := mod(int(env.ZuluTime))
+	dayStart := mod(int(env.ZuluSunriseTime) - civilTwilight)
+	dayEnd := mod(int(env.ZuluSunsetTime) + civilTwilight)
+	if dayStart < dayEnd {
+		return now < dayStart || now >= dayEnd
+	}
+	// Day wraps around midnight Zulu
+	return now >= dayEnd && now < dayStart
+}
+
+func mod(seconds int) int {
+	return ((seconds % secondsPerDay) + secondsPerDay) % secondsPerDay
+}
+
+// zulu returns the simulator Zulu time of a stamp, or its real UTC time
+func zulu(s *oooi.Stamp) *time.Time {
+	if s == nil {
+		return nil
+	}
+	t := s.UTC
+	if !s.SimZulu.IsZero() {
+		t = s.SimZulu
+	}
+	return &t
+}
+
+// minutes prefers the simulator duration, which matches the Zulu times
+func minutes(sim, real float64) int {
+	if sim > 0 {
+		return int(math.Round(sim / 60))
+	}
+	return int(math.Round(real / 60))
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// planAirports reads the departure and destination identifiers of a
+// flight plan (.pln) file, empty when the plan cannot be read
+func planAirports(path string) (departure, arrival string) {
+	if path == "" {
+		return "", ""
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", ""
+	}
+	var plan struct {
+		FlightPlan struct {
+			DepartureID   string `xml:"DepartureID"`
+			DestinationID string `xml:"DestinationID"`
+		} `xml:"FlightPlan.FlightPlan"`
+	}
+	if err := xml.Unmarshal(data, &plan); err != nil {
+		return "", ""
+	}
+	return plan.FlightPlan.DepartureID, plan.FlightPlan.DestinationID
+}
@@ -0,0 +1,163 @@
+package logbook
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/landing"
+	"github.com/mycrew-online/flight-data-recorder/internal/oooi"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const testID = "20260411-100000"
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+// hm returns seconds since midnight
+func hm(hours, minutes int) int32 {
+	return int32(hours*3600 + minutes*60)
+}
+
+func TestIsNight(t *testing.T) {
+	const dawnDusk = 2
+	for _, c := range []struct {
+		name            string
+		timeOfDay       int32
+		now             int32
+		sunrise, sunset int32
+		night           bool
+	}{
+		{"day", timeOfDayDay, hm(23, 0), hm(6, 0), hm(18, 0), false},
+		{"night", timeOfDayNight, hm(12, 0), hm(6, 0), hm(18, 0), true},
+		// Civil twilight, 30 minutes around sunrise and sunset, is day
+		{"before dawn", dawnDusk, hm(5, 20), hm(6, 0), hm(18, 0), true},
+		{"dawn", dawnDusk, hm(5, 40), hm(6, 0), hm(18, 0), false},
+		{"dusk", dawnDusk, hm(18, 20), hm(6, 0), hm(18, 0), false},
+		{"after dusk", dawnDusk, hm(18, 40), hm(6, 0), hm(18, 0), true},
+		// East of the date line the day spans midnight Zulu
+		{"wrapped before dawn", dawnDusk, hm(21, 20), hm(22, 0), hm(10, 0), true},
+		{"wrapped dawn", dawnDusk, hm(21, 40), hm(22, 0), hm(10, 0), false},
+		{"wrapped midnight", dawnDusk, hm(0, 0), hm(22, 0), hm(10, 0), false},
+		{"wrapped dusk", dawnDusk, hm(10, 20), hm(22, 0), hm(10, 0), false},
+		{"wrapped after dusk", dawnDusk, hm(10, 40), hm(22, 0), hm(10, 0), true},
+		// Twilight starting on the previous day
+		{"sunrise after midnight", dawnDusk, hm(23, 55), hm(0, 10), hm(12, 0), false},
+		{"night before sunrise after midnight", dawnDusk, hm(23, 35), hm(0, 10), hm(12, 0), true},
+		{"no sunrise", dawnDusk, hm(12, 0), 0, 0, false},
+	} {
+		env := telemetry.EnvironmentState{TimeOfDay: c.timeOfDay, ZuluTime: c.now, ZuluSunriseTime: c.sunrise, ZuluSunsetTime: c.sunset}
+		if night := isNight(env); night != c.night {
+			t.Errorf("%s: night %v, want %v", c.name, night, c.night)
+		}
+	}
+}
+
+// storeFlight writes a recording of samples a minute apart from testStart,
+// by day until 11:00 and by night after, and returns an engine reading it
+func storeFlight(t *testing.T, plan string) *engine.Engine {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, testID+".fdr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := flightrecording.NewWriter(f, flightrecording.Header{CreatedAt: testStart, FlightPlan: plan, Channels: engine.Channels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i <= 120; i++ {
+		s := engine.Sample{Time: testStart.Add(time.Duration(i) * time.Minute)}
+		s.Environment.TimeOfDay = timeOfDayDay
+		if i >= 60 {
+			s.Environment.TimeOfDay = timeOfDayNight
+		}
+		if err := w.WriteFrame(s.Time, s.Values()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return engine.New(nil, engine.Options{Dir: dir})
+}
+
+// stamp returns the stamp of minutes after testStart
+func stamp(minutes int) *oooi.Stamp {
+	return &oooi.Stamp{UTC: testStart.Add(time.Duration(minutes) * time.Minute)}
+}
+
+func landingReport(t *testing.T, minutes int) engine.Report {
+	t.Helper()
+	data, err := json.Marshal(landing.Report{Time: testStart.Add(time.Duration(minutes) * time.Minute)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine.Report{Kind: landing.ReportKind, Data: data}
+}
+
+func TestFromRecording(t *testing.T) {
+	plan := filepath.Join(t.TempDir(), "LKPR-EDDM.pln")
+	err := os.WriteFile(plan, []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<SimBase.Document Type="AceXML" version="1,0">
+    <FlightPlan.FlightPlan>
+        <DepartureID>LKPR</DepartureID>
+        <DestinationID>EDDM</DestinationID>
+    </FlightPlan.FlightPlan>
+</SimBase.Document>`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := storeFlight(t, plan)
+
+	// Out at 10:00, Off 10:10, On 11:40 and In 11:50, night from 11:00
+	block := func() *oooi.BlockTimes {
+		return &oooi.BlockTimes{Out: stamp(0), Off: stamp(10), On: stamp(100), In: stamp(110), BlockSeconds: 6600, AirSeconds: 5400}
+	}
+	atDouble := block()
+	atDouble.SimBlockSeconds, atDouble.SimAirSeconds = 13200, 10800
+	noStand := block()
+	noStand.Out, noStand.In, noStand.BlockSeconds = nil, nil, 0
+	simZulu := block()
+	simZulu.Out = &oooi.Stamp{UTC: testStart, SimZulu: time.Date(2026, 4, 10, 23, 0, 0, 0, time.UTC)}
+
+	for _, c := range []struct {
+		name    string
+		times   *oooi.BlockTimes
+		reports []engine.Report
+		// date, block, air, night, day landings, night landings
+		date                           string
+		block, air, night, day, nightL int
+	}{
+		{"landing at night", block(), nil, "2026-04-11", 110, 90, 50, 0, 1},
+		{"touch and go by day", block(), []engine.Report{landingReport(t, 30), landingReport(t, 100)}, "2026-04-11", 110, 90, 50, 1, 1},
+		// Night time is the share of the simulator block time
+		{"simulation rate", atDouble, nil, "2026-04-11", 220, 180, 100, 0, 1},
+		{"no stand", noStand, nil, "2026-04-11", 0, 90, 40, 0, 1},
+		{"simulator date", simZulu, []engine.Report{landingReport(t, 59)}, "2026-04-10", 110, 90, 50, 1, 0},
+	} {
+		entry, err := FromRecording(e, engine.Summary{ID: testID, AircraftTitle: "C172", Reports: c.reports, BlockTimes: c.times})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if entry.Date != c.date || entry.BlockMinutes != c.block || entry.AirMinutes != c.air || entry.NightMinutes != c.night ||
+			entry.DayLandings != c.day || entry.NightLandings != c.nightL {
+			t.Errorf("%s: %s, block %d, air %d, night %d min, landings %d by day and %d by night, want %s, %d, %d, %d, %d and %d",
+				c.name, entry.Date, entry.BlockMinutes, entry.AirMinutes, entry.NightMinutes, entry.DayLandings, entry.NightLandings,
+				c.date, c.block, c.air, c.night, c.day, c.nightL)
+		}
+		if entry.ID != testID || entry.Aircraft != "C172" || entry.Departure != "LKPR" || entry.Arrival != "EDDM" {
+			t.Errorf("%s: %+v, want the recording, aircraft and airports of the plan", c.name, entry)
+		}
+	}
+
+	if _, err := FromRecording(e, engine.Summary{ID: testID, BlockTimes: &oooi.BlockTimes{Out: stamp(0), Off: stamp(10)}}); !errors.Is(err, ErrNoFlight) {
+		t.Errorf("flight without landing: %v, want ErrNoFlight", err)
+	}
+}
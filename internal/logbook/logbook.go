@@ -0,0 +1,260 @@
+// Package logbook keeps a pilot logbook of completed flights with totals per
+// aircraft, per month and lifetime
+package logbook
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// corruptExt marks logbook files that could not be read
+const corruptExt = ".corrupt"
+
+var ErrEntryNotFound = errors.New("logbook entry not found")
+
+// Entry is a single flight. Times are simulator Zulu times, or real UTC when
+// the simulator did not report its date. Durations are in minutes.
+type Entry struct {
+	ID            string     `json:"id"`   // Recording ID, or derived from the imported row
+	Date          string     `json:"date"` // YYYY-MM-DD of the first known time
+	Departure     string     `json:"departure"`
+	Arrival       string     `json:"arrival"`
+	Aircraft      string     `json:"aircraft"`
+	Out           *time.Time `json:"out,omitempty"`
+	Off           *time.Time `json:"off,omitempty"`
+	On            *time.Time `json:"on,omitempty"`
+	In            *time.Time `json:"in,omitempty"`
+	BlockMinutes  int        `json:"block_minutes"`
+	AirMinutes    int        `json:"air_minutes"`
+	NightMinutes  int        `json:"night_minutes"`
+	DayLandings   int        `json:"day_landings"`
+	NightLandings int        `json:"night_landings"`
+	Remarks       string     `json:"remarks"`
+	Imported      bool       `json:"imported"`
+}
+
+// Total sums entries
+type Total struct {
+	Flights       int `json:"flights"`
+	BlockMinutes  int `json:"block_minutes"`
+	AirMinutes    int `json:"air_minutes"`
+	NightMinutes  int `json:"night_minutes"`
+	DayLandings   int `json:"day_landings"`
+	NightLandings int `json:"night_landings"`
+}
+
+func (t *Total) add(e Entry) {
+	t.Flights++
+	t.BlockMinutes += e.BlockMinutes
+	t.AirMinutes += e.AirMinutes
+	t.NightMinutes += e.NightMinutes
+	t.DayLandings += e.DayLandings
+	t.NightLandings += e.NightLandings
+}
+
+// GroupTotal is the total of an aircraft or a month ("2006-01")
+type GroupTotal struct {
+	Key string `json:"key"`
+	Total
+}
+
+// Totals are the lifetime totals and their breakdown per aircraft and per
+// month, both sorted by key
+type Totals struct {
+	Lifetime    Total        `json:"lifetime"`
+	PerAircraft []GroupTotal `json:"per_aircraft"`
+	PerMonth    []GroupTotal `json:"per_month"`
+}
+
+// file is the JSON document stored on disk
+type file struct {
+	Version int     `json:"version"`
+	Entries []Entry `json:"entries"`
+}
+
+// Logbook is a list of entries stored as a JSON file. Every change is
+// written to disk immediately.
+type Logbook struct {
+	path    string
+	mu      sync.Mutex
+	entries []Entry
+}
+
+// DefaultPath returns the default logbook file in the user config dir
+func DefaultPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "mcrwfdr", "logbook.json")
+}
+
+// New returns an empty logbook stored at path, call Load to read it
+func New(path string) *Logbook {
+	return &Logbook{path: path}
+}
+
+// Path returns the file the logbook is stored in
+func (l *Logbook) Path() string {
+	return l.path
+}
+
+// Load reads the logbook from disk. A missing file is an empty logbook. A
+// file that cannot be decoded is set aside so it is not overwritten.
+func (l *Logbook) Load() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		l.entries = nil
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read logbook: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		l.entries = nil
+		if err := os.Rename(l.path, l.path+corruptExt); err != nil {
+			return fmt.Errorf("failed to set aside unreadable logbook: %w", err)
+		}
+		return fmt.Errorf("failed to decode logbook, moved to %s: %w", filepath.Base(l.path)+corruptExt, err)
+	}
+	l.entries = f.Entries
+	sortEntries(l.entries)
+	return nil
+}
+
+// Entries returns all entries in chronological order
+func (l *Logbook) Entries() []Entry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]Entry(nil), l.entries...)
+}
+
+// Add stores entries, replacing entries with the same ID
+func (l *Logbook) Add(entries ...Entry) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, entry := range entries {
+		if i := l.indexLocked(entry.ID); i >= 0 {
+			l.entries[i] = entry
+		} else {
+			l.entries = append(l.entries, entry)
+		}
+	}
+	sortEntries(l.entries)
+	return l.saveLocked()
+}
+
+// Remove deletes the entry with the given ID
+func (l *Logbook) Remove(id string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	i := l.indexLocked(id)
+	if i < 0 {
+		return ErrEntryNotFound
+	}
+	l.entries = append(l.entries[:i], l.entries[i+1:]...)
+	return l.saveLocked()
+}
+
+// Has reports whether an entry with the given ID exists
+func (l *Logbook) Has(id string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.indexLocked(id) >= 0
+}
+
+// Totals sums all entries
+func (l *Logbook) Totals() Totals {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var totals Totals
+	aircraft := map[string]*Total{}
+	months := map[string]*Total{}
+	for _, e := range l.entries {
+		totals.Lifetime.add(e)
+		group(aircraft, e.Aircraft).add(e)
+		month := e.Date
+		if len(month) >= 7 {
+			month = month[:7]
+		}
+		group(months, month).add(e)
+	}
+	totals.PerAircraft = sortedGroups(aircraft)
+	totals.PerMonth = sortedGroups(months)
+	return totals
+}
+
+func group(groups map[string]*Total, key string) *Total {
+	t, ok := groups[key]
+	if !ok {
+		t = &Total{}
+		groups[key] = t
+	}
+	return t
+}
+
+func sortedGroups(groups map[string]*Total) []GroupTotal {
+	list := make([]GroupTotal, 0, len(groups))
+	for key, t := range groups {
+		list = append(list, GroupTotal{Key: key, Total: *t})
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Key < list[j].Key })
+	return list
+}
+
+func (l *Logbook) indexLocked(id string) int {
+	for i, e := range l.entries {
+		if e.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// saveLocked writes the logbook to a temporary file and renames it over the
+// previous one, so a crash never leaves a truncated logbook
+func (l *Logbook) saveLocked() error {
+	data, err := json.MarshalIndent(file{Version: 1, Entries: l.entries}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode logbook: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o755); err != nil {
+		return fmt.Errorf("failed to create logbook directory: %w", err)
+	}
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return fmt.Errorf("failed to write logbook: %w", err)
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return fmt.Errorf("failed to write logbook: %w", err)
+	}
+	return nil
+}
+
+// sortEntries orders entries by date and first known time
+func sortEntries(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		if entries[i].Date != entries[j].Date {
+			return entries[i].Date < entries[j].Date
+		}
+		return firstTime(entries[i]).Before(firstTime(entries[j]))
+	})
+}
+
+func firstTime(e Entry) time.Time {
+	for _, t := range []*time.Time{e.Out, e.Off, e.On, e.In} {
+		if t != nil {
+			return *t
+		}
+	}
+	return time.Time{}
+}
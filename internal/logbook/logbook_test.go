@@ -0,0 +1,72 @@
+package logbook
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// at returns a time on testStart's day, hours and minutes Zulu, a day later
+// for each day
+func at(day, hours, minutes int) *time.Time {
+	t := testStart.Truncate(24*time.Hour).AddDate(0, 0, day).Add(time.Duration(hours)*time.Hour + time.Duration(minutes)*time.Minute)
+	return &t
+}
+
+// testEntries are flights in April and May 2026, the last one landing after
+// midnight
+func testEntries() []Entry {
+	return []Entry{
+		{ID: "20260411-100000", Date: "2026-04-11", Departure: "LKPR", Arrival: "EDDM", Aircraft: "C172",
+			Out: at(0, 10, 0), Off: at(0, 10, 10), On: at(0, 11, 40), In: at(0, 11, 50),
+			BlockMinutes: 110, AirMinutes: 90, NightMinutes: 50, NightLandings: 1},
+		{ID: "20260412-080000", Date: "2026-04-12", Departure: "EDDM", Arrival: "LKPR", Aircraft: "A320",
+			Out: at(1, 8, 0), Off: at(1, 8, 15), On: at(1, 9, 5), In: at(1, 9, 15),
+			BlockMinutes: 75, AirMinutes: 50, DayLandings: 1, Remarks: "Gate B12, \"quoted\""},
+		{ID: "20260530-230000", Date: "2026-05-30", Departure: "LKPR", Arrival: "EGLL", Aircraft: "C172",
+			Out: at(49, 23, 0), Off: at(49, 23, 10), On: at(50, 0, 50), In: at(50, 1, 0),
+			BlockMinutes: 120, AirMinutes: 100, NightMinutes: 120, DayLandings: 1, NightLandings: 2},
+	}
+}
+
+func TestTotals(t *testing.T) {
+	l := New(filepath.Join(t.TempDir(), "logbook.json"))
+	entries := testEntries()
+	// Added out of order, kept in chronological order
+	if err := l.Add(entries[2], entries[0], entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	for i, e := range l.Entries() {
+		if e.ID != entries[i].ID {
+			t.Errorf("entry %d is %s, want %s", i, e.ID, entries[i].ID)
+		}
+	}
+
+	totals := l.Totals()
+	if want := (Total{Flights: 3, BlockMinutes: 305, AirMinutes: 240, NightMinutes: 170, DayLandings: 2, NightLandings: 3}); totals.Lifetime != want {
+		t.Errorf("lifetime %+v, want %+v", totals.Lifetime, want)
+	}
+	for _, c := range []struct {
+		name   string
+		groups []GroupTotal
+		want   string
+	}{
+		{"per aircraft", totals.PerAircraft, "[{A320 {1 75 50 0 1 0}} {C172 {2 230 190 170 1 3}}]"},
+		{"per month", totals.PerMonth, "[{2026-04 {2 185 140 50 1 1}} {2026-05 {1 120 100 120 1 2}}]"},
+	} {
+		if got := fmt.Sprint(c.groups); got != c.want {
+			t.Errorf("%s %s, want %s", c.name, got, c.want)
+		}
+	}
+
+	// Replacing an entry replaces its totals
+	replaced := entries[1]
+	replaced.Aircraft = "C172"
+	if err := l.Add(replaced); err != nil {
+		t.Fatal(err)
+	}
+	if got := fmt.Sprint(l.Totals().PerAircraft); got != "[{C172 {3 305 240 170 2 3}}]" {
+		t.Errorf("per aircraft after replacing %s", got)
+	}
+}
@@ -0,0 +1,266 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// DefaultPort is the registered port of NMEA 0183 over IP
+const DefaultPort = 10110
+
+const (
+	// interval between fixes, the rate of a GPS receiver
+	interval = time.Second
+	// writeTimeout drops a TCP client that does not read
+	writeTimeout = 2 * time.Second
+)
+
+// Output sends the sentences of the latest state once a second while the
+// simulator reports its date and time
+type Output struct {
+	tcp, udp string
+	logger   *logadapter.LogzWailsAdapter
+
+	mu        sync.Mutex
+	air       telemetry.AirplaneState
+	env       telemetry.EnvironmentState
+	connected bool
+	haveAir   bool
+	clients   map[net.Conn]struct{}
+}
+
+// NewOutput returns an output serving TCP clients on the listen address tcp
+// and sending to the UDP destination udp. Either may be empty. A bare port
+// listens on all interfaces or broadcasts on the local network; a host
+// without a port gets DefaultPort. Call Attach to start.
+func NewOutput(tcp, udp string) (*Output, error) {
+	if tcp == "" && udp == "" {
+		return nil, errors.New("no NMEA address given")
+	}
+	o := &Output{clients: map[net.Conn]struct{}{}}
+	var err error
+	if tcp != "" {
+		if o.tcp, err = parseAddr(tcp, ""); err != nil {
+			return nil, err
+		}
+	}
+	if udp != "" {
+		if o.udp, err = parseAddr(udp, "255.255.255.255"); err != nil {
+			return nil, err
+		}
+	}
+	return o, nil
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (o *Output) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	o.logger = logger
+}
+
+// parseAddr adds bareHost to a bare port and DefaultPort to a host
+func parseAddr(s, bareHost string) (string, error) {
+	s = strings.TrimSpace(s)
+	if port, err := strconv.Atoi(s); err == nil {
+		if port <= 0 || port > 65535 {
+			return "", fmt.Errorf("invalid NMEA port %d", port)
+		}
+		return net.JoinHostPort(bareHost, strconv.Itoa(port)), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return net.JoinHostPort(s, strconv.Itoa(DefaultPort)), nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid NMEA port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Attach feeds the output from bus, opens the listener and the UDP socket
+// and starts sending. Close the returned subscription to stop.
+func (o *Output) Attach(bus *telemetry.Bus) (*telemetry.Subscription, error) {
+	var (
+		ln  net.Listener
+		udp *net.UDPConn
+	)
+	if o.tcp != "" {
+		var err error
+		if ln, err = net.Listen("tcp", o.tcp); err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", o.tcp, err)
+		}
+		o.logInfo("[NMEA] Listening on ", ln.Addr())
+	}
+	if o.udp != "" {
+		raddr, err := net.ResolveUDPAddr("udp", o.udp)
+		if err == nil {
+			udp, err = net.DialUDP("udp", nil, raddr)
+		}
+		if err != nil {
+			if ln != nil {
+				ln.Close()
+			}
+			return nil, fmt.Errorf("failed to open NMEA socket: %w", err)
+		}
+		o.logInfo("[NMEA] Sending to ", o.udp)
+	}
+
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicEnvironment,
+			telemetry.TopicConnection,
+		},
+		Policy: telemetry.DropOldest,
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for msg := range sub.C() {
+			o.update(msg)
+		}
+	}()
+	if ln != nil {
+		go o.accept(ln)
+	}
+	go o.send(udp, ln, done)
+	return sub, nil
+}
+
+func (o *Output) update(msg telemetry.Message) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	switch p := msg.Payload.(type) {
+	case telemetry.AirplaneState:
+		o.air, o.haveAir = p, true
+	case telemetry.EnvironmentState:
+		o.env = p
+	case telemetry.ConnectionStatus:
+		o.connected = p.Connected
+		if !p.Connected {
+			o.haveAir = false
+		}
+	}
+}
+
+// accept adds TCP clients until the listener is closed
+func (o *Output) accept(ln net.Listener) {
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		o.mu.Lock()
+		o.clients[conn] = struct{}{}
+		o.mu.Unlock()
+		o.logInfo("[NMEA] Client ", conn.RemoteAddr(), " connected")
+	}
+}
+
+// send writes the sentences every interval until done is closed, then
+// closes the sockets
+func (o *Output) send(udp *net.UDPConn, ln net.Listener, done chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer func() {
+		ticker.Stop()
+		if ln != nil {
+			ln.Close()
+		}
+		if udp != nil {
+			udp.Close()
+		}
+		o.mu.Lock()
+		for conn := range o.clients {
+			conn.Close()
+			delete(o.clients, conn)
+		}
+		o.mu.Unlock()
+	}()
+	failing := false
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			fix, ok := o.fix()
+			if !ok {
+				continue
+			}
+			data := []byte(strings.Join(Sentences(fix), ""))
+			if udp != nil {
+				// Log the first failure only, e.g. while the network is down
+				_, err := udp.Write(data)
+				if err != nil && !failing {
+					o.logError("[NMEA] Failed to send: ", err)
+				}
+				failing = err != nil
+			}
+			o.writeClients(data)
+		}
+	}
+}
+
+// writeClients sends to every TCP client, dropping those that do not read
+func (o *Output) writeClients(data []byte) {
+	o.mu.Lock()
+	clients := make([]net.Conn, 0, len(o.clients))
+	for conn := range o.clients {
+		clients = append(clients, conn)
+	}
+	o.mu.Unlock()
+	for _, conn := range clients {
+		conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		if _, err := conn.Write(data); err != nil {
+			o.mu.Lock()
+			delete(o.clients, conn)
+			o.mu.Unlock()
+			conn.Close()
+			o.logInfo("[NMEA] Client ", conn.RemoteAddr(), " disconnected")
+		}
+	}
+}
+
+// fix returns the latest position, false while the simulator is
+// disconnected or did not report its date yet
+func (o *Output) fix() (Fix, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if !o.connected || !o.haveAir {
+		return Fix{}, false
+	}
+	t, ok := o.env.ZuluDateTime()
+	if !ok {
+		return Fix{}, false
+	}
+	a := o.air
+	return Fix{
+		Time:             t,
+		Latitude:         a.Latitude,
+		Longitude:        a.Longitude,
+		Altitude:         a.Altitude,
+		PressureAltitude: o.env.PressureAltitude(a.Altitude),
+		GroundSpeed:      a.GroundVelocity,
+		Track:            a.GroundTrack,
+		Variation:        math.Remainder(a.Heading-a.HeadingMagnetic, 360),
+	}, true
+}
+
+func (o *Output) logInfo(args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (o *Output) logError(args ...interface{}) {
+	if o.logger != nil {
+		o.logger.Error(fmt.Sprint(args...))
+	}
+}
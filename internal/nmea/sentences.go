@@ -0,0 +1,130 @@
+// Package nmea serves the simulated aircraft as NMEA 0183 sentences for
+// moving maps and autopilot bridges, over TCP and UDP
+package nmea
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+const (
+	kmhPerKnot    = 1.852
+	metersPerFoot = 0.3048
+)
+
+// Fix is the position the sentences are built from. Altitudes are in feet
+// above mean sea level, the speed in knots and angles in degrees.
+type Fix struct {
+	Time             time.Time // simulator Zulu time
+	Latitude         float64
+	Longitude        float64
+	Altitude         float64
+	PressureAltitude float64
+	GroundSpeed      float64
+	Track            float64 // true
+	Variation        float64 // magnetic, positive east
+}
+
+// Sentences returns GPRMC, GPGGA, GPVTG and PGRMZ for a fix, each ending in
+// CR LF
+func Sentences(f Fix) []string {
+	return []string{RMC(f), GGA(f), VTG(f), RMZ(f)}
+}
+
+// RMC returns the recommended minimum sentence with time, date, position,
+// speed, track and variation
+func RMC(f Fix) string {
+	t := f.Time.UTC()
+	variation, dir := math.Abs(f.Variation), "E"
+	if f.Variation < 0 {
+		dir = "W"
+	}
+	return sentence("GPRMC",
+		t.Format("150405.00"), "A",
+		latitude(f.Latitude), longitude(f.Longitude),
+		fmt.Sprintf("%.1f", math.Max(f.GroundSpeed, 0)),
+		angle(f.Track),
+		t.Format("020106"),
+		fmt.Sprintf("%.1f", variation), dir,
+		"A", // autonomous
+	)
+}
+
+// GGA returns the fix sentence with position and altitude. The simulator
+// position is exact, so it reports a GPS fix with 12 satellites.
+func GGA(f Fix) string {
+	return sentence("GPGGA",
+		f.Time.UTC().Format("150405.00"),
+		latitude(f.Latitude), longitude(f.Longitude),
+		"1", "12", "1.0",
+		fmt.Sprintf("%.1f", f.Altitude*metersPerFoot), "M",
+		"0.0", "M", // geoid separation
+		"", "", // no differential corrections
+	)
+}
+
+// VTG returns the track and ground speed sentence
+func VTG(f Fix) string {
+	speed := math.Max(f.GroundSpeed, 0)
+	return sentence("GPVTG",
+		angle(f.Track), "T",
+		angle(f.Track-f.Variation), "M",
+		fmt.Sprintf("%.1f", speed), "N",
+		fmt.Sprintf("%.1f", speed*kmhPerKnot), "K",
+		"A",
+	)
+}
+
+// RMZ returns the Garmin pressure altitude sentence, in feet
+func RMZ(f Fix) string {
+	return sentence("PGRMZ", fmt.Sprintf("%.0f", f.PressureAltitude), "f", "3")
+}
+
+// Checksum returns the XOR of the characters between $ and *
+func Checksum(body string) byte {
+	var sum byte
+	for i := 0; i < len(body); i++ {
+		sum ^= body[i]
+	}
+	return sum
+}
+
+func sentence(fields ...string) string {
+	body := strings.Join(fields, ",")
+	return fmt.Sprintf("$%s*%02X\r\n", body, Checksum(body))
+}
+
+// latitude formats ddmm.mmmm,N
+func latitude(deg float64) string {
+	hemisphere := "N"
+	if deg < 0 {
+		hemisphere = "S"
+	}
+	return degreesMinutes(math.Abs(deg), 2) + "," + hemisphere
+}
+
+// longitude formats dddmm.mmmm,E
+func longitude(deg float64) string {
+	hemisphere := "E"
+	if deg < 0 {
+		hemisphere = "W"
+	}
+	return degreesMinutes(math.Abs(deg), 3) + "," + hemisphere
+}
+
+func degreesMinutes(deg float64, width int) string {
+	whole := math.Floor(deg)
+	minutes := math.Round((deg-whole)*60*10000) / 10000
+	if minutes >= 60 {
+		whole, minutes = whole+1, 0
+	}
+	return fmt.Sprintf("%0*d%07.4f", width, int(whole), minutes)
+}
+
+// angle formats a direction from 0.0 to 359.9
+func angle(d float64) string {
+	d = math.Mod(math.Mod(math.Round(d*10)/10, 360)+360, 360)
+	return fmt.Sprintf("%.1f", d)
+}
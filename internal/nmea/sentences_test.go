@@ -0,0 +1,101 @@
+package nmea
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSentences(t *testing.T) {
+	for _, c := range []struct {
+		name string
+		fix  Fix
+		want []string
+	}{
+		{"north east", Fix{
+			Time:             time.Date(2026, 4, 11, 10, 2, 3, 500e6, time.UTC),
+			Latitude:         50.1,
+			Longitude:        14.26,
+			Altitude:         1000,
+			PressureAltitude: 1100.4,
+			GroundSpeed:      120.04,
+			Track:            92.46,
+			Variation:        4.2,
+		}, []string{
+			"$GPRMC,100203.50,A,5006.0000,N,01415.6000,E,120.0,92.5,110426,4.2,E,A*0F\r\n",
+			"$GPGGA,100203.50,5006.0000,N,01415.6000,E,1,12,1.0,304.8,M,0.0,M,,*50\r\n",
+			"$GPVTG,92.5,T,88.3,M,120.0,N,222.3,K,A*2C\r\n",
+			"$PGRMZ,1100,f,3*2B\r\n",
+		}},
+		// Track rounding up to 360 and west variation wrapping the
+		// magnetic track past north, below sea level and moving backwards
+		{"south west", Fix{
+			Time:             time.Date(2026, 12, 31, 23, 59, 59, 0, time.UTC),
+			Latitude:         -33.9425,
+			Longitude:        -151.177,
+			Altitude:         -50,
+			PressureAltitude: -20.4,
+			GroundSpeed:      -1,
+			Track:            359.96,
+			Variation:        -12.5,
+		}, []string{
+			"$GPRMC,235959.00,A,3356.5500,S,15110.6200,W,0.0,0.0,311226,12.5,W,A*19\r\n",
+			"$GPGGA,235959.00,3356.5500,S,15110.6200,W,1,12,1.0,-15.2,M,0.0,M,,*48\r\n",
+			"$GPVTG,0.0,T,12.5,M,0.0,N,0.0,K,A*15\r\n",
+			"$PGRMZ,-20,f,3*04\r\n",
+		}},
+	} {
+		got := Sentences(c.fix)
+		if strings.Join(got, "") != strings.Join(c.want, "") {
+			t.Errorf("%s:\n%q\nwant\n%q", c.name, got, c.want)
+		}
+	}
+}
+
+func TestChecksum(t *testing.T) {
+	// The example of the NMEA 0183 standard
+	if sum := Checksum("GPGGA,123519,4807.038,N,01131.000,E,1,08,0.9,545.4,M,46.9,M,,"); sum != 0x47 {
+		t.Errorf("checksum %02X, want 47", sum)
+	}
+}
+
+func TestDegreesMinutes(t *testing.T) {
+	for _, c := range []struct {
+		deg   float64
+		width int
+		want  string
+	}{
+		{0, 2, "0000.0000"},
+		{7.5, 2, "0730.0000"},
+		{14.26, 3, "01415.6000"},
+		{179.999999, 3, "17959.9999"},
+		// Minutes rounding to 60 carry into the degrees
+		{10.99999999, 2, "1100.0000"},
+		{179.99999999, 3, "18000.0000"},
+	} {
+		if got := degreesMinutes(c.deg, c.width); got != c.want {
+			t.Errorf("%v: %s, want %s", c.deg, got, c.want)
+		}
+	}
+}
+
+func TestAngle(t *testing.T) {
+	for _, c := range []struct {
+		deg  float64
+		want string
+	}{
+		{0, "0.0"},
+		{92.46, "92.5"},
+		{359.94, "359.9"},
+		{359.96, "0.0"},
+		{360, "0.0"},
+		{-0.04, "0.0"},
+		{-90, "270.0"},
+		{-370, "350.0"},
+		{720.05, "0.1"},
+	} {
+		if got := angle(c.deg); got != c.want {
+			t.Errorf("%v: %s, want %s", c.deg, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,290 @@
+// Package oooi derives airline style Out, Off, On and In block times from
+// simulator state updates
+package oooi
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// TopicBlockTimes carries the BlockTimes of the current leg on the telemetry
+// bus whenever one of them changes
+const TopicBlockTimes telemetry.Topic = "block-times"
+
+const (
+	stopSpeed = 1.0 // knots, below is stopped
+	// moveHold of movement from the stand sets Out, including pushback
+	moveHold = 5 * time.Second
+	// airHold and groundHold confirm Off and On, so bounces on the takeoff
+	// roll or the landing do not count
+	airHold    = 5 * time.Second
+	groundHold = 10 * time.Second
+	// parkHold stopped in the parking state sets In
+	parkHold = 5 * time.Second
+	// stopHold sets In when stopped away from a parking spot, e.g. on a
+	// remote stand without parking state, and cancels Out when parked again
+	// before takeoff
+	stopHold = 5 * time.Minute
+)
+
+// Stamp is a block time in real UTC and in simulator Zulu time. SimZulu is
+// zero when the simulator did not report its date.
+type Stamp struct {
+	UTC     time.Time `json:"utc"`
+	SimZulu time.Time `json:"sim_zulu"`
+}
+
+// BlockTimes are the OOOI times of a leg. Times not reached yet are nil.
+// Durations are in seconds and zero until both ends are known.
+type BlockTimes struct {
+	Out *Stamp `json:"out,omitempty"`
+	Off *Stamp `json:"off,omitempty"`
+	On  *Stamp `json:"on,omitempty"`
+	In  *Stamp `json:"in,omitempty"`
+	// BlockSeconds and AirSeconds in real time
+	BlockSeconds float64 `json:"block_seconds"`
+	AirSeconds   float64 `json:"air_seconds"`
+	// SimBlockSeconds and SimAirSeconds in simulator time, which differs
+	// from real time with a simulation rate other than 1 or after slewing
+	SimBlockSeconds float64 `json:"sim_block_seconds"`
+	SimAirSeconds   float64 `json:"sim_air_seconds"`
+}
+
+// Tracker follows the aircraft through a leg. A leg starts when the aircraft
+// leaves the stand and ends when it parks after landing. Taxi stops before
+// Out or after On do not affect the times; moving on after a long stop that
+// set In continues the leg when the aircraft does not take off.
+type Tracker struct {
+	mu        sync.Mutex
+	times     BlockTimes
+	previous  *BlockTimes // leg ended by a long stop, resumed without takeoff
+	env       telemetry.EnvironmentState
+	simulator telemetry.SimulatorState
+	speed     float64
+	haveAir   bool
+	haveSim   bool
+	started   bool
+	atStand   bool // seen stopped at a stand, so movement is an Out
+	since     map[string]Stamp
+}
+
+// NewTracker returns a tracker waiting for the aircraft to leave the stand
+func NewTracker() *Tracker {
+	return &Tracker{since: make(map[string]Stamp)}
+}
+
+// Current returns the block times of the current leg
+func (k *Tracker) Current() BlockTimes {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.times
+}
+
+// UpdateAirplane feeds an airplane state and reports whether the block
+// times changed
+func (k *Tracker) UpdateAirplane(t time.Time, a telemetry.AirplaneState) (BlockTimes, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.speed, k.haveAir = a.GroundVelocity, true
+	return k.evaluate(t)
+}
+
+// UpdateSimulator feeds a simulator state and reports whether the block
+// times changed
+func (k *Tracker) UpdateSimulator(t time.Time, s telemetry.SimulatorState) (BlockTimes, bool) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.simulator, k.haveSim = s, true
+	return k.evaluate(t)
+}
+
+// UpdateEnvironment feeds an environment state for the simulator Zulu time
+func (k *Tracker) UpdateEnvironment(e telemetry.EnvironmentState) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.env = e
+}
+
+// Attach feeds the tracker from bus and publishes changes as
+// TopicBlockTimes. Close the returned subscription to detach.
+func (k *Tracker) Attach(bus *telemetry.Bus) *telemetry.Subscription {
+	// Block times are stamped with the first update of a hold, e.g. the
+	// start of the pushback, so a dropped update would shift them
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicEnvironment,
+		},
+		Buffer: 256,
+		Policy: telemetry.Block,
+	})
+	relay := telemetry.NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range sub.C() {
+			var (
+				times BlockTimes
+				ok    bool
+			)
+			switch state := msg.Payload.(type) {
+			case telemetry.AirplaneState:
+				times, ok = k.UpdateAirplane(msg.Time, state)
+			case telemetry.SimulatorState:
+				times, ok = k.UpdateSimulator(msg.Time, state)
+			case telemetry.EnvironmentState:
+				k.UpdateEnvironment(state)
+			}
+			if ok {
+				relay.Publish(TopicBlockTimes, times)
+			}
+		}
+	}()
+	return sub
+}
+
+func (k *Tracker) stamp(t time.Time) Stamp {
+	s := Stamp{UTC: t.UTC()}
+	if zulu, ok := k.env.ZuluDateTime(); ok {
+		s.SimZulu = zulu
+	}
+	return s
+}
+
+// hold reports whether cond has been true for at least dur and returns the
+// stamp of when it became true
+func (k *Tracker) hold(key string, cond bool, t time.Time, dur time.Duration) (Stamp, bool) {
+	if !cond {
+		delete(k.since, key)
+		return Stamp{}, false
+	}
+	start, ok := k.since[key]
+	if !ok {
+		start = k.stamp(t)
+		k.since[key] = start
+	}
+	return start, t.Sub(start.UTC) >= dur
+}
+
+func (k *Tracker) evaluate(t time.Time) (BlockTimes, bool) {
+	if !k.haveAir || !k.haveSim {
+		return BlockTimes{}, false
+	}
+	onGround := k.simulator.OnGround
+	stopped := onGround && k.speed < stopSpeed
+	parked := stopped && k.simulator.InParkingState != 0
+	moving := onGround && k.speed >= stopSpeed
+	changed := false
+	tm := &k.times
+
+	if !k.started {
+		// A flight loaded on the ground starts at a stand or on the runway
+		k.started = true
+		k.atStand = stopped
+	}
+
+	switch {
+	case tm.In != nil:
+		// Moving again after In starts a new leg, which may turn out to be
+		// the continuation of this one
+		if start, ok := k.hold("move", moving, t, moveHold); ok {
+			prev := *tm
+			k.previous = &prev
+			k.times = BlockTimes{Out: &start}
+			k.since = map[string]Stamp{}
+			changed = true
+		}
+
+	case tm.Off == nil:
+		if start, ok := k.hold("park", parked, t, parkHold); ok {
+			k.atStand = true
+			if k.previous != nil {
+				// Taxied on after a long stop and parked without taking
+				// off, the previous leg ends here
+				k.times, k.previous = *k.previous, nil
+				tm.In = &start
+				changed = true
+			}
+		}
+		if _, ok := k.hold("return", parked, t, stopHold); ok && tm.Out != nil {
+			// Returned to the stand before takeoff. The longer hold keeps
+			// the Out of a pushback that stops on the parking spot.
+			tm.Out = nil
+			changed = true
+		}
+		if start, ok := k.hold("move", moving, t, moveHold); ok && tm.Out == nil && k.atStand {
+			tm.Out = &start
+			changed = true
+		}
+		if start, ok := k.hold("air", !onGround, t, airHold); ok {
+			tm.Off = &start
+			k.previous = nil
+			delete(k.since, "air")
+			changed = true
+		}
+
+	case tm.On == nil:
+		// On is the first contact, bounces shorter than airHold keep it
+		if _, ok := k.hold("air", !onGround, t, airHold); ok {
+			delete(k.since, "ground")
+		}
+		if onGround {
+			start, ok := k.since["ground"]
+			if !ok {
+				start = k.stamp(t)
+				k.since["ground"] = start
+			}
+			if t.Sub(start.UTC) >= groundHold {
+				tm.On = &start
+				changed = true
+			}
+		}
+
+	default:
+		if _, ok := k.hold("air", !onGround, t, airHold); ok {
+			// Touch and go, still airborne
+			tm.On = nil
+			delete(k.since, "ground")
+			changed = true
+			break
+		}
+		start, atPark := k.hold("park", parked, t, parkHold)
+		stop, longStop := k.hold("stop", stopped, t, stopHold)
+		switch {
+		case atPark:
+			tm.In = &start
+			changed = true
+		case longStop:
+			tm.In = &stop
+			changed = true
+		}
+	}
+
+	if !changed {
+		return BlockTimes{}, false
+	}
+	if tm.In != nil {
+		k.atStand = true
+	}
+	k.times.durations()
+	return k.times, true
+}
+
+// durations updates the block and air times from the stamps
+func (b *BlockTimes) durations() {
+	b.BlockSeconds, b.SimBlockSeconds = span(b.Out, b.In)
+	b.AirSeconds, b.SimAirSeconds = span(b.Off, b.On)
+}
+
+func span(from, to *Stamp) (real, sim float64) {
+	if from == nil || to == nil {
+		return 0, 0
+	}
+	real = to.UTC.Sub(from.UTC).Seconds()
+	if !from.SimZulu.IsZero() && !to.SimZulu.IsZero() {
+		sim = to.SimZulu.Sub(from.SimZulu).Seconds()
+	}
+	return real, sim
+}
@@ -0,0 +1,122 @@
+package oooi
+
+import (
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+// step holds the states for a number of seconds, updated once a second
+type step struct {
+	seconds  int
+	onGround bool
+	parking  bool
+	speed    float64
+}
+
+// Steps of a leg
+var (
+	parked  = func(s int) step { return step{seconds: s, onGround: true, parking: true} }
+	stopped = func(s int) step { return step{seconds: s, onGround: true} }
+	push    = func(s int) step { return step{seconds: s, onGround: true, parking: true, speed: 3} }
+	taxi    = func(s int) step { return step{seconds: s, onGround: true, speed: 15} }
+	roll    = func(s int) step { return step{seconds: s, onGround: true, speed: 80} }
+	fly     = func(s int) step { return step{seconds: s, speed: 120} }
+)
+
+// run feeds the steps to k, with the simulator clock running at rate times
+// real time when rate is set, and returns the block times
+func run(k *Tracker, rate int, steps []step) BlockTimes {
+	i := 0
+	for _, s := range steps {
+		for n := 0; n < s.seconds; n++ {
+			t := testStart.Add(time.Duration(i) * time.Second)
+			if rate > 0 {
+				k.UpdateEnvironment(telemetry.EnvironmentState{ZuluYear: 2026, ZuluMonth: 4, ZuluDay: 11, ZuluTime: int32(36000 + rate*i)})
+			}
+			sim := telemetry.SimulatorState{OnGround: s.onGround}
+			if s.parking {
+				sim.InParkingState = 1
+			}
+			k.UpdateSimulator(t, sim)
+			k.UpdateAirplane(t, telemetry.AirplaneState{GroundVelocity: s.speed})
+			i++
+		}
+	}
+	return k.Current()
+}
+
+// at returns the second of the leg a stamp was taken at, -1 when not set
+func at(s *Stamp) int {
+	if s == nil {
+		return -1
+	}
+	return int(s.UTC.Sub(testStart) / time.Second)
+}
+
+func TestBlockTimes(t *testing.T) {
+	for _, c := range []struct {
+		name             string
+		steps            []step
+		out, off, on, in int
+		block, air       float64
+	}{
+		{"pushback and flight", []step{
+			parked(60), push(30), stopped(60), taxi(300), roll(30), fly(1800), roll(30), taxi(200), parked(60),
+		}, 60, 480, 2280, 2510, 2450, 1800},
+		{"taxi stops", []step{
+			parked(60), taxi(100), stopped(200), taxi(100), roll(30), fly(600), roll(30), taxi(100), stopped(240), taxi(60), parked(10),
+		}, 60, 490, 1090, 1520, 1460, 600},
+		{"pushback stopping on the parking spot", []step{
+			parked(60), push(20), parked(60), taxi(30),
+		}, 60, -1, -1, -1, 0, 0},
+		{"return to the stand", []step{
+			parked(60), taxi(20), parked(360), taxi(30),
+		}, 440, -1, -1, -1, 0, 0},
+		{"long stop without parking state", []step{
+			parked(60), taxi(100), roll(30), fly(600), roll(30), taxi(100), stopped(360),
+		}, 60, 190, 790, 920, 860, 600},
+		// Moving on after a long stop starts a new leg, parking without a
+		// takeoff resumes the previous one
+		{"taxi on after a long stop", []step{
+			parked(60), taxi(100), roll(30), fly(600), roll(30), taxi(100), stopped(360), taxi(120), parked(10),
+		}, 60, 190, 790, 1400, 1340, 600},
+		// The first contact is On, until the aircraft flies again
+		{"touch and go", []step{
+			parked(60), taxi(100), roll(30), fly(600), roll(20), fly(300), roll(30), taxi(60), parked(10),
+		}, 60, 190, 1110, 1200, 1140, 920},
+		{"bounce", []step{
+			parked(60), taxi(100), roll(30), fly(600), roll(2), fly(3), roll(30), taxi(60), parked(10),
+		}, 60, 190, 790, 885, 825, 600},
+		// A flight loaded on the runway has no Out
+		{"loaded on the runway", []step{
+			roll(30), fly(600), roll(30), taxi(60), parked(10),
+		}, -1, 30, 630, 720, 0, 600},
+	} {
+		times := run(NewTracker(), 0, c.steps)
+		if at(times.Out) != c.out || at(times.Off) != c.off || at(times.On) != c.on || at(times.In) != c.in {
+			t.Errorf("%s: OOOI %d %d %d %d, want %d %d %d %d", c.name,
+				at(times.Out), at(times.Off), at(times.On), at(times.In), c.out, c.off, c.on, c.in)
+		}
+		if times.BlockSeconds != c.block || times.AirSeconds != c.air {
+			t.Errorf("%s: block %v s, air %v s, want %v and %v", c.name, times.BlockSeconds, times.AirSeconds, c.block, c.air)
+		}
+	}
+}
+
+func TestSimulatorTime(t *testing.T) {
+	// At twice the real rate the simulator clock runs twice as fast
+	times := run(NewTracker(), 2, []step{parked(60), taxi(100), roll(30), fly(600), roll(30), taxi(100), parked(10)})
+	if times.BlockSeconds != 860 || times.AirSeconds != 600 {
+		t.Errorf("block %v s, air %v s, want 860 and 600", times.BlockSeconds, times.AirSeconds)
+	}
+	if times.SimBlockSeconds != 1720 || times.SimAirSeconds != 1200 {
+		t.Errorf("simulator block %v s, air %v s, want 1720 and 1200", times.SimBlockSeconds, times.SimAirSeconds)
+	}
+	if want := time.Date(2026, 4, 11, 10, 2, 0, 0, time.UTC); !times.Out.SimZulu.Equal(want) {
+		t.Errorf("Out at %v simulator time, want %v", times.Out.SimZulu, want)
+	}
+}
@@ -0,0 +1,351 @@
+// Package phase detects the flight phase from simulator state updates
+package phase
+
+import (
+	"math"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// Phase is a flight phase
+type Phase string
+
+const (
+	Unknown         Phase = ""
+	Parked          Phase = "parked"
+	Pushback        Phase = "pushback"
+	TaxiOut         Phase = "taxi-out"
+	TakeoffRoll     Phase = "takeoff-roll"
+	RejectedTakeoff Phase = "rejected-takeoff"
+	InitialClimb    Phase = "initial-climb"
+	Climb           Phase = "climb"
+	Cruise          Phase = "cruise"
+	Descent         Phase = "descent"
+	Approach        Phase = "approach"
+	GoAround        Phase = "go-around"
+	LandingRoll     Phase = "landing-roll"
+	TaxiIn          Phase = "taxi-in"
+)
+
+// TopicPhase carries a Change on the telemetry bus
+const TopicPhase telemetry.Topic = "phase"
+
+// Thresholds of the state machine; speeds in knots, altitudes in feet above
+// ground, vertical speeds in feet per minute
+const (
+	stoppedSpeed     = 1.0
+	pushbackMaxSpeed = 6.0
+	takeoffRollSpeed = 40.0
+	runwayRollSpeed  = 35.0 // takeoff roll threshold when known to be on a runway
+	rejectSpeed      = 30.0
+	taxiSpeed        = 30.0
+	initialClimbAGL  = 1500.0
+	approachAGL      = 3000.0
+	levelVS          = 300.0
+	climbVS          = 500.0
+	descentVS        = -500.0
+	approachVS       = -200.0
+	goAroundVS       = 500.0
+	levelHold        = 60 * time.Second
+	verticalHold     = 30 * time.Second
+	goAroundHold     = 5 * time.Second
+	parkedHold       = 2 * time.Minute
+	standHold        = 5 * time.Minute // stopped on a stand without parking state, as OOOI
+	patternDescentVS = -300.0
+	patternHold      = 10 * time.Second
+)
+
+// Change is a transition between two phases
+type Change struct {
+	Time      time.Time `json:"time"`
+	From      Phase     `json:"from"`
+	To        Phase     `json:"to"`
+	Latitude  float64   `json:"latitude"`
+	Longitude float64   `json:"longitude"`
+	Altitude  float64   `json:"altitude"`
+}
+
+// Status is the current phase and when it was entered
+type Status struct {
+	Phase Phase     `json:"phase"`
+	Since time.Time `json:"since"`
+}
+
+// Detector is a state machine deriving the flight phase from airplane and
+// simulator states. Conditions that could flicker, e.g. level flight, must
+// hold for a while before they cause a transition.
+type Detector struct {
+	mu        sync.Mutex
+	phase     Phase
+	since     time.Time
+	airplane  telemetry.AirplaneState
+	simulator telemetry.SimulatorState
+	haveAir   bool
+	haveSim   bool
+	held      map[string]time.Time
+}
+
+// NewDetector returns a detector in the Unknown phase
+func NewDetector() *Detector {
+	return &Detector{held: make(map[string]time.Time)}
+}
+
+// Status returns the current phase
+func (d *Detector) Status() Status {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return Status{Phase: d.phase, Since: d.since}
+}
+
+// UpdateAirplane feeds an airplane state and returns the resulting change
+func (d *Detector) UpdateAirplane(t time.Time, a telemetry.AirplaneState) (Change, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.airplane, d.haveAir = a, true
+	return d.evaluate(t)
+}
+
+// UpdateSimulator feeds a simulator state and returns the resulting change
+func (d *Detector) UpdateSimulator(t time.Time, s telemetry.SimulatorState) (Change, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.simulator, d.haveSim = s, true
+	return d.evaluate(t)
+}
+
+// Reset returns the detector to the Unknown phase, e.g. after a new flight
+// was loaded
+func (d *Detector) Reset() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.phase, d.since = Unknown, time.Time{}
+	d.haveAir, d.haveSim = false, false
+	d.held = make(map[string]time.Time)
+}
+
+func (d *Detector) evaluate(t time.Time) (Change, bool) {
+	if !d.haveAir || !d.haveSim {
+		return Change{}, false
+	}
+	next := d.next(t)
+	if next == d.phase {
+		return Change{}, false
+	}
+	c := Change{
+		Time:      t,
+		From:      d.phase,
+		To:        next,
+		Latitude:  d.airplane.Latitude,
+		Longitude: d.airplane.Longitude,
+		Altitude:  d.airplane.Altitude,
+	}
+	d.phase, d.since = next, t
+	// Hold timers refer to the previous phase
+	d.held = make(map[string]time.Time)
+	return c, true
+}
+
+// hold reports whether cond has been true for at least dur
+func (d *Detector) hold(key string, cond bool, t time.Time, dur time.Duration) bool {
+	if !cond {
+		delete(d.held, key)
+		return false
+	}
+	start, ok := d.held[key]
+	if !ok {
+		d.held[key] = t
+		start = t
+	}
+	return t.Sub(start) >= dur
+}
+
+// next returns the phase following the current one for the latest states
+func (d *Detector) next(t time.Time) Phase {
+	a, s := d.airplane, d.simulator
+	gs, vs, agl := a.GroundVelocity, a.VerticalSpeed, a.AltAboveGround
+	onGround := s.OnGround
+	parking := s.InParkingState != 0
+	rolling := gs >= takeoffRollSpeed || (s.OnAnyRunway != 0 && gs >= runwayRollSpeed)
+
+	switch d.phase {
+	case Unknown:
+		if onGround {
+			if gs < stoppedSpeed {
+				return Parked
+			}
+			if rolling {
+				return TakeoffRoll
+			}
+			return TaxiOut
+		}
+		switch {
+		case agl < approachAGL && vs < approachVS:
+			return Approach
+		case vs > climbVS:
+			return Climb
+		case vs < descentVS:
+			return Descent
+		}
+		return Cruise
+
+	case Parked:
+		if !onGround {
+			return InitialClimb
+		}
+		if gs >= stoppedSpeed {
+			if parking {
+				return Pushback
+			}
+			return TaxiOut
+		}
+
+	case Pushback:
+		if !onGround {
+			return InitialClimb
+		}
+		if gs > pushbackMaxSpeed || (gs < stoppedSpeed && !parking) {
+			return TaxiOut
+		}
+
+	case TaxiOut, RejectedTakeoff:
+		if !onGround {
+			return InitialClimb
+		}
+		if d.phase == RejectedTakeoff && gs < taxiSpeed/2 {
+			return TaxiOut
+		}
+		if d.phase == TaxiOut && rolling {
+			return TakeoffRoll
+		}
+		if d.phase == TaxiOut && d.hold("parked", gs < stoppedSpeed && parking, t, parkedHold) {
+			return Parked
+		}
+
+	case TakeoffRoll:
+		if !onGround {
+			return InitialClimb
+		}
+		if gs < rejectSpeed {
+			return RejectedTakeoff
+		}
+
+	case InitialClimb:
+		if onGround {
+			return LandingRoll
+		}
+		if agl >= initialClimbAGL {
+			return Climb
+		}
+		if d.hold("pattern", vs < patternDescentVS, t, patternHold) {
+			return Approach
+		}
+
+	case Climb, Cruise, Descent:
+		if onGround {
+			return LandingRoll
+		}
+		if agl < approachAGL && vs < approachVS && d.phase != Climb {
+			return Approach
+		}
+		if d.phase == Climb && agl < approachAGL && d.hold("pattern", vs < patternDescentVS, t, patternHold) {
+			return Approach
+		}
+		level := d.hold("level", math.Abs(vs) < levelVS, t, levelHold)
+		climbing := d.hold("climb", vs > climbVS, t, verticalHold)
+		descending := d.hold("descent", vs < descentVS, t, verticalHold)
+		switch {
+		case d.phase != Cruise && level:
+			return Cruise
+		case d.phase != Climb && climbing:
+			return Climb
+		case d.phase != Descent && descending:
+			return Descent
+		}
+
+	case Approach:
+		if onGround {
+			return LandingRoll
+		}
+		if d.hold("go-around", vs > goAroundVS, t, goAroundHold) {
+			return GoAround
+		}
+
+	case GoAround:
+		if onGround {
+			return LandingRoll
+		}
+		if agl >= approachAGL {
+			return Climb
+		}
+		if d.hold("descent", vs < approachVS, t, verticalHold) {
+			return Approach
+		}
+
+	case LandingRoll:
+		if !onGround {
+			// Touch and go or bounce
+			return InitialClimb
+		}
+		if gs < taxiSpeed {
+			return TaxiIn
+		}
+
+	case TaxiIn:
+		if !onGround {
+			return InitialClimb
+		}
+		if rolling {
+			return TakeoffRoll
+		}
+		// Away from a parking spot only a long stop counts, not holding
+		// short or waiting for a gate
+		stopped := d.hold("stopped", gs < stoppedSpeed, t, standHold)
+		if gs < stoppedSpeed && parking || stopped {
+			return Parked
+		}
+	}
+	return d.phase
+}
+
+// Attach feeds the detector from the airplane and simulator topics of bus
+// and publishes every phase change as TopicPhase. The detector starts over
+// when the simulator disconnects. Close the returned subscription to detach.
+func (d *Detector) Attach(bus *telemetry.Bus) *telemetry.Subscription {
+	// A dropped update can skip a short phase such as the takeoff roll or
+	// restart a hold timer, so wait for the detector instead
+	sub := bus.Subscribe(telemetry.SubscribeOptions{
+		Topics: []telemetry.Topic{
+			telemetry.TopicAirplane,
+			telemetry.TopicSimulator,
+			telemetry.TopicConnection,
+		},
+		Buffer: 256,
+		Policy: telemetry.Block,
+	})
+	relay := telemetry.NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range sub.C() {
+			var (
+				c  Change
+				ok bool
+			)
+			switch state := msg.Payload.(type) {
+			case telemetry.AirplaneState:
+				c, ok = d.UpdateAirplane(msg.Time, state)
+			case telemetry.SimulatorState:
+				c, ok = d.UpdateSimulator(msg.Time, state)
+			case telemetry.ConnectionStatus:
+				if !state.Connected {
+					d.Reset()
+				}
+			}
+			if ok {
+				relay.Publish(TopicPhase, c)
+			}
+		}
+	}()
+	return sub
+}
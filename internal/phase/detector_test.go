@@ -0,0 +1,147 @@
+package phase
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const testTimeout = 2 * time.Second
+
+func receiveChange(t *testing.T, sub *telemetry.Subscription) Change {
+	t.Helper()
+	select {
+	case msg := <-sub.C():
+		return msg.Payload.(Change)
+	case <-time.After(testTimeout):
+		t.Fatal("no phase change published")
+		return Change{}
+	}
+}
+
+func TestAttach(t *testing.T) {
+	bus := telemetry.NewBus()
+	changes := bus.Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{TopicPhase}, Buffer: 8})
+	defer changes.Close()
+	d := NewDetector()
+	sub := d.Attach(bus)
+	defer sub.Close()
+
+	bus.Publish(telemetry.TopicSimulator, telemetry.SimulatorState{OnGround: true, InParkingState: 1})
+	bus.Publish(telemetry.TopicAirplane, telemetry.AirplaneState{})
+	if c := receiveChange(t, changes); c.From != Unknown || c.To != Parked {
+		t.Fatalf("change %s -> %s, want parked", c.From, c.To)
+	}
+
+	// A new connection starts from the Unknown phase, e.g. airborne after
+	// the simulator was restarted with another flight
+	bus.Publish(telemetry.TopicConnection, telemetry.ConnectionStatus{Connected: false})
+	bus.Publish(telemetry.TopicConnection, telemetry.ConnectionStatus{Connected: true})
+	bus.Publish(telemetry.TopicSimulator, telemetry.SimulatorState{})
+	bus.Publish(telemetry.TopicAirplane, telemetry.AirplaneState{AltAboveGround: 8000})
+	if c := receiveChange(t, changes); c.From != Unknown || c.To != Cruise {
+		t.Errorf("change %s -> %s after reconnecting, want unknown -> cruise", c.From, c.To)
+	}
+}
+
+// TestAttachKeepsEveryUpdate publishes faster than the detector runs. A
+// lost update would skip the short pushback.
+func TestAttachKeepsEveryUpdate(t *testing.T) {
+	bus := telemetry.NewBus()
+	changes := bus.Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{TopicPhase}, Buffer: 8})
+	defer changes.Close()
+	d := NewDetector()
+	sub := d.Attach(bus)
+	defer sub.Close()
+
+	bus.Publish(telemetry.TopicSimulator, telemetry.SimulatorState{OnGround: true, InParkingState: 1})
+	for i := 0; i < 1000; i++ {
+		bus.Publish(telemetry.TopicAirplane, telemetry.AirplaneState{})
+	}
+	bus.Publish(telemetry.TopicAirplane, telemetry.AirplaneState{GroundVelocity: 3})
+	for i := 0; i < 1000; i++ {
+		bus.Publish(telemetry.TopicSimulator, telemetry.SimulatorState{OnGround: true})
+	}
+	for _, want := range []Phase{Parked, Pushback} {
+		if c := receiveChange(t, changes); c.To != want {
+			t.Fatalf("change to %s, want %s", c.To, want)
+		}
+	}
+	if sub.Dropped() != 0 {
+		t.Errorf("dropped %d updates", sub.Dropped())
+	}
+}
+
+// step holds the states for a number of seconds, updated once a second
+type step struct {
+	seconds  int
+	onGround bool
+	parking  bool
+	speed    float64
+	agl      float64
+	vs       float64
+}
+
+// run feeds the steps to d and returns the phases it changed to
+func run(d *Detector, start time.Time, steps []step) []Phase {
+	var phases []Phase
+	t := start
+	for _, s := range steps {
+		for i := 0; i < s.seconds; i++ {
+			sim := telemetry.SimulatorState{OnGround: s.onGround}
+			if s.parking {
+				sim.InParkingState = 1
+			}
+			air := telemetry.AirplaneState{GroundVelocity: s.speed, AltAboveGround: s.agl, VerticalSpeed: s.vs}
+			for _, c := range []func() (Change, bool){
+				func() (Change, bool) { return d.UpdateSimulator(t, sim) },
+				func() (Change, bool) { return d.UpdateAirplane(t, air) },
+			} {
+				if c, ok := c(); ok {
+					phases = append(phases, c.To)
+				}
+			}
+			t = t.Add(time.Second)
+		}
+	}
+	return phases
+}
+
+func TestTaxiInStops(t *testing.T) {
+	// Short final, touchdown and the landing roll down to taxi speed
+	landing := []step{
+		{seconds: 5, agl: 300, vs: -600, speed: 70},
+		{seconds: 5, onGround: true, speed: 60},
+		{seconds: 5, onGround: true, speed: 15},
+	}
+	landed := []Phase{Approach, LandingRoll, TaxiIn}
+	for _, c := range []struct {
+		name  string
+		steps []step
+		want  []Phase
+	}{
+		{"holding short", []step{
+			{seconds: 4 * 60, onGround: true},
+			{seconds: 60, onGround: true, speed: 15},
+		}, landed},
+		{"waiting for the gate", []step{
+			{seconds: 4*60 + 50, onGround: true},
+			{seconds: 60, onGround: true, speed: 10},
+			{seconds: 30, onGround: true, parking: true},
+		}, append(landed, Parked)},
+		{"parking spot", []step{
+			{seconds: 2, onGround: true, parking: true},
+		}, append(landed, Parked)},
+		{"stand without parking state", []step{
+			{seconds: 5*60 + 1, onGround: true},
+		}, append(landed, Parked)},
+	} {
+		d := NewDetector()
+		got := run(d, time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC), append(append([]step(nil), landing...), c.steps...))
+		if fmt.Sprint(got) != fmt.Sprint(c.want) {
+			t.Errorf("%s: phases %v, want %v", c.name, got, c.want)
+		}
+	}
+}
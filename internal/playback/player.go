@@ -0,0 +1,389 @@
+// Package playback replays stored recordings into the simulator
+package playback
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	MinSpeed = 0.1
+	MaxSpeed = 16.0
+)
+
+var (
+	ErrNotLoaded   = errors.New("no recording loaded")
+	ErrUnsupported = errors.New("the simulator does not support playback")
+)
+
+// Status describes the player
+type Status struct {
+	Loaded          bool    `json:"loaded"`
+	ID              string  `json:"id,omitempty"`
+	Playing         bool    `json:"playing"`
+	PositionSeconds float64 `json:"position_seconds"`
+	DurationSeconds float64 `json:"duration_seconds"`
+	Speed           float64 `json:"speed"`
+}
+
+// Player replays a recording by sending its airplane states to a target at
+// the recorded cadence, scaled by the playback speed. The simulator's own
+// physics are frozen from the first Play until Stop or the end of the
+// recording.
+type Player struct {
+	target   telemetry.PlaybackTarget
+	logger   *logadapter.LogzWailsAdapter
+	onStatus func(Status)
+
+	// ctl serializes the control methods. It stays held while a session is
+	// stopped, when mu is released for the session goroutine to finish.
+	ctl      sync.Mutex
+	mu       sync.Mutex
+	id       string
+	reader   *engine.RecordingReader
+	start    time.Time
+	duration time.Duration
+	position time.Duration
+	speed    float64
+	frozen   bool
+	session  *session
+}
+
+// session is a running playback goroutine. The reader is owned by the
+// session while it runs; control methods stop it before touching the reader.
+type session struct {
+	stop     chan struct{}
+	finished chan struct{}
+	base     time.Time     // wall clock time of basePos
+	basePos  time.Duration // position when the session started
+	speed    float64
+}
+
+// New returns a player driving source. Recordings can be loaded from any
+// source, but Play returns ErrUnsupported unless it is a PlaybackTarget.
+func New(source telemetry.Source) *Player {
+	target, _ := source.(telemetry.PlaybackTarget)
+	return &Player{target: target, speed: 1}
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (p *Player) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	p.logger = logger
+}
+
+// OnStatus registers a callback invoked when playback starts, stops or
+// reaches the end. It is called without holding the player lock.
+func (p *Player) OnStatus(fn func(Status)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.onStatus = fn
+}
+
+// Load opens a stored recording for playback, replacing the current one
+func (p *Player) Load(e *engine.Engine, id string) (Status, error) {
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	summary, err := e.Summary(id)
+	if err != nil {
+		return p.Status(), err
+	}
+	r, err := e.Open(id)
+	if err != nil {
+		return p.Status(), err
+	}
+	p.stop()
+	p.mu.Lock()
+	p.id = id
+	p.reader = r
+	p.start = r.Header().CreatedAt
+	p.duration = time.Duration(summary.DurationSeconds * float64(time.Second))
+	p.position = 0
+	status := p.statusLocked()
+	p.mu.Unlock()
+	p.logInfo("[Playback] Loaded recording ", id)
+	return status, nil
+}
+
+// Play starts or resumes playback at the current position
+func (p *Player) Play() (Status, error) {
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	p.mu.Lock()
+	if p.reader == nil {
+		p.mu.Unlock()
+		return Status{}, ErrNotLoaded
+	}
+	if p.target == nil {
+		p.mu.Unlock()
+		return p.Status(), ErrUnsupported
+	}
+	if p.session != nil {
+		status := p.statusLocked()
+		p.mu.Unlock()
+		return status, nil
+	}
+	if p.position >= p.duration {
+		p.position = 0
+	}
+	if !p.frozen {
+		if err := p.target.FreezeAircraft(true); err != nil {
+			p.mu.Unlock()
+			return p.Status(), fmt.Errorf("failed to freeze aircraft: %w", err)
+		}
+		p.frozen = true
+	}
+	if err := p.startLocked(); err != nil {
+		p.mu.Unlock()
+		return p.Status(), err
+	}
+	status := p.statusLocked()
+	p.mu.Unlock()
+	p.notify(status)
+	return status, nil
+}
+
+// Pause stops playback at the current position. The aircraft stays frozen.
+func (p *Player) Pause() Status {
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	p.mu.Lock()
+	p.stopLocked()
+	status := p.statusLocked()
+	p.mu.Unlock()
+	p.notify(status)
+	return status
+}
+
+// Seek moves playback to offset from the start of the recording
+func (p *Player) Seek(offset time.Duration) (Status, error) {
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.reader == nil {
+		return Status{}, ErrNotLoaded
+	}
+	playing := p.stopLocked()
+	p.position = min(max(offset, 0), p.duration)
+	if playing {
+		if err := p.startLocked(); err != nil {
+			return p.statusLocked(), err
+		}
+	} else if err := p.showLocked(); err != nil {
+		return p.statusLocked(), err
+	}
+	return p.statusLocked(), nil
+}
+
+// SetSpeed sets the playback speed, 1 is real time
+func (p *Player) SetSpeed(speed float64) (Status, error) {
+	if speed < MinSpeed || speed > MaxSpeed {
+		return p.Status(), fmt.Errorf("playback speed must be between %g and %g", MinSpeed, MaxSpeed)
+	}
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	playing := p.stopLocked()
+	p.speed = speed
+	if playing {
+		if err := p.startLocked(); err != nil {
+			return p.statusLocked(), err
+		}
+	}
+	return p.statusLocked(), nil
+}
+
+// Stop ends playback, releases the aircraft and unloads the recording
+func (p *Player) Stop() Status {
+	p.ctl.Lock()
+	defer p.ctl.Unlock()
+	return p.stop()
+}
+
+func (p *Player) stop() Status {
+	p.mu.Lock()
+	p.stopLocked()
+	p.releaseLocked()
+	if p.reader != nil {
+		p.reader.Close()
+		p.reader = nil
+		p.logInfo("[Playback] Unloaded recording ", p.id)
+	}
+	p.id = ""
+	p.position = 0
+	p.duration = 0
+	status := p.statusLocked()
+	p.mu.Unlock()
+	p.notify(status)
+	return status
+}
+
+// Status returns the current player status
+func (p *Player) Status() Status {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.statusLocked()
+}
+
+func (p *Player) statusLocked() Status {
+	return Status{
+		Loaded:          p.reader != nil,
+		ID:              p.id,
+		Playing:         p.session != nil,
+		PositionSeconds: p.position.Seconds(),
+		DurationSeconds: p.duration.Seconds(),
+		Speed:           p.speed,
+	}
+}
+
+// startLocked positions the reader and starts a playback session
+func (p *Player) startLocked() error {
+	if err := p.reader.Seek(p.start.Add(p.position)); err != nil {
+		return fmt.Errorf("failed to seek recording: %w", err)
+	}
+	s := &session{
+		stop:     make(chan struct{}),
+		finished: make(chan struct{}),
+		base:     time.Now(),
+		basePos:  p.position,
+		speed:    p.speed,
+	}
+	p.session = s
+	go p.run(s)
+	return nil
+}
+
+// stopLocked stops the running session and reports whether there was one.
+// mu is released while waiting for the session goroutine, which may still be
+// reading, so the caller must hold ctl.
+func (p *Player) stopLocked() bool {
+	s := p.session
+	if s == nil {
+		return false
+	}
+	p.session = nil
+	close(s.stop)
+	p.mu.Unlock()
+	<-s.finished
+	p.mu.Lock()
+	return true
+}
+
+// showLocked moves the aircraft to the sample at the current position while
+// paused, e.g. after a seek
+func (p *Player) showLocked() error {
+	if err := p.reader.Seek(p.start.Add(p.position)); err != nil {
+		return fmt.Errorf("failed to seek recording: %w", err)
+	}
+	s, err := p.reader.Next()
+	if errors.Is(err, io.EOF) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if !p.frozen {
+		return nil
+	}
+	return p.target.SetAirplaneState(s.Airplane)
+}
+
+func (p *Player) releaseLocked() {
+	if !p.frozen {
+		return
+	}
+	p.frozen = false
+	if err := p.target.FreezeAircraft(false); err != nil {
+		p.logError("[Playback] Failed to release aircraft: ", err)
+	}
+}
+
+// run sends the samples of the recording to the target until the session
+// is stopped or the recording ends
+func (p *Player) run(s *session) {
+	defer close(s.finished)
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		sample, err := p.reader.Next()
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				p.logError("[Playback] Failed to read recording: ", err)
+			}
+			p.finish(s)
+			return
+		}
+		if sample.Airplane.Latitude == 0 && sample.Airplane.Longitude == 0 {
+			continue
+		}
+		offset := sample.Time.Sub(p.start)
+		due := s.base.Add(time.Duration(float64(offset-s.basePos) / s.speed))
+		if wait := time.Until(due); wait > 0 {
+			timer.Reset(wait)
+			select {
+			case <-s.stop:
+				return
+			case <-timer.C:
+			}
+		} else {
+			select {
+			case <-s.stop:
+				return
+			default:
+			}
+		}
+		if err := p.target.SetAirplaneState(sample.Airplane); err != nil {
+			p.logError("[Playback] Failed to set aircraft state: ", err)
+		}
+		p.mu.Lock()
+		if p.session == s {
+			p.position = offset
+		}
+		p.mu.Unlock()
+	}
+}
+
+// finish ends a session that reached the end of the recording
+func (p *Player) finish(s *session) {
+	p.mu.Lock()
+	if p.session != s {
+		p.mu.Unlock()
+		return
+	}
+	p.session = nil
+	p.position = p.duration
+	p.releaseLocked()
+	status := p.statusLocked()
+	p.mu.Unlock()
+	p.logInfo("[Playback] Reached the end of recording ", status.ID)
+	p.notify(status)
+}
+
+func (p *Player) notify(status Status) {
+	p.mu.Lock()
+	fn := p.onStatus
+	p.mu.Unlock()
+	if fn != nil {
+		fn(status)
+	}
+}
+
+func (p *Player) logInfo(args ...interface{}) {
+	if p.logger != nil {
+		p.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (p *Player) logError(args ...interface{}) {
+	if p.logger != nil {
+		p.logger.Error(fmt.Sprint(args...))
+	}
+}
@@ -0,0 +1,249 @@
+package playback
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/engine"
+	flightrecording "github.com/mycrew-online/flight-data-recorder/pkg/flight-recording"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	testID      = "20260411-100000"
+	testSamples = 20
+	testStep    = 100 * time.Millisecond
+	testTimeout = 5 * time.Second
+)
+
+var testStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+// fakeTarget records the states sent by the player. The embedded Source is
+// nil, the player only calls the PlaybackTarget methods.
+type fakeTarget struct {
+	telemetry.Source
+
+	mu     sync.Mutex
+	states []telemetry.AirplaneState
+	frozen []bool
+}
+
+func (f *fakeTarget) SetAirplaneState(state telemetry.AirplaneState) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.states = append(f.states, state)
+	return nil
+}
+
+func (f *fakeTarget) FreezeAircraft(frozen bool) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.frozen = append(f.frozen, frozen)
+	return nil
+}
+
+func (f *fakeTarget) calls() ([]telemetry.AirplaneState, []bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]telemetry.AirplaneState(nil), f.states...), append([]bool(nil), f.frozen...)
+}
+
+// storeRecording writes testSamples samples testStep apart, the latitude
+// counting up from 1, and the summary of the recording
+func storeRecording(t *testing.T) *engine.Engine {
+	t.Helper()
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, testID+".fdr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w, err := flightrecording.NewWriter(f, flightrecording.Header{CreatedAt: testStart, Channels: engine.Channels})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < testSamples; i++ {
+		s := engine.Sample{
+			Time:     testStart.Add(time.Duration(i) * testStep),
+			Airplane: telemetry.AirplaneState{Latitude: float64(i + 1), Longitude: 14},
+		}
+		if err := w.WriteFrame(s.Time, s.Values()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	duration := time.Duration(testSamples-1) * testStep
+	data, err := json.Marshal(engine.Summary{
+		ID:              testID,
+		StartedAt:       testStart,
+		StoppedAt:       testStart.Add(duration),
+		DurationSeconds: duration.Seconds(),
+		SampleCount:     testSamples,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, testID+".json"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return engine.New(nil, engine.Options{Dir: dir})
+}
+
+func newTestPlayer(t *testing.T) (*Player, *fakeTarget) {
+	t.Helper()
+	target := &fakeTarget{}
+	p := New(target)
+	if _, err := p.Load(storeRecording(t), testID); err != nil {
+		t.Fatal(err)
+	}
+	return p, target
+}
+
+func TestPlayToEnd(t *testing.T) {
+	p, target := newTestPlayer(t)
+	statuses := make(chan Status, 4)
+	p.OnStatus(func(s Status) { statuses <- s })
+	if _, err := p.SetSpeed(MaxSpeed); err != nil {
+		t.Fatal(err)
+	}
+	if status, err := p.Play(); err != nil || !status.Playing {
+		t.Fatalf("Play = %+v, %v", status, err)
+	}
+	if s := <-statuses; !s.Playing {
+		t.Errorf("first status %+v, want playing", s)
+	}
+	select {
+	case s := <-statuses:
+		if s.Playing || s.PositionSeconds != s.DurationSeconds {
+			t.Errorf("status at the end %+v, want stopped at the end", s)
+		}
+	case <-time.After(testTimeout):
+		t.Fatal("playback did not reach the end")
+	}
+
+	states, frozen := target.calls()
+	if len(states) != testSamples {
+		t.Fatalf("sent %d states, want %d", len(states), testSamples)
+	}
+	for i, s := range states {
+		if s.Latitude != float64(i+1) {
+			t.Errorf("state %d at latitude %v, want %d", i, s.Latitude, i+1)
+		}
+	}
+	if len(frozen) != 2 || !frozen[0] || frozen[1] {
+		t.Errorf("freeze calls %v, want [true false]", frozen)
+	}
+}
+
+func TestSeekWhilePaused(t *testing.T) {
+	p, target := newTestPlayer(t)
+	if _, err := p.Play(); err != nil {
+		t.Fatal(err)
+	}
+	if status := p.Pause(); status.Playing {
+		t.Fatal("still playing after Pause")
+	}
+	status, err := p.Seek(time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.PositionSeconds != 1 || status.Playing {
+		t.Errorf("status %+v, want paused at 1 s", status)
+	}
+	// The aircraft is moved to the sample at the new position
+	states, _ := target.calls()
+	if last := states[len(states)-1]; last.Latitude != 11 {
+		t.Errorf("aircraft at latitude %v, want 11", last.Latitude)
+	}
+	if status, _ := p.Seek(time.Hour); status.PositionSeconds != status.DurationSeconds {
+		t.Errorf("seek past the end to %v s, want %v s", status.PositionSeconds, status.DurationSeconds)
+	}
+
+	status = p.Stop()
+	if status.Loaded || status.Playing {
+		t.Errorf("status %+v after Stop, want unloaded", status)
+	}
+	if _, frozen := target.calls(); frozen[len(frozen)-1] {
+		t.Error("aircraft still frozen after Stop")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	p := New(&fakeTarget{})
+	if _, err := p.Play(); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Play without a recording: %v, want %v", err, ErrNotLoaded)
+	}
+	if _, err := p.Seek(0); !errors.Is(err, ErrNotLoaded) {
+		t.Errorf("Seek without a recording: %v, want %v", err, ErrNotLoaded)
+	}
+	if _, err := p.SetSpeed(MaxSpeed * 2); err == nil {
+		t.Error("speed above the maximum accepted")
+	}
+	if _, err := p.Load(storeRecording(t), "missing"); !errors.Is(err, engine.ErrRecordingNotFound) {
+		t.Errorf("Load of a missing recording: %v, want %v", err, engine.ErrRecordingNotFound)
+	}
+
+	// A source without PlaybackTarget can load but not play
+	var source struct{ telemetry.Source }
+	p = New(source)
+	if _, err := p.Load(storeRecording(t), testID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Play(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Play without a target: %v, want %v", err, ErrUnsupported)
+	}
+}
+
+// TestConcurrentControl calls the control methods from several goroutines
+// while playing, as the UI and the HTTP API may. A control method stopping
+// the session must keep the others off the reader until the session
+// goroutine is done with it. Run with -race.
+func TestConcurrentControl(t *testing.T) {
+	p, target := newTestPlayer(t)
+	if _, err := p.Play(); err != nil {
+		t.Fatal(err)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 50; j++ {
+				switch (i + j) % 5 {
+				case 0:
+					p.Seek(time.Duration(j) * testStep)
+				case 1:
+					p.SetSpeed(1 + float64(j%4))
+				case 2:
+					p.Pause()
+				case 3:
+					p.Play()
+				case 4:
+					p.Status()
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(10 * time.Millisecond)
+		p.Stop()
+	}()
+	wg.Wait()
+
+	p.Stop()
+	if status := p.Status(); status.Loaded || status.Playing {
+		t.Errorf("status %+v after Stop, want unloaded", status)
+	}
+	if _, frozen := target.calls(); len(frozen) > 0 && frozen[len(frozen)-1] {
+		t.Error("aircraft still frozen after Stop")
+	}
+}
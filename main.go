@@ -2,9 +2,11 @@ package main
 
 import (
 	"embed"
+	"os"
 
 	//"github.com/mrlm-net/go-logz/pkg/logger"
 	"github.com/mycrew-online/flight-data-recorder/internal"
+	"github.com/mycrew-online/flight-data-recorder/internal/cli"
 	"github.com/mycrew-online/flight-data-recorder/internal/logger"
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -16,6 +18,11 @@ var assets embed.FS
 
 // added comment to trigger rebuild
 func main() {
+	// Run headless when started with a command, e.g. "mcrwfdr record"
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	// Create an instance of the app structure
 	app := internal.NewApp()
 
@@ -0,0 +1,191 @@
+package flightrecording
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// decoder reads primitive values from a record payload
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) fail() {
+	if d.err == nil {
+		d.err = ErrCorrupt
+	}
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail()
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+func (d *decoder) bytes(n uint64) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if uint64(len(d.buf)) < n {
+		d.fail()
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	b := d.bytes(1)
+	if b == nil {
+		return 0
+	}
+	return b[0]
+}
+
+func (d *decoder) string() string {
+	return string(d.bytes(d.uvarint()))
+}
+
+func (d *decoder) value(t ChannelType) any {
+	switch t {
+	case TypeFloat64:
+		b := d.bytes(8)
+		if b == nil {
+			return float64(0)
+		}
+		return math.Float64frombits(binary.LittleEndian.Uint64(b))
+	case TypeInt32:
+		return int32(d.varint())
+	case TypeBool:
+		return d.byte() != 0
+	case TypeString:
+		return d.string()
+	}
+	d.err = fmt.Errorf("%w: unknown channel type %d", ErrCorrupt, t)
+	return nil
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendValue(b []byte, t ChannelType, v any) []byte {
+	switch t {
+	case TypeFloat64:
+		return binary.LittleEndian.AppendUint64(b, math.Float64bits(v.(float64)))
+	case TypeInt32:
+		return binary.AppendVarint(b, int64(v.(int32)))
+	case TypeBool:
+		if v.(bool) {
+			return append(b, 1)
+		}
+		return append(b, 0)
+	case TypeString:
+		return appendString(b, v.(string))
+	}
+	return b
+}
+
+func encodeHeader(h Header) []byte {
+	var b []byte
+	b = appendString(b, h.AppVersion)
+	b = binary.AppendVarint(b, h.CreatedAt.UnixMicro())
+	b = appendString(b, h.AircraftTitle)
+	b = appendString(b, h.FlightLoaded)
+	b = appendString(b, h.FlightPlan)
+	b = binary.AppendUvarint(b, uint64(len(h.Channels)))
+	for _, c := range h.Channels {
+		b = appendString(b, c.Name)
+		b = appendString(b, c.Unit)
+		b = append(b, byte(c.Type))
+	}
+	b = appendString(b, h.DataSource)
+	return b
+}
+
+// decodeHeader parses a header payload. Trailing bytes written by newer
+// versions are ignored.
+func decodeHeader(version uint16, payload []byte) (Header, error) {
+	d := decoder{buf: payload}
+	h := Header{Version: version}
+	h.AppVersion = d.string()
+	h.CreatedAt = time.UnixMicro(d.varint()).UTC()
+	h.AircraftTitle = d.string()
+	h.FlightLoaded = d.string()
+	h.FlightPlan = d.string()
+	n := d.uvarint()
+	if n > uint64(len(payload)) {
+		return h, ErrCorrupt
+	}
+	h.Channels = make([]Channel, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		c := Channel{Name: d.string(), Unit: d.string(), Type: ChannelType(d.byte())}
+		h.Channels = append(h.Channels, c)
+	}
+	if d.err == nil && len(d.buf) > 0 {
+		h.DataSource = d.string()
+	}
+	return h, d.err
+}
+
+func encodeIndex(entries []indexEntry) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(entries)))
+	for _, e := range entries {
+		b = append(b, byte(e.kind))
+		b = binary.AppendVarint(b, e.time)
+		b = binary.AppendUvarint(b, uint64(e.offset))
+	}
+	return b
+}
+
+func decodeIndex(payload []byte) ([]indexEntry, error) {
+	d := decoder{buf: payload}
+	n := d.uvarint()
+	if n > uint64(len(payload)) {
+		return nil, ErrCorrupt
+	}
+	entries := make([]indexEntry, 0, n)
+	for i := uint64(0); i < n && d.err == nil; i++ {
+		entries = append(entries, indexEntry{
+			kind:   RecordKind(d.byte()),
+			time:   d.varint(),
+			offset: int64(d.uvarint()),
+		})
+	}
+	return entries, d.err
+}
+
+// frameTime converts a record timestamp back to wall clock time
+func frameTime(h Header, micros int64) time.Time {
+	return h.CreatedAt.Add(time.Duration(micros) * time.Microsecond)
+}
+
+// recordTime converts wall clock time to a record timestamp
+func recordTime(h Header, t time.Time) int64 {
+	return t.Sub(h.CreatedAt).Microseconds()
+}
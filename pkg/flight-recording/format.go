@@ -0,0 +1,167 @@
+// Package flightrecording implements the versioned binary container used to
+// store flight recordings on disk.
+//
+// A recording starts with a fixed magic and a header (format version, app
+// version, aircraft and flight information, channel table), followed by a
+// stream of length-prefixed, checksummed records. Frames are either keyframes
+// holding every channel value or delta frames holding only the channels that
+// changed since the previous frame. When a recording is closed an index of
+// keyframes and events is appended together with a fixed-size trailer, which
+// lets the Reader seek by time without reading the whole file.
+//
+// Readers skip record kinds they do not know, so newer writers may add
+// record kinds without breaking older readers.
+package flightrecording
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"time"
+)
+
+// FormatVersion is the version written by this package
+const FormatVersion uint16 = 1
+
+// Magic identifies a flight recording file
+var Magic = [8]byte{'M', 'C', 'F', 'D', 'R', 0x00, '\r', '\n'}
+
+// trailerMagic terminates a closed recording, preceded by the index offset
+var trailerMagic = [8]byte{'M', 'C', 'F', 'D', 'R', 'I', 'D', 'X'}
+
+const trailerSize = 16
+
+// DefaultKeyframeInterval is the number of frames between two keyframes
+const DefaultKeyframeInterval = 60
+
+// maxRecordSize guards against allocating garbage lengths from corrupt files
+const maxRecordSize = 16 << 20
+
+var (
+	ErrBadMagic           = errors.New("not a flight recording")
+	ErrUnsupportedVersion = errors.New("unsupported flight recording version")
+	ErrChecksum           = errors.New("flight recording checksum mismatch")
+	ErrCorrupt            = errors.New("corrupt flight recording")
+	ErrClosed             = errors.New("flight recording writer closed")
+)
+
+var crcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// RecordKind identifies the type of a record following the header
+type RecordKind byte
+
+const (
+	KindKeyframe RecordKind = 'K' // All channel values
+	KindDelta    RecordKind = 'D' // Changed channel values only
+	KindEvent    RecordKind = 'E' // Named event with opaque payload
+	KindIndex    RecordKind = 'I' // Keyframe and event offsets, written on close
+)
+
+// ChannelType describes how a channel value is encoded
+type ChannelType uint8
+
+const (
+	TypeFloat64 ChannelType = iota + 1
+	TypeInt32
+	TypeBool
+	TypeString
+)
+
+func (t ChannelType) String() string {
+	switch t {
+	case TypeFloat64:
+		return "float64"
+	case TypeInt32:
+		return "int32"
+	case TypeBool:
+		return "bool"
+	case TypeString:
+		return "string"
+	}
+	return fmt.Sprintf("ChannelType(%d)", uint8(t))
+}
+
+// Channel describes one recorded field
+type Channel struct {
+	Name string      `json:"name"`
+	Unit string      `json:"unit"`
+	Type ChannelType `json:"type"`
+}
+
+// Header describes a recording and its channel table
+type Header struct {
+	Version       uint16    `json:"version"`
+	AppVersion    string    `json:"app_version"`
+	CreatedAt     time.Time `json:"created_at"`
+	AircraftTitle string    `json:"aircraft_title"`
+	FlightLoaded  string    `json:"flight_loaded"`
+	FlightPlan    string    `json:"flight_plan"`
+	Channels      []Channel `json:"channels"`
+	// DataSource names the simulator, e.g. "X-Plane". It follows the
+	// channel table and is empty in recordings written before it was added.
+	DataSource string `json:"data_source,omitempty"`
+}
+
+// ChannelIndex returns the position of the named channel or -1
+func (h Header) ChannelIndex(name string) int {
+	for i, c := range h.Channels {
+		if c.Name == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// Frame holds the value of every channel at a point in time.
+// Values are float64, int32, bool or string according to the channel type.
+type Frame struct {
+	Time   time.Time
+	Values []any
+}
+
+// Event is a named annotation stored alongside frames
+type Event struct {
+	Time time.Time
+	Name string
+	Data []byte
+}
+
+// indexEntry points at a keyframe or event record
+type indexEntry struct {
+	kind   RecordKind
+	time   int64 // microseconds since Header.CreatedAt
+	offset int64
+}
+
+func zeroValue(t ChannelType) any {
+	switch t {
+	case TypeFloat64:
+		return float64(0)
+	case TypeInt32:
+		return int32(0)
+	case TypeBool:
+		return false
+	case TypeString:
+		return ""
+	}
+	return nil
+}
+
+// checkValue verifies that v matches the channel type
+func checkValue(c Channel, v any) error {
+	ok := false
+	switch c.Type {
+	case TypeFloat64:
+		_, ok = v.(float64)
+	case TypeInt32:
+		_, ok = v.(int32)
+	case TypeBool:
+		_, ok = v.(bool)
+	case TypeString:
+		_, ok = v.(string)
+	}
+	if !ok {
+		return fmt.Errorf("channel %q expects %s, got %T", c.Name, c.Type, v)
+	}
+	return nil
+}
@@ -0,0 +1,365 @@
+package flightrecording
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"time"
+)
+
+// Reader provides random access to the frames and events of a recording.
+// Only the header and the index are kept in memory.
+type Reader struct {
+	r         io.ReadSeeker
+	cr        *countingReader
+	header    Header
+	dataStart int64
+	end       int64
+	index     []indexEntry
+	indexed   bool
+	closed    bool // true when the recording has an index and trailer
+	values    []any
+	pending   *Frame
+}
+
+// NewReader reads the header of a recording. If the recording was closed
+// properly its index is loaded from the trailer, otherwise the index is built
+// on demand by scanning the records.
+func NewReader(r io.ReadSeeker) (*Reader, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	fr := &Reader{r: r, cr: &countingReader{br: bufio.NewReader(r)}, end: size}
+	if err := fr.seekTo(0); err != nil {
+		return nil, err
+	}
+	var magic [8]byte
+	if _, err := io.ReadFull(fr.cr, magic[:]); err != nil {
+		return nil, ErrBadMagic
+	}
+	if magic != Magic {
+		return nil, ErrBadMagic
+	}
+	var vb [2]byte
+	if _, err := io.ReadFull(fr.cr, vb[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	version := binary.LittleEndian.Uint16(vb[:])
+	if version == 0 || version > FormatVersion {
+		return nil, fmt.Errorf("%w: %d", ErrUnsupportedVersion, version)
+	}
+	payload, err := fr.readPayload()
+	if err != nil {
+		return nil, err
+	}
+	var cb [4]byte
+	if _, err := io.ReadFull(fr.cr, cb[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if binary.LittleEndian.Uint32(cb[:]) != crc32.Checksum(payload, crcTable) {
+		return nil, ErrChecksum
+	}
+	if fr.header, err = decodeHeader(version, payload); err != nil {
+		return nil, err
+	}
+	fr.dataStart = fr.cr.pos
+	fr.loadTrailer(size)
+	return fr, fr.Rewind()
+}
+
+// Header returns the recording header
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Closed reports whether the recording was closed with an index and trailer
+func (r *Reader) Closed() bool {
+	return r.closed
+}
+
+// DataStart returns the offset of the first record after the header
+func (r *Reader) DataStart() int64 {
+	return r.dataStart
+}
+
+// Rewind positions the reader at the first frame
+func (r *Reader) Rewind() error {
+	r.values = nil
+	r.pending = nil
+	return r.seekTo(r.dataStart)
+}
+
+// Next returns the next frame. Events and unknown records are skipped.
+// It returns io.EOF after the last frame.
+func (r *Reader) Next() (Frame, error) {
+	if r.pending != nil {
+		f := *r.pending
+		r.pending = nil
+		return f, nil
+	}
+	for {
+		if r.cr.pos >= r.end {
+			return Frame{}, io.EOF
+		}
+		kind, payload, err := r.readRecord()
+		if err != nil {
+			return Frame{}, err
+		}
+		switch kind {
+		case KindKeyframe, KindDelta:
+			return r.decodeFrame(kind, payload)
+		case KindIndex:
+			return Frame{}, io.EOF
+		}
+	}
+}
+
+// Seek positions the reader so the following call to Next returns the first
+// frame at or after t.
+func (r *Reader) Seek(t time.Time) error {
+	if err := r.buildIndex(); err != nil {
+		return err
+	}
+	ts := recordTime(r.header, t)
+	keyframes := r.entries(KindKeyframe)
+	i := sort.Search(len(keyframes), func(i int) bool { return keyframes[i].time > ts })
+	start := r.dataStart
+	if i > 0 {
+		start = keyframes[i-1].offset
+	}
+	r.values = nil
+	r.pending = nil
+	if err := r.seekTo(start); err != nil {
+		return err
+	}
+	for {
+		f, err := r.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !f.Time.Before(t) {
+			r.pending = &f
+			return nil
+		}
+	}
+}
+
+// Events returns all events of the recording in order
+func (r *Reader) Events() ([]Event, error) {
+	if err := r.buildIndex(); err != nil {
+		return nil, err
+	}
+	pos, values, pending := r.cr.pos, r.values, r.pending
+	defer func() {
+		_ = r.seekTo(pos)
+		r.values, r.pending = values, pending
+	}()
+	var events []Event
+	for _, e := range r.entries(KindEvent) {
+		if err := r.seekTo(e.offset); err != nil {
+			return events, err
+		}
+		kind, payload, err := r.readRecord()
+		if err != nil {
+			return events, err
+		}
+		if kind != KindEvent {
+			return events, ErrCorrupt
+		}
+		d := decoder{buf: payload}
+		ev := Event{Time: frameTime(r.header, d.varint()), Name: d.string()}
+		ev.Data = append([]byte(nil), d.bytes(d.uvarint())...)
+		if d.err != nil {
+			return events, d.err
+		}
+		events = append(events, ev)
+	}
+	return events, nil
+}
+
+func (r *Reader) decodeFrame(kind RecordKind, payload []byte) (Frame, error) {
+	d := decoder{buf: payload}
+	ts := d.varint()
+	if kind == KindKeyframe {
+		r.values = make([]any, len(r.header.Channels))
+		for i, c := range r.header.Channels {
+			r.values[i] = d.value(c.Type)
+		}
+	} else {
+		if r.values == nil {
+			return Frame{}, fmt.Errorf("%w: delta frame without keyframe", ErrCorrupt)
+		}
+		mask := d.bytes(uint64(len(r.header.Channels)+7) / 8)
+		for i, c := range r.header.Channels {
+			if mask != nil && mask[i/8]&(1<<(i%8)) != 0 {
+				r.values[i] = d.value(c.Type)
+			}
+		}
+	}
+	if d.err != nil {
+		return Frame{}, d.err
+	}
+	values := make([]any, len(r.values))
+	copy(values, r.values)
+	return Frame{Time: frameTime(r.header, ts), Values: values}, nil
+}
+
+// loadTrailer reads the index of a properly closed recording
+func (r *Reader) loadTrailer(size int64) {
+	if size-trailerSize < r.dataStart {
+		return
+	}
+	var tb [trailerSize]byte
+	if err := r.seekTo(size - trailerSize); err != nil {
+		return
+	}
+	if _, err := io.ReadFull(r.cr, tb[:]); err != nil || !bytes.Equal(tb[8:], trailerMagic[:]) {
+		return
+	}
+	offset := int64(binary.LittleEndian.Uint64(tb[:8]))
+	if offset < r.dataStart || offset >= size {
+		return
+	}
+	if err := r.seekTo(offset); err != nil {
+		return
+	}
+	kind, payload, err := r.readRecord()
+	if err != nil || kind != KindIndex {
+		return
+	}
+	index, err := decodeIndex(payload)
+	if err != nil {
+		return
+	}
+	r.index = index
+	r.indexed = true
+	r.closed = true
+	r.end = offset
+}
+
+// buildIndex scans the records of a recording without a trailer. Scanning
+// stops at the first damaged record, which becomes the end of the recording.
+func (r *Reader) buildIndex() error {
+	if r.indexed {
+		return nil
+	}
+	pos, values, pending := r.cr.pos, r.values, r.pending
+	if err := r.seekTo(r.dataStart); err != nil {
+		return err
+	}
+	var index []indexEntry
+	for r.cr.pos < r.end {
+		offset := r.cr.pos
+		kind, payload, err := r.readRecord()
+		if err != nil {
+			r.end = offset
+			break
+		}
+		if kind == KindKeyframe || kind == KindEvent {
+			d := decoder{buf: payload}
+			index = append(index, indexEntry{kind: kind, time: d.varint(), offset: offset})
+		}
+	}
+	r.index = index
+	r.indexed = true
+	r.values, r.pending = values, pending
+	return r.seekTo(pos)
+}
+
+// ValidEnd returns the offset just past the last intact record
+func (r *Reader) ValidEnd() (int64, error) {
+	if err := r.buildIndex(); err != nil {
+		return 0, err
+	}
+	return r.end, nil
+}
+
+func (r *Reader) entries(kind RecordKind) []indexEntry {
+	var out []indexEntry
+	for _, e := range r.index {
+		if e.kind == kind {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func (r *Reader) readRecord() (RecordKind, []byte, error) {
+	var kb [1]byte
+	if _, err := io.ReadFull(r.cr, kb[:]); err != nil {
+		return 0, nil, unexpected(err)
+	}
+	payload, err := r.readPayload()
+	if err != nil {
+		return 0, nil, err
+	}
+	var cb [4]byte
+	if _, err := io.ReadFull(r.cr, cb[:]); err != nil {
+		return 0, nil, unexpected(err)
+	}
+	crc := crc32.Update(crc32.Checksum(kb[:], crcTable), crcTable, payload)
+	if binary.LittleEndian.Uint32(cb[:]) != crc {
+		return 0, nil, ErrChecksum
+	}
+	return RecordKind(kb[0]), payload, nil
+}
+
+func (r *Reader) readPayload() ([]byte, error) {
+	n, err := binary.ReadUvarint(r.cr)
+	if err != nil {
+		return nil, unexpected(err)
+	}
+	if n > maxRecordSize {
+		return nil, ErrCorrupt
+	}
+	payload := make([]byte, n)
+	if _, err := io.ReadFull(r.cr, payload); err != nil {
+		return nil, unexpected(err)
+	}
+	return payload, nil
+}
+
+// countingReader tracks the offset of a buffered stream
+type countingReader struct {
+	br  *bufio.Reader
+	pos int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.br.Read(p)
+	c.pos += int64(n)
+	return n, err
+}
+
+func (c *countingReader) ReadByte() (byte, error) {
+	b, err := c.br.ReadByte()
+	if err == nil {
+		c.pos++
+	}
+	return b, err
+}
+
+func (r *Reader) seekTo(offset int64) error {
+	if _, err := r.r.Seek(offset, io.SeekStart); err != nil {
+		return err
+	}
+	r.cr.br.Reset(r.r)
+	r.cr.pos = offset
+	return nil
+}
+
+func unexpected(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
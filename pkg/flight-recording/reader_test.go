@@ -0,0 +1,316 @@
+package flightrecording
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"flag"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden recordings in testdata")
+
+const goldenFile = "testdata/v1.fdr"
+
+var goldenStart = time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+
+var goldenHeader = Header{
+	AppVersion:    "1.0.0",
+	CreatedAt:     goldenStart,
+	AircraftTitle: "C172",
+	FlightLoaded:  "flights/LKPR.FLT",
+	FlightPlan:    "LKPR-LKTB",
+	Channels: []Channel{
+		{Name: "altitude", Unit: "ft", Type: TypeFloat64},
+		{Name: "gear", Type: TypeInt32},
+		{Name: "on_ground", Type: TypeBool},
+		{Name: "title", Type: TypeString},
+	},
+	DataSource: "Microsoft Flight Simulator",
+}
+
+const goldenFrames = 6
+
+// goldenValues returns the values of frame i. Frames 0 and 3 are keyframes,
+// the others delta frames where some channels keep their value.
+func goldenValues(i int) []any {
+	gear := int32(1)
+	if i >= 3 {
+		gear = 0
+	}
+	title := "C172"
+	if i >= 4 {
+		title = "C172 Skyhawk"
+	}
+	return []any{1000 + 100*float64(i), gear, i < 2, title}
+}
+
+func goldenTime(i int) time.Time {
+	return goldenStart.Add(time.Duration(i) * time.Second)
+}
+
+var goldenEvent = Event{Time: goldenStart.Add(2500 * time.Millisecond), Name: "takeoff", Data: []byte(`{"runway":"24"}`)}
+
+// writeGolden writes the golden recording: the frames, an event and a record
+// of a kind added by a future version, which readers must skip
+func writeGolden(t *testing.T) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	w, err := NewWriter(&b, goldenHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.KeyframeInterval = 3
+	for i := 0; i < goldenFrames; i++ {
+		if err := w.WriteFrame(goldenTime(i), goldenValues(i)); err != nil {
+			t.Fatal(err)
+		}
+		switch i {
+		case 1:
+			if err := w.writeRecord('X', []byte("future")); err != nil {
+				t.Fatal(err)
+			}
+		case 2:
+			if err := w.WriteEvent(goldenEvent.Time, goldenEvent.Name, goldenEvent.Data); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func readGolden(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(goldenFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+// indexOffset returns the offset of the index of a closed recording
+func indexOffset(data []byte) int {
+	return int(binary.LittleEndian.Uint64(data[len(data)-trailerSize:]))
+}
+
+// checkFrames reads all frames of r and compares them to the golden frames
+// from first on
+func checkFrames(t *testing.T, r *Reader, first, last int) {
+	t.Helper()
+	for i := first; i < last; i++ {
+		f, err := r.Next()
+		if err != nil {
+			t.Fatalf("frame %d: %v", i, err)
+		}
+		checkFrame(t, i, f)
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("after frame %d: error %v, want io.EOF", last-1, err)
+	}
+}
+
+func checkFrame(t *testing.T, i int, f Frame) {
+	t.Helper()
+	if !f.Time.Equal(goldenTime(i)) {
+		t.Errorf("frame %d at %v, want %v", i, f.Time, goldenTime(i))
+	}
+	want := goldenValues(i)
+	for c := range want {
+		if f.Values[c] != want[c] {
+			t.Errorf("frame %d: %s = %v, want %v", i, goldenHeader.Channels[c].Name, f.Values[c], want[c])
+		}
+	}
+}
+
+// TestGolden checks that the writer still produces the frozen recording, so
+// format changes are deliberate. Run with -update to rewrite it.
+func TestGolden(t *testing.T) {
+	data := writeGolden(t)
+	if *update {
+		if err := os.WriteFile(goldenFile, data, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(data, readGolden(t)) {
+		t.Errorf("writer output differs from %s", goldenFile)
+	}
+}
+
+func TestReadGolden(t *testing.T) {
+	r, err := NewReader(bytes.NewReader(readGolden(t)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := r.Header()
+	if h.Version != FormatVersion || !h.CreatedAt.Equal(goldenStart) || h.AppVersion != goldenHeader.AppVersion ||
+		h.AircraftTitle != goldenHeader.AircraftTitle || h.FlightLoaded != goldenHeader.FlightLoaded ||
+		h.FlightPlan != goldenHeader.FlightPlan || h.DataSource != goldenHeader.DataSource {
+		t.Errorf("header %+v, want %+v", h, goldenHeader)
+	}
+	if len(h.Channels) != len(goldenHeader.Channels) {
+		t.Fatalf("%d channels, want %d", len(h.Channels), len(goldenHeader.Channels))
+	}
+	for i, c := range goldenHeader.Channels {
+		if h.Channels[i] != c {
+			t.Errorf("channel %d = %+v, want %+v", i, h.Channels[i], c)
+		}
+	}
+	if !r.Closed() {
+		t.Error("recording not closed")
+	}
+	checkFrames(t, r, 0, goldenFrames)
+
+	events, err := r.Events()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || !events[0].Time.Equal(goldenEvent.Time) || events[0].Name != goldenEvent.Name || string(events[0].Data) != string(goldenEvent.Data) {
+		t.Errorf("events %+v, want [%+v]", events, goldenEvent)
+	}
+}
+
+func TestHeaderDataSource(t *testing.T) {
+	payload := encodeHeader(goldenHeader)
+	source := appendString(nil, goldenHeader.DataSource)
+	for _, c := range []struct {
+		name    string
+		payload []byte
+		want    string
+	}{
+		{"current", payload, goldenHeader.DataSource},
+		{"written before the data source", payload[:len(payload)-len(source)], ""},
+		{"with fields of a newer version", append(append([]byte(nil), payload...), 1, 2, 3), goldenHeader.DataSource},
+	} {
+		h, err := decodeHeader(FormatVersion, c.payload)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if h.DataSource != c.want || len(h.Channels) != len(goldenHeader.Channels) {
+			t.Errorf("%s: data source %q with %d channels, want %q with %d", c.name, h.DataSource, len(h.Channels), c.want, len(goldenHeader.Channels))
+		}
+	}
+}
+
+func TestReaderErrors(t *testing.T) {
+	golden := readGolden(t)
+	// The first record follows the header
+	r, err := NewReader(bytes.NewReader(golden))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dataStart := int(r.DataStart())
+
+	for _, c := range []struct {
+		name   string
+		mutate func(b []byte) []byte
+		want   error
+	}{
+		{"empty", func(b []byte) []byte { return nil }, ErrBadMagic},
+		{"bad magic", func(b []byte) []byte { b[0] = 'X'; return b }, ErrBadMagic},
+		{"version 0", func(b []byte) []byte { b[8], b[9] = 0, 0; return b }, ErrUnsupportedVersion},
+		{"newer version", func(b []byte) []byte { b[8]++; return b }, ErrUnsupportedVersion},
+		{"header checksum", func(b []byte) []byte { b[dataStart-1] ^= 0xff; return b }, ErrChecksum},
+		{"header cut short", func(b []byte) []byte { return b[:dataStart-2] }, ErrCorrupt},
+		{"header length too large", func(b []byte) []byte {
+			return append(append([]byte(nil), b[:10]...), binary.AppendUvarint(nil, maxRecordSize+1)...)
+		}, ErrCorrupt},
+	} {
+		b := append([]byte(nil), golden...)
+		_, err := NewReader(bytes.NewReader(c.mutate(b)))
+		if !errors.Is(err, c.want) && !(c.want == ErrCorrupt && errors.Is(err, io.ErrUnexpectedEOF)) {
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+func TestFrameChecksum(t *testing.T) {
+	data := readGolden(t)
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Damage the altitude of the first keyframe: kind, length, timestamp
+	data[r.DataStart()+3] ^= 0xff
+	r, err = NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Next(); !errors.Is(err, ErrChecksum) {
+		t.Errorf("error %v, want %v", err, ErrChecksum)
+	}
+}
+
+func TestUnknownRecordKinds(t *testing.T) {
+	// A frozen recording of a future version may start with unknown records
+	var b bytes.Buffer
+	w, err := NewWriter(&b, goldenHeader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, kind := range []RecordKind{'X', 'Y', 0} {
+		if err := w.writeRecord(kind, []byte{1, 2, 3}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteFrame(goldenTime(0), goldenValues(0)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewReader(bytes.NewReader(b.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFrames(t, r, 0, 1)
+}
+
+func TestSeek(t *testing.T) {
+	golden := readGolden(t)
+	for _, file := range []struct {
+		name string
+		data []byte
+	}{
+		{"closed", golden},
+		// Without index and trailer the index is built by scanning
+		{"not closed", golden[:indexOffset(golden)]},
+	} {
+		for _, c := range []struct {
+			name  string
+			at    time.Time
+			first int // golden frame returned by the next Next
+		}{
+			{"before the start", goldenStart.Add(-time.Hour), 0},
+			{"first frame", goldenTime(0), 0},
+			{"delta frame", goldenTime(2), 2},
+			{"between frames", goldenTime(1).Add(time.Millisecond), 2},
+			{"second keyframe", goldenTime(3), 3},
+			{"delta after the second keyframe", goldenTime(5), 5},
+			{"after the end", goldenTime(goldenFrames), goldenFrames},
+		} {
+			t.Run(file.name+"/"+c.name, func(t *testing.T) {
+				r, err := NewReader(bytes.NewReader(file.data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				// Read some frames first, Seek must not depend on the position
+				for i := 0; i < 4; i++ {
+					if _, err := r.Next(); err != nil {
+						t.Fatal(err)
+					}
+				}
+				if err := r.Seek(c.at); err != nil {
+					t.Fatal(err)
+				}
+				checkFrames(t, r, c.first, goldenFrames)
+			})
+		}
+	}
+}
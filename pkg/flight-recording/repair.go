@@ -0,0 +1,55 @@
+package flightrecording
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// File is the subset of *os.File needed to repair a recording in place
+type File interface {
+	io.ReadWriteSeeker
+	Truncate(size int64) error
+}
+
+// RepairResult describes what Repair did to a recording
+type RepairResult struct {
+	Header         Header
+	TruncatedBytes int64 // Bytes of damaged or partial records removed
+	AlreadyClosed  bool  // The recording had a valid index and trailer
+}
+
+// Repair turns a recording that was not closed, e.g. after a crash, into a
+// valid closed recording. Damaged or partially written trailing records are
+// truncated and the index and trailer are rebuilt from the intact records.
+func Repair(f File) (RepairResult, error) {
+	r, err := NewReader(f)
+	if err != nil {
+		return RepairResult{}, err
+	}
+	result := RepairResult{Header: r.Header(), AlreadyClosed: r.Closed()}
+	if r.Closed() {
+		return result, nil
+	}
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return result, err
+	}
+	end, err := r.ValidEnd()
+	if err != nil {
+		return result, err
+	}
+	result.TruncatedBytes = size - end
+	if err := f.Truncate(end); err != nil {
+		return result, err
+	}
+	if _, err := f.Seek(end, io.SeekStart); err != nil {
+		return result, err
+	}
+	b := appendRecord(nil, KindIndex, encodeIndex(r.index))
+	b = binary.LittleEndian.AppendUint64(b, uint64(end))
+	b = append(b, trailerMagic[:]...)
+	if _, err := f.Write(b); err != nil {
+		return result, err
+	}
+	return result, nil
+}
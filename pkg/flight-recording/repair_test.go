@@ -0,0 +1,133 @@
+package flightrecording
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// repairFile writes data to a temporary file and repairs it
+func repairFile(t *testing.T, data []byte) (RepairResult, []byte, error) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "journal.fdr")
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := Repair(f)
+	f.Close()
+	repaired, readErr := os.ReadFile(path)
+	if readErr != nil {
+		t.Fatal(readErr)
+	}
+	return result, repaired, err
+}
+
+func TestRepair(t *testing.T) {
+	golden := readGolden(t)
+	end := indexOffset(golden)
+	lastFrame := lastFrameOffset(t, golden[:end])
+	for _, c := range []struct {
+		name      string
+		data      []byte
+		truncated int64
+		frames    int
+	}{
+		{"all records intact", golden[:end], 0, goldenFrames},
+		{"partial index", golden[:end+3], 3, goldenFrames},
+		{"partial frame", golden[:end-2], int64(end - 2 - lastFrame), goldenFrames - 1},
+		{"damaged frame", damage(golden[:end], end-2), int64(end - lastFrame), goldenFrames - 1},
+	} {
+		result, repaired, err := repairFile(t, c.data)
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if result.AlreadyClosed || result.TruncatedBytes != c.truncated {
+			t.Errorf("%s: %+v, want %d bytes truncated", c.name, result, c.truncated)
+		}
+		if result.Header.DataSource != goldenHeader.DataSource {
+			t.Errorf("%s: header %+v", c.name, result.Header)
+		}
+		r, err := NewReader(bytes.NewReader(repaired))
+		if err != nil {
+			t.Errorf("%s: repaired recording: %v", c.name, err)
+			continue
+		}
+		if !r.Closed() {
+			t.Errorf("%s: repaired recording not closed", c.name)
+		}
+		checkFrames(t, r, 0, c.frames)
+		// Events and seeking work from the rebuilt index
+		if events, err := r.Events(); err != nil || len(events) != 1 {
+			t.Errorf("%s: events %+v, %v, want the takeoff", c.name, events, err)
+		}
+		if err := r.Seek(goldenTime(3)); err != nil {
+			t.Fatal(err)
+		}
+		checkFrames(t, r, 3, c.frames)
+	}
+}
+
+func TestRepairClosed(t *testing.T) {
+	golden := readGolden(t)
+	result, repaired, err := repairFile(t, golden)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !result.AlreadyClosed || result.TruncatedBytes != 0 {
+		t.Errorf("%+v, want already closed", result)
+	}
+	if !bytes.Equal(repaired, golden) {
+		t.Error("closed recording changed")
+	}
+}
+
+func TestRepairErrors(t *testing.T) {
+	golden := readGolden(t)
+	for _, c := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"not a recording", []byte("not a recording"), ErrBadMagic},
+		{"damaged header", damage(golden, 12), ErrChecksum},
+	} {
+		if _, _, err := repairFile(t, c.data); !errors.Is(err, c.want) {
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+	}
+}
+
+// lastFrameOffset returns the offset of the last frame record of data
+func lastFrameOffset(t *testing.T, data []byte) int {
+	t.Helper()
+	r, err := NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := 0
+	for r.cr.pos < r.end {
+		offset := int(r.cr.pos)
+		kind, _, err := r.readRecord()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if kind == KindKeyframe || kind == KindDelta {
+			last = offset
+		}
+	}
+	return last
+}
+
+// damage returns a copy of data with the byte at offset flipped
+func damage(data []byte, offset int) []byte {
+	b := append([]byte(nil), data...)
+	b[offset] ^= 0xff
+	return b
+}
@@ -0,0 +1,170 @@
+package flightrecording
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"time"
+)
+
+// Writer streams frames and events to a recording
+type Writer struct {
+	w                *bufio.Writer
+	header           Header
+	offset           int64
+	last             []any
+	frames           int
+	KeyframeInterval int
+	index            []indexEntry
+	scratch          []byte
+	closed           bool
+}
+
+// NewWriter writes the recording header to w and returns a Writer for the
+// records that follow. Header.Version is always set to FormatVersion and
+// Header.CreatedAt defaults to the current time.
+func NewWriter(w io.Writer, h Header) (*Writer, error) {
+	for _, c := range h.Channels {
+		if zeroValue(c.Type) == nil {
+			return nil, fmt.Errorf("channel %q has unknown type %d", c.Name, c.Type)
+		}
+	}
+	h.Version = FormatVersion
+	if h.CreatedAt.IsZero() {
+		h.CreatedAt = time.Now()
+	}
+	h.CreatedAt = h.CreatedAt.UTC().Truncate(time.Microsecond)
+
+	fw := &Writer{
+		w:                bufio.NewWriter(w),
+		header:           h,
+		KeyframeInterval: DefaultKeyframeInterval,
+	}
+	payload := encodeHeader(h)
+	b := append([]byte{}, Magic[:]...)
+	b = binary.LittleEndian.AppendUint16(b, h.Version)
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	b = append(b, payload...)
+	b = binary.LittleEndian.AppendUint32(b, crc32.Checksum(payload, crcTable))
+	if err := fw.write(b); err != nil {
+		return nil, err
+	}
+	return fw, nil
+}
+
+// Header returns the header written to the recording
+func (w *Writer) Header() Header {
+	return w.header
+}
+
+// Offset returns the number of bytes written so far
+func (w *Writer) Offset() int64 {
+	return w.offset
+}
+
+// WriteFrame appends a frame. A keyframe is written for the first frame and
+// every KeyframeInterval frames, all others only carry changed values.
+func (w *Writer) WriteFrame(t time.Time, values []any) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if len(values) != len(w.header.Channels) {
+		return fmt.Errorf("frame has %d values, header declares %d channels", len(values), len(w.header.Channels))
+	}
+	for i, c := range w.header.Channels {
+		if err := checkValue(c, values[i]); err != nil {
+			return err
+		}
+	}
+	ts := recordTime(w.header, t)
+	keyframe := w.last == nil || (w.KeyframeInterval > 0 && w.frames%w.KeyframeInterval == 0)
+
+	b := binary.AppendVarint(w.scratch[:0], ts)
+	kind := KindDelta
+	if keyframe {
+		kind = KindKeyframe
+		for i, c := range w.header.Channels {
+			b = appendValue(b, c.Type, values[i])
+		}
+		w.index = append(w.index, indexEntry{kind: KindKeyframe, time: ts, offset: w.offset})
+	} else {
+		maskStart := len(b)
+		b = append(b, make([]byte, (len(values)+7)/8)...)
+		for i, c := range w.header.Channels {
+			if values[i] == w.last[i] {
+				continue
+			}
+			b[maskStart+i/8] |= 1 << (i % 8)
+			b = appendValue(b, c.Type, values[i])
+		}
+	}
+	w.scratch = b
+	if err := w.writeRecord(kind, b); err != nil {
+		return err
+	}
+	if w.last == nil {
+		w.last = make([]any, len(values))
+	}
+	copy(w.last, values)
+	w.frames++
+	return nil
+}
+
+// WriteEvent appends a named event with an opaque payload
+func (w *Writer) WriteEvent(t time.Time, name string, data []byte) error {
+	if w.closed {
+		return ErrClosed
+	}
+	ts := recordTime(w.header, t)
+	b := binary.AppendVarint(nil, ts)
+	b = appendString(b, name)
+	b = binary.AppendUvarint(b, uint64(len(data)))
+	b = append(b, data...)
+	w.index = append(w.index, indexEntry{kind: KindEvent, time: ts, offset: w.offset})
+	return w.writeRecord(KindEvent, b)
+}
+
+// Flush writes buffered records to the underlying writer
+func (w *Writer) Flush() error {
+	return w.w.Flush()
+}
+
+// Close writes the index and trailer and flushes the Writer.
+// It does not close the underlying writer.
+func (w *Writer) Close() error {
+	if w.closed {
+		return ErrClosed
+	}
+	w.closed = true
+	indexOffset := w.offset
+	if err := w.writeRecord(KindIndex, encodeIndex(w.index)); err != nil {
+		return err
+	}
+	b := binary.LittleEndian.AppendUint64(nil, uint64(indexOffset))
+	b = append(b, trailerMagic[:]...)
+	if err := w.write(b); err != nil {
+		return err
+	}
+	return w.w.Flush()
+}
+
+func (w *Writer) writeRecord(kind RecordKind, payload []byte) error {
+	return w.write(appendRecord(make([]byte, 0, len(payload)+binary.MaxVarintLen64+5), kind, payload))
+}
+
+// appendRecord frames a payload as kind, length, payload and checksum
+func appendRecord(b []byte, kind RecordKind, payload []byte) []byte {
+	b = append(b, byte(kind))
+	b = binary.AppendUvarint(b, uint64(len(payload)))
+	b = append(b, payload...)
+	crc := crc32.Update(crc32.Checksum([]byte{byte(kind)}, crcTable), crcTable, payload)
+	return binary.LittleEndian.AppendUint32(b, crc)
+}
+
+func (w *Writer) write(b []byte) error {
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	return err
+}
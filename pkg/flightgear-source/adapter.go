@@ -0,0 +1,356 @@
+package flightgearsource
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// DefaultPort is the port FlightGear is told to send the protocol to
+const DefaultPort = 5500
+
+const (
+	// timeout without packets after which FlightGear is disconnected
+	timeout             = 3 * time.Second
+	retryInterval       = 5 * time.Second
+	environmentInterval = time.Second
+	maxPacketSize       = 65536
+	// System event IDs of the SimConnect manager
+	pauseEventID   = 100
+	crashedEventID = 103
+)
+
+// ErrNotSupported is returned for commands FlightGear's generic output
+// cannot receive, e.g. moving the aircraft for playback
+var ErrNotSupported = errors.New("not supported by the FlightGear generic protocol")
+
+// ParseAddr turns a configured listen address into a UDP address. A bare
+// port listens on all interfaces, e.g. "5500" becomes ":5500"; a host
+// without a port gets DefaultPort.
+func ParseAddr(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("no FlightGear address given")
+	}
+	if port, err := strconv.Atoi(s); err == nil {
+		if port <= 0 || port > 65535 {
+			return "", fmt.Errorf("invalid FlightGear port %d", port)
+		}
+		return net.JoinHostPort("", strconv.Itoa(port)), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return net.JoinHostPort(s, strconv.Itoa(DefaultPort)), nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid FlightGear port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Adapter is a telemetry.Source for FlightGear. It receives the
+// generic protocol output of FlightGear on a UDP address and decodes it with
+// a protocol definition. FlightGear is connected while packets arrive.
+type Adapter struct {
+	listen           string
+	airplaneInterval time.Duration
+	logger           *logadapter.LogzWailsAdapter
+	bus              *telemetry.Bus
+
+	mu              sync.Mutex
+	decoder         *Decoder
+	snapshot        telemetry.Snapshot
+	connected       bool
+	touchdown       bool
+	lastPacket      time.Time
+	lastAirplane    time.Time
+	lastEnvironment time.Time
+
+	stopCh  chan struct{}
+	stopped sync.WaitGroup
+}
+
+// New returns an adapter receiving on listen, see ParseAddr, and decoding
+// packets of protocol. Call StartConnection to connect.
+func New(listen string, protocol *Protocol) (*Adapter, error) {
+	listen, err := ParseAddr(listen)
+	if err != nil {
+		return nil, err
+	}
+	return &Adapter{
+		listen:           listen,
+		airplaneInterval: time.Second,
+		bus:              telemetry.NewBus(),
+		decoder:          NewDecoder(protocol),
+	}, nil
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (a *Adapter) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	a.logger = logger
+}
+
+// SetAirplaneInterval sets how often the airplane state is published, zero
+// publishes every packet, i.e. at the rate given to FlightGear
+func (a *Adapter) SetAirplaneInterval(d time.Duration) {
+	a.airplaneInterval = d
+}
+
+// Addr returns the listen address
+func (a *Adapter) Addr() string {
+	return a.listen
+}
+
+// Name returns "FlightGear"
+func (a *Adapter) Name() string {
+	return "FlightGear"
+}
+
+// Bus returns the telemetry bus the adapter publishes to
+func (a *Adapter) Bus() *telemetry.Bus {
+	return a.bus
+}
+
+// StartConnection opens the listen socket until StopConnection
+func (a *Adapter) StartConnection() {
+	a.stopCh = make(chan struct{})
+	a.stopped.Add(1)
+	go a.run(a.stopCh)
+}
+
+// StopConnection closes the socket
+func (a *Adapter) StopConnection() {
+	if a.stopCh == nil {
+		return
+	}
+	close(a.stopCh)
+	a.stopped.Wait()
+	a.stopCh = nil
+}
+
+// run opens the socket, retrying while the address is unavailable, and
+// serves it until stop is closed
+func (a *Adapter) run(stop chan struct{}) {
+	defer a.stopped.Done()
+	for {
+		conn, err := net.ListenPacket("udp", a.listen)
+		if err == nil {
+			a.serve(conn, stop)
+			return
+		}
+		a.logError("[FlightGear] Failed to open socket: ", err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (a *Adapter) serve(conn net.PacketConn, stop chan struct{}) {
+	a.logInfo("[FlightGear] Receiving on ", conn.LocalAddr())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					a.logError("[FlightGear] Failed to receive: ", err)
+				}
+				return
+			}
+			if err := a.HandlePacket(buf[:n], time.Now()); err != nil {
+				a.logDebug("[FlightGear] Dropping packet: ", err)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			conn.Close()
+			<-done
+			a.setDisconnected()
+			return
+		case now := <-ticker.C:
+			a.checkTimeout(now)
+		}
+	}
+}
+
+// HandlePacket decodes a packet received at now and publishes the resulting
+// states. It is called for every received packet and can replay captured
+// packets without FlightGear.
+func (a *Adapter) HandlePacket(packet []byte, now time.Time) error {
+	a.mu.Lock()
+	if err := a.decoder.Decode(packet); err != nil {
+		a.mu.Unlock()
+		return err
+	}
+	a.lastPacket = now
+	connected := !a.connected
+	a.connected = true
+	if !a.decoder.Ready() {
+		a.mu.Unlock()
+		if connected {
+			a.publishConnected(true)
+		}
+		return nil
+	}
+
+	prev := a.snapshot
+	next := prev
+	next.Simulator = a.decoder.Simulator()
+	publishAirplane := now.Sub(a.lastAirplane) >= a.airplaneInterval
+	if publishAirplane {
+		next.Airplane = a.decoder.Airplane()
+		a.lastAirplane = now
+	}
+	publishEnvironment := now.Sub(a.lastEnvironment) >= environmentInterval
+	if publishEnvironment {
+		next.Environment = a.decoder.Environment(now)
+		a.lastEnvironment = now
+	}
+	if next.Airplane != prev.Airplane || next.Environment != prev.Environment || next.Simulator != prev.Simulator {
+		next.Version++
+		a.snapshot = next
+	}
+	var touchdown *telemetry.TouchdownState
+	if a.touchdown {
+		state := a.decoder.Touchdown()
+		touchdown = &state
+	}
+	a.mu.Unlock()
+
+	// Publish like the SimConnect manager: changed states only, the
+	// connection first and system events before the simulator state
+	if connected {
+		a.publishConnected(true)
+	}
+	if next.Simulator.Pause != prev.Simulator.Pause {
+		a.bus.Publish(telemetry.TopicSystemEvent, telemetry.SystemEvent{ID: pauseEventID, Name: "Pause", Data: uint32(next.Simulator.Pause)})
+	}
+	if next.Simulator.Crashed != prev.Simulator.Crashed && next.Simulator.Crashed != 0 {
+		a.bus.Publish(telemetry.TopicSystemEvent, telemetry.SystemEvent{ID: crashedEventID, Name: "Crashed", Data: 1})
+	}
+	if next.Simulator != prev.Simulator || connected {
+		a.bus.Publish(telemetry.TopicSimulator, next.Simulator)
+	}
+	if publishAirplane && (next.Airplane != prev.Airplane || connected) {
+		a.bus.Publish(telemetry.TopicAirplane, next.Airplane)
+	}
+	if publishEnvironment && (next.Environment != prev.Environment || connected) {
+		a.bus.Publish(telemetry.TopicEnvironment, next.Environment)
+	}
+	if touchdown != nil {
+		a.bus.Publish(telemetry.TopicTouchdown, *touchdown)
+	}
+	return nil
+}
+
+// checkTimeout disconnects FlightGear when no packet arrived within timeout
+func (a *Adapter) checkTimeout(now time.Time) {
+	a.mu.Lock()
+	expired := a.connected && now.Sub(a.lastPacket) > timeout
+	a.mu.Unlock()
+	if expired {
+		a.logInfo("[FlightGear] No data received for ", timeout, ", disconnected")
+		a.setDisconnected()
+	}
+}
+
+// setDisconnected forgets the decoded values so a restarted FlightGear
+// starts from a clean state
+func (a *Adapter) setDisconnected() {
+	a.mu.Lock()
+	was := a.connected
+	a.connected = false
+	a.decoder.Reset()
+	a.lastAirplane, a.lastEnvironment = time.Time{}, time.Time{}
+	a.mu.Unlock()
+	if was {
+		a.publishConnected(false)
+	}
+}
+
+func (a *Adapter) publishConnected(connected bool) {
+	a.bus.Publish(telemetry.TopicConnection, telemetry.ConnectionStatus{Connected: connected})
+}
+
+// Status reports whether FlightGear sends data
+func (a *Adapter) Status() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.connected
+}
+
+// Snapshot returns a consistent copy of all current states
+func (a *Adapter) Snapshot() telemetry.Snapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.snapshot
+}
+
+// GetAirplaneState returns a copy of the current airplane state
+func (a *Adapter) GetAirplaneState() telemetry.AirplaneState {
+	return a.Snapshot().Airplane
+}
+
+// GetEnvironmentState returns a copy of the current environment state
+func (a *Adapter) GetEnvironmentState() telemetry.EnvironmentState {
+	return a.Snapshot().Environment
+}
+
+// GetSimulatorState returns a copy of the current simulator state
+func (a *Adapter) GetSimulatorState() telemetry.SimulatorState {
+	return a.Snapshot().Simulator
+}
+
+// TogglePause is not supported, the generic output only sends
+func (a *Adapter) TogglePause() {
+	a.logDebug("[FlightGear] Cannot toggle pause: ", ErrNotSupported)
+}
+
+// RequestTouchdownData starts or stops publishing TouchdownState on
+// TopicTouchdown for every received packet
+func (a *Adapter) RequestTouchdownData(enabled bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.touchdown = enabled
+	return nil
+}
+
+func (a *Adapter) logInfo(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (a *Adapter) logError(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Error(fmt.Sprint(args...))
+	}
+}
+
+func (a *Adapter) logDebug(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Debug(fmt.Sprint(args...))
+	}
+}
+
+// The generic protocol only outputs, so the adapter is no PlaybackTarget
+var (
+	_ telemetry.Source          = (*Adapter)(nil)
+	_ telemetry.TouchdownSource = (*Adapter)(nil)
+)
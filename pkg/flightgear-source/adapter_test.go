@@ -0,0 +1,148 @@
+package flightgearsource
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+func newTestAdapter(t *testing.T, protocol *Protocol) *Adapter {
+	t.Helper()
+	a, err := New("5500", protocol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetAirplaneInterval(0)
+	return a
+}
+
+func TestReplayTextPacket(t *testing.T) {
+	packet, err := os.ReadFile("testdata/mcrwfdr.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAdapter(t, DefaultProtocol())
+	sub := a.Bus().Subscribe(telemetry.SubscribeOptions{Buffer: 16, Policy: telemetry.Block})
+	defer sub.Close()
+	if err := a.HandlePacket(packet, time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	var topics []telemetry.Topic
+	for len(sub.C()) > 0 {
+		topics = append(topics, (<-sub.C()).Topic)
+	}
+	wantTopics := []telemetry.Topic{telemetry.TopicConnection, telemetry.TopicSimulator, telemetry.TopicAirplane, telemetry.TopicEnvironment}
+	if len(topics) != len(wantTopics) {
+		t.Fatalf("published %v, want %v", topics, wantTopics)
+	}
+	for i := range wantTopics {
+		if topics[i] != wantTopics[i] {
+			t.Errorf("published %v, want %v", topics, wantTopics)
+			break
+		}
+	}
+
+	wantAirplane := telemetry.AirplaneState{
+		Title:           "c172p",
+		Latitude:        50.1,
+		Longitude:       14.26,
+		Altitude:        1200,
+		AltAboveGround:  1000,
+		Pitch:           -2.5, // FlightGear is positive nose up
+		Bank:            10,   // and positive right wing down
+		Heading:         245,
+		HeadingMagnetic: 241,
+		GroundTrack:     244,
+		AngleOfAttack:   4,
+		Airspeed:        110,
+		AirspeedTrue:    115,
+		GroundVelocity:  112,
+		VerticalSpeed:   -510,
+	}
+	if got := a.GetAirplaneState(); got != wantAirplane {
+		t.Errorf("airplane state\n got %+v\nwant %+v", got, wantAirplane)
+	}
+
+	zulu := time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+	wantEnvironment := telemetry.EnvironmentState{
+		ZuluTime:             36000,
+		LocalTime:            43200,
+		SimTime:              600,
+		ZuluDay:              11,
+		ZuluMonth:            4,
+		ZuluYear:             2026,
+		LocalDay:             11,
+		LocalMonth:           4,
+		LocalYear:            2026,
+		ZuluDayOfWeek:        int32(zulu.Weekday()),
+		LocalDayOfWeek:       int32(zulu.Weekday()),
+		SeaLevelPressure:     29.92,
+		AmbientTemperature:   15,
+		AmbientWindDirection: 270,
+		AmbientWindVelocity:  9.7,
+		AmbientVisibility:    10000,
+		TimeZoneOffset:       -7200, // local time is two hours ahead
+		TimeOfDay:            timeOfDayDay,
+	}
+	if got := a.GetEnvironmentState(); got != wantEnvironment {
+		t.Errorf("environment state\n got %+v\nwant %+v", got, wantEnvironment)
+	}
+
+	wantSimulator := telemetry.SimulatorState{Sim: 1, AircraftLoaded: "c172p", SimulationRate: 1}
+	if got := a.GetSimulatorState(); got != wantSimulator {
+		t.Errorf("simulator state\n got %+v\nwant %+v", got, wantSimulator)
+	}
+}
+
+func TestReplayBinaryPacket(t *testing.T) {
+	a := newTestAdapter(t, loadProtocol(t, "testdata/binary.xml"))
+	if err := a.RequestTouchdownData(true); err != nil {
+		t.Fatal(err)
+	}
+	sub := a.Bus().Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{telemetry.TopicTouchdown}, Buffer: 1, Policy: telemetry.Block})
+	defer sub.Close()
+	if err := a.HandlePacket(readPacket(t, "testdata/binary.hex"), time.Now()); err != nil {
+		t.Fatal(err)
+	}
+
+	airplane := a.GetAirplaneState()
+	if airplane.Title != defaultTitle {
+		t.Errorf("title = %q, want %q without an aircraft chunk", airplane.Title, defaultTitle)
+	}
+	if airplane.Latitude != -33.9461 || airplane.Longitude != 151.1772 || airplane.Pitch != 1.5 || airplane.Heading != 90 {
+		t.Errorf("airplane state %+v, want 33.9461S 151.1772E, pitch 1.5 and heading 90", airplane)
+	}
+	if !a.GetSimulatorState().OnGround {
+		t.Error("not on ground with weight on the left main gear")
+	}
+	select {
+	case msg := <-sub.C():
+		if td := msg.Payload.(telemetry.TouchdownState); !td.OnGround || td.Latitude != airplane.Latitude {
+			t.Errorf("touchdown state %+v, want on ground at the airplane position", td)
+		}
+	default:
+		t.Error("no touchdown state published")
+	}
+}
+
+func TestTimeoutResetsDecoder(t *testing.T) {
+	packet, err := os.ReadFile("testdata/mcrwfdr.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := newTestAdapter(t, DefaultProtocol())
+	start := time.Now()
+	if err := a.HandlePacket(packet, start); err != nil {
+		t.Fatal(err)
+	}
+	a.checkTimeout(start.Add(2 * timeout))
+	if a.Status() {
+		t.Fatal("still connected after the timeout")
+	}
+	if a.decoder.Ready() {
+		t.Error("decoder kept the values of the previous connection")
+	}
+}
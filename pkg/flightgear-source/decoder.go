@@ -0,0 +1,221 @@
+package flightgearsource
+
+import (
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	// groundHeight decides on ground from the height above ground when the
+	// protocol has no weight on wheels
+	groundHeight = 3.0
+	// nightSunPitch is the end of civil twilight
+	nightSunPitch = -6.0
+	secondsPerDay = 24 * 60 * 60
+	// defaultTitle names the aircraft when the protocol does not
+	defaultTitle = "FlightGear"
+)
+
+// TimeOfDay values of EnvironmentState, as reported by SimConnect
+const (
+	timeOfDayDawn  = 0
+	timeOfDayDay   = 1
+	timeOfDayDusk  = 2
+	timeOfDayNight = 3
+)
+
+// Decoder keeps the latest values of the received records and maps them to
+// the telemetry states. Pitch and bank follow SimConnect, negative nose up
+// and right wing down. It is not safe for concurrent use.
+type Decoder struct {
+	protocol *Protocol
+	values   [fieldCount]float64
+	seen     [fieldCount]bool
+	aircraft string
+}
+
+// NewDecoder returns a decoder for packets of protocol
+func NewDecoder(protocol *Protocol) *Decoder {
+	return &Decoder{protocol: protocol}
+}
+
+// Decode applies a packet
+func (d *Decoder) Decode(packet []byte) error {
+	values, err := d.protocol.Decode(packet)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		if v.Node == aircraftNode {
+			d.aircraft = v.Text
+			continue
+		}
+		prop, ok := properties[v.Node]
+		if !ok {
+			continue
+		}
+		n := v.Number
+		if prop.Convert != nil {
+			n = prop.Convert(n)
+		}
+		d.values[prop.Field] = n
+		d.seen[prop.Field] = true
+	}
+	return nil
+}
+
+// Reset forgets all received values
+func (d *Decoder) Reset() {
+	*d = Decoder{protocol: d.protocol}
+}
+
+// Ready reports whether the position was received
+func (d *Decoder) Ready() bool {
+	return d.seen[fieldLatitude] && d.seen[fieldLongitude]
+}
+
+func (d *Decoder) onGround() bool {
+	if d.seen[fieldLeftGearOnGround] || d.seen[fieldRightGearOnGround] {
+		return d.values[fieldLeftGearOnGround] != 0 || d.values[fieldRightGearOnGround] != 0
+	}
+	return d.seen[fieldAltAboveGround] && d.values[fieldAltAboveGround] < groundHeight
+}
+
+func (d *Decoder) title() string {
+	if d.aircraft != "" {
+		return d.aircraft
+	}
+	return defaultTitle
+}
+
+// Airplane returns the airplane state. The generic protocol has no NAV1
+// deviations in degrees, so the localizer and glide slope are not reported.
+func (d *Decoder) Airplane() telemetry.AirplaneState {
+	v := &d.values
+	return telemetry.AirplaneState{
+		Title:           d.title(),
+		Latitude:        v[fieldLatitude],
+		Longitude:       v[fieldLongitude],
+		Altitude:        v[fieldAltitude],
+		Heading:         v[fieldHeading],
+		HeadingMagnetic: v[fieldHeadingMagnetic],
+		Airspeed:        v[fieldAirspeed],
+		Bank:            -v[fieldRoll],
+		AltAboveGround:  v[fieldAltAboveGround],
+		Pitch:           -v[fieldPitch],
+		VerticalSpeed:   telemetry.RoundVerticalSpeed(v[fieldVerticalSpeed]),
+		GroundVelocity:  v[fieldGroundSpeed],
+		GroundTrack:     v[fieldTrack],
+		AirspeedTrue:    v[fieldAirspeedTrue],
+		AngleOfAttack:   v[fieldAngleOfAttack],
+	}
+}
+
+// Touchdown returns the touchdown state
+func (d *Decoder) Touchdown() telemetry.TouchdownState {
+	v := &d.values
+	return telemetry.TouchdownState{
+		Latitude:       v[fieldLatitude],
+		Longitude:      v[fieldLongitude],
+		AltAboveGround: v[fieldAltAboveGround],
+		VerticalSpeed:  v[fieldVerticalSpeed],
+		GForce:         v[fieldGForce],
+		Pitch:          -v[fieldPitch],
+		Bank:           -v[fieldRoll],
+		Heading:        v[fieldHeading],
+		GroundVelocity: v[fieldGroundSpeed],
+		Airspeed:       v[fieldAirspeed],
+		OnGround:       d.onGround(),
+	}
+}
+
+// Simulator returns the simulator state. An aircraft on the ground with the
+// parking brake set and the first engine off is reported in parking state.
+func (d *Decoder) Simulator() telemetry.SimulatorState {
+	v := &d.values
+	s := telemetry.SimulatorState{
+		Sim:            1,
+		AircraftLoaded: d.title(),
+		SimulationRate: 1,
+		OnGround:       d.onGround(),
+	}
+	if v[fieldPaused] != 0 {
+		s.Pause = 1
+	}
+	if v[fieldCrashed] != 0 {
+		s.Crashed = 1
+	}
+	if d.seen[fieldSimSpeed] {
+		s.SimulationRate = v[fieldSimSpeed]
+	}
+	if s.OnGround && v[fieldParkingBrake] >= 0.5 && d.seen[fieldEngineRunning] && v[fieldEngineRunning] == 0 {
+		s.InParkingState = 1
+	}
+	return s
+}
+
+// Environment returns the environment state. Without the UTC date in the
+// protocol the date of now is used.
+func (d *Decoder) Environment(now time.Time) telemetry.EnvironmentState {
+	v := &d.values
+	zulu := int(v[fieldZuluTime])
+	local := int(v[fieldLocalTime])
+	if !d.seen[fieldLocalTime] {
+		local = zulu
+	}
+	// Zulu minus local time, positive west like SimConnect
+	offset := wrapDay(zulu - local)
+
+	zuluDate := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	if d.seen[fieldUTCYear] && d.seen[fieldUTCMonth] && d.seen[fieldUTCDay] {
+		zuluDate = time.Date(int(v[fieldUTCYear]), time.Month(v[fieldUTCMonth]), int(v[fieldUTCDay]), 0, 0, 0, 0, time.UTC)
+	}
+	zuluTime := zuluDate.Add(time.Duration(zulu) * time.Second)
+	localTime := zuluTime.Add(-time.Duration(offset) * time.Second)
+
+	e := telemetry.EnvironmentState{
+		ZuluTime:             int32(zulu),
+		LocalTime:            int32(local),
+		SimTime:              int32(v[fieldElapsedTime]),
+		ZuluDay:              int32(zuluTime.Day()),
+		ZuluMonth:            int32(zuluTime.Month()),
+		ZuluYear:             int32(zuluTime.Year()),
+		LocalDay:             int32(localTime.Day()),
+		LocalMonth:           int32(localTime.Month()),
+		LocalYear:            int32(localTime.Year()),
+		ZuluDayOfWeek:        int32(zuluTime.Weekday()),
+		LocalDayOfWeek:       int32(localTime.Weekday()),
+		SeaLevelPressure:     v[fieldSeaLevelPressure],
+		AmbientTemperature:   v[fieldTemperature],
+		AmbientWindDirection: v[fieldWindDirection],
+		AmbientWindVelocity:  v[fieldWindSpeed],
+		AmbientVisibility:    v[fieldVisibility],
+		TimeZoneOffset:       int32(offset),
+		TimeOfDay:            timeOfDayDay,
+	}
+	if d.seen[fieldSunPitch] {
+		switch sun := v[fieldSunPitch]; {
+		case sun >= 0:
+			e.TimeOfDay = timeOfDayDay
+		case sun < nightSunPitch:
+			e.TimeOfDay = timeOfDayNight
+		case local < secondsPerDay/2:
+			e.TimeOfDay = timeOfDayDawn
+		default:
+			e.TimeOfDay = timeOfDayDusk
+		}
+	}
+	return e
+}
+
+// wrapDay keeps a time difference in seconds within half a day
+func wrapDay(seconds int) int {
+	seconds %= secondsPerDay
+	if seconds > secondsPerDay/2 {
+		seconds -= secondsPerDay
+	} else if seconds < -secondsPerDay/2 {
+		seconds += secondsPerDay
+	}
+	return seconds
+}
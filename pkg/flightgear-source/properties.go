@@ -0,0 +1,102 @@
+package flightgearsource
+
+import "math"
+
+// field is a value kept by the Decoder, in the units of the state structs
+type field int
+
+const (
+	fieldLatitude field = iota
+	fieldLongitude
+	fieldAltitude       // feet
+	fieldAltAboveGround // feet
+	fieldPitch          // degrees, positive nose up
+	fieldRoll           // degrees, positive right wing down
+	fieldHeading        // true
+	fieldHeadingMagnetic
+	fieldTrack // true
+	fieldAngleOfAttack
+	fieldAirspeed
+	fieldAirspeedTrue
+	fieldGroundSpeed
+	fieldVerticalSpeed // feet per minute
+	fieldGForce
+	fieldLeftGearOnGround
+	fieldRightGearOnGround
+	fieldParkingBrake
+	fieldEngineRunning
+	fieldCrashed
+	fieldPaused
+	fieldSimSpeed
+	fieldUTCYear
+	fieldUTCMonth
+	fieldUTCDay
+	fieldZuluTime  // seconds since midnight
+	fieldLocalTime // seconds since midnight
+	fieldElapsedTime
+	fieldSunPitch         // degrees above the horizon
+	fieldSeaLevelPressure // inHg
+	fieldTemperature      // celsius
+	fieldWindDirection    // true, from
+	fieldWindSpeed        // knots
+	fieldVisibility       // meters
+	fieldCount
+)
+
+// aircraftNode is the string property naming the aircraft
+const aircraftNode = "/sim/aircraft"
+
+// property maps a property node to a field
+type property struct {
+	Field   field
+	Convert func(float64) float64 // optional
+}
+
+// properties are the property nodes the Decoder understands. Chunks with
+// other nodes are ignored.
+var properties = map[string]property{
+	"/position/latitude-deg":                            {Field: fieldLatitude},
+	"/position/longitude-deg":                           {Field: fieldLongitude},
+	"/position/altitude-ft":                             {Field: fieldAltitude},
+	"/position/altitude-agl-ft":                         {Field: fieldAltAboveGround},
+	"/orientation/pitch-deg":                            {Field: fieldPitch},
+	"/orientation/roll-deg":                             {Field: fieldRoll},
+	"/orientation/heading-deg":                          {Field: fieldHeading},
+	"/orientation/heading-magnetic-deg":                 {Field: fieldHeadingMagnetic},
+	"/orientation/track-deg":                            {Field: fieldTrack},
+	"/orientation/alpha-deg":                            {Field: fieldAngleOfAttack},
+	"/velocities/airspeed-kt":                           {Field: fieldAirspeed},
+	"/instrumentation/airspeed-indicator/true-speed-kt": {Field: fieldAirspeedTrue},
+	"/velocities/groundspeed-kt":                        {Field: fieldGroundSpeed},
+	"/velocities/vertical-speed-fps":                    {Field: fieldVerticalSpeed, Convert: feetPerSecondToMinute},
+	"/accelerations/pilot-g":                            {Field: fieldGForce},
+	"/gear/gear[1]/wow":                                 {Field: fieldLeftGearOnGround},
+	"/gear/gear[2]/wow":                                 {Field: fieldRightGearOnGround},
+	"/controls/gear/brake-parking":                      {Field: fieldParkingBrake},
+	"/engines/engine[0]/running":                        {Field: fieldEngineRunning},
+	"/sim/crashed":                                      {Field: fieldCrashed},
+	"/sim/freeze/master":                                {Field: fieldPaused},
+	"/sim/speed-up":                                     {Field: fieldSimSpeed},
+	"/sim/time/utc/year":                                {Field: fieldUTCYear},
+	"/sim/time/utc/month":                               {Field: fieldUTCMonth},
+	"/sim/time/utc/day":                                 {Field: fieldUTCDay},
+	"/sim/time/utc/day-seconds":                         {Field: fieldZuluTime},
+	"/sim/time/local-day-seconds":                       {Field: fieldLocalTime},
+	"/sim/time/elapsed-sec":                             {Field: fieldElapsedTime},
+	"/sim/time/sun-angle-rad":                           {Field: fieldSunPitch, Convert: sunPitch},
+	"/environment/pressure-sea-level-inhg":              {Field: fieldSeaLevelPressure},
+	"/environment/temperature-degc":                     {Field: fieldTemperature},
+	"/environment/wind-from-heading-deg":                {Field: fieldWindDirection},
+	"/environment/wind-speed-kt":                        {Field: fieldWindSpeed},
+	"/environment/visibility-m":                         {Field: fieldVisibility},
+}
+
+func feetPerSecondToMinute(v float64) float64 {
+	return v * 60
+}
+
+// sunPitch converts the angle between the sun and the zenith in radians to
+// the elevation of the sun in degrees
+func sunPitch(v float64) float64 {
+	return 90 - v*180/math.Pi
+}
@@ -0,0 +1,285 @@
+// Package flightgearsource reads the user aircraft from FlightGear's generic
+// protocol over UDP and publishes it on a telemetry bus, so recording,
+// exports and analysis work unchanged. The protocol
+// XML definition shipped with the recorder is also read by FlightGear.
+package flightgearsource
+
+import (
+	_ "embed"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ProtocolName is the name FlightGear knows the shipped protocol by, i.e.
+// the file name in its Protocol directory without .xml
+const ProtocolName = "mcrwfdr"
+
+//go:embed mcrwfdr.xml
+var defaultProtocol []byte
+
+var (
+	ErrPacketSize = errors.New("FlightGear packet does not match the protocol")
+	ErrNoChunks   = errors.New("protocol has no output chunks")
+)
+
+// Chunk types of the generic protocol
+const (
+	typeInt    = "int"
+	typeBool   = "bool"
+	typeFloat  = "float"
+	typeDouble = "double"
+	typeFixed  = "fixed" // binary only, 16.16 fixed point
+	typeString = "string"
+)
+
+// Chunk is one value of a record, sent by FlightGear from the property node
+// as value * factor + offset
+type Chunk struct {
+	Name   string  `xml:"name"`
+	Type   string  `xml:"type"`
+	Node   string  `xml:"node"`
+	Factor float64 `xml:"factor"`
+	Offset float64 `xml:"offset"`
+}
+
+// Protocol is the output section of a generic protocol definition
+type Protocol struct {
+	BinaryMode    bool    `xml:"binary_mode"`
+	BinaryFooter  string  `xml:"binary_footer"`
+	LineSeparator string  `xml:"line_separator"`
+	VarSeparator  string  `xml:"var_separator"`
+	Chunks        []Chunk `xml:"chunk"`
+}
+
+// definition is the XML document of a generic protocol
+type definition struct {
+	Output Protocol `xml:"generic>output"`
+}
+
+// Value is a chunk of a received record. Numbers are converted back to the
+// property value; bools are 0 or 1.
+type Value struct {
+	Node   string
+	Number float64
+	Text   string
+}
+
+// DefaultPath returns the default protocol file in the user config dir
+func DefaultPath() string {
+	base, err := os.UserConfigDir()
+	if err != nil {
+		base = "."
+	}
+	return filepath.Join(base, "mcrwfdr", ProtocolName+".xml")
+}
+
+// DefaultProtocol returns the shipped protocol
+func DefaultProtocol() *Protocol {
+	p, err := ParseProtocol(defaultProtocol)
+	if err != nil {
+		panic("invalid built-in FlightGear protocol: " + err.Error())
+	}
+	return p
+}
+
+// LoadProtocol reads a protocol file. A missing file is created with the
+// shipped protocol, ready to be copied to FlightGear.
+func LoadProtocol(path string) (*Protocol, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, fmt.Errorf("failed to create protocol directory: %w", err)
+		}
+		if err := os.WriteFile(path, defaultProtocol, 0o644); err != nil {
+			return nil, fmt.Errorf("failed to write default protocol: %w", err)
+		}
+		return DefaultProtocol(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read protocol: %w", err)
+	}
+	return ParseProtocol(data)
+}
+
+// ParseProtocol parses and validates a generic protocol definition
+func ParseProtocol(data []byte) (*Protocol, error) {
+	var def definition
+	if err := xml.Unmarshal(data, &def); err != nil {
+		return nil, fmt.Errorf("invalid protocol: %w", err)
+	}
+	p := &def.Output
+	if len(p.Chunks) == 0 {
+		return nil, ErrNoChunks
+	}
+	p.LineSeparator = separator(p.LineSeparator)
+	p.VarSeparator = separator(p.VarSeparator)
+	for i := range p.Chunks {
+		c := &p.Chunks[i]
+		c.Type = strings.TrimSpace(c.Type)
+		c.Node = strings.TrimSpace(c.Node)
+		if c.Type == "" {
+			c.Type = typeInt
+		}
+		switch c.Type {
+		case typeInt, typeBool, typeFloat, typeDouble:
+		case typeFixed:
+			if !p.BinaryMode {
+				return nil, fmt.Errorf("chunk %q: fixed is only sent in binary mode", c.Name)
+			}
+		case typeString:
+			if p.BinaryMode {
+				return nil, fmt.Errorf("chunk %q: strings are not sent in binary mode", c.Name)
+			}
+		default:
+			return nil, fmt.Errorf("chunk %q: unknown type %q", c.Name, c.Type)
+		}
+		if c.Factor == 0 {
+			c.Factor = 1
+		}
+	}
+	if !p.BinaryMode && p.VarSeparator == "" {
+		return nil, errors.New("protocol has no var_separator")
+	}
+	return p, nil
+}
+
+// separator resolves the separator names of the generic protocol
+func separator(s string) string {
+	switch strings.TrimSpace(s) {
+	case "newline":
+		return "\n"
+	case "carriagereturn":
+		return "\r"
+	case "tab":
+		return "\t"
+	case "space":
+		return " "
+	case "formfeed":
+		return "\f"
+	case "verticaltab":
+		return "\v"
+	case "":
+		// Whitespace only, e.g. a literal space
+		return s
+	}
+	return strings.TrimSpace(s)
+}
+
+// Decode returns the values of every record of a packet, in order
+func (p *Protocol) Decode(packet []byte) ([]Value, error) {
+	if p.BinaryMode {
+		return p.decodeBinary(packet)
+	}
+	var values []Value
+	lines := []string{string(packet)}
+	if p.LineSeparator != "" {
+		lines = strings.Split(string(packet), p.LineSeparator)
+	}
+	for _, line := range lines {
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		fields := strings.Split(line, p.VarSeparator)
+		if len(fields) != len(p.Chunks) {
+			return nil, fmt.Errorf("%w: %d values, %d chunks", ErrPacketSize, len(fields), len(p.Chunks))
+		}
+		for i, c := range p.Chunks {
+			v, err := c.parse(strings.TrimSpace(fields[i]))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+	}
+	return values, nil
+}
+
+func (c Chunk) parse(s string) (Value, error) {
+	v := Value{Node: c.Node}
+	switch c.Type {
+	case typeString:
+		v.Text = s
+		return v, nil
+	case typeBool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return v, fmt.Errorf("chunk %q: invalid bool %q", c.Name, s)
+		}
+		if b {
+			v.Number = 1
+		}
+		return v, nil
+	}
+	n, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return v, fmt.Errorf("chunk %q: invalid number %q", c.Name, s)
+	}
+	v.Number = c.value(n)
+	return v, nil
+}
+
+// value converts a sent number back to the property value
+func (c Chunk) value(n float64) float64 {
+	return (n - c.Offset) / c.Factor
+}
+
+// recordSize returns the size of a binary record without the footer
+func (p *Protocol) recordSize() int {
+	size := 0
+	for _, c := range p.Chunks {
+		switch c.Type {
+		case typeBool:
+			size++
+		case typeDouble:
+			size += 8
+		default:
+			size += 4
+		}
+	}
+	return size
+}
+
+// decodeBinary reads a record in network byte order, followed by a 4 byte
+// length or magic footer when the protocol has one
+func (p *Protocol) decodeBinary(packet []byte) ([]Value, error) {
+	size := p.recordSize()
+	if footer := strings.TrimSpace(p.BinaryFooter); footer != "" && footer != "none" {
+		size += 4
+	}
+	if len(packet) != size {
+		return nil, fmt.Errorf("%w: %d bytes, %d expected", ErrPacketSize, len(packet), size)
+	}
+	values := make([]Value, 0, len(p.Chunks))
+	for _, c := range p.Chunks {
+		var n float64
+		switch c.Type {
+		case typeBool:
+			n = float64(packet[0])
+			packet = packet[1:]
+			values = append(values, Value{Node: c.Node, Number: math.Min(n, 1)})
+			continue
+		case typeDouble:
+			n = math.Float64frombits(binary.BigEndian.Uint64(packet))
+			packet = packet[8:]
+		case typeFloat:
+			n = float64(math.Float32frombits(binary.BigEndian.Uint32(packet)))
+			packet = packet[4:]
+		case typeFixed:
+			n = float64(int32(binary.BigEndian.Uint32(packet))) / 65536
+			packet = packet[4:]
+		default:
+			n = float64(int32(binary.BigEndian.Uint32(packet)))
+			packet = packet[4:]
+		}
+		values = append(values, Value{Node: c.Node, Number: c.value(n)})
+	}
+	return values, nil
+}
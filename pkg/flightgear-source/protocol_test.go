@@ -0,0 +1,174 @@
+package flightgearsource
+
+import (
+	"encoding/hex"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// readPacket reads a hex encoded packet, skipping comment lines
+func readPacket(t *testing.T, name string) []byte {
+	t.Helper()
+	data, err := os.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		packet, err := hex.DecodeString(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return packet
+	}
+	t.Fatalf("no packet in %s", name)
+	return nil
+}
+
+func loadProtocol(t *testing.T, name string) *Protocol {
+	t.Helper()
+	p, err := LoadProtocol(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestDefaultProtocol(t *testing.T) {
+	p, err := ParseProtocol(defaultProtocol)
+	if err != nil {
+		t.Fatalf("shipped protocol: %v", err)
+	}
+	if p.BinaryMode || p.LineSeparator != "\n" || p.VarSeparator != "," {
+		t.Errorf("binary %v, line separator %q, var separator %q, want text, \"\\n\" and \",\"", p.BinaryMode, p.LineSeparator, p.VarSeparator)
+	}
+	// Every node of the shipped protocol must be understood by the decoder
+	nodes := map[string]bool{}
+	for _, c := range p.Chunks {
+		if _, ok := properties[c.Node]; !ok && c.Node != aircraftNode {
+			t.Errorf("chunk %q: node %s is not decoded", c.Name, c.Node)
+		}
+		if nodes[c.Node] {
+			t.Errorf("node %s is sent twice", c.Node)
+		}
+		nodes[c.Node] = true
+	}
+	if got, want := len(p.Chunks), len(properties)+1; got != want {
+		t.Errorf("%d chunks, want %d, one for every known node", got, want)
+	}
+}
+
+func TestLoadProtocolCreatesDefault(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "Protocol", ProtocolName+".xml")
+	p := loadProtocol(t, path)
+	if len(p.Chunks) != len(DefaultProtocol().Chunks) {
+		t.Errorf("%d chunks, want the shipped protocol", len(p.Chunks))
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("protocol not written: %v", err)
+	}
+	if string(data) != string(defaultProtocol) {
+		t.Error("written protocol differs from the shipped one")
+	}
+}
+
+func TestParseProtocolErrors(t *testing.T) {
+	const head = "<PropertyList><generic><output>"
+	const tail = "</output></generic></PropertyList>"
+	for _, c := range []struct {
+		name, xml string
+	}{
+		{"invalid XML", "<PropertyList>"},
+		{"no chunks", head + "<var_separator>,</var_separator>" + tail},
+		{"no separator", head + "<chunk><node>/a</node></chunk>" + tail},
+		{"unknown type", head + "<var_separator>,</var_separator><chunk><type>long</type></chunk>" + tail},
+		{"fixed in text mode", head + "<var_separator>,</var_separator><chunk><type>fixed</type></chunk>" + tail},
+		{"string in binary mode", head + "<binary_mode>true</binary_mode><chunk><type>string</type></chunk>" + tail},
+	} {
+		if _, err := ParseProtocol([]byte(c.xml)); err == nil {
+			t.Errorf("%s: no error", c.name)
+		}
+	}
+}
+
+func TestDecodeText(t *testing.T) {
+	p, err := ParseProtocol([]byte(`<PropertyList><generic><output>
+		<line_separator>newline</line_separator>
+		<var_separator>tab</var_separator>
+		<chunk><node>/a</node></chunk>
+		<chunk><type>float</type><node>/b</node><factor>2</factor><offset>1</offset></chunk>
+		<chunk><type>bool</type><node>/c</node></chunk>
+		<chunk><type>string</type><node>/d</node></chunk>
+	</output></generic></PropertyList>`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two records in one packet, as sent when FlightGear catches up
+	values, err := p.Decode([]byte("1\t5.0\ttrue\tc172p\n2\t7\t0\tc172p\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Value{
+		{Node: "/a", Number: 1}, {Node: "/b", Number: 2}, {Node: "/c", Number: 1}, {Node: "/d", Text: "c172p"},
+		{Node: "/a", Number: 2}, {Node: "/b", Number: 3}, {Node: "/c", Number: 0}, {Node: "/d", Text: "c172p"},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("%d values, want %d", len(values), len(want))
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("value %d = %+v, want %+v", i, values[i], want[i])
+		}
+	}
+
+	if _, err := p.Decode([]byte("1\t2\n")); !errors.Is(err, ErrPacketSize) {
+		t.Errorf("short record: error %v, want %v", err, ErrPacketSize)
+	}
+	if _, err := p.Decode([]byte("x\t2\t0\ta\n")); err == nil {
+		t.Error("invalid number: no error")
+	}
+}
+
+func TestDecodeBinary(t *testing.T) {
+	p := loadProtocol(t, "testdata/binary.xml")
+	packet := readPacket(t, "testdata/binary.hex")
+	values, err := p.Decode(packet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Value{
+		{Node: "/position/latitude-deg", Number: -33.9461},
+		{Node: "/position/longitude-deg", Number: 151.1772},
+		{Node: "/position/altitude-ft", Number: 304.8},
+		{Node: "/orientation/pitch-deg", Number: -1.5},
+		{Node: "/orientation/heading-deg", Number: 90},
+		{Node: "/gear/gear[1]/wow", Number: 1},
+		{Node: "/sim/multiplay/generic/int[0]", Number: 7},
+	}
+	if len(values) != len(want) {
+		t.Fatalf("%d values, want %d", len(values), len(want))
+	}
+	for i, w := range want {
+		v := values[i]
+		// The altitude is sent as a float
+		if v.Node != w.Node || math.Abs(v.Number-w.Number) > 1e-4 {
+			t.Errorf("value %d = %+v, want %+v", i, v, w)
+		}
+	}
+
+	for _, size := range []int{0, len(packet) - 1, len(packet) + 1} {
+		short := make([]byte, size)
+		copy(short, packet)
+		if _, err := p.Decode(short); !errors.Is(err, ErrPacketSize) {
+			t.Errorf("%d bytes: error %v, want %v", size, err, ErrPacketSize)
+		}
+	}
+}
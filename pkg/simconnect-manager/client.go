@@ -0,0 +1,52 @@
+package simconnectmanager
+
+// SimClient is the subset of the SimConnect client used by the manager.
+// It is implemented by the SimConnect DLL on Windows, see
+// DefaultClientFactory, and by FakeClient for tests on every platform.
+type SimClient interface {
+	Connect() error
+	Disconnect() error
+	// Stream returns the received messages. It is closed by Disconnect.
+	Stream() <-chan ClientMessage
+
+	AddToDataDefinition(defineID int, datumName string, unitsName string, datumType DataType, epsilon float32, datumID int) error
+	RequestDataOnSimObject(request int, definition int, object int, period Period, flags DataRequestFlag, origin int, interval int, limit int) error
+	// SetDataOnSimObject sets a data definition encoded by Definition.Encode
+	SetDataOnSimObject(definition int, object int, flags DataSetFlag, data []byte) error
+
+	SubscribeToSystemEvent(id int, event string) error
+	RequestSystemStateAircraftLoaded(requestID uint32) error
+	RequestSystemStateFlightLoaded(requestID uint32) error
+	RequestSystemStateFlightPlan(requestID uint32) error
+	RequestSystemStateSim(requestID uint32) error
+
+	MapClientEventToSimEvent(id int, event string) error
+	AddClientEventToNotificationGroup(group int, event int) error
+	SetNotificationGroupPriority(group int, priority int) error
+	TransmitClientEvent(object int, event int, data int, group int) error
+}
+
+// ClientMessage is a SIMCONNECT_RECV_* message received from SimConnect.
+// Raw holds the whole message including the SIMCONNECT_RECV header.
+type ClientMessage struct {
+	ID    RecvID
+	Raw   []byte
+	Error error
+}
+
+// IsOpen checks if the message is a connection open confirmation
+func (m ClientMessage) IsOpen() bool {
+	return m.ID == RecvIDOpen
+}
+
+// IsQuit checks if the message is a connection quit notification
+func (m ClientMessage) IsQuit() bool {
+	return m.ID == RecvIDQuit
+}
+
+// ClientFactory creates a new, not yet connected SimConnect client.
+// It returns nil when the client cannot be created.
+type ClientFactory func(name string) SimClient
+
+// streamBufferSize matches the message queue of the SimConnect client
+const streamBufferSize = 100
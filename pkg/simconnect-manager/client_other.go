@@ -0,0 +1,9 @@
+//go:build !windows
+
+package simconnectmanager
+
+// DefaultClientFactory returns nil, SimConnect is only available on Windows.
+// The manager stays offline unless another factory is set.
+func DefaultClientFactory(name string) SimClient {
+	return nil
+}
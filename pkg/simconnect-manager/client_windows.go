@@ -0,0 +1,76 @@
+package simconnectmanager
+
+import (
+	"errors"
+	"runtime"
+	"sync"
+	"unsafe"
+
+	"github.com/mrlm-net/simconnect/pkg/client"
+	"github.com/mrlm-net/simconnect/pkg/types"
+)
+
+// engineClient adapts *client.Engine, the client backed by the SimConnect
+// DLL, to SimClient
+type engineClient struct {
+	*client.Engine
+	// done is closed by Disconnect and ends the forwarding of the stream
+	done chan struct{}
+	once sync.Once
+}
+
+var _ SimClient = (*engineClient)(nil)
+
+// DefaultClientFactory creates clients backed by the SimConnect DLL
+func DefaultClientFactory(name string) SimClient {
+	c := client.New(name)
+	if c == nil {
+		// Avoid returning a typed nil wrapped in a non-nil interface
+		return nil
+	}
+	return &engineClient{Engine: c, done: make(chan struct{})}
+}
+
+// Disconnect closes the connection and stops forwarding the stream
+func (e *engineClient) Disconnect() error {
+	e.once.Do(func() { close(e.done) })
+	return e.Engine.Disconnect()
+}
+
+// Stream forwards the messages of the engine until Disconnect. The engine
+// queue bounds the messages waiting for the manager, forwarding blocks
+// rather than adding a second queue that drops messages.
+func (e *engineClient) Stream() <-chan ClientMessage {
+	in := e.Engine.Stream()
+	out := make(chan ClientMessage)
+	go func() {
+		defer close(out)
+		for msg := range in {
+			select {
+			case out <- ClientMessage{ID: RecvID(msg.MessageType), Raw: msg.RawData, Error: msg.Error}:
+			case <-e.done:
+				return
+			}
+		}
+	}()
+	return out
+}
+
+func (e *engineClient) AddToDataDefinition(defineID int, datumName string, unitsName string, datumType DataType, epsilon float32, datumID int) error {
+	return e.Engine.AddToDataDefinition(defineID, datumName, unitsName, types.SIMCONNECT_DATATYPE(datumType), epsilon, datumID)
+}
+
+func (e *engineClient) RequestDataOnSimObject(request int, definition int, object int, period Period, flags DataRequestFlag, origin int, interval int, limit int) error {
+	return e.Engine.RequestDataOnSimObject(request, definition, object, types.SIMCONNECT_PERIOD(period), types.SIMCONNECT_DATA_REQUEST_FLAG(flags), origin, interval, limit)
+}
+
+// SetDataOnSimObject passes data to SimConnect, which copies it before the
+// call returns
+func (e *engineClient) SetDataOnSimObject(definition int, object int, flags DataSetFlag, data []byte) error {
+	if len(data) == 0 {
+		return errors.New("no data to set")
+	}
+	err := e.Engine.SetDataOnSimObject(definition, object, types.SIMCONNECT_DATA_SET_FLAG(flags), 0, len(data), uintptr(unsafe.Pointer(&data[0])))
+	runtime.KeepAlive(data)
+	return err
+}
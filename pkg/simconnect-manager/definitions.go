@@ -0,0 +1,325 @@
+package simconnectmanager
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var ErrPayloadSize = errors.New("simobject data size does not match definition")
+
+// Conversion transforms a numeric simvar between SimConnect units and the
+// units exposed by the state structs
+type Conversion struct {
+	Decode func(float64) float64 // SimConnect value -> state value
+	Encode func(float64) float64 // state value -> SimConnect value, nil when lossy
+}
+
+var (
+	// RadiansToDegrees converts angles requested in radians to degrees
+	RadiansToDegrees = &Conversion{
+		Decode: func(v float64) float64 { return v * 180.0 / math.Pi },
+		Encode: func(v float64) float64 { return v * math.Pi / 180.0 },
+	}
+	// RoundVerticalSpeed drops jitter below 0.1 fpm and rounds to 2 decimal places
+	RoundVerticalSpeed = &Conversion{Decode: telemetry.RoundVerticalSpeed}
+)
+
+// Datum declares one simvar of a data definition
+type Datum struct {
+	Name       string      // Simvar name, e.g. "PLANE LATITUDE"
+	Unit       string      // SimConnect unit, e.g. "radians"
+	Type       DataType    // Datatype requested from SimConnect
+	Field      string      // Target field of the state struct
+	Conversion *Conversion // Optional numeric conversion
+}
+
+// Definition declares a SimConnect data definition and the state struct it
+// is decoded into. Datum order is the order SimConnect packs the payload.
+type Definition struct {
+	ID     int
+	Name   string
+	Target reflect.Type
+	Data   []Datum
+}
+
+// datatypeSizes are the payload sizes of the supported SimConnect datatypes
+var datatypeSizes = map[DataType]int{
+	DataTypeInt32:     4,
+	DataTypeInt64:     8,
+	DataTypeFloat32:   4,
+	DataTypeFloat64:   8,
+	DataTypeString8:   8,
+	DataTypeString32:  32,
+	DataTypeString64:  64,
+	DataTypeString128: 128,
+	DataTypeString256: 256,
+	DataTypeString260: 260,
+}
+
+const (
+	AirplaneDefineID    = 1
+	EnvironmentDefineID = 2
+	SimulatorDefineID   = 3
+	PlaybackDefineID    = 4
+	TouchdownDefineID   = 5
+)
+
+// AirplaneDefinition is decoded into AirplaneState
+var AirplaneDefinition = mustDefinition(AirplaneDefineID, "airplane", telemetry.AirplaneState{}, []Datum{
+	{Name: "TITLE", Unit: "", Type: DataTypeString256, Field: "Title"},
+	{Name: "PLANE LATITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Latitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE LONGITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Longitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE ALTITUDE", Unit: "feet", Type: DataTypeFloat64, Field: "Altitude"},
+	{Name: "PLANE HEADING DEGREES TRUE", Unit: "radians", Type: DataTypeFloat64, Field: "Heading", Conversion: RadiansToDegrees},
+	{Name: "PLANE HEADING DEGREES MAGNETIC", Unit: "radians", Type: DataTypeFloat64, Field: "HeadingMagnetic", Conversion: RadiansToDegrees},
+	{Name: "AIRSPEED INDICATED", Unit: "knots", Type: DataTypeFloat64, Field: "Airspeed"},
+	{Name: "PLANE BANK DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Bank"},
+	{Name: "PLANE ALT ABOVE GROUND", Unit: "feet", Type: DataTypeFloat64, Field: "AltAboveGround"},
+	{Name: "PLANE PITCH DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Pitch"},
+	{Name: "VERTICAL SPEED", Unit: "feet per minute", Type: DataTypeFloat64, Field: "VerticalSpeed", Conversion: RoundVerticalSpeed},
+	{Name: "GROUND VELOCITY", Unit: "knots", Type: DataTypeFloat64, Field: "GroundVelocity"},
+	{Name: "GPS GROUND TRUE TRACK", Unit: "radians", Type: DataTypeFloat64, Field: "GroundTrack", Conversion: RadiansToDegrees},
+	{Name: "AIRSPEED TRUE", Unit: "knots", Type: DataTypeFloat64, Field: "AirspeedTrue"},
+	{Name: "ANGLE OF ATTACK INDICATOR", Unit: "degrees", Type: DataTypeFloat64, Field: "AngleOfAttack"},
+	{Name: "NAV HAS LOCALIZER:1", Unit: "bool", Type: DataTypeFloat64, Field: "NavHasLocalizer"},
+	{Name: "NAV LOCALIZER:1", Unit: "degrees", Type: DataTypeFloat64, Field: "NavLocalizerCourse"},
+	{Name: "NAV RADIAL ERROR:1", Unit: "degrees", Type: DataTypeFloat64, Field: "NavRadialError"},
+	{Name: "NAV HAS GLIDE SLOPE:1", Unit: "bool", Type: DataTypeFloat64, Field: "NavHasGlideSlope"},
+	{Name: "NAV GLIDE SLOPE ERROR:1", Unit: "degrees", Type: DataTypeFloat64, Field: "NavGlideSlopeError"},
+})
+
+// EnvironmentDefinition is decoded into EnvironmentState
+var EnvironmentDefinition = mustDefinition(EnvironmentDefineID, "environment", telemetry.EnvironmentState{}, []Datum{
+	{Name: "ZULU TIME", Unit: "seconds", Type: DataTypeInt32, Field: "ZuluTime"},
+	{Name: "LOCAL TIME", Unit: "seconds", Type: DataTypeInt32, Field: "LocalTime"},
+	{Name: "SIMULATION TIME", Unit: "seconds", Type: DataTypeInt32, Field: "SimTime"},
+	{Name: "ZULU DAY OF MONTH", Unit: "number", Type: DataTypeInt32, Field: "ZuluDay"},
+	{Name: "ZULU MONTH OF YEAR", Unit: "number", Type: DataTypeInt32, Field: "ZuluMonth"},
+	{Name: "ZULU YEAR", Unit: "number", Type: DataTypeInt32, Field: "ZuluYear"},
+	{Name: "LOCAL DAY OF MONTH", Unit: "number", Type: DataTypeInt32, Field: "LocalDay"},
+	{Name: "LOCAL MONTH OF YEAR", Unit: "number", Type: DataTypeInt32, Field: "LocalMonth"},
+	{Name: "LOCAL YEAR", Unit: "number", Type: DataTypeInt32, Field: "LocalYear"},
+	{Name: "ZULU DAY OF WEEK", Unit: "number", Type: DataTypeInt32, Field: "ZuluDayOfWeek"},
+	{Name: "LOCAL DAY OF WEEK", Unit: "number", Type: DataTypeInt32, Field: "LocalDayOfWeek"},
+	// Weather variables
+	{Name: "SEA LEVEL PRESSURE", Unit: "inHg", Type: DataTypeFloat64, Field: "SeaLevelPressure"},
+	{Name: "AMBIENT TEMPERATURE", Unit: "celsius", Type: DataTypeFloat64, Field: "AmbientTemperature"},
+	{Name: "AMBIENT WIND DIRECTION", Unit: "degrees", Type: DataTypeFloat64, Field: "AmbientWindDirection"},
+	{Name: "AMBIENT WIND VELOCITY", Unit: "knots", Type: DataTypeFloat64, Field: "AmbientWindVelocity"},
+	{Name: "AMBIENT VISIBILITY", Unit: "meters", Type: DataTypeFloat64, Field: "AmbientVisibility"},
+	{Name: "TIME ZONE OFFSET", Unit: "seconds", Type: DataTypeInt32, Field: "TimeZoneOffset"},
+	{Name: "ZULU SUNRISE TIME", Unit: "seconds", Type: DataTypeInt32, Field: "ZuluSunriseTime"},
+	{Name: "ZULU SUNSET TIME", Unit: "seconds", Type: DataTypeInt32, Field: "ZuluSunsetTime"},
+	{Name: "TIME OF DAY", Unit: "enum", Type: DataTypeInt32, Field: "TimeOfDay"},
+})
+
+// SimulatorDefinition is decoded into SimulatorState. Pause, Crashed, View and
+// the loaded files come from system events and system state requests instead.
+var SimulatorDefinition = mustDefinition(SimulatorDefineID, "simulator", telemetry.SimulatorState{}, []Datum{
+	{Name: "SIMULATION RATE", Unit: "", Type: DataTypeFloat64, Field: "SimulationRate"},
+	{Name: "REALISM", Unit: "", Type: DataTypeInt32, Field: "Realism"},
+	{Name: "SURFACE CONDITION", Unit: "", Type: DataTypeInt32, Field: "SurfaceCondition"},
+	{Name: "SURFACE INFO VALID", Unit: "bool", Type: DataTypeInt32, Field: "SurfaceInfoValid"},
+	{Name: "SURFACE TYPE", Unit: "", Type: DataTypeInt32, Field: "SurfaceType"},
+	{Name: "ON ANY RUNWAY", Unit: "", Type: DataTypeInt32, Field: "OnAnyRunway"},
+	{Name: "PLANE IN PARKING STATE", Unit: "", Type: DataTypeInt32, Field: "InParkingState"},
+	{Name: "SIM ON GROUND", Unit: "bool", Type: DataTypeFloat64, Field: "OnGround"},
+})
+
+// PlaybackDefinition is encoded from AirplaneState to move the user aircraft
+// during playback. It is only used with SetDataOnSimObject.
+var PlaybackDefinition = mustDefinition(PlaybackDefineID, "playback", telemetry.AirplaneState{}, []Datum{
+	{Name: "PLANE LATITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Latitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE LONGITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Longitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE ALTITUDE", Unit: "feet", Type: DataTypeFloat64, Field: "Altitude"},
+	{Name: "PLANE PITCH DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Pitch"},
+	{Name: "PLANE BANK DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Bank"},
+	{Name: "PLANE HEADING DEGREES TRUE", Unit: "radians", Type: DataTypeFloat64, Field: "Heading", Conversion: RadiansToDegrees},
+	{Name: "AIRSPEED TRUE", Unit: "knots", Type: DataTypeFloat64, Field: "AirspeedTrue"},
+	{Name: "VERTICAL SPEED", Unit: "feet per minute", Type: DataTypeFloat64, Field: "VerticalSpeed"},
+})
+
+// TouchdownDefinition is decoded into TouchdownState. It is only requested
+// every simulation frame around a landing, see RequestTouchdownData.
+var TouchdownDefinition = mustDefinition(TouchdownDefineID, "touchdown", telemetry.TouchdownState{}, []Datum{
+	{Name: "PLANE LATITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Latitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE LONGITUDE", Unit: "radians", Type: DataTypeFloat64, Field: "Longitude", Conversion: RadiansToDegrees},
+	{Name: "PLANE ALT ABOVE GROUND", Unit: "feet", Type: DataTypeFloat64, Field: "AltAboveGround"},
+	{Name: "VERTICAL SPEED", Unit: "feet per minute", Type: DataTypeFloat64, Field: "VerticalSpeed"},
+	{Name: "G FORCE", Unit: "gforce", Type: DataTypeFloat64, Field: "GForce"},
+	{Name: "PLANE PITCH DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Pitch"},
+	{Name: "PLANE BANK DEGREES", Unit: "degrees", Type: DataTypeFloat64, Field: "Bank"},
+	{Name: "PLANE HEADING DEGREES TRUE", Unit: "radians", Type: DataTypeFloat64, Field: "Heading", Conversion: RadiansToDegrees},
+	{Name: "GROUND VELOCITY", Unit: "knots", Type: DataTypeFloat64, Field: "GroundVelocity"},
+	{Name: "AIRSPEED INDICATED", Unit: "knots", Type: DataTypeFloat64, Field: "Airspeed"},
+	{Name: "SIM ON GROUND", Unit: "bool", Type: DataTypeFloat64, Field: "OnGround"},
+})
+
+// registry holds every definition by its ID
+var registry = map[int]Definition{
+	AirplaneDefineID:    AirplaneDefinition,
+	EnvironmentDefineID: EnvironmentDefinition,
+	SimulatorDefineID:   SimulatorDefinition,
+	PlaybackDefineID:    PlaybackDefinition,
+	TouchdownDefineID:   TouchdownDefinition,
+}
+
+// DefinitionByID returns the definition registered with id
+func DefinitionByID(id int) (Definition, bool) {
+	d, ok := registry[id]
+	return d, ok
+}
+
+// mustDefinition validates a definition against its target struct. Invalid
+// definitions are programming errors and panic at startup.
+func mustDefinition(id int, name string, target any, data []Datum) Definition {
+	d := Definition{ID: id, Name: name, Target: reflect.TypeOf(target), Data: data}
+	for _, datum := range data {
+		if _, ok := datatypeSizes[datum.Type]; !ok {
+			panic(fmt.Sprintf("definition %s: %s has unsupported datatype %d", name, datum.Name, datum.Type))
+		}
+		f, ok := d.Target.FieldByName(datum.Field)
+		if !ok {
+			panic(fmt.Sprintf("definition %s: %s has no field %s", name, d.Target, datum.Field))
+		}
+		if !fieldAccepts(f.Type.Kind(), datum.Type) {
+			panic(fmt.Sprintf("definition %s: field %s (%s) cannot hold %s", name, datum.Field, f.Type.Kind(), datum.Name))
+		}
+	}
+	return d
+}
+
+func isString(t DataType) bool {
+	return t >= DataTypeString8 && t <= DataTypeString260
+}
+
+func fieldAccepts(kind reflect.Kind, t DataType) bool {
+	if isString(t) {
+		return kind == reflect.String
+	}
+	switch kind {
+	case reflect.Int, reflect.Int32, reflect.Int64, reflect.Float64, reflect.Float32, reflect.Bool:
+		return true
+	}
+	return false
+}
+
+// Size returns the payload size in bytes SimConnect sends for the definition
+func (d Definition) Size() int {
+	size := 0
+	for _, datum := range d.Data {
+		size += datatypeSizes[datum.Type]
+	}
+	return size
+}
+
+// Register adds every datum of the definition to SimConnect
+func (d Definition) Register(c SimClient) error {
+	for i, datum := range d.Data {
+		if err := c.AddToDataDefinition(d.ID, datum.Name, datum.Unit, datum.Type, 0.0, i); err != nil {
+			return fmt.Errorf("failed to add %s to definition %s: %w", datum.Name, d.Name, err)
+		}
+	}
+	return nil
+}
+
+// Decode reads a SimConnect payload into dst, a pointer to the target state
+// struct. Only the declared fields are written.
+func (d Definition) Decode(payload []byte, dst any) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.Elem().Type() != d.Target {
+		return fmt.Errorf("definition %s decodes into *%s, got %T", d.Name, d.Target, dst)
+	}
+	if len(payload) < d.Size() {
+		return fmt.Errorf("%w: definition %s expects %d bytes, got %d", ErrPayloadSize, d.Name, d.Size(), len(payload))
+	}
+	target := v.Elem()
+	offset := 0
+	for _, datum := range d.Data {
+		size := datatypeSizes[datum.Type]
+		raw := payload[offset : offset+size]
+		offset += size
+		field := target.FieldByName(datum.Field)
+		if isString(datum.Type) {
+			field.SetString(bytesToString(raw))
+			continue
+		}
+		var num float64
+		switch datum.Type {
+		case DataTypeInt32:
+			num = float64(int32(binary.LittleEndian.Uint32(raw)))
+		case DataTypeInt64:
+			num = float64(int64(binary.LittleEndian.Uint64(raw)))
+		case DataTypeFloat32:
+			num = float64(math.Float32frombits(binary.LittleEndian.Uint32(raw)))
+		case DataTypeFloat64:
+			num = math.Float64frombits(binary.LittleEndian.Uint64(raw))
+		}
+		if datum.Conversion != nil && datum.Conversion.Decode != nil {
+			num = datum.Conversion.Decode(num)
+		}
+		setNumber(field, num)
+	}
+	return nil
+}
+
+// Encode builds the payload SimConnect would send for src, the target state
+// struct. It is the inverse of Decode for lossless conversions.
+func (d Definition) Encode(src any) []byte {
+	v := reflect.Indirect(reflect.ValueOf(src))
+	b := make([]byte, 0, d.Size())
+	for _, datum := range d.Data {
+		field := v.FieldByName(datum.Field)
+		if isString(datum.Type) {
+			raw := make([]byte, datatypeSizes[datum.Type])
+			copy(raw[:len(raw)-1], field.String())
+			b = append(b, raw...)
+			continue
+		}
+		num := getNumber(field)
+		if datum.Conversion != nil && datum.Conversion.Encode != nil {
+			num = datum.Conversion.Encode(num)
+		}
+		switch datum.Type {
+		case DataTypeInt32:
+			b = binary.LittleEndian.AppendUint32(b, uint32(int32(num)))
+		case DataTypeInt64:
+			b = binary.LittleEndian.AppendUint64(b, uint64(int64(num)))
+		case DataTypeFloat32:
+			b = binary.LittleEndian.AppendUint32(b, math.Float32bits(float32(num)))
+		case DataTypeFloat64:
+			b = binary.LittleEndian.AppendUint64(b, math.Float64bits(num))
+		}
+	}
+	return b
+}
+
+func setNumber(field reflect.Value, num float64) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		field.SetInt(int64(num))
+	case reflect.Float32, reflect.Float64:
+		field.SetFloat(num)
+	case reflect.Bool:
+		field.SetBool(num > 0.5)
+	}
+}
+
+func getNumber(field reflect.Value) float64 {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		return float64(field.Int())
+	case reflect.Float32, reflect.Float64:
+		return field.Float()
+	case reflect.Bool:
+		if field.Bool() {
+			return 1
+		}
+	}
+	return 0
+}
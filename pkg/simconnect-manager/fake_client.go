@@ -0,0 +1,368 @@
+package simconnectmanager
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"sync"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+var ErrFakeClosed = errors.New("fake SimConnect client is closed")
+
+// FakeDatum records an AddToDataDefinition call
+type FakeDatum struct {
+	Name     string
+	Unit     string
+	DataType DataType
+	DatumID  int
+}
+
+// FakeRequest records a RequestDataOnSimObject call
+type FakeRequest struct {
+	RequestID int
+	DefineID  int
+	ObjectID  int
+	Period    Period
+	Flags     DataRequestFlag
+	Origin    int
+	Interval  int
+	Limit     int
+}
+
+// FakeSetData records a SetDataOnSimObject call with a copy of the data
+type FakeSetData struct {
+	DefineID int
+	ObjectID int
+	Flags    DataSetFlag
+	Data     []byte
+	// State is Data decoded into the target struct of the registered
+	// definition, e.g. AirplaneState for PlaybackDefineID. It is nil for
+	// unknown definitions.
+	State any
+}
+
+// FakeTransmit records a TransmitClientEvent call
+type FakeTransmit struct {
+	ObjectID int
+	EventID  int
+	Data     int
+	GroupID  int
+}
+
+// FakeClient is an in-process SimClient that records the calls made by the
+// manager and emits scripted SIMCONNECT_RECV_* messages. Messages are laid
+// out exactly like SimConnect delivers them, so the manager decodes them
+// with the same code path.
+type FakeClient struct {
+	// ConnectErr is returned by Connect when set
+	ConnectErr error
+	// EventErr is returned by MapClientEventToSimEvent and
+	// TransmitClientEvent when set
+	EventErr error
+	// AutoRespond answers system state requests with the values below
+	AutoRespond    bool
+	Sim            uint32
+	AircraftLoaded string
+	FlightLoaded   string
+	FlightPlan     string
+
+	mu           sync.Mutex
+	queue        chan ClientMessage
+	connected    bool
+	closed       bool
+	definitions  map[int][]FakeDatum
+	requests     []FakeRequest
+	setData      []FakeSetData
+	systemEvents map[int]string
+	clientEvents map[int]string
+	transmitted  []FakeTransmit
+}
+
+// NewFakeClient returns a fake client answering system state requests for a
+// running simulator
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		AutoRespond:  true,
+		Sim:          1,
+		queue:        make(chan ClientMessage, streamBufferSize),
+		definitions:  make(map[int][]FakeDatum),
+		systemEvents: make(map[int]string),
+		clientEvents: make(map[int]string),
+	}
+}
+
+// Factory returns a ClientFactory handing out this client
+func (f *FakeClient) Factory() ClientFactory {
+	return func(string) SimClient { return f }
+}
+
+func (f *FakeClient) Connect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.ConnectErr != nil {
+		return f.ConnectErr
+	}
+	if f.closed {
+		return ErrFakeClosed
+	}
+	f.connected = true
+	return nil
+}
+
+func (f *FakeClient) Disconnect() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if !f.closed {
+		f.closed = true
+		f.connected = false
+		close(f.queue)
+	}
+	return nil
+}
+
+func (f *FakeClient) Stream() <-chan ClientMessage {
+	return f.queue
+}
+
+func (f *FakeClient) AddToDataDefinition(defineID int, datumName string, unitsName string, datumType DataType, epsilon float32, datumID int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.definitions[defineID] = append(f.definitions[defineID], FakeDatum{Name: datumName, Unit: unitsName, DataType: datumType, DatumID: datumID})
+	return nil
+}
+
+func (f *FakeClient) RequestDataOnSimObject(request int, definition int, object int, period Period, flags DataRequestFlag, origin int, interval int, limit int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, FakeRequest{
+		RequestID: request,
+		DefineID:  definition,
+		ObjectID:  object,
+		Period:    period,
+		Flags:     flags,
+		Origin:    origin,
+		Interval:  interval,
+		Limit:     limit,
+	})
+	return nil
+}
+
+func (f *FakeClient) SetDataOnSimObject(definition int, object int, flags DataSetFlag, data []byte) error {
+	call := FakeSetData{
+		DefineID: definition,
+		ObjectID: object,
+		Flags:    flags,
+		Data:     append([]byte(nil), data...),
+	}
+	if def, ok := DefinitionByID(definition); ok {
+		state := reflect.New(def.Target)
+		if err := def.Decode(data, state.Interface()); err != nil {
+			return err
+		}
+		call.State = state.Elem().Interface()
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrFakeClosed
+	}
+	f.setData = append(f.setData, call)
+	return nil
+}
+
+func (f *FakeClient) SubscribeToSystemEvent(id int, event string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.systemEvents[id] = event
+	return nil
+}
+
+func (f *FakeClient) RequestSystemStateAircraftLoaded(requestID uint32) error {
+	return f.respondSystemState(requestID, 0, f.AircraftLoaded)
+}
+
+func (f *FakeClient) RequestSystemStateFlightLoaded(requestID uint32) error {
+	return f.respondSystemState(requestID, 0, f.FlightLoaded)
+}
+
+func (f *FakeClient) RequestSystemStateFlightPlan(requestID uint32) error {
+	return f.respondSystemState(requestID, 0, f.FlightPlan)
+}
+
+func (f *FakeClient) RequestSystemStateSim(requestID uint32) error {
+	return f.respondSystemState(requestID, f.Sim, "")
+}
+
+func (f *FakeClient) MapClientEventToSimEvent(id int, event string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.EventErr != nil {
+		return f.EventErr
+	}
+	f.clientEvents[id] = event
+	return nil
+}
+
+func (f *FakeClient) AddClientEventToNotificationGroup(group int, event int) error {
+	return f.checkOpen()
+}
+
+func (f *FakeClient) SetNotificationGroupPriority(group int, priority int) error {
+	return f.checkOpen()
+}
+
+func (f *FakeClient) TransmitClientEvent(object int, event int, data int, group int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrFakeClosed
+	}
+	if f.EventErr != nil {
+		return f.EventErr
+	}
+	f.transmitted = append(f.transmitted, FakeTransmit{ObjectID: object, EventID: event, Data: data, GroupID: group})
+	return nil
+}
+
+// Connected reports whether Connect succeeded and Disconnect was not called
+func (f *FakeClient) Connected() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.connected
+}
+
+// Definitions returns the data definitions registered for defineID
+func (f *FakeClient) Definitions(defineID int) []FakeDatum {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeDatum(nil), f.definitions[defineID]...)
+}
+
+// Requests returns all data requests made so far
+func (f *FakeClient) Requests() []FakeRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeRequest(nil), f.requests...)
+}
+
+// SetData returns all SetDataOnSimObject calls made so far
+func (f *FakeClient) SetData() []FakeSetData {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeSetData(nil), f.setData...)
+}
+
+// ClientEvents returns the mapped client events keyed by client event ID
+func (f *FakeClient) ClientEvents() map[int]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[int]string, len(f.clientEvents))
+	for k, v := range f.clientEvents {
+		out[k] = v
+	}
+	return out
+}
+
+// SystemEvents returns the subscribed system events keyed by event ID
+func (f *FakeClient) SystemEvents() map[int]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	out := make(map[int]string, len(f.systemEvents))
+	for k, v := range f.systemEvents {
+		out[k] = v
+	}
+	return out
+}
+
+// Transmitted returns all client events transmitted so far
+func (f *FakeClient) Transmitted() []FakeTransmit {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]FakeTransmit(nil), f.transmitted...)
+}
+
+// SendOpen emits SIMCONNECT_RECV_ID_OPEN
+func (f *FakeClient) SendOpen() {
+	f.send(RecvIDOpen, nil)
+}
+
+// SendQuit emits SIMCONNECT_RECV_ID_QUIT
+func (f *FakeClient) SendQuit() {
+	f.send(RecvIDQuit, nil)
+}
+
+// SendEvent emits a subscribed system event, e.g. Pause with data 1
+func (f *FakeClient) SendEvent(eventID uint32, data uint32) {
+	f.send(RecvIDEvent, appendDwords(nil, 0, eventID, data))
+}
+
+// SendSystemState emits a response to a system state request
+func (f *FakeClient) SendSystemState(requestID uint32, integer uint32, str string) {
+	body := appendDwords(nil, requestID, integer, 0)
+	text := make([]byte, systemStateStringSize)
+	copy(text[:len(text)-1], str)
+	f.send(RecvIDSystemState, append(body, text...))
+}
+
+// SendSimObjectData emits simobject data for defineID with a raw payload
+func (f *FakeClient) SendSimObjectData(defineID uint32, payload []byte) {
+	body := appendDwords(nil, defineID, ObjectIDUser, defineID, 0, 1, 1, uint32(len(payload)+7)/8)
+	f.send(RecvIDSimObjectData, append(body, payload...))
+}
+
+// SendAirplaneState emits airplane data as the simulator would send it
+func (f *FakeClient) SendAirplaneState(s telemetry.AirplaneState) {
+	f.SendSimObjectData(AirplaneDefineID, AirplaneDefinition.Encode(s))
+}
+
+// SendEnvironmentState emits environment data as the simulator would send it
+func (f *FakeClient) SendEnvironmentState(s telemetry.EnvironmentState) {
+	f.SendSimObjectData(EnvironmentDefineID, EnvironmentDefinition.Encode(s))
+}
+
+// SendSimulatorState emits simulator data as the simulator would send it
+func (f *FakeClient) SendSimulatorState(s telemetry.SimulatorState) {
+	f.SendSimObjectData(SimulatorDefineID, SimulatorDefinition.Encode(s))
+}
+
+func (f *FakeClient) respondSystemState(requestID uint32, integer uint32, str string) error {
+	if err := f.checkOpen(); err != nil {
+		return err
+	}
+	if f.AutoRespond {
+		f.SendSystemState(requestID, integer, str)
+	}
+	return nil
+}
+
+func (f *FakeClient) checkOpen() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return ErrFakeClosed
+	}
+	return nil
+}
+
+// send queues a message unless the client is closed. Like the real client it
+// drops messages when the queue is full.
+func (f *FakeClient) send(id RecvID, body []byte) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.closed {
+		return
+	}
+	select {
+	case f.queue <- ClientMessage{ID: id, Raw: newRecv(id, body)}:
+	default:
+	}
+}
+
+func appendDwords(b []byte, values ...uint32) []byte {
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, v)
+	}
+	return b
+}
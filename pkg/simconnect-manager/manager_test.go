@@ -0,0 +1,316 @@
+package simconnectmanager
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	logz "github.com/mrlm-net/go-logz/pkg/logger"
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const testTimeout = 2 * time.Second
+
+// fakeFactory hands out a new FakeClient for every connection attempt, like
+// DefaultClientFactory creates a new SimConnect client
+type fakeFactory struct {
+	mu      sync.Mutex
+	created []*FakeClient
+	next    chan *FakeClient
+	// connectErrs fail the first connection attempts
+	connectErrs []error
+	// eventErr fails the client event calls of every client
+	eventErr error
+}
+
+func newFakeFactory() *fakeFactory {
+	return &fakeFactory{next: make(chan *FakeClient, 16)}
+}
+
+func (ff *fakeFactory) create(string) SimClient {
+	f := NewFakeClient()
+	ff.mu.Lock()
+	if len(ff.connectErrs) > 0 {
+		f.ConnectErr, ff.connectErrs = ff.connectErrs[0], ff.connectErrs[1:]
+	}
+	f.EventErr = ff.eventErr
+	ff.created = append(ff.created, f)
+	ff.mu.Unlock()
+	ff.next <- f
+	return f
+}
+
+func (ff *fakeFactory) wait(t *testing.T) *FakeClient {
+	t.Helper()
+	select {
+	case f := <-ff.next:
+		return f
+	case <-time.After(testTimeout):
+		t.Fatal("no client created")
+		return nil
+	}
+}
+
+func newTestManager(ff *fakeFactory) *SimConnectManager {
+	m := NewSimConnectManager()
+	m.SetLogger(nil)
+	m.SetClientFactory(ff.create)
+	m.SetRetryInterval(10 * time.Millisecond)
+	return m
+}
+
+func subscribeConnection(m *SimConnectManager) *telemetry.Subscription {
+	return m.Bus().Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{telemetry.TopicConnection}, Buffer: 64, Policy: telemetry.Block})
+}
+
+// waitConnection waits until the connection status want is published
+func waitConnection(t *testing.T, sub *telemetry.Subscription, want bool) {
+	t.Helper()
+	timeout := time.After(testTimeout)
+	for {
+		select {
+		case msg := <-sub.C():
+			if msg.Payload.(telemetry.ConnectionStatus).Connected == want {
+				return
+			}
+		case <-timeout:
+			t.Fatalf("timed out waiting for connected = %v", want)
+		}
+	}
+}
+
+// eventually polls cond until it holds
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(testTimeout)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReconnectCycle(t *testing.T) {
+	ff := newFakeFactory()
+	m := newTestManager(ff)
+	sub := subscribeConnection(m)
+	defer sub.Close()
+
+	m.StartConnection()
+	first := ff.wait(t)
+	waitConnection(t, sub, true)
+	if !m.Status() {
+		t.Fatal("Status() = false after connecting")
+	}
+	if got, want := len(first.Definitions(AirplaneDefineID)), len(AirplaneDefinition.Data); got != want {
+		t.Errorf("airplane definition has %d datums, want %d", got, want)
+	}
+	first.SendAirplaneState(telemetry.AirplaneState{Title: "first", Latitude: 50})
+	eventually(t, "airplane state of the first client", func() bool {
+		return m.GetAirplaneState().Title == "first"
+	})
+
+	// The simulator quits, the manager reconnects with a new client
+	first.SendQuit()
+	waitConnection(t, sub, false)
+	if first.Connected() {
+		t.Error("first client still connected after quit")
+	}
+	second := ff.wait(t)
+	waitConnection(t, sub, true)
+	if second == first {
+		t.Fatal("reconnected with the closed client")
+	}
+	if got, want := len(second.Definitions(AirplaneDefineID)), len(AirplaneDefinition.Data); got != want {
+		t.Errorf("airplane definition has %d datums after reconnect, want %d", got, want)
+	}
+	if len(second.Requests()) == 0 {
+		t.Error("no data requested after reconnect")
+	}
+	second.SendAirplaneState(telemetry.AirplaneState{Title: "second", Latitude: 51})
+	eventually(t, "airplane state of the second client", func() bool {
+		return m.GetAirplaneState().Title == "second"
+	})
+
+	// Messages of the closed client are ignored
+	first.SendAirplaneState(telemetry.AirplaneState{Title: "stale"})
+
+	m.StopConnection()
+	waitConnection(t, sub, false)
+	if m.Status() {
+		t.Error("Status() = true after StopConnection")
+	}
+	if second.Connected() {
+		t.Error("second client still connected after StopConnection")
+	}
+	if got := m.GetAirplaneState().Title; got != "second" {
+		t.Errorf("airplane title = %q, want %q", got, "second")
+	}
+}
+
+func TestConnectRetry(t *testing.T) {
+	ff := newFakeFactory()
+	ff.connectErrs = []error{errors.New("simulator not running"), errors.New("simulator not running")}
+	m := newTestManager(ff)
+	sub := subscribeConnection(m)
+	defer sub.Close()
+
+	m.StartConnection()
+	defer m.StopConnection()
+	for i := 0; i < 2; i++ {
+		failed := ff.wait(t)
+		waitConnection(t, sub, false)
+		eventually(t, "release of the failed client", func() bool {
+			return failed.checkOpen() != nil
+		})
+	}
+	ff.wait(t)
+	waitConnection(t, sub, true)
+}
+
+func TestDisconnectWhileOffline(t *testing.T) {
+	ff := newFakeFactory()
+	m := newTestManager(ff)
+	// Stopping a manager that never connected must not block or panic
+	m.StartConnection()
+	ff.wait(t)
+	m.StopConnection()
+	m.StopConnection()
+}
+
+func TestSystemEvents(t *testing.T) {
+	ff := newFakeFactory()
+	m := newTestManager(ff)
+	sub := subscribeConnection(m)
+	defer sub.Close()
+	m.StartConnection()
+	defer m.StopConnection()
+	f := ff.wait(t)
+	waitConnection(t, sub, true)
+
+	if got := f.SystemEvents()[100]; got != "Pause" {
+		t.Errorf("system event 100 = %q, want Pause", got)
+	}
+	f.SendEvent(100, 1)
+	eventually(t, "pause", func() bool { return m.GetSimulatorState().Pause == 1 })
+
+	f.SendSystemState(101, 0, "Cessna 172")
+	eventually(t, "aircraft loaded", func() bool {
+		return m.GetSimulatorState().AircraftLoaded == "Cessna 172"
+	})
+}
+
+func TestSetAirplaneState(t *testing.T) {
+	ff := newFakeFactory()
+	m := newTestManager(ff)
+	if err := m.SetAirplaneState(telemetry.AirplaneState{}); !errors.Is(err, telemetry.ErrNotConnected) {
+		t.Fatalf("SetAirplaneState while offline = %v, want ErrNotConnected", err)
+	}
+	sub := subscribeConnection(m)
+	defer sub.Close()
+	m.StartConnection()
+	defer m.StopConnection()
+	f := ff.wait(t)
+	waitConnection(t, sub, true)
+
+	state := telemetry.AirplaneState{Latitude: 50.1, Longitude: 14.26, Altitude: 1200, Pitch: -2.5, Bank: 10, Heading: 245, AirspeedTrue: 110, VerticalSpeed: -500}
+	if err := m.SetAirplaneState(state); err != nil {
+		t.Fatal(err)
+	}
+	calls := f.SetData()
+	if len(calls) != 1 {
+		t.Fatalf("%d SetDataOnSimObject calls, want 1", len(calls))
+	}
+	call := calls[0]
+	if call.DefineID != PlaybackDefineID || call.ObjectID != ObjectIDUser {
+		t.Errorf("set definition %d on object %d, want %d on %d", call.DefineID, call.ObjectID, PlaybackDefineID, ObjectIDUser)
+	}
+	if len(call.Data) != PlaybackDefinition.Size() {
+		t.Errorf("set %d bytes, want %d", len(call.Data), PlaybackDefinition.Size())
+	}
+	got, ok := call.State.(telemetry.AirplaneState)
+	if !ok {
+		t.Fatalf("State is %T, want AirplaneState", call.State)
+	}
+	const epsilon = 1e-9
+	for _, c := range []struct {
+		name      string
+		got, want float64
+	}{
+		{"latitude", got.Latitude, state.Latitude},
+		{"longitude", got.Longitude, state.Longitude},
+		{"altitude", got.Altitude, state.Altitude},
+		{"pitch", got.Pitch, state.Pitch},
+		{"bank", got.Bank, state.Bank},
+		{"heading", got.Heading, state.Heading},
+		{"true airspeed", got.AirspeedTrue, state.AirspeedTrue},
+		{"vertical speed", got.VerticalSpeed, state.VerticalSpeed},
+	} {
+		if d := c.got - c.want; d > epsilon || d < -epsilon {
+			t.Errorf("%s = %v, want %v", c.name, c.got, c.want)
+		}
+	}
+
+	if err := m.FreezeAircraft(true); err != nil {
+		t.Fatal(err)
+	}
+	if got := len(f.Transmitted()); got != len(freezeEvents) {
+		t.Errorf("%d events transmitted, want %d", got, len(freezeEvents))
+	}
+}
+
+// errorLog collects the messages logged at error level
+type errorLog struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *errorLog) logger() *logadapter.LogzWailsAdapter {
+	return logadapter.New(logz.NewLogger(logz.LogOptions{
+		Level: logz.Debug,
+		Outputs: []logz.OutputFunc{func(level logz.LogLevel, message string) {
+			if level == logz.Error {
+				l.mu.Lock()
+				l.messages = append(l.messages, message)
+				l.mu.Unlock()
+			}
+		}},
+	}))
+}
+
+func (l *errorLog) contains(s string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for _, msg := range l.messages {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestEventErrorsAreLogged(t *testing.T) {
+	ff := newFakeFactory()
+	ff.eventErr = errors.New("event rejected")
+	m := newTestManager(ff)
+	var log errorLog
+	m.SetLogger(log.logger())
+	sub := subscribeConnection(m)
+	defer sub.Close()
+	m.StartConnection()
+	defer m.StopConnection()
+	ff.wait(t)
+	waitConnection(t, sub, true)
+
+	if !log.contains("Failed to map PAUSE_ON event: event rejected") {
+		t.Error("mapping error not logged")
+	}
+	m.TogglePause()
+	if !log.contains("Failed to toggle pause: event rejected") {
+		t.Error("toggle error not logged")
+	}
+}
@@ -0,0 +1,166 @@
+package simconnectmanager
+
+import (
+	"encoding/binary"
+	"math"
+)
+
+// The SimConnect enumerations and messages used by the manager, with the
+// values and memory layouts of SimConnect.h. The SimConnect client only
+// builds on Windows, so the manager declares its own and the Windows client
+// converts them, see client_windows.go. This keeps the manager and
+// FakeClient buildable and testable on every platform.
+
+// DataType is SIMCONNECT_DATATYPE
+type DataType uint32
+
+const (
+	DataTypeInt32 DataType = iota + 1
+	DataTypeInt64
+	DataTypeFloat32
+	DataTypeFloat64
+	DataTypeString8
+	DataTypeString32
+	DataTypeString64
+	DataTypeString128
+	DataTypeString256
+	DataTypeString260
+)
+
+// Period is SIMCONNECT_PERIOD, how often requested data is sent
+type Period uint32
+
+const (
+	PeriodNever Period = iota
+	PeriodOnce
+	PeriodVisualFrame
+	PeriodSimFrame
+	PeriodSecond
+)
+
+// DataRequestFlag is SIMCONNECT_DATA_REQUEST_FLAG
+type DataRequestFlag uint32
+
+const (
+	DataRequestFlagDefault DataRequestFlag = 0
+	// DataRequestFlagChanged sends data only when a value changed
+	DataRequestFlagChanged DataRequestFlag = 1
+)
+
+// DataSetFlag is SIMCONNECT_DATA_SET_FLAG
+type DataSetFlag uint32
+
+const DataSetFlagDefault DataSetFlag = 0
+
+// ObjectIDUser is SIMCONNECT_OBJECT_ID_USER, the user aircraft
+const ObjectIDUser = 0
+
+// RecvID is SIMCONNECT_RECV_ID, the kind of a received message
+type RecvID uint32
+
+const (
+	RecvIDException     RecvID = 1
+	RecvIDOpen          RecvID = 2
+	RecvIDQuit          RecvID = 3
+	RecvIDEvent         RecvID = 4
+	RecvIDSimObjectData RecvID = 8
+	RecvIDSystemState   RecvID = 15
+)
+
+// recvHeaderSize is the size of SIMCONNECT_RECV, i.e. dwSize, dwVersion and
+// dwID, which starts every message
+const recvHeaderSize = 12
+
+// Sizes of the fixed parts of the messages, including the header
+const (
+	recvEventSize       = recvHeaderSize + 12
+	recvSystemStateSize = recvHeaderSize + 12 + systemStateStringSize
+	// recvSimObjectDataSize excludes dwData, the first dword of the payload
+	recvSimObjectDataSize = recvHeaderSize + 28
+	systemStateStringSize = 260
+)
+
+// RecvEvent is SIMCONNECT_RECV_EVENT
+type RecvEvent struct {
+	GroupID uint32
+	EventID uint32
+	Data    uint32
+}
+
+// RecvSystemState is SIMCONNECT_RECV_SYSTEM_STATE
+type RecvSystemState struct {
+	RequestID uint32
+	Integer   uint32
+	Float     float32
+	String    string
+}
+
+// RecvSimObjectData is SIMCONNECT_RECV_SIMOBJECT_DATA. Data is the payload
+// starting at dwData, packed in the order of the data definition.
+type RecvSimObjectData struct {
+	RequestID   uint32
+	ObjectID    uint32
+	DefineID    uint32
+	Flags       uint32
+	EntryNumber uint32
+	OutOf       uint32
+	DefineCount uint32
+	Data        []byte
+}
+
+// Event decodes a RecvIDEvent message
+func (m ClientMessage) Event() (RecvEvent, bool) {
+	if m.ID != RecvIDEvent || len(m.Raw) < recvEventSize {
+		return RecvEvent{}, false
+	}
+	return RecvEvent{
+		GroupID: dword(m.Raw, 0),
+		EventID: dword(m.Raw, 1),
+		Data:    dword(m.Raw, 2),
+	}, true
+}
+
+// SystemState decodes a RecvIDSystemState message
+func (m ClientMessage) SystemState() (RecvSystemState, bool) {
+	if m.ID != RecvIDSystemState || len(m.Raw) < recvSystemStateSize {
+		return RecvSystemState{}, false
+	}
+	return RecvSystemState{
+		RequestID: dword(m.Raw, 0),
+		Integer:   dword(m.Raw, 1),
+		Float:     math.Float32frombits(dword(m.Raw, 2)),
+		String:    bytesToString(m.Raw[recvHeaderSize+12 : recvSystemStateSize]),
+	}, true
+}
+
+// SimObjectData decodes a RecvIDSimObjectData message
+func (m ClientMessage) SimObjectData() (RecvSimObjectData, bool) {
+	if m.ID != RecvIDSimObjectData || len(m.Raw) < recvSimObjectDataSize {
+		return RecvSimObjectData{}, false
+	}
+	return RecvSimObjectData{
+		RequestID:   dword(m.Raw, 0),
+		ObjectID:    dword(m.Raw, 1),
+		DefineID:    dword(m.Raw, 2),
+		Flags:       dword(m.Raw, 3),
+		EntryNumber: dword(m.Raw, 4),
+		OutOf:       dword(m.Raw, 5),
+		DefineCount: dword(m.Raw, 6),
+		Data:        m.Raw[recvSimObjectDataSize:],
+	}, true
+}
+
+// dword returns the i-th little-endian dword following the header
+func dword(raw []byte, i int) uint32 {
+	return binary.LittleEndian.Uint32(raw[recvHeaderSize+4*i:])
+}
+
+// newRecv lays out a message of kind id with the given body after the
+// header, as SimConnect delivers it
+func newRecv(id RecvID, body []byte) []byte {
+	raw := make([]byte, 0, recvHeaderSize+len(body))
+	raw = binary.LittleEndian.AppendUint32(raw, uint32(recvHeaderSize+len(body)))
+	raw = binary.LittleEndian.AppendUint32(raw, 0) // dwVersion
+	raw = binary.LittleEndian.AppendUint32(raw, uint32(id))
+	return append(raw, body...)
+}
@@ -0,0 +1,37 @@
+package simconnectmanager
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// stateStore holds the current Snapshot using copy-on-write: readers load
+// the current pointer without locking, writers copy, modify and publish a
+// new Snapshot while holding writeMu.
+type stateStore struct {
+	current atomic.Pointer[telemetry.Snapshot]
+	writeMu sync.Mutex
+}
+
+func (s *stateStore) load() telemetry.Snapshot {
+	if snap := s.current.Load(); snap != nil {
+		return *snap
+	}
+	return telemetry.Snapshot{}
+}
+
+// update applies fn to a copy of the current snapshot and publishes it when
+// fn reports a change. It returns the resulting snapshot.
+func (s *stateStore) update(fn func(next *telemetry.Snapshot) bool) (telemetry.Snapshot, bool) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	next := s.load()
+	if !fn(&next) {
+		return next, false
+	}
+	next.Version++
+	s.current.Store(&next)
+	return next, true
+}
@@ -0,0 +1,128 @@
+package simconnectmanager
+
+import (
+	"math"
+	"sync"
+	"testing"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// TestStateStoreConcurrent checks that readers never see a partially
+// updated snapshot while several writers update it. Run with -race.
+func TestStateStoreConcurrent(t *testing.T) {
+	const (
+		writers = 4
+		updates = 2000
+		readers = 4
+	)
+	var s stateStore
+	stop := make(chan struct{})
+	var reading sync.WaitGroup
+	for i := 0; i < readers; i++ {
+		reading.Add(1)
+		go func() {
+			defer reading.Done()
+			var last uint64
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snap := s.load()
+				// Every update writes the version into all states at once
+				if v := float64(snap.Version); snap.Airplane.Altitude != v || snap.Environment.AmbientTemperature != v || snap.Simulator.SimulationRate != v {
+					t.Errorf("inconsistent snapshot %d: altitude %v, temperature %v, rate %v", snap.Version, snap.Airplane.Altitude, snap.Environment.AmbientTemperature, snap.Simulator.SimulationRate)
+					return
+				}
+				if snap.Version < last {
+					t.Errorf("version went back from %d to %d", last, snap.Version)
+					return
+				}
+				last = snap.Version
+			}
+		}()
+	}
+
+	var writing sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		writing.Add(1)
+		go func() {
+			defer writing.Done()
+			for j := 0; j < updates; j++ {
+				s.update(func(next *telemetry.Snapshot) bool {
+					v := float64(next.Version + 1)
+					next.Airplane.Altitude = v
+					next.Environment.AmbientTemperature = v
+					next.Simulator.SimulationRate = v
+					return true
+				})
+			}
+		}()
+	}
+	writing.Wait()
+	close(stop)
+	reading.Wait()
+
+	if got := s.load().Version; got != writers*updates {
+		t.Errorf("version %d after %d updates", got, writers*updates)
+	}
+	if _, changed := s.update(func(*telemetry.Snapshot) bool { return false }); changed {
+		t.Error("update without change reported a change")
+	}
+	if got := s.load().Version; got != writers*updates {
+		t.Errorf("update without change bumped the version to %d", got)
+	}
+}
+
+// TestReadsDuringReconnect reads the state from several goroutines while
+// the simulator quits and the manager reconnects. Run with -race.
+func TestReadsDuringReconnect(t *testing.T) {
+	const reconnects = 5
+	ff := newFakeFactory()
+	m := newTestManager(ff)
+	sub := subscribeConnection(m)
+	defer sub.Close()
+
+	stop := make(chan struct{})
+	var reading sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		reading.Add(1)
+		go func() {
+			defer reading.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				snap := m.Snapshot()
+				if a := m.GetAirplaneState(); a.Latitude < snap.Airplane.Latitude {
+					t.Errorf("airplane state went back from %v to %v", snap.Airplane.Latitude, a.Latitude)
+					return
+				}
+				m.GetEnvironmentState()
+				m.GetSimulatorState()
+				m.Status()
+			}
+		}()
+	}
+
+	m.StartConnection()
+	for i := 1; i <= reconnects; i++ {
+		f := ff.wait(t)
+		waitConnection(t, sub, true)
+		for j := 0; j < 20; j++ {
+			f.SendAirplaneState(telemetry.AirplaneState{Title: "C172", Latitude: float64(i*100 + j)})
+		}
+		want := float64(i*100 + 19)
+		// The latitude is sent in radians
+		eventually(t, "airplane state", func() bool { return math.Abs(m.GetAirplaneState().Latitude-want) < 1e-9 })
+		f.SendQuit()
+		waitConnection(t, sub, false)
+	}
+	m.StopConnection()
+	close(stop)
+	reading.Wait()
+}
@@ -0,0 +1,27 @@
+package simconnectmanager
+
+// RequestTouchdownData starts or stops publishing TouchdownState on
+// TopicTouchdown every simulation frame. The request is meant to be
+// temporary, e.g. from short final until the landing roll. While enabled it
+// is also made on every new connection.
+func (m *SimConnectManager) RequestTouchdownData(enabled bool) error {
+	m.touchdownMu.Lock()
+	defer m.touchdownMu.Unlock()
+	if m.touchdown == enabled {
+		return nil
+	}
+	m.touchdown = enabled
+	c := m.currentClient()
+	if c == nil {
+		return nil
+	}
+	return requestTouchdown(c, enabled)
+}
+
+func requestTouchdown(c SimClient, enabled bool) error {
+	period := PeriodNever
+	if enabled {
+		period = PeriodSimFrame
+	}
+	return c.RequestDataOnSimObject(TouchdownDefineID, TouchdownDefineID, 0, period, DataRequestFlagDefault, 0, 0, 0)
+}
@@ -0,0 +1,278 @@
+package telemetry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Topic identifies the kind of message published on the Bus
+type Topic string
+
+const (
+	TopicAirplane    Topic = "airplane"     // Payload: AirplaneState
+	TopicEnvironment Topic = "environment"  // Payload: EnvironmentState
+	TopicSimulator   Topic = "simulator"    // Payload: SimulatorState
+	TopicConnection  Topic = "connection"   // Payload: ConnectionStatus
+	TopicSystemEvent Topic = "system-event" // Payload: SystemEvent
+	TopicTouchdown   Topic = "touchdown"    // Payload: TouchdownState
+)
+
+// Message is a single telemetry update. The concrete type of Payload is
+// determined by Topic.
+type Message struct {
+	Topic   Topic
+	Time    time.Time
+	Payload any
+}
+
+// ConnectionStatus is published whenever the simulator connection changes
+type ConnectionStatus struct {
+	Connected bool `json:"connected"`
+}
+
+// SystemEvent is a subscribed SimConnect system event such as Pause or Crashed
+type SystemEvent struct {
+	ID   uint32 `json:"id"`
+	Name string `json:"name"`
+	Data uint32 `json:"data"`
+}
+
+// Policy decides what happens when a subscriber's buffer is full
+type Policy int
+
+const (
+	// DropNewest discards the message that does not fit
+	DropNewest Policy = iota
+	// DropOldest discards the oldest buffered message to make room
+	DropOldest
+	// Block waits until the subscriber has room, stalling the publisher.
+	// Blocking subscribers must not subscribe or publish while handling a
+	// message, they publish through a Relay.
+	Block
+)
+
+// DefaultBufferSize is used when SubscribeOptions.Buffer is not set
+const DefaultBufferSize = 64
+
+// SubscribeOptions configure a subscription
+type SubscribeOptions struct {
+	// Topics to receive, all topics when empty
+	Topics []Topic
+	// Buffer is the number of messages queued for the subscriber
+	Buffer int
+	// Policy applied when the buffer is full
+	Policy Policy
+}
+
+// Bus fans out telemetry messages to any number of subscribers. Each
+// subscriber has its own buffer, so a slow subscriber only affects others
+// when it uses the Block policy.
+type Bus struct {
+	mu   sync.RWMutex
+	subs map[*Subscription]struct{}
+}
+
+// NewBus returns an empty Bus
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives messages from a Bus until it is closed
+type Subscription struct {
+	bus     *Bus
+	topics  map[Topic]bool
+	policy  Policy
+	ch      chan Message
+	sendMu  sync.Mutex
+	done    chan struct{}
+	once    sync.Once
+	dropped atomic.Uint64
+}
+
+// Subscribe registers a new subscriber
+func (b *Bus) Subscribe(opts SubscribeOptions) *Subscription {
+	if opts.Buffer <= 0 {
+		opts.Buffer = DefaultBufferSize
+	}
+	s := &Subscription{
+		bus:    b,
+		policy: opts.Policy,
+		ch:     make(chan Message, opts.Buffer),
+		done:   make(chan struct{}),
+	}
+	if len(opts.Topics) > 0 {
+		s.topics = make(map[Topic]bool, len(opts.Topics))
+		for _, t := range opts.Topics {
+			s.topics[t] = true
+		}
+	}
+	b.mu.Lock()
+	b.subs[s] = struct{}{}
+	b.mu.Unlock()
+	return s
+}
+
+// Publish delivers a message to every subscriber of its topic
+func (b *Bus) Publish(topic Topic, payload any) {
+	msg := Message{Topic: topic, Time: time.Now(), Payload: payload}
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for s := range b.subs {
+		if s.topics == nil || s.topics[topic] {
+			s.deliver(msg)
+		}
+	}
+}
+
+// StateListener receives every state update published on the bus
+type StateListener interface {
+	OnAirplaneState(state AirplaneState)
+	OnEnvironmentState(state EnvironmentState)
+	OnSimulatorState(state SimulatorState)
+}
+
+// AddListener subscribes l to all state updates. Listeners are called from
+// their own goroutine in publish order and never miss an update; a slow
+// listener stalls the publisher. Close the returned subscription to detach.
+func (b *Bus) AddListener(l StateListener) *Subscription {
+	sub := b.Subscribe(SubscribeOptions{
+		Topics: []Topic{TopicAirplane, TopicEnvironment, TopicSimulator},
+		Policy: Block,
+	})
+	go func() {
+		for msg := range sub.C() {
+			switch p := msg.Payload.(type) {
+			case AirplaneState:
+				l.OnAirplaneState(p)
+			case EnvironmentState:
+				l.OnEnvironmentState(p)
+			case SimulatorState:
+				l.OnSimulatorState(p)
+			}
+		}
+	}()
+	return sub
+}
+
+// Relay publishes messages on a bus from its own goroutine, in order and
+// without dropping any. A blocking subscriber publishes what it derives from
+// a message through a relay, as publishing itself could deadlock the bus.
+type Relay struct {
+	bus    *Bus
+	mu     sync.Mutex
+	queue  []relayed
+	closed bool
+	wake   chan struct{}
+}
+
+type relayed struct {
+	topic   Topic
+	payload any
+}
+
+// NewRelay returns a relay publishing on b until it is closed
+func NewRelay(b *Bus) *Relay {
+	r := &Relay{bus: b, wake: make(chan struct{}, 1)}
+	go r.run()
+	return r
+}
+
+// Publish queues a message. It never blocks, the queue has no limit.
+func (r *Relay) Publish(topic Topic, payload any) {
+	r.mu.Lock()
+	if !r.closed {
+		r.queue = append(r.queue, relayed{topic, payload})
+	}
+	r.mu.Unlock()
+	r.signal()
+}
+
+// Close stops the relay once the queued messages are published. Messages
+// published after Close are discarded.
+func (r *Relay) Close() {
+	r.mu.Lock()
+	r.closed = true
+	r.mu.Unlock()
+	r.signal()
+}
+
+func (r *Relay) signal() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (r *Relay) run() {
+	for {
+		r.mu.Lock()
+		queue, closed := r.queue, r.closed
+		r.queue = nil
+		r.mu.Unlock()
+		if len(queue) == 0 {
+			if closed {
+				return
+			}
+			<-r.wake
+			continue
+		}
+		for _, m := range queue {
+			r.bus.Publish(m.topic, m.payload)
+		}
+	}
+}
+
+// C returns the channel messages are delivered on. It is closed by Close.
+func (s *Subscription) C() <-chan Message {
+	return s.ch
+}
+
+// Dropped returns the number of messages discarded because the buffer was full
+func (s *Subscription) Dropped() uint64 {
+	return s.dropped.Load()
+}
+
+// Close unsubscribes and closes the message channel. It is safe to call
+// more than once and unblocks a publisher waiting on this subscriber.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		// No publisher holds the read lock anymore, nothing sends on ch
+		close(s.ch)
+	})
+}
+
+func (s *Subscription) deliver(msg Message) {
+	switch s.policy {
+	case Block:
+		select {
+		case s.ch <- msg:
+		case <-s.done:
+		}
+	case DropOldest:
+		s.sendMu.Lock()
+		defer s.sendMu.Unlock()
+		for {
+			select {
+			case s.ch <- msg:
+				return
+			default:
+			}
+			select {
+			case <-s.ch:
+				s.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case s.ch <- msg:
+		default:
+			s.dropped.Add(1)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package telemetry
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+const testTimeout = 2 * time.Second
+
+func receive(t *testing.T, sub *Subscription) Message {
+	t.Helper()
+	select {
+	case msg := <-sub.C():
+		return msg
+	case <-time.After(testTimeout):
+		t.Fatal("no message received")
+		return Message{}
+	}
+}
+
+func TestTopics(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe(SubscribeOptions{Buffer: 8})
+	airplane := bus.Subscribe(SubscribeOptions{Topics: []Topic{TopicAirplane}, Buffer: 8})
+	defer all.Close()
+	defer airplane.Close()
+
+	bus.Publish(TopicSimulator, SimulatorState{Sim: 1})
+	bus.Publish(TopicAirplane, AirplaneState{Title: "C172"})
+
+	if got := receive(t, all).Topic; got != TopicSimulator {
+		t.Errorf("first message on %s, want %s", got, TopicSimulator)
+	}
+	if got := receive(t, all).Topic; got != TopicAirplane {
+		t.Errorf("second message on %s, want %s", got, TopicAirplane)
+	}
+	msg := receive(t, airplane)
+	if msg.Payload.(AirplaneState).Title != "C172" {
+		t.Errorf("payload %+v, want the airplane state", msg.Payload)
+	}
+	if len(airplane.C()) != 0 {
+		t.Error("received a topic that was not subscribed")
+	}
+}
+
+func TestPolicies(t *testing.T) {
+	for _, c := range []struct {
+		policy  Policy
+		want    []int
+		dropped uint64
+	}{
+		{DropNewest, []int{0, 1}, 3},
+		{DropOldest, []int{3, 4}, 3},
+	} {
+		bus := NewBus()
+		sub := bus.Subscribe(SubscribeOptions{Buffer: 2, Policy: c.policy})
+		for i := 0; i < 5; i++ {
+			bus.Publish(TopicSystemEvent, i)
+		}
+		sub.Close()
+		var got []int
+		for msg := range sub.C() {
+			got = append(got, msg.Payload.(int))
+		}
+		if len(got) != len(c.want) || got[0] != c.want[0] || got[1] != c.want[1] {
+			t.Errorf("policy %d: received %v, want %v", c.policy, got, c.want)
+		}
+		if sub.Dropped() != c.dropped {
+			t.Errorf("policy %d: dropped %d, want %d", c.policy, sub.Dropped(), c.dropped)
+		}
+	}
+}
+
+func TestBlockDeliversEverything(t *testing.T) {
+	const n = 1000
+	bus := NewBus()
+	sub := bus.Subscribe(SubscribeOptions{Buffer: 1, Policy: Block})
+	defer sub.Close()
+	go func() {
+		for i := 0; i < n; i++ {
+			bus.Publish(TopicSystemEvent, i)
+		}
+	}()
+	for i := 0; i < n; i++ {
+		if got := receive(t, sub).Payload.(int); got != i {
+			t.Fatalf("received %d, want %d", got, i)
+		}
+	}
+	if sub.Dropped() != 0 {
+		t.Errorf("dropped %d messages", sub.Dropped())
+	}
+}
+
+func TestCloseUnblocksPublisher(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(SubscribeOptions{Buffer: 1, Policy: Block})
+	bus.Publish(TopicSystemEvent, 0)
+	published := make(chan struct{})
+	go func() {
+		bus.Publish(TopicSystemEvent, 1) // blocks on the full buffer
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	select {
+	case <-published:
+	case <-time.After(testTimeout):
+		t.Fatal("publisher still blocked after Close")
+	}
+	sub.Close()
+}
+
+type recordingListener struct {
+	mu     sync.Mutex
+	titles []string
+	events int
+}
+
+func (l *recordingListener) OnAirplaneState(s AirplaneState) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.titles = append(l.titles, s.Title)
+}
+
+func (l *recordingListener) OnEnvironmentState(EnvironmentState) { l.count() }
+func (l *recordingListener) OnSimulatorState(SimulatorState)     { l.count() }
+
+func (l *recordingListener) count() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events++
+}
+
+func TestAddListener(t *testing.T) {
+	bus := NewBus()
+	l := &recordingListener{}
+	sub := bus.AddListener(l)
+	for _, title := range []string{"a", "b", "c"} {
+		bus.Publish(TopicAirplane, AirplaneState{Title: title})
+		bus.Publish(TopicEnvironment, EnvironmentState{})
+		bus.Publish(TopicSimulator, SimulatorState{})
+		bus.Publish(TopicConnection, ConnectionStatus{Connected: true})
+	}
+	deadline := time.Now().Add(testTimeout)
+	for {
+		l.mu.Lock()
+		titles, events := append([]string(nil), l.titles...), l.events
+		l.mu.Unlock()
+		if len(titles) == 3 && events == 6 {
+			if titles[0] != "a" || titles[1] != "b" || titles[2] != "c" {
+				t.Errorf("airplane states in order %v, want [a b c]", titles)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("listener received %d airplane and %d other states, want 3 and 6", len(titles), events)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sub.Close()
+}
+
+// TestConcurrentUse publishes from several goroutines while subscribers of
+// every policy come and go, as the manager, the analyzers and the frontend
+// do. Run with -race.
+func TestConcurrentUse(t *testing.T) {
+	const (
+		publishers  = 4
+		messages    = 2000
+		subscribers = 8
+	)
+	bus := NewBus()
+	// A blocking subscriber that stays for the whole test sees every message
+	// of every publisher in order
+	steady := bus.Subscribe(SubscribeOptions{Buffer: 16, Policy: Block})
+	var received sync.WaitGroup
+	received.Add(1)
+	go func() {
+		defer received.Done()
+		// Unblocks the publishers when the test fails
+		defer steady.Close()
+		last := make([]int, publishers)
+		for i := range last {
+			last[i] = -1
+		}
+		for n := 0; n < publishers*messages; n++ {
+			var msg Message
+			select {
+			case msg = <-steady.C():
+			case <-time.After(testTimeout):
+				t.Errorf("received %d of %d messages", n, publishers*messages)
+				return
+			}
+			p := msg.Payload.([2]int)
+			if p[1] != last[p[0]]+1 {
+				t.Errorf("publisher %d: message %d after %d", p[0], p[1], last[p[0]])
+				return
+			}
+			last[p[0]] = p[1]
+		}
+	}()
+
+	stop := make(chan struct{})
+	var churn sync.WaitGroup
+	for i := 0; i < subscribers; i++ {
+		churn.Add(1)
+		go func(policy Policy) {
+			defer churn.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				sub := bus.Subscribe(SubscribeOptions{Buffer: 4, Policy: policy})
+				for j := 0; j < 10; j++ {
+					select {
+					case <-sub.C():
+					default:
+					}
+				}
+				sub.Close()
+			}
+		}(Policy(i % 3))
+	}
+
+	var publishing sync.WaitGroup
+	for p := 0; p < publishers; p++ {
+		publishing.Add(1)
+		go func(p int) {
+			defer publishing.Done()
+			for i := 0; i < messages; i++ {
+				bus.Publish(TopicAirplane, [2]int{p, i})
+			}
+		}(p)
+	}
+	publishing.Wait()
+	received.Wait()
+	close(stop)
+	churn.Wait()
+}
+
+// TestRelay derives a message from every message of a blocking subscriber,
+// like the analyzers, while subscribers come and go. Publishing from the
+// handler instead deadlocks once a Subscribe waits for the bus.
+func TestRelay(t *testing.T) {
+	const n = 1000
+	bus := NewBus()
+	in := bus.Subscribe(SubscribeOptions{Topics: []Topic{TopicAirplane}, Buffer: 1, Policy: Block})
+	out := bus.Subscribe(SubscribeOptions{Topics: []Topic{TopicSystemEvent}, Buffer: 1, Policy: Block})
+	defer out.Close()
+	relay := NewRelay(bus)
+	go func() {
+		defer relay.Close()
+		for msg := range in.C() {
+			relay.Publish(TopicSystemEvent, msg.Payload)
+		}
+	}()
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				bus.Subscribe(SubscribeOptions{}).Close()
+			}
+		}
+	}()
+	go func() {
+		for i := 0; i < n; i++ {
+			bus.Publish(TopicAirplane, i)
+		}
+		in.Close()
+	}()
+	for i := 0; i < n; i++ {
+		if got := receive(t, out).Payload.(int); got != i {
+			t.Fatalf("relayed %d, want %d", got, i)
+		}
+	}
+}
+
+func TestRelayClose(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(SubscribeOptions{Buffer: 8})
+	defer sub.Close()
+	relay := NewRelay(bus)
+	relay.Publish(TopicSystemEvent, 1)
+	relay.Publish(TopicSystemEvent, 2)
+	relay.Close()
+	relay.Publish(TopicSystemEvent, 3)
+	relay.Close()
+	for _, want := range []int{1, 2} {
+		if got := receive(t, sub).Payload.(int); got != want {
+			t.Fatalf("relayed %d, want %d", got, want)
+		}
+	}
+	select {
+	case msg := <-sub.C():
+		t.Errorf("relayed %v after Close", msg.Payload)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
@@ -0,0 +1,56 @@
+// Package telemetry holds the simulator states, the bus they are published
+// on and the Source interface implemented by every simulator connection. It
+// has no platform specific code, so everything built on it runs and tests
+// on every platform.
+package telemetry
+
+import (
+	"errors"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+)
+
+// ErrNotConnected is returned by calls that need a simulator connection
+var ErrNotConnected = errors.New("not connected to the simulator")
+
+// Source is a simulator connection publishing AirplaneState,
+// EnvironmentState and SimulatorState on its bus. Recording, exports and
+// analysis only depend on the bus and this interface, so they work the same
+// for every simulator. Capabilities only some simulators have are separate
+// interfaces, see PlaybackTarget and TouchdownSource.
+type Source interface {
+	// Name names the simulator, e.g. in recordings and exports
+	Name() string
+	// Bus returns the telemetry bus the source publishes to
+	Bus() *Bus
+	// SetLogger allows injection of a custom logger
+	SetLogger(logger *logadapter.LogzWailsAdapter)
+	// StartConnection connects to the simulator in the background and keeps
+	// reconnecting until StopConnection
+	StartConnection()
+	StopConnection()
+	// Status reports whether the simulator is connected
+	Status() bool
+	Snapshot() Snapshot
+	GetAirplaneState() AirplaneState
+	GetEnvironmentState() EnvironmentState
+	GetSimulatorState() SimulatorState
+	TogglePause()
+}
+
+// PlaybackTarget is a Source that can move the user aircraft, so
+// recordings can be played back in the simulator
+type PlaybackTarget interface {
+	// SetAirplaneState moves the user aircraft to the position, attitude and
+	// speeds of state
+	SetAirplaneState(state AirplaneState) error
+	// FreezeAircraft stops or resumes the simulator's own physics
+	FreezeAircraft(frozen bool) error
+}
+
+// TouchdownSource is a Source that can publish TouchdownState on
+// TopicTouchdown at a high rate, for landing analysis
+type TouchdownSource interface {
+	// RequestTouchdownData starts or stops publishing TouchdownState
+	RequestTouchdownData(enabled bool) error
+}
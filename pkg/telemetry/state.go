@@ -0,0 +1,138 @@
+package telemetry
+
+import (
+	"math"
+	"time"
+)
+
+// AirplaneState holds the main simvars to be monitored and is extensible for future fields
+type AirplaneState struct {
+	Title           string  `json:"title"`
+	Latitude        float64 `json:"latitude"`
+	Longitude       float64 `json:"longitude"`
+	Altitude        float64 `json:"altitude"`
+	Heading         float64 `json:"heading"`
+	HeadingMagnetic float64 `json:"heading_magnetic"`
+	Airspeed        float64 `json:"airspeed"`
+	Bank            float64 `json:"bank"`
+	AltAboveGround  float64 `json:"alt_above_ground"`
+	Pitch           float64 `json:"pitch"`
+	VerticalSpeed   float64 `json:"vertical_speed"`
+	GroundVelocity  float64 `json:"ground_velocity"`
+	GroundTrack     float64 `json:"ground_track"` // true, degrees
+	AirspeedTrue    float64 `json:"airspeed_true"`
+	AngleOfAttack   float64 `json:"angle_of_attack"`
+	// NAV1 localizer and glide slope, valid when the Has flags are set
+	NavHasLocalizer    bool    `json:"nav_has_localizer"`
+	NavLocalizerCourse float64 `json:"nav_localizer_course"`
+	NavRadialError     float64 `json:"nav_radial_error"`
+	NavHasGlideSlope   bool    `json:"nav_has_glide_slope"`
+	NavGlideSlopeError float64 `json:"nav_glide_slope_error"`
+}
+
+// EnvironmentState holds the main environment vars to be monitored
+type EnvironmentState struct {
+	ZuluTime       int32 `json:"zulu_time"`
+	LocalTime      int32 `json:"local_time"`
+	SimTime        int32 `json:"sim_time"`
+	ZuluDay        int32 `json:"zulu_day"`
+	ZuluMonth      int32 `json:"zulu_month"`
+	ZuluYear       int32 `json:"zulu_year"`
+	LocalDay       int32 `json:"local_day"`
+	LocalMonth     int32 `json:"local_month"`
+	LocalYear      int32 `json:"local_year"`
+	ZuluDayOfWeek  int32 `json:"zulu_day_of_week"`
+	LocalDayOfWeek int32 `json:"local_day_of_week"`
+	// Weather variables
+	SeaLevelPressure     float64 `json:"sea_level_pressure"`
+	AmbientTemperature   float64 `json:"ambient_temperature"`
+	AmbientWindDirection float64 `json:"ambient_wind_direction"`
+	AmbientWindVelocity  float64 `json:"ambient_wind_velocity"`
+	AmbientVisibility    float64 `json:"ambient_visibility"`
+	// Add more fields as needed for future extension
+	// New simvars
+	TimeZoneOffset  int32 `json:"time_zone_offset"`
+	ZuluSunriseTime int32 `json:"zulu_sunrise_time"`
+	ZuluSunsetTime  int32 `json:"zulu_sunset_time"`
+	TimeOfDay       int32 `json:"time_of_day"`
+}
+
+// ZuluDateTime returns the simulator Zulu date and time, false when the
+// simulator did not report a date yet
+func (e EnvironmentState) ZuluDateTime() (time.Time, bool) {
+	if e.ZuluYear == 0 || e.ZuluMonth == 0 || e.ZuluDay == 0 {
+		return time.Time{}, false
+	}
+	day := time.Date(int(e.ZuluYear), time.Month(e.ZuluMonth), int(e.ZuluDay), 0, 0, 0, 0, time.UTC)
+	return day.Add(time.Duration(e.ZuluTime) * time.Second), true
+}
+
+// StandardPressure is the ISA sea level pressure in inHg
+const StandardPressure = 29.92126
+
+// PressureAltitude estimates the ISA pressure altitude in feet from a true
+// altitude in feet and the sea level pressure, about 1000 ft per inHg. It is
+// the true altitude while the simulator did not report the pressure.
+func (e EnvironmentState) PressureAltitude(altitude float64) float64 {
+	if e.SeaLevelPressure <= 0 {
+		return altitude
+	}
+	return altitude + (StandardPressure-e.SeaLevelPressure)*1000
+}
+
+// --- SimulatorState for system state monitoring ---
+type SimulatorState struct {
+	Sim              int     `json:"sim"`
+	Pause            int     `json:"pause"`
+	Crashed          int     `json:"crashed"`
+	View             int     `json:"view"`
+	AircraftLoaded   string  `json:"aircraft_loaded"`
+	FlightLoaded     string  `json:"flight_loaded"`
+	FlightPlan       string  `json:"flight_plan"`
+	SimulationRate   float64 `json:"simulation_rate"`
+	Realism          int     `json:"realism"`
+	SurfaceCondition int     `json:"surface_condition"`
+	SurfaceInfoValid int     `json:"surface_info_valid"`
+	SurfaceType      int     `json:"surface_type"`
+	OnAnyRunway      int     `json:"on_any_runway"`
+	InParkingState   int     `json:"in_parking_state"`
+	OnGround         bool    `json:"on_ground"`
+}
+
+// No mutex or methods needed, match AirplaneState/EnvironmentState style
+
+// ...implement Pause, Crashed, View similarly if needed...
+
+// TouchdownState is sampled every simulation frame while touchdown data is
+// requested, see RequestTouchdownData
+type TouchdownState struct {
+	Latitude       float64 `json:"latitude"`
+	Longitude      float64 `json:"longitude"`
+	AltAboveGround float64 `json:"alt_above_ground"`
+	VerticalSpeed  float64 `json:"vertical_speed"`
+	GForce         float64 `json:"g_force"`
+	Pitch          float64 `json:"pitch"`
+	Bank           float64 `json:"bank"`
+	Heading        float64 `json:"heading"`
+	GroundVelocity float64 `json:"ground_velocity"`
+	Airspeed       float64 `json:"airspeed"`
+	OnGround       bool    `json:"on_ground"`
+}
+
+// Snapshot is a consistent, immutable view of all simulator states.
+// Version increases with every committed update.
+type Snapshot struct {
+	Version     uint64           `json:"version"`
+	Airplane    AirplaneState    `json:"airplane"`
+	Environment EnvironmentState `json:"environment"`
+	Simulator   SimulatorState   `json:"simulator"`
+}
+
+// RoundVerticalSpeed drops jitter below 0.1 fpm and rounds to 2 decimal
+// places. Sources apply it to AirplaneState.VerticalSpeed.
+func RoundVerticalSpeed(v float64) float64 {
+	if math.Abs(v) < 0.1 {
+		return 0.0
+	}
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,19 @@
+package telemetry
+
+import "testing"
+
+func TestPressureAltitude(t *testing.T) {
+	for _, c := range []struct {
+		pressure, altitude, want float64
+	}{
+		{0, 1200, 1200}, // not reported
+		{StandardPressure, 1200, 1200},
+		{30.42126, 1200, 700}, // high pressure, below the true altitude
+		{28.92126, 1200, 2200},
+	} {
+		got := EnvironmentState{SeaLevelPressure: c.pressure}.PressureAltitude(c.altitude)
+		if got < c.want-1e-6 || got > c.want+1e-6 {
+			t.Errorf("%v ft at %v inHg: %v ft, want %v", c.altitude, c.pressure, got, c.want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package telemetry
+
+import (
+	"context"
+
+	"github.com/wailsapp/wails/v2/pkg/runtime"
+)
+
+// wailsBufferSize keeps a few seconds of updates for the frontend; older
+// states are superseded by newer ones anyway
+const wailsBufferSize = 16
+
+// SubscribeWails forwards the states and the connection status published on
+// bus to the Wails frontend until the returned subscription is closed
+func SubscribeWails(ctx context.Context, bus *Bus) *Subscription {
+	sub := bus.Subscribe(SubscribeOptions{
+		Topics: []Topic{TopicAirplane, TopicEnvironment, TopicSimulator, TopicConnection},
+		Buffer: wailsBufferSize,
+		Policy: DropOldest,
+	})
+	go emitToWails(ctx, sub)
+	return sub
+}
+
+// emitToWails forwards bus messages as Wails events until sub is closed
+func emitToWails(ctx context.Context, sub *Subscription) {
+	for msg := range sub.C() {
+		switch p := msg.Payload.(type) {
+		case AirplaneState:
+			runtime.EventsEmit(ctx, "airplane::state", p)
+		case EnvironmentState:
+			runtime.EventsEmit(ctx, "environment::state", p)
+		case SimulatorState:
+			runtime.EventsEmit(ctx, "simulator::state", p)
+		case ConnectionStatus:
+			runtime.EventsEmit(ctx, "global::sim-status", p.Connected)
+		}
+	}
+}
@@ -0,0 +1,449 @@
+package xplanesource
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/internal/logadapter"
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	// DefaultPort is the port X-Plane receives UDP on
+	DefaultPort = 49000
+	// DefaultListen receives the replies of X-Plane and its Data Output
+	DefaultListen = ":49003"
+	// DefaultRate is how many times a second X-Plane sends the datarefs
+	DefaultRate = 20
+)
+
+const (
+	// timeout without packets after which X-Plane is disconnected
+	timeout = 3 * time.Second
+	// resubscribeInterval repeats the RREF requests while X-Plane is
+	// disconnected, e.g. until it is started
+	resubscribeInterval = 5 * time.Second
+	retryInterval       = 5 * time.Second
+	environmentInterval = time.Second
+	maxPacketSize       = 65536
+	// System event IDs of the SimConnect manager
+	pauseEventID   = 100
+	crashedEventID = 103
+)
+
+const (
+	pauseCommand = "sim/operation/pause_toggle"
+	// freezeDataref stops the flight model of the user aircraft
+	freezeDataref = "sim/operation/override/override_planepath[0]"
+)
+
+// ParseAddr turns a configured X-Plane address into a UDP address. A bare
+// port is on this computer, e.g. "49000" becomes "127.0.0.1:49000"; a host
+// without a port gets DefaultPort.
+func ParseAddr(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return "", errors.New("no X-Plane address given")
+	}
+	if port, err := strconv.Atoi(s); err == nil {
+		if port <= 0 || port > 65535 {
+			return "", fmt.Errorf("invalid X-Plane port %d", port)
+		}
+		return net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), nil
+	}
+	host, port, err := net.SplitHostPort(s)
+	if err != nil {
+		return net.JoinHostPort(s, strconv.Itoa(DefaultPort)), nil
+	}
+	if _, err := strconv.Atoi(port); err != nil {
+		return "", fmt.Errorf("invalid X-Plane port %q", port)
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// Adapter is a telemetry.Source for X-Plane. It subscribes the
+// datarefs with RREF and decodes the replies and any DATA output sent to
+// its listen address. X-Plane is connected while packets arrive.
+type Adapter struct {
+	addr             string
+	listen           string
+	airplaneInterval time.Duration
+	logger           *logadapter.LogzWailsAdapter
+	bus              *telemetry.Bus
+
+	mu              sync.Mutex
+	decoder         Decoder
+	snapshot        telemetry.Snapshot
+	connected       bool
+	touchdown       bool
+	lastPacket      time.Time
+	lastAirplane    time.Time
+	lastEnvironment time.Time
+	conn            net.PacketConn
+	remote          *net.UDPAddr
+
+	stopCh  chan struct{}
+	stopped sync.WaitGroup
+}
+
+// New returns an adapter for X-Plane at addr, see ParseAddr, receiving on
+// DefaultListen. Call StartConnection to connect.
+func New(addr string) (*Adapter, error) {
+	addr, err := ParseAddr(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &Adapter{
+		addr:             addr,
+		listen:           DefaultListen,
+		airplaneInterval: time.Second,
+		bus:              telemetry.NewBus(),
+	}, nil
+}
+
+// SetLogger allows injection of a custom logger (Wails/go-logz adapter)
+func (a *Adapter) SetLogger(logger *logadapter.LogzWailsAdapter) {
+	a.logger = logger
+}
+
+// SetAirplaneInterval sets how often the airplane state is published, zero
+// publishes every packet, i.e. DefaultRate times a second
+func (a *Adapter) SetAirplaneInterval(d time.Duration) {
+	a.airplaneInterval = d
+}
+
+// Addr returns the X-Plane address
+func (a *Adapter) Addr() string {
+	return a.addr
+}
+
+// Name returns "X-Plane"
+func (a *Adapter) Name() string {
+	return "X-Plane"
+}
+
+// Bus returns the telemetry bus the adapter publishes to
+func (a *Adapter) Bus() *telemetry.Bus {
+	return a.bus
+}
+
+// StartConnection opens the listen socket and subscribes the datarefs until
+// StopConnection
+func (a *Adapter) StartConnection() {
+	a.stopCh = make(chan struct{})
+	a.stopped.Add(1)
+	go a.run(a.stopCh)
+}
+
+// StopConnection unsubscribes the datarefs and closes the socket
+func (a *Adapter) StopConnection() {
+	if a.stopCh == nil {
+		return
+	}
+	close(a.stopCh)
+	a.stopped.Wait()
+	a.stopCh = nil
+}
+
+// run opens the socket, retrying while the address is unavailable, and
+// serves it until stop is closed
+func (a *Adapter) run(stop chan struct{}) {
+	defer a.stopped.Done()
+	for {
+		remote, err := net.ResolveUDPAddr("udp", a.addr)
+		var conn net.PacketConn
+		if err == nil {
+			conn, err = net.ListenPacket("udp", a.listen)
+		}
+		if err == nil {
+			a.serve(conn, remote, stop)
+			return
+		}
+		a.logError("[X-Plane] Failed to open socket: ", err)
+		select {
+		case <-stop:
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+func (a *Adapter) serve(conn net.PacketConn, remote *net.UDPAddr, stop chan struct{}) {
+	a.mu.Lock()
+	a.conn, a.remote = conn, remote
+	a.mu.Unlock()
+	a.logInfo("[X-Plane] Receiving on ", conn.LocalAddr(), ", subscribing at ", a.addr)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		buf := make([]byte, maxPacketSize)
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				if !errors.Is(err, net.ErrClosed) {
+					a.logError("[X-Plane] Failed to receive: ", err)
+				}
+				return
+			}
+			if err := a.HandlePacket(buf[:n], time.Now()); err != nil {
+				a.logDebug("[X-Plane] Dropping packet: ", err)
+			}
+		}
+	}()
+
+	a.subscribe(DefaultRate)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	lastSubscribe := time.Now()
+	for {
+		select {
+		case <-stop:
+			a.subscribe(0)
+			a.mu.Lock()
+			a.conn, a.remote = nil, nil
+			a.mu.Unlock()
+			conn.Close()
+			<-done
+			a.setDisconnected()
+			return
+		case now := <-ticker.C:
+			a.checkTimeout(now)
+			if !a.Status() && now.Sub(lastSubscribe) >= resubscribeInterval {
+				a.subscribe(DefaultRate)
+				lastSubscribe = now
+			}
+		}
+	}
+}
+
+// subscribe requests every dataref at rate times a second, zero
+// unsubscribes
+func (a *Adapter) subscribe(rate int32) {
+	for i, ref := range datarefs {
+		packet, err := rrefRequest(rate, int32(i), ref.Name)
+		if err == nil {
+			err = a.send(packet)
+		}
+		if err != nil {
+			a.logDebug("[X-Plane] Failed to subscribe ", ref.Name, ": ", err)
+			return
+		}
+	}
+}
+
+// send writes a packet to X-Plane
+func (a *Adapter) send(packet []byte) error {
+	a.mu.Lock()
+	conn, remote := a.conn, a.remote
+	a.mu.Unlock()
+	if conn == nil {
+		return telemetry.ErrNotConnected
+	}
+	_, err := conn.WriteTo(packet, remote)
+	return err
+}
+
+// HandlePacket decodes an RREF or DATA packet received at now and publishes
+// the resulting states. It is called for every received packet and can
+// replay recorded packets without X-Plane.
+func (a *Adapter) HandlePacket(packet []byte, now time.Time) error {
+	a.mu.Lock()
+	if err := a.decoder.Decode(packet); err != nil {
+		a.mu.Unlock()
+		return err
+	}
+	a.lastPacket = now
+	connected := !a.connected
+	a.connected = true
+	if !a.decoder.Ready() {
+		a.mu.Unlock()
+		if connected {
+			a.publishConnected(true)
+		}
+		return nil
+	}
+
+	prev := a.snapshot
+	next := prev
+	next.Simulator = a.decoder.Simulator()
+	publishAirplane := now.Sub(a.lastAirplane) >= a.airplaneInterval
+	if publishAirplane {
+		next.Airplane = a.decoder.Airplane()
+		a.lastAirplane = now
+	}
+	publishEnvironment := now.Sub(a.lastEnvironment) >= environmentInterval
+	if publishEnvironment {
+		next.Environment = a.decoder.Environment(now)
+		a.lastEnvironment = now
+	}
+	changed := next.Airplane != prev.Airplane || next.Environment != prev.Environment || next.Simulator != prev.Simulator
+	if changed {
+		next.Version++
+		a.snapshot = next
+	}
+	var touchdown *telemetry.TouchdownState
+	if a.touchdown {
+		state := a.decoder.Touchdown()
+		touchdown = &state
+	}
+	a.mu.Unlock()
+
+	// Publish like the SimConnect manager: changed states only, the
+	// connection first and system events before the simulator state
+	if connected {
+		a.publishConnected(true)
+	}
+	if next.Simulator.Pause != prev.Simulator.Pause {
+		a.bus.Publish(telemetry.TopicSystemEvent, telemetry.SystemEvent{ID: pauseEventID, Name: "Pause", Data: uint32(next.Simulator.Pause)})
+	}
+	if next.Simulator.Crashed != prev.Simulator.Crashed && next.Simulator.Crashed != 0 {
+		a.bus.Publish(telemetry.TopicSystemEvent, telemetry.SystemEvent{ID: crashedEventID, Name: "Crashed", Data: 1})
+	}
+	if next.Simulator != prev.Simulator || connected {
+		a.bus.Publish(telemetry.TopicSimulator, next.Simulator)
+	}
+	if publishAirplane && (next.Airplane != prev.Airplane || connected) {
+		a.bus.Publish(telemetry.TopicAirplane, next.Airplane)
+	}
+	if publishEnvironment && (next.Environment != prev.Environment || connected) {
+		a.bus.Publish(telemetry.TopicEnvironment, next.Environment)
+	}
+	if touchdown != nil {
+		a.bus.Publish(telemetry.TopicTouchdown, *touchdown)
+	}
+	return nil
+}
+
+// checkTimeout disconnects X-Plane when no packet arrived within timeout
+func (a *Adapter) checkTimeout(now time.Time) {
+	a.mu.Lock()
+	expired := a.connected && now.Sub(a.lastPacket) > timeout
+	a.mu.Unlock()
+	if expired {
+		a.logInfo("[X-Plane] No data received for ", timeout, ", disconnected")
+		a.setDisconnected()
+	}
+}
+
+// setDisconnected forgets the decoded values so a restarted X-Plane starts
+// from a clean state
+func (a *Adapter) setDisconnected() {
+	a.mu.Lock()
+	was := a.connected
+	a.connected = false
+	a.decoder = Decoder{}
+	a.lastAirplane, a.lastEnvironment = time.Time{}, time.Time{}
+	a.mu.Unlock()
+	if was {
+		a.publishConnected(false)
+	}
+}
+
+func (a *Adapter) publishConnected(connected bool) {
+	a.bus.Publish(telemetry.TopicConnection, telemetry.ConnectionStatus{Connected: connected})
+}
+
+// Status reports whether X-Plane sends data
+func (a *Adapter) Status() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.connected
+}
+
+// Snapshot returns a consistent copy of all current states
+func (a *Adapter) Snapshot() telemetry.Snapshot {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.snapshot
+}
+
+// GetAirplaneState returns a copy of the current airplane state
+func (a *Adapter) GetAirplaneState() telemetry.AirplaneState {
+	return a.Snapshot().Airplane
+}
+
+// GetEnvironmentState returns a copy of the current environment state
+func (a *Adapter) GetEnvironmentState() telemetry.EnvironmentState {
+	return a.Snapshot().Environment
+}
+
+// GetSimulatorState returns a copy of the current simulator state
+func (a *Adapter) GetSimulatorState() telemetry.SimulatorState {
+	return a.Snapshot().Simulator
+}
+
+// TogglePause runs the pause command of X-Plane
+func (a *Adapter) TogglePause() {
+	if !a.Status() {
+		a.logDebug("[X-Plane] Cannot toggle pause while disconnected")
+		return
+	}
+	if err := a.send(command(pauseCommand)); err != nil {
+		a.logError("[X-Plane] Failed to toggle pause: ", err)
+	}
+}
+
+// SetAirplaneState moves the user aircraft to the position and attitude of
+// state. X-Plane sets no speeds this way, so freeze the aircraft first.
+func (a *Adapter) SetAirplaneState(state telemetry.AirplaneState) error {
+	if !a.Status() {
+		return telemetry.ErrNotConnected
+	}
+	return a.send(vehxPacket(state.Latitude, state.Longitude, state.Altitude/feetPerMeter,
+		float32(state.Heading), float32(-state.Pitch), float32(-state.Bank)))
+}
+
+// FreezeAircraft stops or resumes the flight model of the user aircraft
+func (a *Adapter) FreezeAircraft(frozen bool) error {
+	if !a.Status() {
+		return telemetry.ErrNotConnected
+	}
+	var value float32
+	if frozen {
+		value = 1
+	}
+	packet, err := drefPacket(freezeDataref, value)
+	if err != nil {
+		return err
+	}
+	return a.send(packet)
+}
+
+// RequestTouchdownData starts or stops publishing TouchdownState on
+// TopicTouchdown for every received packet
+func (a *Adapter) RequestTouchdownData(enabled bool) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.touchdown = enabled
+	return nil
+}
+
+func (a *Adapter) logInfo(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Info(fmt.Sprint(args...))
+	}
+}
+
+func (a *Adapter) logError(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Error(fmt.Sprint(args...))
+	}
+}
+
+func (a *Adapter) logDebug(args ...interface{}) {
+	if a.logger != nil {
+		a.logger.Debug(fmt.Sprint(args...))
+	}
+}
+
+var (
+	_ telemetry.Source          = (*Adapter)(nil)
+	_ telemetry.PlaybackTarget  = (*Adapter)(nil)
+	_ telemetry.TouchdownSource = (*Adapter)(nil)
+)
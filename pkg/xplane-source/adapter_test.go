@@ -0,0 +1,253 @@
+package xplanesource
+
+import (
+	"bufio"
+	"encoding/hex"
+	"errors"
+	"math"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+// readPackets reads the hex encoded packets of a capture, one per line
+func readPackets(t *testing.T, name string) [][]byte {
+	t.Helper()
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var packets [][]byte
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, maxPacketSize*2)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		packet, err := hex.DecodeString(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		packets = append(packets, packet)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return packets
+}
+
+// replay feeds the packets of a capture to a new adapter once a second, as
+// often as the environment is published, and returns the messages
+// published on its bus
+func replay(t *testing.T, packets [][]byte, start time.Time, seconds int) (*Adapter, []telemetry.Message) {
+	t.Helper()
+	a, err := New("49000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	a.SetAirplaneInterval(0)
+	sub := a.Bus().Subscribe(telemetry.SubscribeOptions{Buffer: 64, Policy: telemetry.Block})
+	defer sub.Close()
+	for second := 0; second < seconds; second++ {
+		now := start.Add(time.Duration(second) * time.Second)
+		for i, packet := range packets {
+			if err := a.HandlePacket(packet, now); err != nil {
+				t.Fatalf("packet %d: %v", i, err)
+			}
+		}
+	}
+	var messages []telemetry.Message
+	for {
+		select {
+		case msg := <-sub.C():
+			messages = append(messages, msg)
+		default:
+			return a, messages
+		}
+	}
+}
+
+type value struct {
+	name      string
+	got, want float64
+}
+
+func checkValues(t *testing.T, values []value) {
+	t.Helper()
+	for _, v := range values {
+		// Values arrive as float32
+		if math.Abs(v.got-v.want) > 1e-4*math.Max(1, math.Abs(v.want)) {
+			t.Errorf("%s = %v, want %v", v.name, v.got, v.want)
+		}
+	}
+}
+
+func TestReplayRREF(t *testing.T) {
+	start := time.Date(2026, 4, 11, 10, 0, 0, 0, time.UTC)
+	a, messages := replay(t, readPackets(t, "testdata/rref.hex"), start, 2)
+
+	if len(messages) == 0 || messages[0].Topic != telemetry.TopicConnection || !messages[0].Payload.(telemetry.ConnectionStatus).Connected {
+		t.Fatalf("first message %+v, want connected", messages[0])
+	}
+	if !a.Status() {
+		t.Error("Status() = false while receiving packets")
+	}
+	published := map[telemetry.Topic]int{}
+	for _, msg := range messages {
+		published[msg.Topic]++
+	}
+	for _, topic := range []telemetry.Topic{telemetry.TopicAirplane, telemetry.TopicEnvironment, telemetry.TopicSimulator} {
+		if published[topic] == 0 {
+			t.Errorf("nothing published on %s", topic)
+		}
+	}
+
+	airplane := a.GetAirplaneState()
+	if airplane.Title != "C172" {
+		t.Errorf("title = %q, want C172", airplane.Title)
+	}
+	checkValues(t, []value{
+		{"latitude", airplane.Latitude, 50.1},
+		{"longitude", airplane.Longitude, 14.26},
+		{"altitude", airplane.Altitude, 1200},
+		{"height above ground", airplane.AltAboveGround, 1000},
+		// X-Plane is positive nose up and right wing down, SimConnect is not
+		{"pitch", airplane.Pitch, -2.5},
+		{"bank", airplane.Bank, 10},
+		{"heading", airplane.Heading, 245},
+		{"magnetic heading", airplane.HeadingMagnetic, 241},
+		{"track", airplane.GroundTrack, 244},
+		{"indicated airspeed", airplane.Airspeed, 110},
+		{"true airspeed", airplane.AirspeedTrue, 60 * knotsPerMeterS},
+		{"ground speed", airplane.GroundVelocity, 58 * knotsPerMeterS},
+		{"vertical speed", airplane.VerticalSpeed, -500},
+		{"angle of attack", airplane.AngleOfAttack, 4},
+	})
+
+	simulator := a.GetSimulatorState()
+	want := telemetry.SimulatorState{Sim: 1, AircraftLoaded: "C172", SimulationRate: 1}
+	if simulator != want {
+		t.Errorf("simulator state = %+v, want %+v", simulator, want)
+	}
+
+	env := a.GetEnvironmentState()
+	if env.ZuluTime != 36000 || env.LocalTime != 43200 || env.TimeZoneOffset != -7200 {
+		t.Errorf("zulu %d, local %d, offset %d, want 36000, 43200, -7200", env.ZuluTime, env.LocalTime, env.TimeZoneOffset)
+	}
+	// Day 100 after January 1st
+	if env.LocalYear != 2026 || env.LocalMonth != 4 || env.LocalDay != 11 {
+		t.Errorf("local date %d-%d-%d, want 2026-4-11", env.LocalYear, env.LocalMonth, env.LocalDay)
+	}
+	if env.TimeOfDay != timeOfDayDay {
+		t.Errorf("time of day = %d, want day", env.TimeOfDay)
+	}
+	checkValues(t, []value{
+		{"sea level pressure", env.SeaLevelPressure, 29.92},
+		{"temperature", env.AmbientTemperature, 15},
+		{"wind direction", env.AmbientWindDirection, 270},
+		{"wind speed", env.AmbientWindVelocity, 5 * knotsPerMeterS},
+		{"visibility", env.AmbientVisibility, 10000},
+	})
+}
+
+func TestReplayDATA(t *testing.T) {
+	a, _ := replay(t, readPackets(t, "testdata/data.hex"), time.Now(), 1)
+	airplane := a.GetAirplaneState()
+	if airplane.Title != defaultTitle {
+		t.Errorf("title = %q, want %q", airplane.Title, defaultTitle)
+	}
+	checkValues(t, []value{
+		{"latitude", airplane.Latitude, 47.5},
+		{"longitude", airplane.Longitude, -122.3},
+		{"altitude", airplane.Altitude, 2500},
+		{"height above ground", airplane.AltAboveGround, 2000},
+		{"pitch", airplane.Pitch, 3},
+		{"bank", airplane.Bank, -15},
+		{"heading", airplane.Heading, 90},
+		{"magnetic heading", airplane.HeadingMagnetic, 87},
+		{"indicated airspeed", airplane.Airspeed, 95},
+		{"true airspeed", airplane.AirspeedTrue, 100},
+		{"ground speed", airplane.GroundVelocity, 98},
+		{"vertical speed", airplane.VerticalSpeed, -700},
+	})
+	if a.GetSimulatorState().OnGround {
+		t.Error("on ground at 2000 ft above ground")
+	}
+}
+
+func TestReplayTouchdown(t *testing.T) {
+	a, err := New("49000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := a.Bus().Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{telemetry.TopicTouchdown}, Buffer: 8, Policy: telemetry.Block})
+	defer sub.Close()
+	if err := a.RequestTouchdownData(true); err != nil {
+		t.Fatal(err)
+	}
+	for _, packet := range readPackets(t, "testdata/data.hex") {
+		if err := a.HandlePacket(packet, time.Now()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case msg := <-sub.C():
+		td := msg.Payload.(telemetry.TouchdownState)
+		checkValues(t, []value{
+			{"vertical speed", td.VerticalSpeed, -700},
+			{"g-force", td.GForce, 1.1},
+			{"pitch", td.Pitch, 3},
+		})
+	default:
+		t.Fatal("no touchdown state published")
+	}
+}
+
+func TestHandlePacketErrors(t *testing.T) {
+	a, err := New("49000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rref := readPackets(t, "testdata/rref.hex")[0]
+	for _, c := range []struct {
+		name   string
+		packet []byte
+		want   error
+	}{
+		{"empty", nil, ErrUnknownPacket},
+		{"unknown label", []byte("BECN\x00\x01\x02\x03"), ErrUnknownPacket},
+		{"truncated RREF", rref[:len(rref)-3], ErrShortPacket},
+		{"truncated DATA", append([]byte("DATA*"), make([]byte, dataRecordSize-1)...), ErrShortPacket},
+	} {
+		if err := a.HandlePacket(c.packet, time.Now()); !errors.Is(err, c.want) {
+			t.Errorf("%s: error %v, want %v", c.name, err, c.want)
+		}
+	}
+	if a.Status() {
+		t.Error("connected after invalid packets only")
+	}
+}
+
+func TestTimeoutDisconnects(t *testing.T) {
+	start := time.Now()
+	a, _ := replay(t, readPackets(t, "testdata/rref.hex"), start, 2)
+	sub := a.Bus().Subscribe(telemetry.SubscribeOptions{Topics: []telemetry.Topic{telemetry.TopicConnection}, Buffer: 1, Policy: telemetry.Block})
+	defer sub.Close()
+
+	a.checkTimeout(start.Add(timeout))
+	if !a.Status() {
+		t.Fatal("disconnected before the timeout")
+	}
+	a.checkTimeout(start.Add(2 * timeout))
+	if a.Status() {
+		t.Fatal("still connected after the timeout")
+	}
+	if msg := <-sub.C(); msg.Payload.(telemetry.ConnectionStatus).Connected {
+		t.Error("published connected on timeout")
+	}
+}
@@ -0,0 +1,150 @@
+package xplanesource
+
+// field is a value kept by the Decoder, in the units of the state structs
+type field int
+
+const (
+	fieldLatitude field = iota
+	fieldLongitude
+	fieldAltitude       // feet
+	fieldAltAboveGround // feet
+	fieldPitch          // degrees, positive nose up
+	fieldRoll           // degrees, positive right wing down
+	fieldHeading        // true
+	fieldHeadingMagnetic
+	fieldTrack // true
+	fieldAirspeed
+	fieldAirspeedTrue
+	fieldGroundSpeed
+	fieldVerticalSpeed // feet per minute
+	fieldAngleOfAttack
+	fieldGForce
+	fieldOnGround
+	fieldOnRunway
+	fieldCrashed
+	fieldParkingBrake // ratio
+	fieldEngineRunning
+	fieldPaused
+	fieldSimSpeed
+	fieldZuluTime  // seconds since midnight
+	fieldLocalTime // seconds since midnight
+	fieldLocalDate // days since January 1st
+	fieldFlightTime
+	fieldSeaLevelPressure // inHg
+	fieldTemperature      // celsius
+	fieldWindDirection    // true
+	fieldWindSpeed        // knots
+	fieldVisibility       // meters
+	fieldSunPitch         // degrees above the horizon
+	fieldICAO             // first of icaoLength characters
+	fieldCount            = fieldICAO + icaoLength
+)
+
+// icaoLength is the number of characters of the ICAO type designator read
+// from X-Plane
+const icaoLength = 8
+
+const (
+	feetPerMeter   = 1 / 0.3048
+	knotsPerMeterS = 3600 / 1852.0
+	secondsPerHour = 3600
+)
+
+// dataref is a subscribed dataref, scaled into a field
+type dataref struct {
+	Name  string
+	Field field
+	Scale float64 // multiplies the value, 0 keeps it
+}
+
+// datarefs are subscribed with RREF, the index of a dataref is its RREF
+// index
+var datarefs = append([]dataref{
+	{Name: "sim/flightmodel/position/latitude", Field: fieldLatitude},
+	{Name: "sim/flightmodel/position/longitude", Field: fieldLongitude},
+	{Name: "sim/flightmodel/position/elevation", Field: fieldAltitude, Scale: feetPerMeter},
+	{Name: "sim/flightmodel/position/y_agl", Field: fieldAltAboveGround, Scale: feetPerMeter},
+	{Name: "sim/flightmodel/position/theta", Field: fieldPitch},
+	{Name: "sim/flightmodel/position/phi", Field: fieldRoll},
+	{Name: "sim/flightmodel/position/psi", Field: fieldHeading},
+	{Name: "sim/flightmodel/position/mag_psi", Field: fieldHeadingMagnetic},
+	{Name: "sim/flightmodel/position/hpath", Field: fieldTrack},
+	{Name: "sim/flightmodel/position/indicated_airspeed", Field: fieldAirspeed},
+	{Name: "sim/flightmodel/position/true_airspeed", Field: fieldAirspeedTrue, Scale: knotsPerMeterS},
+	{Name: "sim/flightmodel/position/groundspeed", Field: fieldGroundSpeed, Scale: knotsPerMeterS},
+	{Name: "sim/flightmodel/position/vh_ind_fpm", Field: fieldVerticalSpeed},
+	{Name: "sim/flightmodel/position/alpha", Field: fieldAngleOfAttack},
+	{Name: "sim/flightmodel/forces/g_nrml", Field: fieldGForce},
+	{Name: "sim/flightmodel/failures/onground_any", Field: fieldOnGround},
+	{Name: "sim/flightmodel2/misc/has_crashed", Field: fieldCrashed},
+	{Name: "sim/cockpit2/controls/parking_brake_ratio", Field: fieldParkingBrake},
+	{Name: "sim/flightmodel/engine/ENGN_running[0]", Field: fieldEngineRunning},
+	{Name: "sim/time/paused", Field: fieldPaused},
+	{Name: "sim/time/sim_speed", Field: fieldSimSpeed},
+	{Name: "sim/time/zulu_time_sec", Field: fieldZuluTime},
+	{Name: "sim/time/local_time_sec", Field: fieldLocalTime},
+	{Name: "sim/time/local_date_days", Field: fieldLocalDate},
+	{Name: "sim/time/total_flight_time_sec", Field: fieldFlightTime},
+	{Name: "sim/weather/barometer_sealevel_inhg", Field: fieldSeaLevelPressure},
+	{Name: "sim/weather/temperature_ambient_c", Field: fieldTemperature},
+	{Name: "sim/weather/wind_direction_degt", Field: fieldWindDirection},
+	// Despite its name the wind speed is in meters per second
+	{Name: "sim/weather/wind_speed_kt", Field: fieldWindSpeed, Scale: knotsPerMeterS},
+	{Name: "sim/weather/visibility_reported_m", Field: fieldVisibility},
+	{Name: "sim/graphics/scenery/sun_pitch_degrees", Field: fieldSunPitch},
+}, icaoDatarefs()...)
+
+func icaoDatarefs() []dataref {
+	refs := make([]dataref, icaoLength)
+	for i := range refs {
+		refs[i] = dataref{Name: "sim/aircraft/view/acf_ICAO[" + string(rune('0'+i)) + "]", Field: fieldICAO + field(i)}
+	}
+	return refs
+}
+
+// dataSlot maps a value of a DATA record to a field
+type dataSlot struct {
+	Slot  int
+	Field field
+	Scale float64 // multiplies the value, 0 keeps it
+}
+
+// dataGroups maps the DATA output groups, selected in X-Plane's Data Output
+// settings, to fields. The groups are numbered as in X-Plane 11 and 12.
+var dataGroups = map[int32][]dataSlot{
+	1: { // times
+		{Slot: 5, Field: fieldZuluTime, Scale: secondsPerHour},
+		{Slot: 6, Field: fieldLocalTime, Scale: secondsPerHour},
+	},
+	3: { // speeds
+		{Slot: 0, Field: fieldAirspeed},
+		{Slot: 2, Field: fieldAirspeedTrue},
+		{Slot: 3, Field: fieldGroundSpeed},
+	},
+	4: { // Mach, VVI, g-load
+		{Slot: 2, Field: fieldVerticalSpeed},
+		{Slot: 4, Field: fieldGForce},
+	},
+	5: { // weather
+		{Slot: 0, Field: fieldSeaLevelPressure},
+		{Slot: 3, Field: fieldWindSpeed},
+		{Slot: 4, Field: fieldWindDirection},
+	},
+	17: { // pitch, roll, headings
+		{Slot: 0, Field: fieldPitch},
+		{Slot: 1, Field: fieldRoll},
+		{Slot: 2, Field: fieldHeading},
+		{Slot: 3, Field: fieldHeadingMagnetic},
+	},
+	18: { // angle of attack, sideslip, paths
+		{Slot: 0, Field: fieldAngleOfAttack},
+		{Slot: 2, Field: fieldTrack},
+	},
+	20: { // latitude, longitude, altitude
+		{Slot: 0, Field: fieldLatitude},
+		{Slot: 1, Field: fieldLongitude},
+		{Slot: 2, Field: fieldAltitude},
+		{Slot: 3, Field: fieldAltAboveGround},
+		{Slot: 4, Field: fieldOnRunway},
+	},
+}
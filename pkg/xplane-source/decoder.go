@@ -0,0 +1,239 @@
+package xplanesource
+
+import (
+	"strings"
+	"time"
+
+	"github.com/mycrew-online/flight-data-recorder/pkg/telemetry"
+)
+
+const (
+	// groundHeight decides on ground from the height above ground when
+	// only DATA packets are received, which do not report it
+	groundHeight = 3.0
+	// nightSunPitch is the end of civil twilight
+	nightSunPitch = -6.0
+	secondsPerDay = 24 * 60 * 60
+	// defaultTitle names the aircraft until X-Plane reports its type
+	defaultTitle = "X-Plane"
+)
+
+// TimeOfDay values of EnvironmentState, as reported by SimConnect
+const (
+	timeOfDayDawn  = 0
+	timeOfDayDay   = 1
+	timeOfDayDusk  = 2
+	timeOfDayNight = 3
+)
+
+// Decoder keeps the latest values of RREF and DATA packets and maps them to
+// the telemetry states. Pitch and bank follow SimConnect, negative nose up
+// and right wing down. It is not safe for concurrent use.
+type Decoder struct {
+	values [fieldCount]float64
+	seen   [fieldCount]bool
+}
+
+// Decode applies an RREF or DATA packet. Values it does not know are
+// ignored.
+func (d *Decoder) Decode(packet []byte) error {
+	switch label(packet) {
+	case labelRREF:
+		values, err := parseRREF(packet)
+		if err != nil {
+			return err
+		}
+		for _, v := range values {
+			if v.Index < 0 || int(v.Index) >= len(datarefs) {
+				continue
+			}
+			ref := datarefs[v.Index]
+			d.set(ref.Field, float64(v.Value), ref.Scale)
+		}
+	case labelDATA:
+		records, err := parseDATA(packet)
+		if err != nil {
+			return err
+		}
+		for _, r := range records {
+			for _, slot := range dataGroups[r.Group] {
+				if r.Values[slot.Slot] == dataUnused {
+					continue
+				}
+				d.set(slot.Field, float64(r.Values[slot.Slot]), slot.Scale)
+			}
+		}
+	default:
+		return ErrUnknownPacket
+	}
+	return nil
+}
+
+func (d *Decoder) set(f field, v, scale float64) {
+	if scale != 0 {
+		v *= scale
+	}
+	d.values[f] = v
+	d.seen[f] = true
+}
+
+// Ready reports whether the position was received
+func (d *Decoder) Ready() bool {
+	return d.seen[fieldLatitude] && d.seen[fieldLongitude]
+}
+
+func (d *Decoder) onGround() bool {
+	if d.seen[fieldOnGround] {
+		return d.values[fieldOnGround] != 0
+	}
+	return d.seen[fieldAltAboveGround] && d.values[fieldAltAboveGround] < groundHeight
+}
+
+// title returns the ICAO type designator of the aircraft
+func (d *Decoder) title() string {
+	var b strings.Builder
+	for i := 0; i < icaoLength; i++ {
+		c := byte(d.values[fieldICAO+field(i)])
+		if c == 0 {
+			break
+		}
+		b.WriteByte(c)
+	}
+	if title := strings.TrimSpace(b.String()); title != "" {
+		return title
+	}
+	return defaultTitle
+}
+
+// Airplane returns the airplane state. X-Plane has no equivalent of the NAV1
+// deviations in degrees, so the localizer and glide slope are not reported.
+func (d *Decoder) Airplane() telemetry.AirplaneState {
+	v := &d.values
+	return telemetry.AirplaneState{
+		Title:           d.title(),
+		Latitude:        v[fieldLatitude],
+		Longitude:       v[fieldLongitude],
+		Altitude:        v[fieldAltitude],
+		Heading:         v[fieldHeading],
+		HeadingMagnetic: v[fieldHeadingMagnetic],
+		Airspeed:        v[fieldAirspeed],
+		Bank:            -v[fieldRoll],
+		AltAboveGround:  v[fieldAltAboveGround],
+		Pitch:           -v[fieldPitch],
+		VerticalSpeed:   telemetry.RoundVerticalSpeed(v[fieldVerticalSpeed]),
+		GroundVelocity:  v[fieldGroundSpeed],
+		GroundTrack:     v[fieldTrack],
+		AirspeedTrue:    v[fieldAirspeedTrue],
+		AngleOfAttack:   v[fieldAngleOfAttack],
+	}
+}
+
+// Touchdown returns the touchdown state
+func (d *Decoder) Touchdown() telemetry.TouchdownState {
+	v := &d.values
+	return telemetry.TouchdownState{
+		Latitude:       v[fieldLatitude],
+		Longitude:      v[fieldLongitude],
+		AltAboveGround: v[fieldAltAboveGround],
+		VerticalSpeed:  v[fieldVerticalSpeed],
+		GForce:         v[fieldGForce],
+		Pitch:          -v[fieldPitch],
+		Bank:           -v[fieldRoll],
+		Heading:        v[fieldHeading],
+		GroundVelocity: v[fieldGroundSpeed],
+		Airspeed:       v[fieldAirspeed],
+		OnGround:       d.onGround(),
+	}
+}
+
+// Simulator returns the simulator state. An aircraft on the ground with the
+// parking brake set and the first engine off is reported in parking state.
+func (d *Decoder) Simulator() telemetry.SimulatorState {
+	v := &d.values
+	s := telemetry.SimulatorState{
+		Sim:            1,
+		AircraftLoaded: d.title(),
+		SimulationRate: 1,
+		OnGround:       d.onGround(),
+	}
+	if v[fieldPaused] != 0 {
+		s.Pause = 1
+	}
+	if v[fieldCrashed] != 0 {
+		s.Crashed = 1
+	}
+	if d.seen[fieldSimSpeed] {
+		s.SimulationRate = v[fieldSimSpeed]
+	}
+	if v[fieldOnRunway] != 0 {
+		s.OnAnyRunway = 1
+	}
+	if s.OnGround && v[fieldParkingBrake] >= 0.5 && d.seen[fieldEngineRunning] && v[fieldEngineRunning] == 0 {
+		s.InParkingState = 1
+	}
+	return s
+}
+
+// Environment returns the environment state. X-Plane reports the day of the
+// year only, the year is taken from now; without the day the date of now is
+// used.
+func (d *Decoder) Environment(now time.Time) telemetry.EnvironmentState {
+	v := &d.values
+	zulu := int(v[fieldZuluTime])
+	local := int(v[fieldLocalTime])
+	// Zulu minus local time, positive west like SimConnect
+	offset := wrapDay(zulu - local)
+
+	localDate := time.Date(now.Year(), 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, int(v[fieldLocalDate]))
+	if !d.seen[fieldLocalDate] {
+		localDate = now.Add(-time.Duration(offset) * time.Second)
+		localDate = time.Date(localDate.Year(), localDate.Month(), localDate.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	localTime := localDate.Add(time.Duration(local) * time.Second)
+	zuluTime := localTime.Add(time.Duration(offset) * time.Second)
+
+	e := telemetry.EnvironmentState{
+		ZuluTime:             int32(zulu),
+		LocalTime:            int32(local),
+		SimTime:              int32(v[fieldFlightTime]),
+		ZuluDay:              int32(zuluTime.Day()),
+		ZuluMonth:            int32(zuluTime.Month()),
+		ZuluYear:             int32(zuluTime.Year()),
+		LocalDay:             int32(localTime.Day()),
+		LocalMonth:           int32(localTime.Month()),
+		LocalYear:            int32(localTime.Year()),
+		ZuluDayOfWeek:        int32(zuluTime.Weekday()),
+		LocalDayOfWeek:       int32(localTime.Weekday()),
+		SeaLevelPressure:     v[fieldSeaLevelPressure],
+		AmbientTemperature:   v[fieldTemperature],
+		AmbientWindDirection: v[fieldWindDirection],
+		AmbientWindVelocity:  v[fieldWindSpeed],
+		AmbientVisibility:    v[fieldVisibility],
+		TimeZoneOffset:       int32(offset),
+		TimeOfDay:            timeOfDayDay,
+	}
+	if d.seen[fieldSunPitch] {
+		switch sun := v[fieldSunPitch]; {
+		case sun >= 0:
+			e.TimeOfDay = timeOfDayDay
+		case sun < nightSunPitch:
+			e.TimeOfDay = timeOfDayNight
+		case local < secondsPerDay/2:
+			e.TimeOfDay = timeOfDayDawn
+		default:
+			e.TimeOfDay = timeOfDayDusk
+		}
+	}
+	return e
+}
+
+// wrapDay keeps a time difference in seconds within half a day
+func wrapDay(seconds int) int {
+	seconds %= secondsPerDay
+	if seconds > secondsPerDay/2 {
+		seconds -= secondsPerDay
+	} else if seconds < -secondsPerDay/2 {
+		seconds += secondsPerDay
+	}
+	return seconds
+}
@@ -0,0 +1,151 @@
+// Package xplanesource reads the user aircraft from X-Plane 11 and 12 over
+// the X-Plane UDP protocol and publishes it on a telemetry bus, so
+// recording, exports and analysis work unchanged. Datarefs are
+// subscribed with RREF; the legacy DATA output is decoded as well.
+package xplanesource
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Packet labels, followed by one byte on the wire
+const (
+	labelRREF = "RREF"
+	labelDATA = "DATA"
+	labelCMND = "CMND"
+	labelDREF = "DREF"
+	labelVEHX = "VEHX"
+)
+
+const (
+	headerSize = 5
+	// rrefPathSize and drefPathSize are the fixed path lengths of RREF
+	// requests and DREF packets
+	rrefPathSize = 400
+	drefPathSize = 500
+	// rrefValueSize is an index and a value of an RREF reply
+	rrefValueSize = 8
+	// dataRecordSize is a group index and eight values of a DATA packet
+	dataRecordSize = 36
+	// dataUnused marks the unused values of a DATA record
+	dataUnused = -999
+)
+
+var (
+	ErrUnknownPacket = errors.New("unknown X-Plane packet")
+	ErrShortPacket   = errors.New("truncated X-Plane packet")
+)
+
+// rrefValue is one dataref value of an RREF reply
+type rrefValue struct {
+	Index int32
+	Value float32
+}
+
+// dataRecord is one group of a DATA packet
+type dataRecord struct {
+	Group  int32
+	Values [8]float32
+}
+
+// label returns the label of a packet, empty when it is too short
+func label(packet []byte) string {
+	if len(packet) < headerSize {
+		return ""
+	}
+	return string(packet[:4])
+}
+
+// parseRREF returns the values of an RREF reply
+func parseRREF(packet []byte) ([]rrefValue, error) {
+	if label(packet) != labelRREF {
+		return nil, ErrUnknownPacket
+	}
+	body := packet[headerSize:]
+	if len(body)%rrefValueSize != 0 {
+		return nil, ErrShortPacket
+	}
+	values := make([]rrefValue, 0, len(body)/rrefValueSize)
+	for ; len(body) > 0; body = body[rrefValueSize:] {
+		values = append(values, rrefValue{
+			Index: int32(binary.LittleEndian.Uint32(body)),
+			Value: math.Float32frombits(binary.LittleEndian.Uint32(body[4:])),
+		})
+	}
+	return values, nil
+}
+
+// parseDATA returns the records of a DATA packet
+func parseDATA(packet []byte) ([]dataRecord, error) {
+	if label(packet) != labelDATA {
+		return nil, ErrUnknownPacket
+	}
+	body := packet[headerSize:]
+	if len(body)%dataRecordSize != 0 {
+		return nil, ErrShortPacket
+	}
+	records := make([]dataRecord, 0, len(body)/dataRecordSize)
+	for ; len(body) > 0; body = body[dataRecordSize:] {
+		r := dataRecord{Group: int32(binary.LittleEndian.Uint32(body))}
+		for i := range r.Values {
+			r.Values[i] = math.Float32frombits(binary.LittleEndian.Uint32(body[4+4*i:]))
+		}
+		records = append(records, r)
+	}
+	return records, nil
+}
+
+func header(label string, size int) []byte {
+	packet := make([]byte, headerSize, headerSize+size)
+	copy(packet, label)
+	return packet
+}
+
+// path appends a zero padded dataref path of size bytes
+func path(packet []byte, name string, size int) ([]byte, error) {
+	if len(name) >= size {
+		return nil, fmt.Errorf("dataref %q is too long", name)
+	}
+	field := make([]byte, size)
+	copy(field, name)
+	return append(packet, field...), nil
+}
+
+// rrefRequest subscribes dataref name as index at frequency times a second,
+// a frequency of zero unsubscribes
+func rrefRequest(frequency, index int32, name string) ([]byte, error) {
+	packet := header(labelRREF, 8+rrefPathSize)
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(frequency))
+	packet = binary.LittleEndian.AppendUint32(packet, uint32(index))
+	return path(packet, name, rrefPathSize)
+}
+
+// command runs an X-Plane command, e.g. sim/operation/pause_toggle
+func command(name string) []byte {
+	return append(header(labelCMND, len(name)), name...)
+}
+
+// drefPacket sets a writable dataref
+func drefPacket(name string, value float32) ([]byte, error) {
+	packet := header(labelDREF, 4+drefPathSize)
+	packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(value))
+	return path(packet, name, drefPathSize)
+}
+
+// vehxPacket places aircraft 0, the user aircraft, at a position in degrees
+// and meters and an attitude in degrees, positive nose up and right wing
+// down
+func vehxPacket(latitude, longitude, elevation float64, heading, pitch, roll float32) []byte {
+	packet := header(labelVEHX, 40)
+	packet = binary.LittleEndian.AppendUint32(packet, 0)
+	for _, v := range []float64{latitude, longitude, elevation} {
+		packet = binary.LittleEndian.AppendUint64(packet, math.Float64bits(v))
+	}
+	for _, v := range []float32{heading, pitch, roll} {
+		packet = binary.LittleEndian.AppendUint32(packet, math.Float32bits(v))
+	}
+	return packet
+}
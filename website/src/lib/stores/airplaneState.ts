@@ -15,8 +15,14 @@ export interface AirplaneState {
   pitch: number;
   vertical_speed: number; // Changed to number for consistency
   ground_velocity: number; // Changed to number for consistency
+  ground_track: number;
   airpeed_true: number; // Changed to number for consistency
   angle_of_attack: number; // Changed to number for consistency
+  nav_has_localizer: boolean;
+  nav_localizer_course: number;
+  nav_radial_error: number;
+  nav_has_glide_slope: boolean;
+  nav_glide_slope_error: number;
   // Add other properties as needed from your backend
 }
 
@@ -0,0 +1,51 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import { GetLastApproachReport } from '$lib/wailsjs/go/internal/App';
+
+export interface ApproachCheck {
+  name: string;
+  value: number;
+  min?: number;
+  max?: number;
+  pass: boolean;
+}
+
+export interface ApproachGate {
+  height: number;
+  applicable: boolean;
+  reached: boolean;
+  time: string;
+  stable: boolean;
+  checks: ApproachCheck[] | null;
+}
+
+export interface ApproachReport {
+  start: string;
+  end: string;
+  outcome: 'landed' | 'go-around';
+  conditions: 'imc' | 'vmc';
+  target_speed: number;
+  target_source: '' | 'configured' | 'threshold';
+  gates: ApproachGate[];
+  stable: boolean;
+  minimum_agl: number;
+  latitude: number;
+  longitude: number;
+}
+
+export const approachReport = writable<ApproachReport | null>(null);
+
+// Initialize with backend status
+GetLastApproachReport().then((report: ApproachReport | null) => {
+  approachReport.set(report);
+});
+
+EventsOn('approach::report', (report: ApproachReport) => {
+  approachReport.set(report);
+});
+
+// Names of the failed checks at the applicable gate
+export function failedChecks(report: ApproachReport): string[] {
+  const gate = report.gates.find((g) => g.applicable);
+  return (gate?.checks ?? []).filter((c) => !c.pass).map((c) => c.name.replace('_', ' '));
+}
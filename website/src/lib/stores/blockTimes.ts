@@ -0,0 +1,38 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import { GetCurrentBlockTimes } from '$lib/wailsjs/go/internal/App';
+
+export interface BlockStamp {
+  utc: string;
+  sim_zulu: string;
+}
+
+export interface BlockTimes {
+  out?: BlockStamp;
+  off?: BlockStamp;
+  on?: BlockStamp;
+  in?: BlockStamp;
+  block_seconds: number;
+  air_seconds: number;
+  sim_block_seconds: number;
+  sim_air_seconds: number;
+}
+
+export const blockTimes = writable<BlockTimes | null>(null);
+
+// Initialize with backend status
+GetCurrentBlockTimes().then((times: BlockTimes) => {
+  blockTimes.set(times);
+});
+
+EventsOn('flight::block-times', (times: BlockTimes) => {
+  blockTimes.set(times);
+});
+
+// Formats a stamp as HH:MMZ in simulator Zulu time, falling back to real UTC
+export function formatBlockStamp(stamp: BlockStamp | undefined): string {
+  if (!stamp) return '--:--';
+  const zulu = stamp.sim_zulu && !stamp.sim_zulu.startsWith('0001-') ? stamp.sim_zulu : stamp.utc;
+  const d = new Date(zulu);
+  return `${d.getUTCHours().toString().padStart(2, '0')}:${d.getUTCMinutes().toString().padStart(2, '0')}Z`;
+}
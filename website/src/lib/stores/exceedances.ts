@@ -0,0 +1,36 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+
+export type Severity = 'low' | 'medium' | 'high';
+
+export interface Exceedance {
+  rule_id: string;
+  name: string;
+  severity: Severity;
+  channel: string;
+  threshold: number;
+  peak: number;
+  peak_time: string;
+  start: string;
+  end: string;
+  seconds: number;
+  latitude: number;
+  longitude: number;
+  altitude: number;
+}
+
+// Number of finished exceedances kept for display
+const maxExceedances = 5;
+
+// Most recent exceedances of this session, newest first
+export const exceedances = writable<Exceedance[]>([]);
+
+EventsOn('flight::exceedance', (event: Exceedance) => {
+  exceedances.update((list) => [event, ...list].slice(0, maxExceedances));
+});
+
+export const severityClasses: Record<Severity, string> = {
+  low: 'bg-yellow-50 text-yellow-800',
+  medium: 'bg-orange-50 text-orange-800',
+  high: 'bg-red-50 text-red-800'
+};
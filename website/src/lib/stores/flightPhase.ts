@@ -0,0 +1,47 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import { GetFlightPhase } from '$lib/wailsjs/go/internal/App';
+
+export type FlightPhase =
+  | ''
+  | 'parked'
+  | 'pushback'
+  | 'taxi-out'
+  | 'takeoff-roll'
+  | 'rejected-takeoff'
+  | 'initial-climb'
+  | 'climb'
+  | 'cruise'
+  | 'descent'
+  | 'approach'
+  | 'go-around'
+  | 'landing-roll'
+  | 'taxi-in';
+
+export interface PhaseChange {
+  time: string;
+  from: FlightPhase;
+  to: FlightPhase;
+  latitude: number;
+  longitude: number;
+  altitude: number;
+}
+
+export const flightPhase = writable<FlightPhase>('');
+
+// Initialize with backend status
+GetFlightPhase().then((status: { phase: FlightPhase }) => {
+  flightPhase.set(status.phase);
+});
+
+EventsOn('flight::phase', (change: PhaseChange) => {
+  flightPhase.set(change.to);
+});
+
+export function phaseLabel(phase: FlightPhase): string {
+  if (!phase) {
+    return 'Unknown';
+  }
+  const label = phase.replace(/-/g, ' ');
+  return label.charAt(0).toUpperCase() + label.slice(1);
+}
@@ -0,0 +1,32 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import { GetLastLandingReport } from '$lib/wailsjs/go/internal/App';
+
+export interface LandingReport {
+  time: string;
+  latitude: number;
+  longitude: number;
+  heading: number;
+  vertical_speed: number;
+  peak_g: number;
+  pitch: number;
+  bank: number;
+  ground_speed: number;
+  airspeed: number;
+  float_distance: number;
+  float_seconds: number;
+  bounces: number;
+  touch_and_go: boolean;
+  frames: number;
+}
+
+export const landingReport = writable<LandingReport | null>(null);
+
+// Initialize with backend status
+GetLastLandingReport().then((report: LandingReport | null) => {
+  landingReport.set(report);
+});
+
+EventsOn('landing::report', (report: LandingReport) => {
+  landingReport.set(report);
+});
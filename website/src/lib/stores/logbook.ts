@@ -0,0 +1,74 @@
+import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import { ExportLogbook, GetLogbook, GetLogbookTotals, ImportLogbook } from '$lib/wailsjs/go/internal/App';
+
+export interface LogbookEntry {
+  id: string;
+  date: string;
+  departure: string;
+  arrival: string;
+  aircraft: string;
+  out?: string;
+  off?: string;
+  on?: string;
+  in?: string;
+  block_minutes: number;
+  air_minutes: number;
+  night_minutes: number;
+  day_landings: number;
+  night_landings: number;
+  remarks: string;
+  imported: boolean;
+}
+
+export interface LogbookTotal {
+  flights: number;
+  block_minutes: number;
+  air_minutes: number;
+  night_minutes: number;
+  day_landings: number;
+  night_landings: number;
+}
+
+export interface LogbookGroupTotal extends LogbookTotal {
+  key: string;
+}
+
+export interface LogbookTotals {
+  lifetime: LogbookTotal;
+  per_aircraft: LogbookGroupTotal[] | null;
+  per_month: LogbookGroupTotal[] | null;
+}
+
+export const logbookEntries = writable<LogbookEntry[]>([]);
+export const logbookTotals = writable<LogbookTotals | null>(null);
+
+export async function refreshLogbook() {
+  const [entries, totals] = await Promise.all([GetLogbook(), GetLogbookTotals()]);
+  logbookEntries.set(entries ?? []);
+  logbookTotals.set(totals);
+}
+
+// Returns the written path, empty when the dialog was cancelled
+export async function exportLogbook(): Promise<string> {
+  return ExportLogbook('');
+}
+
+// Returns the number of imported entries
+export async function importLogbook(): Promise<number> {
+  const count = await ImportLogbook('');
+  await refreshLogbook();
+  return count;
+}
+
+// Formats minutes as H:MM
+export function formatMinutes(minutes: number): string {
+  return `${Math.floor(minutes / 60)}:${(minutes % 60).toString().padStart(2, '0')}`;
+}
+
+refreshLogbook();
+
+// A stopped recording adds its flight to the logbook
+EventsOn('recording::status', (status: { recording: boolean }) => {
+  if (!status.recording) refreshLogbook();
+});
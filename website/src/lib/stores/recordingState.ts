@@ -1,17 +1,74 @@
 import { writable } from 'svelte/store';
+import { EventsOn } from '$lib/wailsjs/runtime/runtime';
+import type { BlockTimes } from '$lib/stores/blockTimes';
+import { GetRecordingStatus, GetRecoveredRecordings, StartRecording, StopRecording } from '$lib/wailsjs/go/internal/App';
 
 export type RecordingState = 'idle' | 'recording' | 'stopping';
 
+export interface RecordingStatus {
+  recording: boolean;
+  id?: string;
+  started_at: string;
+  sample_count: number;
+  aircraft_title?: string;
+}
+
+export interface RecordingSummary {
+  id: string;
+  started_at: string;
+  stopped_at: string;
+  duration_seconds: number;
+  sample_count: number;
+  aircraft_title: string;
+  recovered: boolean;
+  reports?: { kind: string; time: string; data: unknown }[];
+  block_times?: BlockTimes;
+}
+
 export const recordingState = writable<RecordingState>('idle');
+export const lastRecording = writable<RecordingSummary | null>(null);
+// Recordings restored on startup after a crash or forced shutdown
+export const recoveredRecordings = writable<RecordingSummary[]>([]);
+
+// Initialize with backend status
+GetRecordingStatus().then((status: RecordingStatus) => {
+  recordingState.set(status.recording ? 'recording' : 'idle');
+});
 
-export function startRecording() {
-  recordingState.set('recording');
-  // TODO: Call backend to start recording
+GetRecoveredRecordings().then((list: RecordingSummary[] | null) => {
+  recoveredRecordings.set(list ?? []);
+});
+
+EventsOn('recording::status', (status: RecordingStatus) => {
+  recordingState.set(status.recording ? 'recording' : 'idle');
+});
+
+export async function startRecording() {
+  try {
+    await StartRecording();
+    recordingState.set('recording');
+  } catch (err) {
+    console.error('Failed to start recording', err);
+    recordingState.set('idle');
+  }
 }
 
-export function stopRecording() {
+export async function stopRecording() {
   recordingState.set('stopping');
-  // TODO: Call backend to stop recording
-  // Simulate stop after delay for UI feedback
-  setTimeout(() => recordingState.set('idle'), 1000);
+  try {
+    const summary: RecordingSummary = await StopRecording();
+    lastRecording.set(summary);
+  } catch (err) {
+    console.error('Failed to stop recording', err);
+  }
+  recordingState.set('idle');
+}
+
+export function toggleRecording(state: RecordingState) {
+  if (state === 'recording') {
+    return stopRecording();
+  }
+  if (state === 'idle') {
+    return startRecording();
+  }
 }